@@ -3,3 +3,6 @@ package db
 import "fmt"
 
 var ErrNoMatch = fmt.Errorf("no matching record")
+
+// postgres error code of a unique constraint violation
+const uniqueViolation = "23505"
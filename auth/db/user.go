@@ -1,13 +1,16 @@
 package db
 
 import (
+	er "backend/auth/errors"
 	"backend/utils"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"io"
 
-	"github.com/go-playground/validator"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type User struct {
@@ -18,22 +21,12 @@ type User struct {
 	CreatedAt string    `json:"-"`
 }
 
-func (p *User) FromJSON(r io.Reader) error {
-	e := json.NewDecoder(r)
-	return e.Decode(p)
-}
-
-func (user *User) Validate() error {
-	validate := validator.New()
-	return validate.Struct(user)
-}
-
 func (user *User) ToJSON(w io.Writer) error {
 	e := json.NewEncoder(w)
 	return e.Encode(user)
 }
 
-func (db *UsersDb) AddUser(user *User) error {
+func (db *UsersDb) AddUser(ctx context.Context, user *User) error {
 	hash, err := utils.Hash(user.Password)
 	if err != nil {
 		return err
@@ -41,7 +34,11 @@ func (db *UsersDb) AddUser(user *User) error {
 	user.Password = hash
 	var id uuid.UUID
 	query := `INSERT INTO users (username, email, password) VALUES ($1, $2, $3) RETURNING id`
-	err = db.Conn.QueryRow(query, user.Username, user.Email, user.Password).Scan(&id)
+	err = db.Conn.QueryRowContext(ctx, query, user.Username, user.Email, user.Password).Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return er.ErrEmailTaken
+	}
 	if err != nil {
 		return err
 	}
@@ -49,10 +46,10 @@ func (db *UsersDb) AddUser(user *User) error {
 	return nil
 }
 
-func (db *UsersDb) GetUserById(userId int) (User, error) {
+func (db *UsersDb) GetUserById(ctx context.Context, userId int) (User, error) {
 	user := User{}
 	query := `SELECT * FROM users WHERE id = $1;`
-	row := db.Conn.QueryRow(query, userId)
+	row := db.Conn.QueryRowContext(ctx, query, userId)
 	switch err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Password); err {
 	case sql.ErrNoRows:
 		return user, ErrNoMatch
@@ -61,9 +58,9 @@ func (db *UsersDb) GetUserById(userId int) (User, error) {
 	}
 }
 
-func (db *UsersDb) DeleteUser(userId int) error {
+func (db *UsersDb) DeleteUser(ctx context.Context, userId int) error {
 	query := `DELETE FROM users WHERE id = $1;`
-	_, err := db.Conn.Exec(query, userId)
+	_, err := db.Conn.ExecContext(ctx, query, userId)
 	switch err {
 	case sql.ErrNoRows:
 		return ErrNoMatch
@@ -72,14 +69,14 @@ func (db *UsersDb) DeleteUser(userId int) error {
 	}
 }
 
-func (db *UsersDb) UpdateUser(user *User) error {
+func (db *UsersDb) UpdateUser(ctx context.Context, user *User) error {
 	hash, err := utils.Hash(user.Password)
 	if err != nil {
 		return err
 	}
 	user.Password = hash
 	query := `UPDATE users SET username = $1, email = $2, password = $3 WHERE id = $4;`
-	_, err = db.Conn.Exec(query, user.Username, user.Email, user.Password, user.Id)
+	_, err = db.Conn.ExecContext(ctx, query, user.Username, user.Email, user.Password, user.Id)
 	switch err {
 	case sql.ErrNoRows:
 		return ErrNoMatch
@@ -88,14 +85,14 @@ func (db *UsersDb) UpdateUser(user *User) error {
 	}
 }
 
-func (db *UsersDb) GetUser(email, password string) (User, error) {
+func (db *UsersDb) GetUser(ctx context.Context, email, password string) (User, error) {
 	user := User{}
 	dbpass, err := utils.Hash(password)
 	if err != nil {
 		return user, err
 	}
 	query := `SELECT * FROM users WHERE email = $1 AND password = $2;`
-	row := db.Conn.QueryRow(query, email, dbpass)
+	row := db.Conn.QueryRowContext(ctx, query, email, dbpass)
 	switch err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.CreatedAt); err {
 	case sql.ErrNoRows:
 		return user, ErrNoMatch
@@ -103,3 +100,27 @@ func (db *UsersDb) GetUser(email, password string) (User, error) {
 		return user, err
 	}
 }
+
+// Get users matching any of the given emails or ids. Passwords are not loaded.
+func (db *UsersDb) LookupUsers(ctx context.Context, emails []string, ids []uuid.UUID) ([]User, error) {
+	idStrs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		idStrs = append(idStrs, id.String())
+	}
+	query := `SELECT id, username, email FROM users WHERE email = ANY($1) OR id = ANY($2::uuid[]);`
+	rows, err := db.Conn.QueryContext(ctx, query, pq.Array(emails), pq.Array(idStrs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		user := User{}
+		err := rows.Scan(&user.Id, &user.Username, &user.Email)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
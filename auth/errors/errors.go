@@ -0,0 +1,11 @@
+package errors
+
+import (
+	"backend/utils"
+	"net/http"
+)
+
+// Errors of the auth service. Codes are part of the API, clients match on them, so they
+// must not change once released.
+var ErrInvalidCredentials = utils.NewAPIError("invalid_credentials", http.StatusUnauthorized, "invalid email or password")
+var ErrEmailTaken = utils.NewAPIError("email_taken", http.StatusConflict, "a user with this email already exists")
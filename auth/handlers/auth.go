@@ -2,22 +2,24 @@ package handlers
 
 import (
 	db "backend/auth/db"
+	er "backend/auth/errors"
+	"backend/logging"
+	"backend/metrics"
+	mw "backend/middlewares"
 	utils "backend/utils"
-	"encoding/json"
-	"io"
-	"log"
 	"net/http"
 
-	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 )
 
 type Auth struct {
-	l  *log.Logger
+	l  logrus.FieldLogger
 	db *db.UsersDb
 }
 
 // NewAuth creates a new auth handler with the given logger
-func NewAuth(l *log.Logger, db *db.UsersDb) *Auth {
+func NewAuth(l logrus.FieldLogger, db *db.UsersDb) *Auth {
 	return &Auth{l, db}
 }
 
@@ -27,19 +29,15 @@ type registerRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-func (req *registerRequest) FromJSON(r io.Reader) error {
-	e := json.NewDecoder(r)
-	return e.Decode(req)
-}
-
 type loginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
 }
 
-func (req *loginRequest) FromJSON(r io.Reader) error {
-	e := json.NewDecoder(r)
-	return e.Decode(req)
+// Answer to a successful login or registration
+type tokenResponse struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
 }
 
 /**
@@ -53,45 +51,39 @@ func (req *loginRequest) FromJSON(r io.Reader) error {
 
 // Register a new user
 func (auth *Auth) Register(rw http.ResponseWriter, r *http.Request) {
-	auth.l.Println("Handle POST Login")
+	logging.ForRequest(r, auth.l).Println("Handle POST Login")
 
 	req := registerRequest{}
-	err := req.FromJSON(r.Body)
-	if err != nil {
-		http.Error(rw, "Unable to unmarshal json", http.StatusBadRequest)
-		return
-	}
-
-	//validate the request
-	validate := validator.New()
-	err = validate.Struct(req)
-	if err != nil {
-		auth.l.Println("Error validating user", err)
-		utils.WriteFailureResponse(rw, http.StatusBadRequest, err.Error())
+	if !utils.DecodeAndValidate(rw, r, &req) {
 		return
 	}
 
 	newUser := &db.User{Username: req.Username, Email: req.Email, Password: req.Password}
-	err = auth.db.AddUser(newUser)
+	err := auth.db.AddUser(r.Context(), newUser)
 	if err != nil {
-		auth.l.Println("Error adding user", err)
-		utils.WriteFailureResponse(rw, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, auth.l).Println("Error adding user", err)
+		if err == er.ErrEmailTaken {
+			metrics.Registrations.WithLabelValues("email_taken").Inc()
+		} else {
+			metrics.Registrations.WithLabelValues("error").Inc()
+		}
+		utils.WriteError(rw, r, err)
 		return
 	}
 
-	auth.l.Printf("New user with %s added successfully", newUser.Id.String())
+	logging.ForRequest(r, auth.l).Printf("New user with %s added successfully", newUser.Id.String())
 
-	res := make(map[string]interface{})
-	res["id"] = newUser.Id.String()
-	res["token"], err = utils.GenerateJWT(newUser.Id.String(), "samudai-dash", "samudai-auth")
+	token, err := utils.GenerateJWT(newUser.Id.String(), "samudai-dash", "samudai-auth")
 
 	if err != nil {
-		auth.l.Println("Error generating token", err)
-		utils.WriteFailureResponse(rw, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, auth.l).Println("Error generating token", err)
+		metrics.Registrations.WithLabelValues("error").Inc()
+		utils.WriteError(rw, r, err)
 		return
 	}
 
-	utils.WriteSuccessResponse(rw, http.StatusOK, res)
+	metrics.Registrations.WithLabelValues("success").Inc()
+	utils.WriteSuccessResponse(rw, http.StatusOK, tokenResponse{ID: newUser.Id.String(), Token: token})
 }
 
 /**
@@ -104,53 +96,82 @@ func (auth *Auth) Register(rw http.ResponseWriter, r *http.Request) {
 
 // Login handles the login request
 func (auth *Auth) Login(rw http.ResponseWriter, r *http.Request) {
-	auth.l.Println("Handle POST Login")
+	logging.ForRequest(r, auth.l).Println("Handle POST Login")
 
 	req := loginRequest{}
-	err := req.FromJSON(r.Body)
-	if err != nil {
-		http.Error(rw, "Unable to unmarshal json", http.StatusBadRequest)
+	if !utils.DecodeAndValidate(rw, r, &req) {
 		return
 	}
 
-	//validate the request
-	validate := validator.New()
-	err = validate.Struct(req)
+	user, err := auth.db.GetUser(r.Context(), req.Email, req.Password)
 	if err != nil {
-		auth.l.Println("Error validating user", err)
-		utils.WriteFailureResponse(rw, http.StatusBadRequest, err.Error())
+		logging.ForRequest(r, auth.l).Println("Error getting user", err)
+		if err == db.ErrNoMatch {
+			metrics.LoginAttempts.WithLabelValues("invalid_credentials").Inc()
+			utils.WriteError(rw, r, er.ErrInvalidCredentials)
+		} else {
+			metrics.LoginAttempts.WithLabelValues("error").Inc()
+			utils.WriteError(rw, r, err)
+		}
 		return
 	}
 
-	user, err := auth.db.GetUser(req.Email, req.Password)
-	if err == db.ErrNoMatch {
-		auth.l.Println("Error getting user", err)
-		if err == db.ErrNoMatch {
-			utils.WriteFailureResponse(rw, http.StatusUnauthorized, err.Error())
-		} else {
-			utils.WriteFailureResponse(rw, http.StatusInternalServerError, err.Error())
+	logging.ForRequest(r, auth.l).Printf("User with %d found successfully", user.Id)
 
-		}
+	token, err := utils.GenerateJWT(user.Id.String(), "samudai-dash", "samudai-auth")
+
+	if err != nil {
+		logging.ForRequest(r, auth.l).Println("Error generating token", err)
+		metrics.LoginAttempts.WithLabelValues("error").Inc()
+		utils.WriteError(rw, r, err)
 		return
 	}
 
-	auth.l.Printf("User with %d found successfully", user.Id)
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
+	utils.WriteSuccessResponse(rw, http.StatusOK, tokenResponse{ID: user.Id.String(), Token: token})
+}
+
+/**
+ * @api {post} /refresh Refresh token
+ * @apiName Trade a valid token for a new one with a fresh expiry
+ * @apiGroup Auth
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+// Refresh issues a new token to the holder of a valid one, as long as their user still exists
+func (auth *Auth) Refresh(rw http.ResponseWriter, r *http.Request) {
+	logging.ForRequest(r, auth.l).Println("Handle POST Refresh")
+
+	userId, err := mw.GetUserIDFromVars(r)
+	if err != nil {
+		utils.WriteError(rw, r, utils.ErrInvalidToken)
+		return
+	}
 
-	res := make(map[string]interface{})
-	res["id"] = user.Id.String()
-	res["token"], err = utils.GenerateJWT(user.Id.String(), "samudai-dash", "samudai-auth")
+	users, err := auth.db.LookupUsers(r.Context(), nil, []uuid.UUID{userId})
+	if err != nil {
+		logging.ForRequest(r, auth.l).Println("Error looking up user", err)
+		utils.WriteError(rw, r, err)
+		return
+	}
+	if len(users) == 0 {
+		//the user was deleted after the token was issued
+		utils.WriteError(rw, r, utils.ErrInvalidToken)
+		return
+	}
 
+	token, err := utils.GenerateJWT(userId.String(), "samudai-dash", "samudai-auth")
 	if err != nil {
-		auth.l.Println("Error generating token", err)
-		utils.WriteFailureResponse(rw, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, auth.l).Println("Error generating token", err)
+		utils.WriteError(rw, r, err)
 		return
 	}
 
-	utils.WriteSuccessResponse(rw, http.StatusOK, res)
+	utils.WriteSuccessResponse(rw, http.StatusOK, tokenResponse{ID: userId.String(), Token: token})
 }
 
 func (auth *Auth) Test(rw http.ResponseWriter, r *http.Request) {
-	auth.l.Println("Handle Test")
+	logging.ForRequest(r, auth.l).Println("Handle Test")
 
 	utils.WriteSuccessResponse(rw, http.StatusOK, "Test")
 }
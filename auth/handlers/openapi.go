@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"backend/openapi"
+	"net/http"
+)
+
+// Routes of the auth service as described in its OpenAPI document. Keep in step with the
+// router, a test fails when a routed path is missing here.
+func Routes() []openapi.Route {
+	return []openapi.Route{
+		{Method: http.MethodPost, Path: "/register", ID: "Register", Tag: "Auth", Public: true, Summary: "Register a user and get a token for them",
+			Body: registerRequest{}, Data: tokenResponse{}},
+		{Method: http.MethodPost, Path: "/login", ID: "Login", Tag: "Auth", Public: true, Summary: "Log in with email and password",
+			Body: loginRequest{}, Data: tokenResponse{}},
+		{Method: http.MethodPost, Path: "/refresh", ID: "Refresh", Tag: "Auth", Summary: "Trade a valid token for a new one with a fresh expiry",
+			Data: tokenResponse{}},
+		{Method: http.MethodGet, Path: "/test", ID: "Test", Tag: "Auth", Public: true, Summary: "Check the service answers",
+			Data: ""},
+		{Method: http.MethodPost, Path: "/users/lookup", ID: "LookupUsers", Tag: "Auth", Summary: "Resolve users by email or id. Unknown entries are left out.",
+			Body: lookupUsersRequest{}, Data: []userInfo{}},
+	}
+}
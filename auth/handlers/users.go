@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"backend/logging"
+	utils "backend/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type lookupUsersRequest struct {
+	Emails []string    `json:"emails" validate:"max=500,dive,email"`
+	Ids    []uuid.UUID `json:"ids" validate:"max=500"`
+}
+
+type userInfo struct {
+	Id       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+}
+
+/**
+ * @api {post} /users/lookup Lookup users
+ * @apiName Resolve users by email or id
+ * @apiGroup Auth
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiBody {String[]} [emails] Emails to resolve
+ * @apiBody {String[]} [ids] User ids to resolve
+ */
+
+// LookupUsers resolves emails and ids to users. Unknown entries are left out of the result.
+func (auth *Auth) LookupUsers(rw http.ResponseWriter, r *http.Request) {
+	logging.ForRequest(r, auth.l).Println("Handle POST LookupUsers")
+
+	req := lookupUsersRequest{}
+	if !utils.DecodeAndValidate(rw, r, &req) {
+		return
+	}
+
+	users, err := auth.db.LookupUsers(r.Context(), req.Emails, req.Ids)
+	if err != nil {
+		logging.ForRequest(r, auth.l).Println("Error looking up users", err)
+		utils.WriteError(rw, r, err)
+		return
+	}
+
+	res := make([]userInfo, 0, len(users))
+	for _, user := range users {
+		res = append(res, userInfo{user.Id, user.Username, user.Email})
+	}
+
+	utils.WriteSuccessResponse(rw, http.StatusOK, res)
+}
@@ -3,62 +3,90 @@ package main
 import (
 	db "backend/auth/db"
 	"backend/auth/handlers"
-	mw "backend/middlewares"
+	"backend/config"
+	"backend/health"
+	"backend/logging"
+	"backend/metrics"
+	"backend/tracing"
+	"backend/utils"
 	"context"
+	"flag"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
-	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	//getting environment variables
-	port := os.Getenv("PORT")
-	dbUser, dbPassword, dbName :=
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB")
+	//settings from the config file, env and flags
+	cfg := config.DefaultAuth()
+	err := config.Load(&cfg, os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		logging.New("auth-service", logrus.InfoLevel).Fatalf("Could not load config: %v", err)
+	}
 
 	//logger to inject to use across services
-	logger := log.New(os.Stdout, "auth-service ", log.LstdFlags)
-	bindAddress := fmt.Sprintf(":%s", port)
+	logger := logging.New("auth-service", cfg.Telemetry.LogLevel)
+	logger.WithField("config", config.Redacted(cfg)).Info("Loaded config")
+	bindAddress := fmt.Sprintf(":%d", cfg.Server.Port)
+	utils.ConfigureJWT(cfg.JWT.Secret, cfg.JWT.Expiry)
+
+	//tracing, spans are exported as set in config
+	shutdownTracing, err := tracing.Init(context.Background(), "auth-service", cfg.Telemetry.TracesExporter)
+	if err != nil {
+		logger.Fatalf("Could not set up tracing: %v", err)
+	}
+
+	//postgres may still be starting, it is retried for up to the startup timeout
+	startupCtx, cancelStartup := context.WithTimeout(context.Background(), cfg.Lifecycle.StartupTimeout)
+	defer cancelStartup()
 
 	//database init
-	database, err := db.Initialize(dbUser, dbPassword, dbName)
+	database, err := db.Initialize(startupCtx, cfg.Database, logger)
 	if err != nil {
 		logger.Fatalf("Could not set up database: %v", err)
 	}
 	//close database connection on service end
 	defer database.Conn.Close()
 
+	//prometheus metrics
+	registry := metrics.NewRegistry(database.Conn, cfg.Database.Name)
+	registry.MustRegister(metrics.LoginAttempts)
+	registry.MustRegister(metrics.Registrations)
+
+	//readiness covers every dependency needed to serve requests
+	checker := health.NewChecker(cfg.Lifecycle.HealthCheckTimeout)
+	checker.Add("postgres", health.Postgres(database.Conn))
+	checker.Add("migrations", health.Migrations(database.Conn, database.Version))
+
 	//create handlers
 	authHandler := handlers.NewAuth(logger, &database)
 
-	//serve mux
-	serveMux := mux.NewRouter()
-	serveMux.Use(mw.JSONContentHeaders)
-	serveMux.HandleFunc("/register", authHandler.Register).Methods("POST")
-	serveMux.HandleFunc("/login", authHandler.Login).Methods("POST")
-	serveMux.HandleFunc("/test", authHandler.Test).Methods("GET")
+	router := newRouter(logger, cfg.Server.TrustedProxies, routeHandlers{
+		checker: checker,
+		metrics: metrics.Handler(registry),
+		auth:    authHandler,
+	})
 
 	// create a new server
 	server := http.Server{
-		Addr:         bindAddress,       // configure the bind address
-		Handler:      serveMux,          // set the default handler
-		ErrorLog:     logger,            // set the logger for the server
-		ReadTimeout:  5 * time.Second,   // max time to read request from the client
-		WriteTimeout: 10 * time.Second,  // max time to write response to the client
-		IdleTimeout:  120 * time.Second, // max time for connections using TCP Keep-Alive
+		Addr:         bindAddress,               // configure the bind address
+		Handler:      router,                    // set the default handler
+		ErrorLog:     logging.StdLogger(logger), // set the logger for the server
+		ReadTimeout:  cfg.Server.ReadTimeout,    // max time to read request from the client
+		WriteTimeout: cfg.Server.WriteTimeout,   // max time to write response to the client
+		IdleTimeout:  cfg.Server.IdleTimeout,    // max time for connections using TCP Keep-Alive
 	}
 
 	// start the server
 	go func() {
-		logger.Printf("Starting server on port %s", port)
+		logger.Printf("Starting server on port %d", cfg.Server.Port)
 
 		err := server.ListenAndServe()
 		if err != nil {
@@ -74,11 +102,13 @@ func main() {
 
 	// Block until a signal is received.
 	sig := <-stopChannel
-	log.Println("Got signal:", sig)
-	log.Println("Shutting down gracefully.")
+	logger.Println("Got signal:", sig)
+	logger.Println("Shutting down gracefully.")
 
-	// gracefully shutdown the server, waiting max 30 seconds for current operations to complete
-	ctx, cancelFunc := context.WithTimeout(context.Background(), 30*time.Second)
+	// gracefully shutdown the server, waiting for current operations to complete
+	ctx, cancelFunc := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	server.Shutdown(ctx)
+	//flush spans still waiting to be exported
+	shutdownTracing(ctx)
 	cancelFunc()
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"backend/auth/handlers"
+	"backend/health"
+	mw "backend/middlewares"
+	"backend/openapi"
+	"backend/tracing"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Everything the routes of the service are served by
+type routeHandlers struct {
+	checker *health.Checker
+	metrics http.Handler
+	auth    *handlers.Auth
+}
+
+// Routes of the service. Every route must also be in apiSpec.
+func newRouter(logger logrus.FieldLogger, trustedProxies []mw.IPNet, h routeHandlers) *mux.Router {
+	serveMux := mux.NewRouter()
+	serveMux.Use(tracing.Middleware("auth-service"))
+	serveMux.Use(mw.RealIP(trustedProxies))
+	serveMux.Use(mw.RequestID(logger))
+	serveMux.Use(mw.AccessLog)
+	serveMux.Use(mw.Metrics)
+	serveMux.Use(mw.JSONContentHeaders)
+	serveMux.HandleFunc("/healthz", h.checker.Live).Methods("GET")
+	serveMux.HandleFunc("/readyz", h.checker.Ready).Methods("GET")
+	serveMux.Handle("/metrics", h.metrics).Methods("GET")
+	serveMux.Handle("/openapi.json", apiSpec().Handler()).Methods("GET")
+	serveMux.HandleFunc("/register", h.auth.Register).Methods("POST")
+	serveMux.HandleFunc("/login", h.auth.Login).Methods("POST")
+	serveMux.HandleFunc("/test", h.auth.Test).Methods("GET")
+
+	//routes that need a valid token
+	authR := serveMux.NewRoute().Subrouter()
+	authR.Use(mw.AuthMiddleware)
+	authR.HandleFunc("/users/lookup", h.auth.LookupUsers).Methods("POST")
+	authR.HandleFunc("/refresh", h.auth.Refresh).Methods("POST")
+
+	return serveMux
+}
+
+// OpenAPI document of the service
+func apiSpec() *openapi.Document {
+	doc := openapi.New("Auth service", "1.0.0", "Registration, login and user lookup. Errors carry a stable code, send Accept: application/problem+json to get them as RFC 7807 problem details.")
+	doc.Add(openapi.ServiceRoutes()...)
+	doc.Add(handlers.Routes()...)
+	return doc
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// Asks for a role on a dashboard
+func (c *Client) RequestAccess(ctx context.Context, id uuid.UUID, role, justification string) (*models.AccessRequest, error) {
+	body := map[string]interface{}{"role": role, "justification": justification}
+	req := &models.AccessRequest{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: dashPath(id, "/access-requests"), body: body}, req, nil)
+	return req, err
+}
+
+// Access requests for a dashboard in the given state, pending ones when status is empty
+func (c *Client) GetAccessRequests(ctx context.Context, id uuid.UUID, status string) ([]*models.AccessRequest, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	reqs := []*models.AccessRequest{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: dashPath(id, "/access-requests"), query: q}, &reqs, nil)
+	return reqs, err
+}
+
+// Access requests you made
+func (c *Client) GetMyAccessRequests(ctx context.Context) ([]*models.AccessRequest, error) {
+	reqs := []*models.AccessRequest{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/access-requests"}, &reqs, nil)
+	return reqs, err
+}
+
+// Grants the requester a role on the dashboard
+func (c *Client) ApproveAccessRequest(ctx context.Context, id uuid.UUID, in Approval) (*models.AccessRequest, error) {
+	req := &models.AccessRequest{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/access-requests/" + id.String() + "/approve", body: in}, req, nil)
+	return req, err
+}
+
+// Turns down a request for access, with an optional note to the requester
+func (c *Client) DenyAccessRequest(ctx context.Context, id uuid.UUID, note *string) (*models.AccessRequest, error) {
+	body := map[string]interface{}{"note": note}
+	req := &models.AccessRequest{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/access-requests/" + id.String() + "/deny", body: body}, req, nil)
+	return req, err
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Answer of the auth service to a login, registration or refresh
+type AuthResponse struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
+// Creates a user and logs in as them, returning their id
+func (c *Client) Register(ctx context.Context, username, email, password string) (uuid.UUID, error) {
+	body := map[string]string{"username": username, "email": email, "password": password}
+	res := &AuthResponse{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/register", body: body, session: true}, res, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	err = c.setSession(res, email, password)
+	return c.UserID(), err
+}
+
+// Logs in, returning the id of the user. The credentials are kept to log in again when
+// the token is rejected.
+func (c *Client) Login(ctx context.Context, email, password string) (uuid.UUID, error) {
+	body := map[string]string{"email": email, "password": password}
+	res := &AuthResponse{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/login", body: body, session: true}, res, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	err = c.setSession(res, email, password)
+	return c.UserID(), err
+}
+
+// Trades the token for a new one with a fresh expiry. Calls do this on their own when
+// the token is about to expire.
+func (c *Client) Refresh(ctx context.Context) error {
+	res := &AuthResponse{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/refresh", session: true}, res, nil)
+	if err != nil {
+		return err
+	}
+	return c.setSession(res, "", "")
+}
+
+// Resolves emails and ids to users, unknown ones are left out
+func (c *Client) LookupUsers(ctx context.Context, emails []string, ids []uuid.UUID) ([]*models.User, error) {
+	body := map[string]interface{}{"emails": emails, "ids": ids}
+	users := []*models.User{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/users/lookup", body: body}, &users, nil)
+	return users, err
+}
@@ -0,0 +1,310 @@
+package client
+
+import (
+	"backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+// Client of the auth and dashboard APIs, both reached through the gateway at BaseURL.
+// Logging in or registering keeps the token, which is sent with every request and
+// refreshed before it expires. Safe for concurrent use once configured.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	//bounds each call other than event streams, none when zero
+	Timeout time.Duration
+	//attempts of GET, PUT and DELETE calls that fail with a network error or a 429, 502, 503 or 504
+	MaxAttempts int
+	Backoff     utils.Backoff
+	//tokens expiring sooner than this are refreshed before a call
+	RefreshBefore time.Duration
+
+	//held while checking and renewing the token so concurrent calls renew it once
+	refreshMu sync.Mutex
+	mu        sync.Mutex
+	token     string
+	userID    uuid.UUID
+	//kept to log in again when a token is rejected
+	email, password string
+}
+
+// Creates a client of the gateway at baseURL, such as http://localhost:8080
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:       strings.TrimRight(baseURL, "/"),
+		HTTPClient:    &http.Client{},
+		Timeout:       30 * time.Second,
+		MaxAttempts:   3,
+		Backoff:       utils.Backoff{Initial: 200 * time.Millisecond, Max: 2 * time.Second},
+		RefreshBefore: time.Minute,
+	}
+}
+
+// Token sent with calls, empty before logging in
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// Id of the user logged in
+func (c *Client) UserID() uuid.UUID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.userID
+}
+
+// Use token for calls, such as one obtained elsewhere. It cannot be renewed by logging
+// in again once it is rejected.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.email, c.password = "", ""
+}
+
+// A call to the API
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   interface{}
+	//streams are not bounded by Timeout
+	stream bool
+	//calls that set up the session are not refreshed or retried after logging in again
+	session bool
+}
+
+// Body of every response other than raw ones, see utils.WriteSuccessResponse and utils.WriteError
+type envelope struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Meta    json.RawMessage `json:"meta"`
+	Error   string          `json:"error"`
+	Code    string          `json:"code"`
+	Problem *utils.Problem  `json:"problem"`
+	Message string          `json:"message"`
+}
+
+// Makes the call and decodes the data and meta of the response into data and meta, each
+// skipped when nil
+func (c *Client) do(ctx context.Context, req *request, data, meta interface{}) error {
+	if !req.stream && c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	env := envelope{}
+	err = json.NewDecoder(resp.Body).Decode(&env)
+	if err != nil {
+		return fmt.Errorf("%s %s: decoding response: %w", req.method, req.path, err)
+	}
+	if data != nil && len(env.Data) > 0 {
+		err = json.Unmarshal(env.Data, data)
+		if err != nil {
+			return fmt.Errorf("%s %s: decoding data: %w", req.method, req.path, err)
+		}
+	}
+	if meta != nil && len(env.Meta) > 0 {
+		err = json.Unmarshal(env.Meta, meta)
+		if err != nil {
+			return fmt.Errorf("%s %s: decoding meta: %w", req.method, req.path, err)
+		}
+	}
+	return nil
+}
+
+// Sends the call with the token, refreshing it first when it is about to expire and
+// logging in again once when it is rejected. Responses other than 2xx are returned as
+// an *Error, the body of others is left to the caller to read and close.
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	if !req.session {
+		err := c.refreshIfExpiring(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.sendWithRetries(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && !req.session && c.canLogin() {
+		apiErr := readError(resp)
+		if apiErr.Code != utils.ErrInvalidToken.Code {
+			return nil, apiErr
+		}
+		err = c.relogin(ctx)
+		if err != nil {
+			return nil, apiErr
+		}
+		resp, err = c.sendWithRetries(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode >= 300 {
+		return nil, readError(resp)
+	}
+	return resp, nil
+}
+
+// Idempotent calls are sent again after failures that may not last
+func (c *Client) sendWithRetries(ctx context.Context, req *request) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attempts := 1
+	switch req.method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+		attempts = c.MaxAttempts
+	}
+
+	wait := c.Backoff.Initial
+	for attempt := 1; ; attempt++ {
+		resp, err := c.sendOnce(ctx, req, body)
+		if attempt >= attempts || ctx.Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+
+		pause := wait
+		if resp != nil {
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				pause = time.Duration(secs) * time.Second
+			}
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(pause)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		wait *= 2
+		if wait > c.Backoff.Max {
+			wait = c.Backoff.Max
+		}
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (c *Client) sendOnce(ctx context.Context, req *request, body []byte) (*http.Response, error) {
+	u := c.BaseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.Token(); token != "" {
+		httpReq.Header.Set("Authorization", token)
+	}
+	return c.HTTPClient.Do(httpReq)
+}
+
+// Renews the token when it expires within RefreshBefore. Tokens that cannot be read are
+// left for the server to judge.
+func (c *Client) refreshIfExpiring(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	token := c.Token()
+	if token == "" || c.RefreshBefore <= 0 {
+		return nil
+	}
+	claims := jwt.MapClaims{}
+	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	if err != nil {
+		return nil
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Until(time.Unix(int64(exp), 0)) > c.RefreshBefore {
+		return nil
+	}
+	if time.Now().After(time.Unix(int64(exp), 0)) {
+		//too late to refresh, logging in again is all that is left
+		if c.canLogin() {
+			return c.relogin(ctx)
+		}
+		return nil
+	}
+	return c.Refresh(ctx)
+}
+
+func (c *Client) canLogin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.email != ""
+}
+
+func (c *Client) relogin(ctx context.Context) error {
+	c.mu.Lock()
+	email, password := c.email, c.password
+	c.mu.Unlock()
+	_, err := c.Login(ctx, email, password)
+	return err
+}
+
+func (c *Client) setSession(res *AuthResponse, email, password string) error {
+	id, err := uuid.Parse(res.ID)
+	if err != nil {
+		return fmt.Errorf("invalid user id %q: %w", res.ID, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = res.Token
+	c.userID = id
+	if email != "" {
+		c.email, c.password = email, password
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+var userId = uuid.MustParse("11111111-1111-4111-8111-111111111111")
+
+// Client of srv that does not wait between attempts
+func newTestClient(srv *httptest.Server) *Client {
+	c := New(srv.URL + "/")
+	c.Backoff = utils.Backoff{Initial: time.Millisecond, Max: time.Millisecond}
+	return c
+}
+
+func serve(t *testing.T, h http.Handler) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// Token expiring in d, the client reads it without checking its signature
+func tokenExpiringIn(t *testing.T, d time.Duration) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": time.Now().Add(d).Unix()}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestListDashboards(t *testing.T) {
+	folder := uuid.New()
+	srv := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "tok" {
+			t.Errorf("Authorization %q, want tok", got)
+		}
+		want := fmt.Sprintf("cursor=c1&folder=%s&limit=5&order=desc&q=sales&tag=a&tag=b&views=false", folder)
+		if r.URL.Path != "/dashboard" || r.URL.RawQuery != want {
+			t.Errorf("got %s?%s, want /dashboard?%s", r.URL.Path, r.URL.RawQuery, want)
+		}
+		utils.WriteSuccessResponseWithMeta(w, http.StatusOK, []*models.Dash{{ID: uuid.New(), Name: "sales"}}, utils.PageMeta{NextCursor: "c2", Total: 6})
+	}))
+	c := newTestClient(srv)
+	c.SetToken("tok")
+
+	page, err := c.ListDashboards(context.Background(), ListOptions{
+		Query: "sales", Tags: []string{"a", "b"}, FolderID: &folder, Desc: true, Limit: 5, Cursor: "c1", WithoutViews: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Name != "sales" || page.NextCursor != "c2" || page.Total != 6 {
+		t.Errorf("got page %+v", page)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/roles":
+			utils.WriteError(w, r, er.ErrNoPerm)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, "<html>bad gateway</html>")
+		}
+	}))
+	c := newTestClient(srv)
+	c.MaxAttempts = 1
+
+	_, err := c.GetRoles(context.Background())
+	var apiErr *Error
+	if !errors.Is(err, er.ErrNoPerm) || !errors.As(err, &apiErr) || apiErr.Status != http.StatusForbidden {
+		t.Errorf("got %v, want %v", err, er.ErrNoPerm)
+	}
+
+	_, err = c.GetTags(context.Background())
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway || apiErr.Code != "" {
+		t.Errorf("gateway error page: got %#v", err)
+	}
+	if errors.Is(err, er.ErrNoPerm) {
+		t.Error("error without a code matches an API error")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var calls int32
+	srv := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		utils.WriteSuccessResponse(w, http.StatusOK, []*models.Role{})
+	}))
+	c := newTestClient(srv)
+
+	_, err := c.GetRoles(context.Background())
+	if err != nil || calls != 3 {
+		t.Errorf("GET: got %v after %d calls, want success after 3", err, calls)
+	}
+
+	//calls creating something are not sent twice
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.CreateDash(context.Background(), DashInput{Name: "sales"})
+	if err == nil || calls != 1 {
+		t.Errorf("POST: got %v after %d calls, want a failure after 1", err, calls)
+	}
+}
+
+func TestLogsInAgainWhenTokenRejected(t *testing.T) {
+	var logins int32
+	srv := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			n := atomic.AddInt32(&logins, 1)
+			utils.WriteSuccessResponse(w, http.StatusOK, &AuthResponse{ID: userId.String(), Token: fmt.Sprintf("tok%d", n)})
+		case "/roles":
+			if r.Header.Get("Authorization") == "tok1" {
+				utils.WriteError(w, r, utils.ErrInvalidToken)
+				return
+			}
+			utils.WriteSuccessResponse(w, http.StatusOK, []*models.Role{{Name: "viewer"}})
+		}
+	}))
+	c := newTestClient(srv)
+
+	id, err := c.Login(context.Background(), "me@example.com", "secret")
+	if err != nil || id != userId {
+		t.Fatalf("Login: got %s, %v", id, err)
+	}
+	roles, err := c.GetRoles(context.Background())
+	if err != nil || len(roles) != 1 {
+		t.Fatalf("GetRoles: got %v, %v", roles, err)
+	}
+	if logins != 2 || c.Token() != "tok2" {
+		t.Errorf("logged in %d times with token %s, want twice ending with tok2", logins, c.Token())
+	}
+
+	//tokens set from elsewhere cannot be renewed, the rejection is returned
+	c.SetToken("tok1")
+	_, err = c.GetRoles(context.Background())
+	if !errors.Is(err, utils.ErrInvalidToken) {
+		t.Errorf("with a set token: got %v, want %v", err, utils.ErrInvalidToken)
+	}
+}
+
+func TestRefreshesExpiringToken(t *testing.T) {
+	expiring, fresh := tokenExpiringIn(t, 30*time.Second), tokenExpiringIn(t, time.Hour)
+	var refreshes int32
+	srv := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/refresh":
+			atomic.AddInt32(&refreshes, 1)
+			if r.Header.Get("Authorization") != expiring {
+				t.Error("refresh not sent with the expiring token")
+			}
+			utils.WriteSuccessResponse(w, http.StatusOK, &AuthResponse{ID: userId.String(), Token: fresh})
+		case "/tags":
+			if r.Header.Get("Authorization") != fresh {
+				t.Error("call not sent with the refreshed token")
+			}
+			utils.WriteSuccessResponse(w, http.StatusOK, []*models.TagCount{})
+		}
+	}))
+	c := newTestClient(srv)
+	c.SetToken(expiring)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetTags(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if refreshes != 1 || c.Token() != fresh || c.UserID() != userId {
+		t.Errorf("refreshed %d times, want once", refreshes)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	dashId := uuid.New()
+	srv := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != dashPath(dashId, "/events") || r.Header.Get("Last-Event-ID") != "1-0" {
+			t.Errorf("got %s with Last-Event-ID %q", r.URL.Path, r.Header.Get("Last-Event-ID"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "retry: 3000\n\n: ping\n\nid: 2-0\nevent: dashboard.updated\ndata: {\"a\":\ndata: 1}\n\ndata: x\n\n")
+	}))
+	c := newTestClient(srv)
+
+	stream, err := c.Events(context.Background(), dashId, "1-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	ev, err := stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.ID != "2-0" || ev.Type != models.EventDashUpdated || string(ev.Data) != "{\"a\":\n1}" || stream.LastEventID != "2-0" {
+		t.Errorf("got event %+v, last id %s", ev, stream.LastEventID)
+	}
+	ev, err = stream.Next()
+	if err != nil || ev.Type != "message" || string(ev.Data) != "x" || stream.LastEventID != "2-0" {
+		t.Errorf("event without type or id: got %+v, %v", ev, err)
+	}
+	_, err = stream.Next()
+	if err != io.EOF {
+		t.Errorf("end of stream: got %v, want EOF", err)
+	}
+}
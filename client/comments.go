@@ -0,0 +1,65 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func commentPath(id uuid.UUID, rest string) string {
+	return "/comments/" + id.String() + rest
+}
+
+// Comment threads on a dashboard
+func (c *Client) GetDashComments(ctx context.Context, id uuid.UUID) ([]*models.Comment, error) {
+	comments := []*models.Comment{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: dashPath(id, "/comments")}, &comments, nil)
+	return comments, err
+}
+
+// Starts a thread on a dashboard, or replies to parentId when set. Users are mentioned with @email.
+func (c *Client) AddDashComment(ctx context.Context, id uuid.UUID, body string, parentId *uuid.UUID) (*models.Comment, error) {
+	return c.addComment(ctx, dashPath(id, "/comments"), body, parentId)
+}
+
+// Comment threads on a view
+func (c *Client) GetViewComments(ctx context.Context, id uuid.UUID) ([]*models.Comment, error) {
+	comments := []*models.Comment{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: viewPath(id, "/comments")}, &comments, nil)
+	return comments, err
+}
+
+// Starts a thread on a view, or replies to parentId when set
+func (c *Client) AddViewComment(ctx context.Context, id uuid.UUID, body string, parentId *uuid.UUID) (*models.Comment, error) {
+	return c.addComment(ctx, viewPath(id, "/comments"), body, parentId)
+}
+
+func (c *Client) addComment(ctx context.Context, path, body string, parentId *uuid.UUID) (*models.Comment, error) {
+	in := map[string]interface{}{"body": body, "parentId": parentId}
+	comment := &models.Comment{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: path, body: in}, comment, nil)
+	return comment, err
+}
+
+func (c *Client) UpdateComment(ctx context.Context, id uuid.UUID, body string) (*models.Comment, error) {
+	in := map[string]interface{}{"body": body}
+	comment := &models.Comment{}
+	err := c.do(ctx, &request{method: http.MethodPut, path: commentPath(id, ""), body: in}, comment, nil)
+	return comment, err
+}
+
+// Deletes a comment along with its replies
+func (c *Client) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: commentPath(id, "")}, nil, nil)
+}
+
+// Resolves the thread the comment belongs to
+func (c *Client) ResolveComment(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodPost, path: commentPath(id, "/resolve")}, nil, nil)
+}
+
+func (c *Client) UnresolveComment(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: commentPath(id, "/resolve")}, nil, nil)
+}
@@ -0,0 +1,261 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"backend/utils"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+func dashPath(id uuid.UUID, rest string) string {
+	return "/dashboard/" + id.String() + rest
+}
+
+func viewPath(id uuid.UUID, rest string) string {
+	return "/view/" + id.String() + rest
+}
+
+func (c *Client) CreateDash(ctx context.Context, in DashInput) (*models.Dash, error) {
+	dash := &models.Dash{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/dashboard", body: in}, dash, nil)
+	return dash, err
+}
+
+// Page of the dashboards you can read. Pass the NextCursor of a page as the Cursor of
+// opts to get the next one.
+func (c *Client) ListDashboards(ctx context.Context, opts ListOptions) (*models.DashPage, error) {
+	q := url.Values{}
+	if opts.Query != "" {
+		q.Set("q", opts.Query)
+	}
+	for _, tag := range opts.Tags {
+		q.Add("tag", tag)
+	}
+	if opts.FolderID != nil {
+		q.Set("folder", opts.FolderID.String())
+	}
+	if opts.Sort != "" {
+		q.Set("sort", opts.Sort)
+	}
+	if opts.Desc {
+		q.Set("order", "desc")
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		q.Set("cursor", opts.Cursor)
+	}
+	if opts.WithoutViews {
+		q.Set("views", "false")
+	}
+
+	items := []*models.Dash{}
+	meta := utils.PageMeta{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/dashboard", query: q}, &items, &meta)
+	return &models.DashPage{Items: items, NextCursor: meta.NextCursor, Total: meta.Total}, err
+}
+
+func (c *Client) GetStarredDashboards(ctx context.Context) ([]*models.Dash, error) {
+	dashs := []*models.Dash{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/dashboard/starred"}, &dashs, nil)
+	return dashs, err
+}
+
+// Dashboards you opened recently, latest first. The server default applies when limit is zero.
+func (c *Client) GetRecentDashboards(ctx context.Context, limit int) ([]*models.Dash, error) {
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	dashs := []*models.Dash{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/dashboard/recent", query: q}, &dashs, nil)
+	return dashs, err
+}
+
+func (c *Client) GetDash(ctx context.Context, id uuid.UUID) (*models.Dash, error) {
+	dash := &models.Dash{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: dashPath(id, "")}, dash, nil)
+	return dash, err
+}
+
+func (c *Client) UpdateDash(ctx context.Context, id uuid.UUID, in DashInput) (*models.Dash, error) {
+	dash := &models.Dash{}
+	err := c.do(ctx, &request{method: http.MethodPut, path: dashPath(id, ""), body: in}, dash, nil)
+	return dash, err
+}
+
+// Moves a dashboard to trash
+func (c *Client) DeleteDash(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: dashPath(id, "")}, nil, nil)
+}
+
+func (c *Client) RestoreDash(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodPost, path: dashPath(id, "/restore")}, nil, nil)
+}
+
+func (c *Client) CloneDash(ctx context.Context, id uuid.UUID, opts CloneOptions) (*models.Dash, error) {
+	dash := &models.Dash{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: dashPath(id, "/clone"), body: opts}, dash, nil)
+	return dash, err
+}
+
+func (c *Client) UpdateLayout(ctx context.Context, id uuid.UUID, layout []*models.LayoutItem) ([]*models.LayoutItem, error) {
+	body := map[string]interface{}{"layout": layout}
+	res := []*models.LayoutItem{}
+	err := c.do(ctx, &request{method: http.MethodPut, path: dashPath(id, "/layout"), body: body}, &res, nil)
+	return res, err
+}
+
+// Replaces the tags of a dashboard, returning them as stored
+func (c *Client) SetTags(ctx context.Context, id uuid.UUID, tags []string) ([]string, error) {
+	body := map[string]interface{}{"tags": tags}
+	res := []string{}
+	err := c.do(ctx, &request{method: http.MethodPut, path: dashPath(id, "/tags"), body: body}, &res, nil)
+	return res, err
+}
+
+func (c *Client) GetTags(ctx context.Context) ([]*models.TagCount, error) {
+	tags := []*models.TagCount{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/tags"}, &tags, nil)
+	return tags, err
+}
+
+// Moves a dashboard into a folder, or out of folders when folderId is nil
+func (c *Client) MoveDash(ctx context.Context, id uuid.UUID, folderId *uuid.UUID) error {
+	body := map[string]interface{}{"folderId": folderId}
+	return c.do(ctx, &request{method: http.MethodPut, path: dashPath(id, "/folder"), body: body}, nil, nil)
+}
+
+func (c *Client) StarDash(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodPut, path: dashPath(id, "/star")}, nil, nil)
+}
+
+func (c *Client) UnstarDash(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: dashPath(id, "/star")}, nil, nil)
+}
+
+// Exports a dashboard with its views and layout, and role assignments when includeRoles is set
+func (c *Client) ExportDash(ctx context.Context, id uuid.UUID, includeRoles bool) (*models.DashExport, error) {
+	q := url.Values{}
+	if includeRoles {
+		q.Set("includeRoles", "true")
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	resp, err := c.send(ctx, &request{method: http.MethodGet, path: dashPath(id, "/export"), query: q})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	//the document is served as is, not in the envelope
+	doc := &models.DashExport{}
+	return doc, json.NewDecoder(resp.Body).Decode(doc)
+}
+
+// Creates a dashboard from an export document. With dryRun nothing is created, the
+// report tells what would be.
+func (c *Client) ImportDash(ctx context.Context, doc *models.DashExport, dryRun bool) (*models.ImportReport, error) {
+	q := url.Values{}
+	if dryRun {
+		q.Set("dryRun", "true")
+	}
+	report := &models.ImportReport{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/dashboard/import", query: q, body: doc}, report, nil)
+	return report, err
+}
+
+func (c *Client) GetTrash(ctx context.Context) (*models.Trash, error) {
+	trash := &models.Trash{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/trash"}, trash, nil)
+	return trash, err
+}
+
+func (c *Client) MarkTemplate(ctx context.Context, id uuid.UUID, vars []*models.TemplateVariable) error {
+	body := map[string]interface{}{"variables": vars}
+	return c.do(ctx, &request{method: http.MethodPut, path: dashPath(id, "/template"), body: body}, nil, nil)
+}
+
+func (c *Client) UnmarkTemplate(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: dashPath(id, "/template")}, nil, nil)
+}
+
+func (c *Client) GetTemplates(ctx context.Context) ([]*models.Dash, error) {
+	dashs := []*models.Dash{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/templates"}, &dashs, nil)
+	return dashs, err
+}
+
+func (c *Client) InstantiateTemplate(ctx context.Context, id uuid.UUID, opts InstantiateOptions) (*models.Dash, error) {
+	dash := &models.Dash{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/templates/" + id.String() + "/instantiate", body: opts}, dash, nil)
+	return dash, err
+}
+
+func (c *Client) CreateView(ctx context.Context, in ViewInput) (*models.View, error) {
+	view := &models.View{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/view", body: in}, view, nil)
+	return view, err
+}
+
+func (c *Client) GetView(ctx context.Context, id uuid.UUID) (*models.View, error) {
+	view := &models.View{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: viewPath(id, "")}, view, nil)
+	return view, err
+}
+
+// Not implemented by the server yet, fails with not_implemented
+func (c *Client) UpdateView(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodPut, path: viewPath(id, "")}, nil, nil)
+}
+
+// Moves a view to trash
+func (c *Client) DeleteView(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: viewPath(id, "")}, nil, nil)
+}
+
+func (c *Client) RestoreView(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodPost, path: viewPath(id, "/restore")}, nil, nil)
+}
+
+func (c *Client) GetRoles(ctx context.Context) ([]*models.Role, error) {
+	roles := []*models.Role{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/roles"}, &roles, nil)
+	return roles, err
+}
+
+// Members of a dashboard with their roles and permissions
+func (c *Client) GetDashUsers(ctx context.Context, id uuid.UUID) ([]*models.Role, error) {
+	roles := []*models.Role{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: dashPath(id, "/users")}, &roles, nil)
+	return roles, err
+}
+
+// Gives a user a role on a dashboard, replacing the one they had
+func (c *Client) AddUserToDash(ctx context.Context, id uuid.UUID, grant Grant) error {
+	return c.do(ctx, &request{method: http.MethodPost, path: dashPath(id, "/users"), body: grant}, nil, nil)
+}
+
+func (c *Client) RemoveUserFromDash(ctx context.Context, id, userId uuid.UUID) error {
+	body := map[string]interface{}{"userId": userId}
+	return c.do(ctx, &request{method: http.MethodDelete, path: dashPath(id, "/users"), body: body}, nil, nil)
+}
+
+// Gives a user a role on a view, replacing the one they had
+func (c *Client) AddUserToView(ctx context.Context, id uuid.UUID, grant Grant) error {
+	return c.do(ctx, &request{method: http.MethodPost, path: viewPath(id, "/users"), body: grant}, nil, nil)
+}
+
+func (c *Client) RemoveUserFromView(ctx context.Context, id, userId uuid.UUID) error {
+	body := map[string]interface{}{"userId": userId}
+	return c.do(ctx, &request{method: http.MethodDelete, path: viewPath(id, "/users"), body: body}, nil, nil)
+}
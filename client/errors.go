@@ -0,0 +1,56 @@
+package client
+
+import (
+	"backend/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// A call the API answered with an error. Compare it to the errors of the services with
+// errors.Is, such as errors.Is(err, er.ErrNoPerm), which matches on the code.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	//invalid fields of a request that failed validation
+	Fields    []utils.FieldError
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+	}
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
+}
+
+// Matches API errors with the same code
+func (e *Error) Is(target error) bool {
+	apiErr, ok := target.(*utils.APIError)
+	return ok && e.Code != "" && apiErr.Code == e.Code
+}
+
+// Reads the error out of a failed response and closes its body. Responses that did not
+// come from the services, such as a gateway error page, keep only their status.
+func readError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+	e := &Error{Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, utils.MaxBodyBytes))
+	if err != nil {
+		return e
+	}
+	env := envelope{}
+	if json.Unmarshal(raw, &env) != nil || env.Code == "" {
+		return e
+	}
+	e.Code = env.Code
+	e.Message = env.Error
+	if env.Problem != nil {
+		e.Fields = env.Problem.Errors
+		e.RequestID = env.Problem.RequestID
+	}
+	return e
+}
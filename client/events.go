@@ -0,0 +1,81 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Server-sent events of a dashboard, see Events
+type EventStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	//id of the last event read, to resume the stream from
+	LastEventID string
+}
+
+// Opens the stream of changes to a dashboard. Pass the LastEventID of a previous stream to
+// resume after it. The stream is not bounded by Timeout, cancel ctx or Close it when done.
+func (c *Client) Events(ctx context.Context, id uuid.UUID, lastEventId string) (*EventStream, error) {
+	header := http.Header{"Accept": {"text/event-stream"}}
+	if lastEventId != "" {
+		header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := c.send(ctx, &request{method: http.MethodGet, path: dashPath(id, "/events"), header: header, stream: true})
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &EventStream{body: resp.Body, scanner: scanner, LastEventID: lastEventId}, nil
+}
+
+// Blocks until the next event and returns it. Comments and retry hints are skipped. Returns
+// io.EOF once the server ends the stream.
+func (s *EventStream) Next() (*models.StreamEvent, error) {
+	ev := &models.StreamEvent{}
+	var data []string
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" {
+			//a blank line ends an event, those without a type are retry hints
+			if ev.Type == "" && data == nil {
+				continue
+			}
+			if ev.Type == "" {
+				ev.Type = "message"
+			}
+			ev.Data = []byte(strings.Join(data, "\n"))
+			if ev.ID != "" {
+				s.LastEventID = ev.ID
+			}
+			return ev, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			ev.ID = value
+		case "event":
+			ev.Type = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
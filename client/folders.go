@@ -0,0 +1,62 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func folderPath(id uuid.UUID, rest string) string {
+	return "/folders/" + id.String() + rest
+}
+
+// Creates a folder, you become its admin
+func (c *Client) CreateFolder(ctx context.Context, in FolderInput) (*models.Folder, error) {
+	folder := &models.Folder{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/folders", body: in}, folder, nil)
+	return folder, err
+}
+
+func (c *Client) GetFolders(ctx context.Context) ([]*models.Folder, error) {
+	folders := []*models.Folder{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/folders"}, &folders, nil)
+	return folders, err
+}
+
+func (c *Client) GetFolder(ctx context.Context, id uuid.UUID) (*models.Folder, error) {
+	folder := &models.Folder{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: folderPath(id, "")}, folder, nil)
+	return folder, err
+}
+
+// Renames a folder or moves it under another
+func (c *Client) UpdateFolder(ctx context.Context, id uuid.UUID, in FolderInput) (*models.Folder, error) {
+	folder := &models.Folder{}
+	err := c.do(ctx, &request{method: http.MethodPut, path: folderPath(id, ""), body: in}, folder, nil)
+	return folder, err
+}
+
+// Deletes a folder, which must hold no folders or dashboards
+func (c *Client) DeleteFolder(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: folderPath(id, "")}, nil, nil)
+}
+
+// Users with a role on a folder, including roles inherited from folders above it
+func (c *Client) GetFolderUsers(ctx context.Context, id uuid.UUID) ([]*models.Role, error) {
+	roles := []*models.Role{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: folderPath(id, "/users")}, &roles, nil)
+	return roles, err
+}
+
+// Gives a user a role on a folder, replacing the one they had. Folder roles do not expire.
+func (c *Client) AddUserToFolder(ctx context.Context, id, userId uuid.UUID, role string) error {
+	body := map[string]interface{}{"userId": userId, "role": role}
+	return c.do(ctx, &request{method: http.MethodPost, path: folderPath(id, "/users"), body: body}, nil, nil)
+}
+
+func (c *Client) RemoveUserFromFolder(ctx context.Context, id, userId uuid.UUID) error {
+	body := map[string]interface{}{"userId": userId}
+	return c.do(ctx, &request{method: http.MethodDelete, path: folderPath(id, "/users"), body: body}, nil, nil)
+}
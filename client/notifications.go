@@ -0,0 +1,69 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Page of your notifications, newest first, only unread ones when unread is set
+func (c *Client) GetNotifications(ctx context.Context, unread bool, limit, offset int) (*models.NotificationPage, error) {
+	q := pageQuery(limit, offset)
+	if unread {
+		q.Set("unread", "true")
+	}
+	items := []*models.Notification{}
+	meta := struct {
+		Total  int `json:"total"`
+		Unread int `json:"unread"`
+	}{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/notifications", query: q}, &items, &meta)
+	return &models.NotificationPage{Items: items, Total: meta.Total, Unread: meta.Unread}, err
+}
+
+func (c *Client) GetUnreadCount(ctx context.Context) (int, error) {
+	res := struct {
+		Unread int `json:"unread"`
+	}{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/notifications/unread-count"}, &res, nil)
+	return res.Unread, err
+}
+
+// Marks notifications as read, returning how many were unread
+func (c *Client) MarkRead(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	body := map[string]interface{}{"ids": ids}
+	return c.markRead(ctx, &request{method: http.MethodPost, path: "/notifications/read", body: body})
+}
+
+func (c *Client) MarkAllRead(ctx context.Context) (int64, error) {
+	return c.markRead(ctx, &request{method: http.MethodPost, path: "/notifications/read-all"})
+}
+
+func (c *Client) MarkNotificationRead(ctx context.Context, id uuid.UUID) (int64, error) {
+	return c.markRead(ctx, &request{method: http.MethodPost, path: "/notifications/" + id.String() + "/read"})
+}
+
+func (c *Client) markRead(ctx context.Context, req *request) (int64, error) {
+	res := struct {
+		Marked int64 `json:"marked"`
+	}{}
+	err := c.do(ctx, req, &res, nil)
+	return res.Marked, err
+}
+
+// Whether you get each kind of notification
+func (c *Client) GetNotificationPreferences(ctx context.Context) ([]*models.NotificationPreference, error) {
+	prefs := []*models.NotificationPreference{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/notifications/preferences"}, &prefs, nil)
+	return prefs, err
+}
+
+// Turns kinds of notification on or off, kinds left out keep their setting
+func (c *Client) SetNotificationPreferences(ctx context.Context, prefs map[string]bool) ([]*models.NotificationPreference, error) {
+	body := map[string]interface{}{"preferences": prefs}
+	res := []*models.NotificationPreference{}
+	err := c.do(ctx, &request{method: http.MethodPut, path: "/notifications/preferences", body: body}, &res, nil)
+	return res, err
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"backend/utils"
+	"context"
+	"net/http"
+)
+
+// Searches the dashboards and views you can read. typ is dashboard or view to only get
+// results of that kind, both when empty.
+func (c *Client) Search(ctx context.Context, q, typ string, limit, offset int) (*models.SearchPage, error) {
+	query := pageQuery(limit, offset)
+	query.Set("q", q)
+	if typ != "" {
+		query.Set("type", typ)
+	}
+	items := []*models.SearchResult{}
+	meta := utils.PageMeta{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/search", query: query}, &items, &meta)
+	return &models.SearchPage{Items: items, Total: meta.Total}, err
+}
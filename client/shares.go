@@ -0,0 +1,55 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// Creates a read-only public link to a dashboard, or to one of its views. The token is only
+// returned here.
+func (c *Client) CreateShareLink(ctx context.Context, id uuid.UUID, in ShareLinkInput) (*models.ShareLink, error) {
+	link := &models.ShareLink{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: dashPath(id, "/shares"), body: in}, link, nil)
+	return link, err
+}
+
+// Share links of a dashboard and its views, without their tokens
+func (c *Client) GetShareLinks(ctx context.Context, id uuid.UUID) ([]*models.ShareLink, error) {
+	links := []*models.ShareLink{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: dashPath(id, "/shares")}, &links, nil)
+	return links, err
+}
+
+func (c *Client) RevokeShareLink(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: "/shares/" + id.String()}, nil, nil)
+}
+
+// Creates a short-lived signed token to embed a dashboard or one of its views
+func (c *Client) CreateEmbedToken(ctx context.Context, id uuid.UUID, in EmbedTokenInput) (*models.EmbedToken, error) {
+	token := &models.EmbedToken{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: dashPath(id, "/embed-tokens"), body: in}, token, nil)
+	return token, err
+}
+
+// Reads what a share link points to. The password is only needed for protected links.
+func (c *Client) OpenShareLink(ctx context.Context, token, password string) (*models.SharedResource, error) {
+	header := http.Header{}
+	if password != "" {
+		header.Set("X-Share-Password", password)
+	}
+	res := &models.SharedResource{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/public/shares/" + url.PathEscape(token), header: header}, res, nil)
+	return res, err
+}
+
+// Reads what an embed token grants access to
+func (c *Client) OpenEmbed(ctx context.Context, token string) (*models.SharedResource, error) {
+	q := url.Values{"token": {token}}
+	res := &models.SharedResource{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/public/embed", query: q}, res, nil)
+	return res, err
+}
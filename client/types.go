@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Name and description of a dashboard to create or update
+type DashInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ViewInput struct {
+	DashboardID uuid.UUID       `json:"dashboardId"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Config      json.RawMessage `json:"config,omitempty"`
+}
+
+// A role for a user on a dashboard or view, lapsing at ExpiresAt when set
+type Grant struct {
+	UserID    uuid.UUID  `json:"userId"`
+	Role      string     `json:"role"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type CloneOptions struct {
+	//name of the copy, "Copy of" the original when empty
+	Name          string `json:"name,omitempty"`
+	IncludeGrants bool   `json:"includeGrants"`
+}
+
+type InstantiateOptions struct {
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description"`
+	Values      map[string]string `json:"values,omitempty"`
+}
+
+// Filters and paging of ListDashboards
+type ListOptions struct {
+	Query    string
+	Tags     []string
+	FolderID *uuid.UUID
+	//name, created or updated
+	Sort   string
+	Desc   bool
+	Limit  int
+	Cursor string
+	//leave the views of each dashboard out
+	WithoutViews bool
+}
+
+type FolderInput struct {
+	Name     string     `json:"name"`
+	ParentID *uuid.UUID `json:"parentId"`
+}
+
+type ShareLinkInput struct {
+	ViewID    *uuid.UUID `json:"viewId,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Password  string     `json:"password,omitempty"`
+}
+
+type EmbedTokenInput struct {
+	ViewID *uuid.UUID `json:"viewId,omitempty"`
+	//lifetime of the token, the server default when zero
+	TTLSeconds int `json:"ttlSeconds,omitempty"`
+}
+
+// Decision on an access request. Role overrides the requested one when set.
+type Approval struct {
+	Role      string     `json:"role,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Note      *string    `json:"note,omitempty"`
+}
+
+type WebhookInput struct {
+	//dashboard whose events are sent, every dashboard when nil
+	DashID *uuid.UUID `json:"dashId,omitempty"`
+	URL    string     `json:"url"`
+	Events []string   `json:"events"`
+	//signs deliveries, generated when empty
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookUpdate struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
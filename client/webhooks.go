@@ -0,0 +1,74 @@
+package client
+
+import (
+	"backend/dashboard/models"
+	"backend/utils"
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+func webhookPath(id uuid.UUID, rest string) string {
+	return "/webhooks/" + id.String() + rest
+}
+
+// Registers an endpoint receiving events. The secret signing deliveries is only returned here.
+func (c *Client) CreateWebhook(ctx context.Context, in WebhookInput) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	err := c.do(ctx, &request{method: http.MethodPost, path: "/webhooks", body: in}, hook, nil)
+	return hook, err
+}
+
+func (c *Client) GetWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	hooks := []*models.Webhook{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: "/webhooks"}, &hooks, nil)
+	return hooks, err
+}
+
+func (c *Client) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: webhookPath(id, "")}, hook, nil)
+	return hook, err
+}
+
+func (c *Client) UpdateWebhook(ctx context.Context, id uuid.UUID, in WebhookUpdate) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	err := c.do(ctx, &request{method: http.MethodPut, path: webhookPath(id, ""), body: in}, hook, nil)
+	return hook, err
+}
+
+// Deletes a webhook along with its delivery log
+func (c *Client) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: webhookPath(id, "")}, nil, nil)
+}
+
+// Page of the delivery log of a webhook, newest first, with the total number of deliveries
+func (c *Client) GetDeliveries(ctx context.Context, id uuid.UUID, limit, offset int) ([]*models.WebhookDelivery, int, error) {
+	deliveries := []*models.WebhookDelivery{}
+	meta := utils.PageMeta{}
+	err := c.do(ctx, &request{method: http.MethodGet, path: webhookPath(id, "/deliveries"), query: pageQuery(limit, offset)}, &deliveries, &meta)
+	return deliveries, meta.Total, err
+}
+
+// Queues the payload of a past delivery again as a new delivery
+func (c *Client) Redeliver(ctx context.Context, id, deliveryId uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	path := webhookPath(id, "/deliveries/"+deliveryId.String()+"/redeliver")
+	err := c.do(ctx, &request{method: http.MethodPost, path: path}, delivery, nil)
+	return delivery, err
+}
+
+// limit and offset of a page, the server defaults apply to those left at zero
+func pageQuery(limit, offset int) url.Values {
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		q.Set("offset", strconv.Itoa(offset))
+	}
+	return q
+}
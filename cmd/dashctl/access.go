@@ -0,0 +1,262 @@
+package main
+
+import (
+	"backend/client"
+	"backend/dashboard/models"
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Resolves an email to the id of its user
+func (a *app) userByEmail(ctx context.Context, email string) (*models.User, error) {
+	users, err := a.client.LookupUsers(ctx, []string{email}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("no user with email %s", email)
+}
+
+// Emails of the users with ids, those not found are left out
+func (a *app) emails(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	emails := map[uuid.UUID]string{}
+	if len(ids) == 0 {
+		return emails, nil
+	}
+	users, err := a.client.LookupUsers(ctx, nil, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		emails[u.ID] = u.Email
+	}
+	return emails, nil
+}
+
+func share(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("share", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user")
+	role := flags.String("role", "", "role to give, see dashctl roles")
+	expires := flags.Duration("expires", 0, "revoke the role after this long, such as 72h. Admin roles cannot expire.")
+	err := parseFlags(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if *email == "" || *role == "" {
+		return usagef("-email and -role are required")
+	}
+	dashId, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	user, err := a.userByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	grant := client.Grant{UserID: user.ID, Role: *role}
+	if *expires > 0 {
+		at := time.Now().Add(*expires).UTC()
+		grant.ExpiresAt = &at
+	}
+	err = a.client.AddUserToDash(ctx, dashId, grant)
+	if err != nil {
+		return err
+	}
+	a.out.note("Gave %s the role %s on dashboard %s", *email, *role, dashId)
+	return nil
+}
+
+func unshare(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("unshare", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user")
+	err := parseFlags(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if *email == "" {
+		return usagef("-email is required")
+	}
+	dashId, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	user, err := a.userByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+	err = a.client.RemoveUserFromDash(ctx, dashId, user.ID)
+	if err != nil {
+		return err
+	}
+	a.out.note("Revoked the role of %s on dashboard %s", *email, dashId)
+	return nil
+}
+
+// A role of a user on a dashboard, granted on it directly or on a folder above it
+type member struct {
+	UserID      uuid.UUID  `json:"userId"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	Permissions []string   `json:"permissions"`
+	FolderID    *uuid.UUID `json:"inheritedFrom,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+func (m *member) source() string {
+	if m.FolderID == nil {
+		return "direct"
+	}
+	return "folder " + m.FolderID.String()
+}
+
+// Roles on a dashboard with the emails of their users, sorted by email
+func (a *app) members(ctx context.Context, dashId uuid.UUID) ([]*member, error) {
+	roles, err := a.client.GetDashUsers(ctx, dashId)
+	if err != nil {
+		return nil, err
+	}
+	ids := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, r := range roles {
+		if !seen[r.UserId] {
+			seen[r.UserId] = true
+			ids = append(ids, r.UserId)
+		}
+	}
+	emails, err := a.emails(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*member, 0, len(roles))
+	for _, r := range roles {
+		m := &member{UserID: r.UserId, Email: emails[r.UserId], Role: r.Name, FolderID: r.FolderID, ExpiresAt: r.ExpiresAt}
+		for _, p := range r.Permissions {
+			m.Permissions = append(m.Permissions, p.Name)
+		}
+		sort.Strings(m.Permissions)
+		members = append(members, m)
+	}
+	sort.SliceStable(members, func(i, j int) bool { return members[i].Email < members[j].Email })
+	return members, nil
+}
+
+func members(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("members", flag.ContinueOnError)
+	err := parseFlags(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	dashId, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	list, err := a.members(ctx, dashId)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"EMAIL", "USER ID", "ROLE", "SOURCE", "EXPIRES"}}
+	for _, m := range list {
+		t.rows = append(t.rows, []string{orDash(m.Email), m.UserID.String(), m.Role, m.source(), formatTime(m.ExpiresAt)})
+	}
+	return a.out.print(list, t)
+}
+
+// Permissions a user holds on a dashboard, the union of those of all their roles on it
+type effectivePerms struct {
+	UserID      uuid.UUID `json:"userId"`
+	Email       string    `json:"email"`
+	Permissions []string  `json:"permissions"`
+	Roles       []*member `json:"roles"`
+}
+
+func perms(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("perms", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user, yourself by default")
+	err := parseFlags(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	dashId, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	res := &effectivePerms{UserID: a.session.UserID, Email: a.session.Email, Permissions: []string{}, Roles: []*member{}}
+	if *email != "" {
+		user, err := a.userByEmail(ctx, *email)
+		if err != nil {
+			return err
+		}
+		res.UserID, res.Email = user.ID, user.Email
+	}
+
+	list, err := a.members(ctx, dashId)
+	if err != nil {
+		return err
+	}
+	held := map[string]bool{}
+	for _, m := range list {
+		if m.UserID != res.UserID {
+			continue
+		}
+		res.Roles = append(res.Roles, m)
+		for _, p := range m.Permissions {
+			if !held[p] {
+				held[p] = true
+				res.Permissions = append(res.Permissions, p)
+			}
+		}
+	}
+	sort.Strings(res.Permissions)
+
+	t := table{header: []string{"ROLE", "SOURCE", "EXPIRES", "PERMISSIONS"}}
+	for _, m := range res.Roles {
+		t.rows = append(t.rows, []string{m.Role, m.source(), formatTime(m.ExpiresAt), strings.Join(m.Permissions, ",")})
+	}
+	err = a.out.print(res, t)
+	if err != nil {
+		return err
+	}
+	if len(res.Roles) == 0 {
+		a.out.note("%s has no access to dashboard %s", res.Email, dashId)
+	} else {
+		a.out.note("Effective permissions: %s", strings.Join(res.Permissions, ", "))
+	}
+	return nil
+}
+
+func roles(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("roles", flag.ContinueOnError)
+	err := parseFlags(flags, args, 0)
+	if err != nil {
+		return err
+	}
+	list, err := a.client.GetRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"ROLE", "PERMISSIONS"}}
+	for _, r := range list {
+		names := []string{}
+		for _, p := range r.Permissions {
+			names = append(names, p.Name)
+		}
+		sort.Strings(names)
+		t.rows = append(t.rows, []string{r.Name, strings.Join(names, ",")})
+	}
+	return a.out.print(list, t)
+}
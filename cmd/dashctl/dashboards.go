@@ -0,0 +1,223 @@
+package main
+
+import (
+	"backend/client"
+	"backend/dashboard/models"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Flag that can be repeated, such as -tag a -tag b
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func parseID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, usagef("invalid id %q", s)
+	}
+	return id, nil
+}
+
+func listDashs(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	opts := client.ListOptions{WithoutViews: true}
+	var tags stringList
+	flags.StringVar(&opts.Query, "q", "", "only dashboards matching these terms")
+	flags.Var(&tags, "tag", "only dashboards with this tag, repeat for several")
+	folder := flags.String("folder", "", "only dashboards in this folder")
+	flags.StringVar(&opts.Sort, "sort", "", "name, created or updated")
+	flags.BoolVar(&opts.Desc, "desc", false, "sort in descending order")
+	flags.IntVar(&opts.Limit, "limit", 0, "page size, the server default when zero")
+	all := flags.Bool("all", false, "follow pages until the last one")
+	err := parseFlags(flags, args, 0)
+	if err != nil {
+		return err
+	}
+	opts.Tags = tags
+	if *folder != "" {
+		id, err := parseID(*folder)
+		if err != nil {
+			return err
+		}
+		opts.FolderID = &id
+	}
+
+	dashs := []*models.Dash{}
+	for {
+		page, err := a.client.ListDashboards(ctx, opts)
+		if err != nil {
+			return err
+		}
+		dashs = append(dashs, page.Items...)
+		if !*all || page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	t := table{header: []string{"ID", "NAME", "TAGS", "FOLDER", "UPDATED"}}
+	for _, d := range dashs {
+		folder := "-"
+		if d.FolderID != nil {
+			folder = d.FolderID.String()
+		}
+		t.rows = append(t.rows, []string{d.ID.String(), d.Name, orDash(strings.Join(d.Tags, ",")), folder, formatTime(d.UpdatedAt)})
+	}
+	return a.out.print(dashs, t)
+}
+
+func getDash(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	err := parseFlags(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	dash, err := a.client.GetDash(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	t := table{
+		header: []string{"KIND", "ID", "NAME", "DESCRIPTION"},
+		rows:   [][]string{{"dashboard", dash.ID.String(), dash.Name, orDash(dash.Description)}},
+	}
+	for _, v := range dash.Views {
+		t.rows = append(t.rows, []string{"view", v.ID.String(), v.Name, orDash(v.Description)})
+	}
+	return a.out.print(dash, t)
+}
+
+func createDash(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	in := client.DashInput{}
+	flags.StringVar(&in.Name, "name", "", "name of the dashboard")
+	flags.StringVar(&in.Description, "description", "", "description of the dashboard")
+	err := parseFlags(flags, args, 0)
+	if err != nil {
+		return err
+	}
+	if in.Name == "" {
+		return usagef("-name is required")
+	}
+	dash, err := a.client.CreateDash(ctx, in)
+	if err != nil {
+		return err
+	}
+	return a.out.print(dash, table{
+		header: []string{"ID", "NAME"},
+		rows:   [][]string{{dash.ID.String(), dash.Name}},
+	})
+}
+
+func deleteDash(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ContinueOnError)
+	err := parseFlags(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	err = a.client.DeleteDash(ctx, id)
+	if err != nil {
+		return err
+	}
+	a.out.note("Moved dashboard %s to trash", id)
+	return nil
+}
+
+// The export document is written as is whatever the output mode, it is JSON already
+func exportDash(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	includeRoles := flags.Bool("roles", false, "include role assignments by email, needs edit_access")
+	file := flags.String("file", "", "write the document to this file instead of stdout")
+	err := parseFlags(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	doc, err := a.client.ExportDash(ctx, id, *includeRoles)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	raw = append(raw, '\n')
+	if *file == "" {
+		_, err = a.out.w.Write(raw)
+		return err
+	}
+	return os.WriteFile(*file, raw, 0644)
+}
+
+func importDash(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only validate the document and report what would be created")
+	err := parseFlags(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	doc := &models.DashExport{}
+	err = json.NewDecoder(r).Decode(doc)
+	if err != nil {
+		return fmt.Errorf("reading export document: %w", err)
+	}
+
+	report, err := a.client.ImportDash(ctx, doc, *dryRun)
+	if err != nil {
+		return err
+	}
+	id := "-"
+	if report.Dashboard != nil {
+		id = report.Dashboard.ID.String()
+	}
+	err = a.out.print(report, table{
+		header: []string{"ID", "NAME", "VIEWS", "GRANTS", "DRY RUN"},
+		rows:   [][]string{{id, report.DashboardName, strconv.Itoa(report.Views), strconv.Itoa(len(report.Grants)), strconv.FormatBool(report.DryRun)}},
+	})
+	if err != nil {
+		return err
+	}
+	if len(report.UnresolvedEmails) > 0 {
+		a.out.note("No user with these emails, their roles were skipped: %s", strings.Join(report.UnresolvedEmails, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+// Command dashctl administers dashboards and access to them through the gateway.
+//
+//	dashctl [-url URL] [-output table|json] <command> [flags] [args]
+//
+// Log in once with dashctl login, the session is kept in the config directory of the user
+// and its token renewed as it is used.
+package main
+
+import (
+	"backend/client"
+	"backend/utils"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// Exit codes
+const (
+	exitFailed = 1
+	exitUsage  = 2
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, app *app, args []string) error
+	//runs without a session
+	public bool
+}
+
+var commands = []*command{
+	{name: "login", args: "-email EMAIL", summary: "Log in and keep the session. The password is read from DASHCTL_PASSWORD or stdin.", run: login, public: true},
+	{name: "logout", summary: "Forget the session", run: logout, public: true},
+	{name: "whoami", summary: "Show the user logged in", run: whoami},
+	{name: "list", args: "[-q TERMS] [-tag TAG]... [-folder ID] [-sort name|created|updated] [-desc] [-limit N] [-all]", summary: "List dashboards you can read", run: listDashs},
+	{name: "get", args: "DASH_ID", summary: "Show a dashboard with its views", run: getDash},
+	{name: "create", args: "-name NAME [-description TEXT]", summary: "Create a dashboard", run: createDash},
+	{name: "delete", args: "DASH_ID", summary: "Move a dashboard to trash", run: deleteDash},
+	{name: "export", args: "[-roles] [-file PATH] DASH_ID", summary: "Write the export document of a dashboard to stdout or a file", run: exportDash},
+	{name: "import", args: "[-dry-run] PATH|-", summary: "Create a dashboard from an export document", run: importDash},
+	{name: "share", args: "-email EMAIL -role ROLE [-expires DURATION] DASH_ID", summary: "Give a user a role on a dashboard, replacing the one they had", run: share},
+	{name: "unshare", args: "-email EMAIL DASH_ID", summary: "Revoke the role of a user on a dashboard", run: unshare},
+	{name: "members", args: "DASH_ID", summary: "List users with a role on a dashboard, including roles inherited from folders", run: members},
+	{name: "perms", args: "[-email EMAIL] DASH_ID", summary: "Show the effective permissions of a user on a dashboard, yours by default", run: perms},
+	{name: "roles", summary: "List roles with their permissions", run: roles},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dashctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	baseURL := fs.String("url", "", "gateway the services are reached through, the one logged in to or "+defaultURL+" by default")
+	output := fs.String("output", "table", "table or json")
+	configPath := fs.String("config", "", "session file, "+defaultSessionPath()+" by default")
+	fs.Usage = func() { usage(fs, stderr) }
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "invalid output %q, use table or json\n", *output)
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	cmd := findCommand(fs.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(stderr, "unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	app, err := newApp(*configPath, *baseURL, &printer{json: *output == "json", w: stdout})
+	if err == nil && !cmd.public && app.session.Token == "" {
+		err = errors.New("not logged in, run dashctl login first")
+	}
+	if err == nil {
+		err = cmd.run(ctx, app, fs.Args()[1:])
+	}
+	if err == nil && !cmd.public {
+		//keep the token renewed while running
+		err = app.saveToken()
+	}
+
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return exitUsage
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(stderr, "%v\nusage: dashctl %s %s\n", err, cmd.name, cmd.args)
+		return exitUsage
+	}
+	printError(stderr, err)
+	return exitFailed
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "usage: dashctl [flags] <command> [command flags] [args]")
+	fmt.Fprintln(w, "\nflags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(w, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// Arguments of a command that are missing or wrong
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{fmt.Sprintf(format, args...)}
+}
+
+// Parses the flags of a command and checks it got exactly nargs arguments
+func parseFlags(fs *flag.FlagSet, args []string, nargs int) error {
+	fs.SetOutput(io.Discard)
+	err := fs.Parse(args)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	if fs.NArg() != nargs {
+		return usagef("expected %d argument(s), got %d", nargs, fs.NArg())
+	}
+	return nil
+}
+
+// Prints err along with the invalid fields of a request, if any
+func printError(w io.Writer, err error) {
+	fmt.Fprintf(w, "error: %v\n", err)
+	if errors.Is(err, utils.ErrInvalidToken) {
+		fmt.Fprintln(w, "the session expired, run dashctl login again")
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		return
+	}
+	fields := apiErr.Fields
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	for _, f := range fields {
+		fmt.Fprintf(w, "  %s: %s\n", f.Field, f.Message)
+	}
+	if apiErr.RequestID != "" {
+		fmt.Fprintf(w, "request id: %s\n", apiErr.RequestID)
+	}
+}
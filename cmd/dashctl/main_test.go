@@ -0,0 +1,175 @@
+package main
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/utils"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var (
+	me     = uuid.MustParse("11111111-1111-4111-8111-111111111111")
+	other  = uuid.MustParse("22222222-2222-4222-8222-222222222222")
+	dashId = uuid.MustParse("33333333-3333-4333-8333-333333333333")
+	folder = uuid.MustParse("44444444-4444-4444-8444-444444444444")
+)
+
+// Gateway answering the calls dashctl makes with canned data
+func newGateway(t *testing.T) *httptest.Server {
+	t.Helper()
+	read, edit := &models.Permission{Name: "read"}, &models.Permission{Name: "edit"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteSuccessResponse(w, http.StatusOK, map[string]string{"id": me.String(), "token": "session-token"})
+	})
+	mux.HandleFunc("/users/lookup", func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteSuccessResponse(w, http.StatusOK, []*models.User{
+			{ID: me, Email: "me@example.com", Username: "me"},
+			{ID: other, Email: "another@example.com"},
+		})
+	})
+	mux.HandleFunc("/dashboard/"+dashId.String()+"/users", func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteSuccessResponse(w, http.StatusOK, []*models.Role{
+			{Name: "viewer", UserId: me, Permissions: []*models.Permission{read}},
+			{Name: "editor", UserId: me, FolderID: &folder, Permissions: []*models.Permission{edit, read}},
+			{Name: "viewer", UserId: other, Permissions: []*models.Permission{read}},
+		})
+	})
+	mux.HandleFunc("/dashboard/", func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteError(w, r, er.ErrNoPerm)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// Writes a session logged in to srv and returns its path
+func loggedIn(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.json")
+	raw, _ := json.Marshal(&session{URL: srv.URL, Email: "me@example.com", UserID: me, Token: "session-token"})
+	err := os.WriteFile(path, raw, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runCmd(args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestLoginKeepsTokenOnly(t *testing.T) {
+	srv := newGateway(t)
+	path := filepath.Join(t.TempDir(), "dashctl", "session.json")
+	t.Setenv("DASHCTL_PASSWORD", "hunter2")
+
+	code, _, stderr := runCmd("-config", path, "-url", srv.URL, "login", "-email", "me@example.com")
+	if code != 0 {
+		t.Fatalf("login exited %d: %s", code, stderr)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "hunter2") {
+		t.Error("session keeps the password")
+	}
+	s := &session{}
+	err = json.Unmarshal(raw, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Token != "session-token" || s.UserID != me || s.URL != srv.URL {
+		t.Errorf("session %+v", s)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("session file mode %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestMembers(t *testing.T) {
+	srv := newGateway(t)
+	path := loggedIn(t, srv)
+
+	code, stdout, stderr := runCmd("-config", path, "-output", "json", "members", dashId.String())
+	if code != 0 {
+		t.Fatalf("members exited %d: %s", code, stderr)
+	}
+	list := []*member{}
+	err := json.Unmarshal([]byte(stdout), &list)
+	if err != nil {
+		t.Fatalf("decoding %q: %v", stdout, err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("got %d members, want 3", len(list))
+	}
+	if list[0].Email != "another@example.com" || list[1].Email != "me@example.com" {
+		t.Errorf("members not sorted by email: %s, %s", list[0].Email, list[1].Email)
+	}
+	if src := list[2].source(); src != "folder "+folder.String() {
+		t.Errorf("source of the folder role %q", src)
+	}
+	if got := strings.Join(list[2].Permissions, ","); got != "edit,read" {
+		t.Errorf("permissions %s, want edit,read", got)
+	}
+}
+
+func TestPermsJoinsRoles(t *testing.T) {
+	srv := newGateway(t)
+	path := loggedIn(t, srv)
+
+	code, stdout, stderr := runCmd("-config", path, "-output", "json", "perms", dashId.String())
+	if code != 0 {
+		t.Fatalf("perms exited %d: %s", code, stderr)
+	}
+	res := &effectivePerms{}
+	err := json.Unmarshal([]byte(stdout), res)
+	if err != nil {
+		t.Fatalf("decoding %q: %v", stdout, err)
+	}
+	if res.UserID != me || len(res.Roles) != 2 || strings.Join(res.Permissions, ",") != "edit,read" {
+		t.Errorf("got %s with %d roles and permissions %v", res.UserID, len(res.Roles), res.Permissions)
+	}
+
+	code, stdout, _ = runCmd("-config", path, "perms", "-email", "another@example.com", dashId.String())
+	if code != 0 || !strings.Contains(stdout, "Effective permissions: read") {
+		t.Errorf("perms of another user exited %d:\n%s", code, stdout)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := newGateway(t)
+	path := loggedIn(t, srv)
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{"no command", []string{"-config", path}, exitUsage, "usage: dashctl"},
+		{"unknown command", []string{"-config", path, "frobnicate"}, exitUsage, `unknown command "frobnicate"`},
+		{"missing flag", []string{"-config", path, "share", dashId.String()}, exitUsage, "-email and -role are required"},
+		{"invalid id", []string{"-config", path, "get", "42"}, exitUsage, `invalid id "42"`},
+		{"api error", []string{"-config", path, "get", dashId.String()}, exitFailed, "no permission"},
+		{"not logged in", []string{"-config", filepath.Join(t.TempDir(), "none.json"), "-url", srv.URL, "roles"}, exitFailed, "not logged in"},
+	}
+	for _, tt := range tests {
+		code, _, stderr := runCmd(tt.args...)
+		if code != tt.code || !strings.Contains(stderr, tt.stderr) {
+			t.Errorf("%s: exited %d with %q, want %d with %q", tt.name, code, stderr, tt.code, tt.stderr)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"backend/client"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session kept between runs. It holds the token only, never the password.
+type session struct {
+	URL    string    `json:"url"`
+	Email  string    `json:"email"`
+	UserID uuid.UUID `json:"userId"`
+	Token  string    `json:"token"`
+}
+
+// State shared by the commands of a run
+type app struct {
+	client  *client.Client
+	session *session
+	path    string
+	out     *printer
+}
+
+func defaultSessionPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "dashctl", "session.json")
+}
+
+// Loads the session at path and sets up a client of baseURL, falling back to the gateway
+// logged in to
+func newApp(path, baseURL string, out *printer) (*app, error) {
+	if path == "" {
+		path = defaultSessionPath()
+	}
+	s := &session{}
+	raw, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		err = json.Unmarshal(raw, s)
+		if err != nil {
+			return nil, fmt.Errorf("reading session %s: %w", path, err)
+		}
+	}
+
+	if baseURL == "" {
+		baseURL = s.URL
+	}
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	c := client.New(baseURL)
+	//tokens last hours, renewing well ahead keeps a session in use alive
+	c.RefreshBefore = time.Hour
+	if s.Token != "" {
+		c.SetToken(s.Token)
+	}
+	return &app{client: c, session: s, path: path, out: out}, nil
+}
+
+func (a *app) save() error {
+	raw, err := json.MarshalIndent(a.session, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(a.path), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(a.path, raw, 0600)
+}
+
+// Saves the token when the client renewed it
+func (a *app) saveToken() error {
+	token := a.client.Token()
+	if token == a.session.Token {
+		return nil
+	}
+	a.session.Token = token
+	return a.save()
+}
+
+func login(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user")
+	err := parseFlags(flags, args, 0)
+	if err != nil {
+		return err
+	}
+	if *email == "" {
+		return usagef("-email is required")
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	userId, err := a.client.Login(ctx, *email, password)
+	if err != nil {
+		return err
+	}
+
+	a.session = &session{URL: a.client.BaseURL, Email: *email, UserID: userId, Token: a.client.Token()}
+	err = a.save()
+	if err != nil {
+		return err
+	}
+	return a.out.print(a.session.user(), table{
+		header: []string{"USER ID", "EMAIL", "URL"},
+		rows:   [][]string{{userId.String(), *email, a.session.URL}},
+	})
+}
+
+// Password from DASHCTL_PASSWORD, otherwise the first line of stdin. The line is echoed
+// when typed in a terminal, pipe it in to keep it off screen.
+func readPassword() (string, error) {
+	if password := os.Getenv("DASHCTL_PASSWORD"); password != "" {
+		return password, nil
+	}
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("empty password")
+	}
+	return password, nil
+}
+
+func logout(ctx context.Context, a *app, args []string) error {
+	err := parseFlags(flag.NewFlagSet("logout", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(a.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func whoami(ctx context.Context, a *app, args []string) error {
+	err := parseFlags(flag.NewFlagSet("whoami", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	//asking the auth service checks the session still works
+	users, err := a.client.LookupUsers(ctx, nil, []uuid.UUID{a.session.UserID})
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return fmt.Errorf("user %s no longer exists", a.session.UserID)
+	}
+	return a.out.print(users[0], table{
+		header: []string{"USER ID", "USERNAME", "EMAIL"},
+		rows:   [][]string{{users[0].ID.String(), users[0].Username, users[0].Email}},
+	})
+}
+
+// The session without its token, for printing
+func (s *session) user() interface{} {
+	return struct {
+		UserID uuid.UUID `json:"userId"`
+		Email  string    `json:"email"`
+		URL    string    `json:"url"`
+	}{s.UserID, s.Email, s.URL}
+}
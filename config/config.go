@@ -0,0 +1,302 @@
+package config
+
+import (
+	mw "backend/middlewares"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// HTTP server settings
+type Server struct {
+	Port            int           `yaml:"port" env:"PORT" desc:"port to listen on"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" desc:"max time to read a request"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" desc:"max time to write a response"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" desc:"max time to keep idle connections open"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" desc:"max time to finish requests on shutdown"`
+	TrustedProxies  []mw.IPNet    `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" desc:"comma separated addresses or CIDR ranges of proxies whose X-Real-IP is believed"`
+}
+
+// Postgres connection and pool settings
+type Database struct {
+	Host            string        `yaml:"host" env:"POSTGRES_HOST" required:"true" desc:"postgres host"`
+	Port            int           `yaml:"port" env:"POSTGRES_PORT" desc:"postgres port"`
+	User            string        `yaml:"user" env:"POSTGRES_USER" required:"true" desc:"postgres user"`
+	Password        string        `yaml:"password" env:"POSTGRES_PASSWORD" secret:"true" desc:"postgres password"`
+	Name            string        `yaml:"name" env:"POSTGRES_DB" required:"true" desc:"postgres database"`
+	SSLMode         string        `yaml:"ssl_mode" env:"POSTGRES_SSLMODE" desc:"disable, allow, prefer, require, verify-ca or verify-full"`
+	SSLRootCert     string        `yaml:"ssl_root_cert" env:"POSTGRES_SSLROOTCERT" desc:"CA certificate used to verify the server"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"POSTGRES_MAX_OPEN_CONNS" desc:"max open connections, 0 for no limit"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"POSTGRES_MAX_IDLE_CONNS" desc:"max idle connections kept in the pool"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"POSTGRES_CONN_MAX_LIFETIME" desc:"max time a connection is reused"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"POSTGRES_CONN_MAX_IDLE_TIME" desc:"max time a connection stays idle"`
+	MigrationsPath  string        `yaml:"migrations_path" env:"MIGRATIONS_PATH" required:"true" desc:"source of migrations, such as file:///migrations"`
+}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Connection string for lib/pq
+func (d *Database) DSN() string {
+	parts := []string{
+		"host=" + dsnValue(d.Host),
+		fmt.Sprintf("port=%d", d.Port),
+		"user=" + dsnValue(d.User),
+		"password=" + dsnValue(d.Password),
+		"dbname=" + dsnValue(d.Name),
+		"sslmode=" + dsnValue(d.SSLMode),
+	}
+	if d.SSLRootCert != "" {
+		parts = append(parts, "sslrootcert="+dsnValue(d.SSLRootCert))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quotes a value of a key=value connection string
+func dsnValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+func (d *Database) validate() []string {
+	var problems []string
+	if d.Port <= 0 || d.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("POSTGRES_PORT (database.port): %d is not a valid port", d.Port))
+	}
+	if !contains(sslModes, d.SSLMode) {
+		problems = append(problems, fmt.Sprintf("POSTGRES_SSLMODE (database.ssl_mode): %q is not one of %s", d.SSLMode, strings.Join(sslModes, ", ")))
+	}
+	if d.MaxOpenConns < 0 || d.MaxIdleConns < 0 {
+		problems = append(problems, "database.max_open_conns and database.max_idle_conns cannot be negative")
+	}
+	if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		problems = append(problems, "database.max_idle_conns cannot exceed database.max_open_conns")
+	}
+	return problems
+}
+
+// Redis connection settings
+type Redis struct {
+	Addr     string `yaml:"addr" env:"REDIS_ADDR" required:"true" desc:"redis host:port"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true" desc:"redis password"`
+	DB       int    `yaml:"db" env:"REDIS_DB" desc:"redis database number"`
+}
+
+// Token settings, shared by the service issuing tokens and the ones verifying them
+type JWT struct {
+	Secret string        `yaml:"secret" env:"JWT_SECRET_KEY" required:"true" secret:"true" desc:"key tokens are signed with"`
+	Expiry time.Duration `yaml:"expiry" env:"JWT_EXPIRY" desc:"lifetime of login tokens"`
+}
+
+// Logging and tracing settings
+type Telemetry struct {
+	LogLevel       logrus.Level `yaml:"log_level" env:"LOG_LEVEL" desc:"debug, info, warn or error"`
+	TracesExporter string       `yaml:"traces_exporter" env:"OTEL_TRACES_EXPORTER" desc:"none, stdout or otlp"`
+}
+
+var tracesExporters = []string{"none", "stdout", "otlp"}
+
+func (t *Telemetry) validate() []string {
+	if !contains(tracesExporters, t.TracesExporter) {
+		return []string{fmt.Sprintf("OTEL_TRACES_EXPORTER (telemetry.traces_exporter): %q is not one of %s", t.TracesExporter, strings.Join(tracesExporters, ", "))}
+	}
+	return nil
+}
+
+// Startup and health check settings
+type Lifecycle struct {
+	StartupTimeout     time.Duration `yaml:"startup_timeout" env:"STARTUP_TIMEOUT" desc:"max time to wait for dependencies at startup"`
+	HealthCheckTimeout time.Duration `yaml:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT" desc:"max time a readiness check waits on a dependency"`
+}
+
+// Config of the auth service
+type Auth struct {
+	Server    Server    `yaml:"server"`
+	Database  Database  `yaml:"database"`
+	JWT       JWT       `yaml:"jwt"`
+	Telemetry Telemetry `yaml:"telemetry"`
+	Lifecycle Lifecycle `yaml:"lifecycle"`
+}
+
+// Defaults of the auth service, matching docker-compose
+func DefaultAuth() Auth {
+	return Auth{
+		Server:    defaultServer(),
+		Database:  defaultDatabase("auth_db"),
+		JWT:       JWT{Expiry: 4 * time.Hour},
+		Telemetry: defaultTelemetry(),
+		Lifecycle: defaultLifecycle(),
+	}
+}
+
+// Checks values beyond being set
+func (c *Auth) Validate() error {
+	var problems []string
+	problems = append(problems, c.Server.validate()...)
+	problems = append(problems, c.Database.validate()...)
+	problems = append(problems, c.Telemetry.validate()...)
+	problems = append(problems, c.Lifecycle.validate()...)
+	if c.JWT.Expiry <= 0 {
+		problems = append(problems, "JWT_EXPIRY (jwt.expiry) must be positive")
+	}
+	return joinProblems(problems)
+}
+
+// Config of the dashboard service
+type Dashboard struct {
+	Server    Server    `yaml:"server"`
+	Database  Database  `yaml:"database"`
+	Redis     Redis     `yaml:"redis"`
+	JWT       JWT       `yaml:"jwt"`
+	Telemetry Telemetry `yaml:"telemetry"`
+	Lifecycle Lifecycle `yaml:"lifecycle"`
+
+	AuthServiceURL string `yaml:"auth_service_url" env:"AUTH_SERVICE_URL" required:"true" desc:"auth service resolving users by email"`
+	//users allowed to manage webhooks receiving events of every dashboard
+	SystemAdminIDs []uuid.UUID `yaml:"system_admin_ids" env:"SYSTEM_ADMIN_IDS" desc:"comma separated ids of system admins"`
+
+	Trash struct {
+		Retention     time.Duration `yaml:"retention" env:"TRASH_RETENTION" desc:"time trashed dashboards and views are kept"`
+		PurgeInterval time.Duration `yaml:"purge_interval" env:"TRASH_PURGE_INTERVAL" desc:"time between purges of the trash"`
+	} `yaml:"trash"`
+	Grants struct {
+		SweepInterval time.Duration `yaml:"sweep_interval" env:"GRANT_SWEEP_INTERVAL" desc:"time between sweeps of lapsed grants"`
+		ExpiryNotice  time.Duration `yaml:"expiry_notice" env:"GRANT_EXPIRY_NOTICE" desc:"how long before a grant lapses admins are told"`
+	} `yaml:"grants"`
+	EmbedTokenTTL time.Duration `yaml:"embed_token_ttl" env:"EMBED_TOKEN_TTL" desc:"lifetime of embed tokens unless a caller asks for another"`
+	Webhooks      struct {
+		DispatchInterval    time.Duration `yaml:"dispatch_interval" env:"WEBHOOK_DISPATCH_INTERVAL" desc:"max time before queued deliveries are picked up"`
+		AllowPrivateTargets bool          `yaml:"allow_private_targets" env:"WEBHOOK_ALLOW_PRIVATE_TARGETS" desc:"deliver to loopback and private addresses, for local receivers only"`
+	} `yaml:"webhooks"`
+	RateLimit struct {
+		Default   mw.RateLimit `yaml:"default" env:"RATE_LIMIT" desc:"requests allowed per client, such as 300/1m"`
+		Overrides string       `yaml:"overrides" env:"RATE_LIMIT_OVERRIDES" desc:"routes with a limit of their own, such as \"POST /dashboard/import=10/1m;GET /search=60/1m\""`
+	} `yaml:"rate_limit"`
+}
+
+// Defaults of the dashboard service, matching docker-compose
+func DefaultDashboard() Dashboard {
+	c := Dashboard{
+		Server:         defaultServer(),
+		Database:       defaultDatabase("dash_db"),
+		Redis:          Redis{Addr: "redis:6379"},
+		JWT:            JWT{Expiry: 4 * time.Hour},
+		Telemetry:      defaultTelemetry(),
+		Lifecycle:      defaultLifecycle(),
+		AuthServiceURL: "http://auth_server:8080",
+		EmbedTokenTTL:  5 * time.Minute,
+	}
+	c.Trash.Retention = 30 * 24 * time.Hour
+	c.Trash.PurgeInterval = time.Hour
+	c.Grants.SweepInterval = time.Minute
+	c.Grants.ExpiryNotice = 24 * time.Hour
+	c.Webhooks.DispatchInterval = 10 * time.Second
+	c.RateLimit.Default = mw.RateLimit{Requests: 300, Window: time.Minute}
+	return c
+}
+
+// Checks values beyond being set
+func (c *Dashboard) Validate() error {
+	var problems []string
+	problems = append(problems, c.Server.validate()...)
+	problems = append(problems, c.Database.validate()...)
+	problems = append(problems, c.Telemetry.validate()...)
+	problems = append(problems, c.Lifecycle.validate()...)
+	positive := map[string]time.Duration{
+		"TRASH_RETENTION (trash.retention)":                      c.Trash.Retention,
+		"TRASH_PURGE_INTERVAL (trash.purge_interval)":            c.Trash.PurgeInterval,
+		"GRANT_SWEEP_INTERVAL (grants.sweep_interval)":           c.Grants.SweepInterval,
+		"GRANT_EXPIRY_NOTICE (grants.expiry_notice)":             c.Grants.ExpiryNotice,
+		"EMBED_TOKEN_TTL (embed_token_ttl)":                      c.EmbedTokenTTL,
+		"WEBHOOK_DISPATCH_INTERVAL (webhooks.dispatch_interval)": c.Webhooks.DispatchInterval,
+	}
+	problems = append(problems, checkPositive(positive)...)
+	return joinProblems(problems)
+}
+
+func defaultServer() Server {
+	return Server{
+		Port:            8080,
+		ReadTimeout:     5 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		IdleTimeout:     120 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+func (s *Server) validate() []string {
+	var problems []string
+	if s.Port <= 0 || s.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("PORT (server.port): %d is not a valid port", s.Port))
+	}
+	problems = append(problems, checkPositive(map[string]time.Duration{
+		"SERVER_READ_TIMEOUT (server.read_timeout)":         s.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT (server.write_timeout)":       s.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT (server.idle_timeout)":         s.IdleTimeout,
+		"SERVER_SHUTDOWN_TIMEOUT (server.shutdown_timeout)": s.ShutdownTimeout,
+	})...)
+	return problems
+}
+
+func defaultDatabase(host string) Database {
+	return Database{
+		Host:            host,
+		Port:            5432,
+		SSLMode:         "disable",
+		MaxOpenConns:    20,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+		MigrationsPath:  "file:///migrations",
+	}
+}
+
+func defaultTelemetry() Telemetry {
+	return Telemetry{LogLevel: logrus.InfoLevel, TracesExporter: "none"}
+}
+
+func defaultLifecycle() Lifecycle {
+	return Lifecycle{StartupTimeout: time.Minute, HealthCheckTimeout: 2 * time.Second}
+}
+
+func (l *Lifecycle) validate() []string {
+	return checkPositive(map[string]time.Duration{
+		"STARTUP_TIMEOUT (lifecycle.startup_timeout)":           l.StartupTimeout,
+		"HEALTH_CHECK_TIMEOUT (lifecycle.health_check_timeout)": l.HealthCheckTimeout,
+	})
+}
+
+// reports the durations, keyed by setting name, that are not positive, sorted by name
+func checkPositive(durations map[string]time.Duration) []string {
+	var problems []string
+	for name, d := range durations {
+		if d <= 0 {
+			problems = append(problems, fmt.Sprintf("%s must be positive", name))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func joinProblems(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(problems, "\n  - "))
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// env var naming the YAML config file when -config is not given
+const configFileEnv = "CONFIG_FILE"
+
+// shown in place of secrets that are set
+const redacted = "******"
+
+// A single setting of a config struct. Settings are tagged with
+//   - yaml: key of the setting in its section of the config file, also naming its flag
+//   - env: env var overriding the file
+//   - required: "true" when the setting must not be left empty
+//   - secret: "true" when the value must not be printed
+//   - desc: help text of the flag
+type setting struct {
+	key      string
+	env      string
+	desc     string
+	required bool
+	secret   bool
+	value    reflect.Value
+}
+
+// name of a setting in errors, env var first as that is how most deployments set it
+func (s *setting) name() string {
+	if s.env == "" {
+		return s.key
+	}
+	return fmt.Sprintf("%s (%s)", s.env, s.key)
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Collects the settings of the struct v, descending into sections. Keys are the yaml
+// tags of the sections and the setting joined with dots.
+func settings(v reflect.Value, prefix string) []*setting {
+	var out []*setting
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("yaml")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		fv := v.Field(i)
+		if field.Type.Kind() == reflect.Struct && !isLeaf(field.Type) {
+			out = append(out, settings(fv, key+".")...)
+			continue
+		}
+		out = append(out, &setting{
+			key:      key,
+			env:      field.Tag.Get("env"),
+			desc:     field.Tag.Get("desc"),
+			required: field.Tag.Get("required") == "true",
+			secret:   field.Tag.Get("secret") == "true",
+			value:    fv,
+		})
+	}
+	return out
+}
+
+// structs holding a single value, such as a time or a rate limit, rather than a section
+func isLeaf(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// Load cfg, a pointer to a config struct holding defaults, from the YAML file named by
+// -config or CONFIG_FILE, then env vars, then flags in args. Each source overrides the one
+// before. Every missing or invalid value is reported in the returned error, and configs
+// with a Validate method are checked with it last.
+func Load(cfg interface{}, args []string) error {
+	all := settings(reflect.ValueOf(cfg).Elem(), "")
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(configFileEnv), "path of a YAML config file, also read from "+configFileEnv)
+	byKey := make(map[string]*setting, len(all))
+	for _, s := range all {
+		byKey[s.key] = s
+		usage := s.desc
+		if s.env != "" {
+			usage = fmt.Sprintf("%s (env %s)", usage, s.env)
+		}
+		fs.String(s.key, "", strings.TrimSpace(usage))
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	if *configFile != "" {
+		problems = append(problems, loadFile(*configFile, byKey)...)
+	}
+
+	for _, s := range all {
+		if s.env == "" {
+			continue
+		}
+		//empty vars count as unset, env files often list every var
+		if val := os.Getenv(s.env); val != "" {
+			if err := set(s.value, val); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", s.name(), err))
+			}
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := byKey[f.Name]
+		if !ok {
+			return
+		}
+		if err := set(s.value, f.Value.String()); err != nil {
+			problems = append(problems, fmt.Sprintf("-%s: %v", f.Name, err))
+		}
+	})
+
+	for _, s := range all {
+		if s.required && s.value.IsZero() {
+			problems = append(problems, fmt.Sprintf("%s is required", s.name()))
+		}
+	}
+
+	if v, ok := cfg.(interface{ Validate() error }); ok && len(problems) == 0 {
+		if err := v.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid config:\n  - " + strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// Read the settings found in the YAML file at path. Keys that match no setting are
+// reported so typos do not go unnoticed.
+func loadFile(path string, byKey map[string]*setting) []string {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("config file: %v", err)}
+	}
+	doc := map[string]interface{}{}
+	err = yaml.Unmarshal(raw, &doc)
+	if err != nil {
+		return []string{fmt.Sprintf("config file %s: %v", path, err)}
+	}
+
+	values := map[string]string{}
+	flatten(doc, "", values)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("config file %s: unknown setting %s", path, key))
+			continue
+		}
+		if err := set(s.value, values[key]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	return problems
+}
+
+// flattens nested YAML maps into dotted keys, lists become comma separated values
+func flatten(doc map[string]interface{}, prefix string, out map[string]string) {
+	for k, v := range doc {
+		key := prefix + k
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flatten(v, key+".", out)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(items, ",")
+		case nil:
+			out[key] = ""
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// Parses raw into the setting v. Lists are comma separated.
+func set(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("invalid value %q: %v", raw, err)
+		}
+		return nil
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice:
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, part := range strings.Split(raw, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := set(item, part); err != nil {
+				return err
+			}
+			items = reflect.Append(items, item)
+		}
+		v.Set(items)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// Returns the effective settings of cfg keyed by their dotted names, with secrets hidden,
+// for logging at startup
+func Redacted(cfg interface{}) map[string]string {
+	out := map[string]string{}
+	for _, s := range settings(reflect.Indirect(reflect.ValueOf(cfg)), "") {
+		switch {
+		case s.secret && !s.value.IsZero():
+			out[s.key] = redacted
+		default:
+			out[s.key] = format(s.value)
+		}
+	}
+	return out
+}
+
+func format(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err == nil {
+			return string(text)
+		}
+	}
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = format(v.Index(i))
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -1,41 +1,50 @@
 package db
 
 import (
+	"backend/config"
+	"backend/tracing"
+	"backend/utils"
+	"context"
 	"database/sql"
-	"fmt"
-	"log"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
-)
-
-const (
-	HOST = "dash_db"
-	PORT = 5432
+	"github.com/sirupsen/logrus"
 )
 
 type DashboardDb struct {
 	Conn *sql.DB
+	//schema version reached by migrations at startup
+	Version uint
 }
 
-func Initialize(username, password, database string) (DashboardDb, error) {
+// wait between attempts to reach the database at startup
+var connectBackoff = utils.Backoff{Initial: 500 * time.Millisecond, Max: 5 * time.Second}
+
+// Connects to the database and applies migrations. The database is retried with backoff
+// until ctx is done, as it may still be starting.
+func Initialize(ctx context.Context, cfg config.Database, l logrus.FieldLogger) (DashboardDb, error) {
 	//creating database connection
 	db := DashboardDb{}
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		HOST, PORT, username, password, database)
-
-	conn, err := sql.Open("postgres", dsn)
+	conn, err := tracing.OpenPostgres(cfg.DSN())
 	if err != nil {
 		return db, err
 	}
+	conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	conn.SetMaxIdleConns(cfg.MaxIdleConns)
+	conn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	conn.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 	db.Conn = conn
-	err = db.Conn.Ping()
+	err = utils.Retry(ctx, connectBackoff, db.Conn.PingContext, func(err error, wait time.Duration) {
+		l.Printf("Database not reachable, retrying in %s: %v", wait, err)
+	})
 	if err != nil {
 		return db, err
 	}
-	log.Println("Database connection established")
+	l.Println("Database connection established")
 
 	//setting up migrations
 	//driver instance for migration
@@ -43,8 +52,8 @@ func Initialize(username, password, database string) (DashboardDb, error) {
 	if err != nil {
 		return db, err
 	}
-	//path migations is as per defined in Dockerfile
-	m, err := migrate.NewWithDatabaseInstance("file:///migrations", "postgres", driver)
+	//the Dockerfile copies migrations to the default path
+	m, err := migrate.NewWithDatabaseInstance(cfg.MigrationsPath, "postgres", driver)
 
 	if err != nil {
 		return db, err
@@ -54,7 +63,12 @@ func Initialize(username, password, database string) (DashboardDb, error) {
 		return db, err
 	}
 
-	log.Println("Migrations applied successfully")
+	version, _, err := m.Version()
+	if err != nil {
+		return db, err
+	}
+	db.Version = version
+	l.Printf("Migrations applied successfully, schema at version %d", version)
 
 	return db, nil
 }
@@ -0,0 +1,16 @@
+-- read-only links to a dashboard, or to one of its views when view_id is set.
+-- only a hash of the token is stored, the token itself is shown once on creation.
+CREATE TABLE share_link(
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    dashboard_id uuid NOT NULL REFERENCES dashboard(id) ON DELETE CASCADE,
+    view_id uuid REFERENCES view(id) ON DELETE CASCADE,
+    token_hash text NOT NULL UNIQUE,
+    password_hash text,
+    expires_at timestamp,
+    created_by uuid NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    revoked_at timestamp,
+    last_used_at timestamp
+);
+
+CREATE INDEX share_link_dashboard_idx ON share_link (dashboard_id);
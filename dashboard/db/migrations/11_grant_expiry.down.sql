@@ -0,0 +1,87 @@
+-- permission views without grant expiry
+DROP VIEW IF EXISTS dashboard_perms;
+DROP VIEW IF EXISTS view_perms;
+
+-- dashboard perm view, direct roles and roles inherited from folders.
+-- folder_id is the folder an inherited role was granted on, NULL for direct roles.
+CREATE VIEW dashboard_perms AS
+SELECT
+    urd.user_id as user_id,
+    d.id as dash_id,
+    d.name as dash_name,
+    d.description as dash_description,
+    r.id as role_id,
+    r.name as role_name,
+    p.id as perm_id,
+    p.name as perm_name,
+    NULL::uuid as folder_id
+FROM
+    roles r,
+    permissions p,
+    role_has_permissions rhp,
+    user_role_dashboard urd,
+    dashboard d
+WHERE
+    urd.role_id = rhp.role_id
+    AND rhp.role_id = r.id
+    AND rhp.permission_id = p.id
+    AND urd.dashboard_id = d.id
+    AND d.deleted_at IS NULL
+UNION ALL
+SELECT
+    fp.user_id as user_id,
+    d.id as dash_id,
+    d.name as dash_name,
+    d.description as dash_description,
+    fp.role_id as role_id,
+    fp.role_name as role_name,
+    fp.perm_id as perm_id,
+    fp.perm_name as perm_name,
+    fp.granted_on as folder_id
+FROM
+    folder_perms fp,
+    dashboard d
+WHERE
+    fp.folder_id = d.folder_id
+    AND d.deleted_at IS NULL;
+
+-- view perm view, hiding trashed views and views of trashed dashboards
+CREATE VIEW view_perms AS
+SELECT
+    v.dashboard_id as dash_id,
+    urv.user_id as user_id,
+    v.id as view_id,
+    v.name as view_name,
+    v.description as view_desc,
+    r.id as role_id,
+    r.name as role_name,
+    p.id as perm_id,
+    p.name as perm_name
+FROM
+    roles r,
+    permissions p,
+    role_has_permissions rhp,
+    user_role_view urv,
+    view v,
+    dashboard d
+WHERE
+    urv.role_id = rhp.role_id
+    AND rhp.role_id = r.id
+    AND rhp.permission_id = p.id
+    AND urv.view_id = v.id
+    AND v.dashboard_id = d.id
+    AND v.deleted_at IS NULL
+    AND d.deleted_at IS NULL;
+
+DROP TABLE IF EXISTS access_audit;
+
+DROP INDEX IF EXISTS user_role_view_expires_idx;
+DROP INDEX IF EXISTS user_role_dashboard_expires_idx;
+
+ALTER TABLE user_role_view
+    DROP COLUMN IF EXISTS expiry_notified_at,
+    DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE user_role_dashboard
+    DROP COLUMN IF EXISTS expiry_notified_at,
+    DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,103 @@
+-- grants can lapse at expires_at, admin grants never do.
+-- expiry_notified_at is set once admins were told a grant is about to lapse.
+ALTER TABLE user_role_dashboard
+    ADD COLUMN expires_at timestamp,
+    ADD COLUMN expiry_notified_at timestamp;
+
+ALTER TABLE user_role_view
+    ADD COLUMN expires_at timestamp,
+    ADD COLUMN expiry_notified_at timestamp;
+
+CREATE INDEX user_role_dashboard_expires_idx ON user_role_dashboard (expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX user_role_view_expires_idx ON user_role_view (expires_at) WHERE expires_at IS NOT NULL;
+
+-- record of grants removed without a user revoking them
+CREATE TABLE access_audit(
+    id bigserial PRIMARY KEY,
+    action text NOT NULL,
+    user_id uuid NOT NULL,
+    role_id int,
+    dashboard_id uuid,
+    view_id uuid,
+    expires_at timestamp,
+    created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX access_audit_dashboard_idx ON access_audit (dashboard_id, created_at);
+
+-- dashboard perm view, leaving out lapsed grants
+CREATE
+OR REPLACE VIEW dashboard_perms AS
+SELECT
+    urd.user_id as user_id,
+    d.id as dash_id,
+    d.name as dash_name,
+    d.description as dash_description,
+    r.id as role_id,
+    r.name as role_name,
+    p.id as perm_id,
+    p.name as perm_name,
+    NULL::uuid as folder_id,
+    urd.expires_at as expires_at
+FROM
+    roles r,
+    permissions p,
+    role_has_permissions rhp,
+    user_role_dashboard urd,
+    dashboard d
+WHERE
+    urd.role_id = rhp.role_id
+    AND rhp.role_id = r.id
+    AND rhp.permission_id = p.id
+    AND urd.dashboard_id = d.id
+    AND d.deleted_at IS NULL
+    AND (urd.expires_at IS NULL OR urd.expires_at > NOW())
+UNION ALL
+SELECT
+    fp.user_id as user_id,
+    d.id as dash_id,
+    d.name as dash_name,
+    d.description as dash_description,
+    fp.role_id as role_id,
+    fp.role_name as role_name,
+    fp.perm_id as perm_id,
+    fp.perm_name as perm_name,
+    fp.granted_on as folder_id,
+    NULL::timestamp as expires_at
+FROM
+    folder_perms fp,
+    dashboard d
+WHERE
+    fp.folder_id = d.folder_id
+    AND d.deleted_at IS NULL;
+
+-- view perm view, leaving out lapsed grants
+CREATE
+OR REPLACE VIEW view_perms AS
+SELECT
+    v.dashboard_id as dash_id,
+    urv.user_id as user_id,
+    v.id as view_id,
+    v.name as view_name,
+    v.description as view_desc,
+    r.id as role_id,
+    r.name as role_name,
+    p.id as perm_id,
+    p.name as perm_name,
+    urv.expires_at as expires_at
+FROM
+    roles r,
+    permissions p,
+    role_has_permissions rhp,
+    user_role_view urv,
+    view v,
+    dashboard d
+WHERE
+    urv.role_id = rhp.role_id
+    AND rhp.role_id = r.id
+    AND rhp.permission_id = p.id
+    AND urv.view_id = v.id
+    AND v.dashboard_id = d.id
+    AND v.deleted_at IS NULL
+    AND d.deleted_at IS NULL
+    AND (urv.expires_at IS NULL OR urv.expires_at > NOW());
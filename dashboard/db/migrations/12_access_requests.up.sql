@@ -0,0 +1,18 @@
+-- requests by users for a role on a dashboard, decided by holders of edit_access
+CREATE TABLE access_request(
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    dashboard_id uuid NOT NULL REFERENCES dashboard(id) ON DELETE CASCADE,
+    requester_id uuid NOT NULL,
+    role_id int NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    justification text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    decided_by uuid,
+    granted_role_id int REFERENCES roles(id) ON DELETE SET NULL,
+    decision_note text,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    decided_at timestamp
+);
+
+-- a user has at most one open request per dashboard
+CREATE UNIQUE INDEX access_request_pending_idx ON access_request (dashboard_id, requester_id) WHERE status = 'pending';
+CREATE INDEX access_request_requester_idx ON access_request (requester_id, created_at);
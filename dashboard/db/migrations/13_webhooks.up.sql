@@ -0,0 +1,32 @@
+-- endpoints receiving events, for one dashboard or for all of them when dashboard_id is null
+CREATE TABLE webhook(
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    dashboard_id uuid REFERENCES dashboard(id) ON DELETE CASCADE,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text[] NOT NULL,
+    active boolean NOT NULL DEFAULT true,
+    created_by uuid NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webhook_dashboard_idx ON webhook (dashboard_id);
+
+-- queue and log of events sent to webhooks
+CREATE TABLE webhook_delivery(
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id uuid NOT NULL REFERENCES webhook(id) ON DELETE CASCADE,
+    event_id uuid NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts int NOT NULL DEFAULT 0,
+    next_attempt_at timestamp NOT NULL DEFAULT NOW(),
+    last_status_code int,
+    last_error text,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    delivered_at timestamp
+);
+
+CREATE INDEX webhook_delivery_due_idx ON webhook_delivery (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_delivery_webhook_idx ON webhook_delivery (webhook_id, created_at);
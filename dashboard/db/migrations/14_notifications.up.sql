@@ -0,0 +1,23 @@
+-- in-app notifications of a user, unread while read_at is null
+CREATE TABLE notification(
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    type text NOT NULL,
+    dashboard_id uuid REFERENCES dashboard(id) ON DELETE CASCADE,
+    view_id uuid REFERENCES view(id) ON DELETE CASCADE,
+    message text NOT NULL,
+    data jsonb,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    read_at timestamp
+);
+
+CREATE INDEX notification_user_idx ON notification (user_id, created_at DESC);
+CREATE INDEX notification_unread_idx ON notification (user_id) WHERE read_at IS NULL;
+
+-- kinds of notification a user turned off, every kind is on unless listed here
+CREATE TABLE notification_preference(
+    user_id uuid NOT NULL,
+    type text NOT NULL,
+    enabled boolean NOT NULL,
+    PRIMARY KEY (user_id, type)
+);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS dashboard_is_template_idx;
+
+ALTER TABLE dashboard
+    DROP COLUMN is_template,
+    DROP COLUMN template_variables;
+
+ALTER TABLE view
+    DROP COLUMN config;
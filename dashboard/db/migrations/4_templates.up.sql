@@ -0,0 +1,10 @@
+-- widget configuration of a view, free form json
+ALTER TABLE view
+    ADD COLUMN config jsonb NOT NULL DEFAULT '{}';
+
+-- dashboards marked as templates declare variables substituted on instantiation
+ALTER TABLE dashboard
+    ADD COLUMN is_template boolean NOT NULL DEFAULT false,
+    ADD COLUMN template_variables jsonb NOT NULL DEFAULT '[]';
+
+CREATE INDEX dashboard_is_template_idx ON dashboard (is_template) WHERE is_template;
@@ -0,0 +1,2 @@
+ALTER TABLE dashboard
+    DROP COLUMN layout;
@@ -0,0 +1,3 @@
+-- grid placement of views on a dashboard
+ALTER TABLE dashboard
+    ADD COLUMN layout jsonb NOT NULL DEFAULT '[]';
@@ -0,0 +1,23 @@
+-- comment threads on dashboards, or on a view when view_id is set
+CREATE TABLE comment(
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    dashboard_id uuid NOT NULL REFERENCES dashboard(id) ON DELETE CASCADE,
+    view_id uuid REFERENCES view(id) ON DELETE CASCADE,
+    parent_id uuid REFERENCES comment(id) ON DELETE CASCADE, -- replies point to the root of the thread
+    author_id uuid NOT NULL,
+    body text NOT NULL,
+    resolved_at timestamp,
+    resolved_by uuid,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    updated_at timestamp
+);
+
+CREATE INDEX comment_dashboard_idx ON comment (dashboard_id, created_at) WHERE view_id IS NULL;
+CREATE INDEX comment_view_idx ON comment (view_id, created_at) WHERE view_id IS NOT NULL;
+
+-- users mentioned in a comment
+CREATE TABLE comment_mention(
+    comment_id uuid NOT NULL REFERENCES comment(id) ON DELETE CASCADE,
+    user_id uuid NOT NULL,
+    PRIMARY KEY (comment_id, user_id)
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS dashboard_updated_at_id_idx;
+DROP INDEX IF EXISTS dashboard_created_at_id_idx;
+DROP INDEX IF EXISTS dashboard_name_id_idx;
+
+ALTER TABLE dashboard
+    DROP COLUMN updated_at;
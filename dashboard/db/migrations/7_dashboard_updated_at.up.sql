@@ -0,0 +1,9 @@
+ALTER TABLE dashboard
+    ADD COLUMN updated_at timestamp NOT NULL DEFAULT NOW();
+
+UPDATE dashboard SET updated_at = created_at;
+
+-- keyset pagination of dashboard listings
+CREATE INDEX dashboard_name_id_idx ON dashboard (name, id);
+CREATE INDEX dashboard_created_at_id_idx ON dashboard (created_at, id);
+CREATE INDEX dashboard_updated_at_id_idx ON dashboard (updated_at, id);
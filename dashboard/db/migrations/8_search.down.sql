@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS view_search_idx;
+DROP INDEX IF EXISTS dashboard_search_idx;
+
+ALTER TABLE view DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE dashboard DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,17 @@
+-- full-text search over dashboards and views, names rank above descriptions
+ALTER TABLE dashboard
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED;
+
+-- widget titles anywhere in the view config are searchable too
+ALTER TABLE view
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+        setweight(to_tsvector('english', jsonb_path_query_array(config, 'strict $.**.title')::text), 'C')
+    ) STORED;
+
+CREATE INDEX dashboard_search_idx ON dashboard USING GIN (search_vector);
+CREATE INDEX view_search_idx ON view USING GIN (search_vector);
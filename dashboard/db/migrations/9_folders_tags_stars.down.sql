@@ -0,0 +1,38 @@
+DROP TABLE IF EXISTS dashboard_visit;
+DROP TABLE IF EXISTS dashboard_star;
+DROP TABLE IF EXISTS dashboard_tags;
+
+-- dashboard perm view without folder roles
+DROP VIEW IF EXISTS dashboard_perms;
+
+CREATE VIEW dashboard_perms AS
+SELECT
+    urd.user_id as user_id,
+    d.id as dash_id,
+    d.name as dash_name,
+    d.description as dash_description,
+    r.id as role_id,
+    r.name as role_name,
+    p.id as perm_id,
+    p.name as perm_name
+FROM
+    roles r,
+    permissions p,
+    role_has_permissions rhp,
+    user_role_dashboard urd,
+    dashboard d
+WHERE
+    urd.role_id = rhp.role_id
+    AND rhp.role_id = r.id
+    AND rhp.permission_id = p.id
+    AND urd.dashboard_id = d.id
+    AND d.deleted_at IS NULL;
+
+DROP VIEW IF EXISTS folder_perms;
+DROP VIEW IF EXISTS folder_ancestors;
+DROP TABLE IF EXISTS user_role_folder;
+
+DROP INDEX IF EXISTS dashboard_folder_idx;
+ALTER TABLE dashboard DROP COLUMN IF EXISTS folder_id;
+
+DROP TABLE IF EXISTS folder;
@@ -0,0 +1,128 @@
+-- folders nest through parent_id; dashboards inside inherit roles granted on any enclosing folder
+CREATE TABLE folder(
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    parent_id uuid REFERENCES folder(id),
+    name text NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX folder_parent_idx ON folder (parent_id);
+
+ALTER TABLE dashboard
+    ADD COLUMN folder_id uuid REFERENCES folder(id);
+
+CREATE INDEX dashboard_folder_idx ON dashboard (folder_id) WHERE folder_id IS NOT NULL;
+
+-- relating users to roles for folder
+CREATE TABLE user_role_folder(
+    user_id uuid NOT NULL,
+    role_id int NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    folder_id uuid NOT NULL REFERENCES folder(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, folder_id) -- user can have only one role per folder
+);
+
+-- every folder paired with itself and each folder above it
+CREATE
+OR REPLACE VIEW folder_ancestors AS
+WITH RECURSIVE fa(folder_id, ancestor_id) AS (
+    SELECT id, id FROM folder
+    UNION
+    SELECT fa.folder_id, f.parent_id
+    FROM fa JOIN folder f ON f.id = fa.ancestor_id
+    WHERE f.parent_id IS NOT NULL
+)
+SELECT folder_id, ancestor_id FROM fa;
+
+-- folder perm view, a role on a folder applies to all folders below it
+CREATE
+OR REPLACE VIEW folder_perms AS
+SELECT
+    urf.user_id as user_id,
+    fa.folder_id as folder_id,
+    urf.folder_id as granted_on,
+    r.id as role_id,
+    r.name as role_name,
+    p.id as perm_id,
+    p.name as perm_name
+FROM
+    roles r,
+    permissions p,
+    role_has_permissions rhp,
+    user_role_folder urf,
+    folder_ancestors fa
+WHERE
+    urf.role_id = rhp.role_id
+    AND rhp.role_id = r.id
+    AND rhp.permission_id = p.id
+    AND urf.folder_id = fa.ancestor_id;
+
+-- dashboard perm view, direct roles and roles inherited from folders.
+-- folder_id is the folder an inherited role was granted on, NULL for direct roles.
+CREATE
+OR REPLACE VIEW dashboard_perms AS
+SELECT
+    urd.user_id as user_id,
+    d.id as dash_id,
+    d.name as dash_name,
+    d.description as dash_description,
+    r.id as role_id,
+    r.name as role_name,
+    p.id as perm_id,
+    p.name as perm_name,
+    NULL::uuid as folder_id
+FROM
+    roles r,
+    permissions p,
+    role_has_permissions rhp,
+    user_role_dashboard urd,
+    dashboard d
+WHERE
+    urd.role_id = rhp.role_id
+    AND rhp.role_id = r.id
+    AND rhp.permission_id = p.id
+    AND urd.dashboard_id = d.id
+    AND d.deleted_at IS NULL
+UNION ALL
+SELECT
+    fp.user_id as user_id,
+    d.id as dash_id,
+    d.name as dash_name,
+    d.description as dash_description,
+    fp.role_id as role_id,
+    fp.role_name as role_name,
+    fp.perm_id as perm_id,
+    fp.perm_name as perm_name,
+    fp.granted_on as folder_id
+FROM
+    folder_perms fp,
+    dashboard d
+WHERE
+    fp.folder_id = d.folder_id
+    AND d.deleted_at IS NULL;
+
+-- free-form labels on dashboards
+CREATE TABLE dashboard_tags(
+    dashboard_id uuid NOT NULL REFERENCES dashboard(id) ON DELETE CASCADE,
+    tag text NOT NULL,
+    PRIMARY KEY (dashboard_id, tag)
+);
+
+CREATE INDEX dashboard_tags_tag_idx ON dashboard_tags (tag);
+
+-- dashboards starred by a user
+CREATE TABLE dashboard_star(
+    user_id uuid NOT NULL,
+    dashboard_id uuid NOT NULL REFERENCES dashboard(id) ON DELETE CASCADE,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, dashboard_id)
+);
+
+-- last time a user opened a dashboard
+CREATE TABLE dashboard_visit(
+    user_id uuid NOT NULL,
+    dashboard_id uuid NOT NULL REFERENCES dashboard(id) ON DELETE CASCADE,
+    visited_at timestamp NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, dashboard_id)
+);
+
+CREATE INDEX dashboard_visit_user_idx ON dashboard_visit (user_id, visited_at DESC);
@@ -1,8 +1,36 @@
 package errors
 
-import "fmt"
+import (
+	"backend/utils"
+	"net/http"
+)
 
-var ErrUnauthorized = fmt.Errorf("unauthorized")
-var ErrNoPerm = fmt.Errorf("no permission")
-var ErrUnimplemented = fmt.Errorf("unimplemented")
-var ErrCannotRevokeLastAdmin = fmt.Errorf("cannot revoke last admin")
+// Errors of the dashboard service. Codes are part of the API, clients match on them, so
+// they must not change once released.
+var ErrUnauthorized = utils.NewAPIError("unauthorized", http.StatusUnauthorized, "unauthorized")
+var ErrNoPerm = utils.NewAPIError("no_permission", http.StatusForbidden, "no permission")
+var ErrUnimplemented = utils.ErrNotImplemented
+var ErrCannotRevokeLastAdmin = utils.NewAPIError("last_admin", http.StatusConflict, "cannot revoke last admin")
+var ErrNotFound = utils.ErrNotFound
+var ErrRestoreWindowExpired = utils.NewAPIError("restore_window_expired", http.StatusGone, "restore window has expired")
+var ErrNotTemplate = utils.NewAPIError("not_template", http.StatusBadRequest, "dashboard is not a template")
+var ErrTemplateVariables = utils.NewAPIError("invalid_template_variables", http.StatusBadRequest, "invalid template variables")
+var ErrUnsupportedExportVersion = utils.NewAPIError("unsupported_export_version", http.StatusBadRequest, "unsupported export version")
+var ErrInvalidExport = utils.NewAPIError("invalid_export", http.StatusBadRequest, "invalid export document")
+var ErrInvalidLayout = utils.NewAPIError("invalid_layout", http.StatusBadRequest, "layout refers to a view not on this dashboard")
+var ErrNotAuthor = utils.NewAPIError("not_author", http.StatusForbidden, "only the author can change a comment")
+var ErrInvalidParent = utils.NewAPIError("invalid_parent", http.StatusBadRequest, "parent comment is not on this dashboard or view")
+var ErrInvalidCursor = utils.NewAPIError("invalid_cursor", http.StatusBadRequest, "invalid cursor")
+var ErrFolderNotEmpty = utils.NewAPIError("folder_not_empty", http.StatusConflict, "folder is not empty")
+var ErrFolderCycle = utils.NewAPIError("folder_cycle", http.StatusConflict, "folder cannot be moved into itself")
+var ErrInvalidTag = utils.NewAPIError("invalid_tag", http.StatusBadRequest, "invalid tag")
+var ErrInvalidExpiry = utils.NewAPIError("invalid_expiry", http.StatusBadRequest, "invalid expiry")
+var ErrPasswordRequired = utils.NewAPIError("password_required", http.StatusUnauthorized, "a valid password is required")
+var ErrAdminGrantCannotExpire = utils.NewAPIError("admin_grant_cannot_expire", http.StatusBadRequest, "admin grants cannot expire")
+var ErrInvalidRole = utils.NewAPIError("invalid_role", http.StatusBadRequest, "invalid role name")
+var ErrDuplicateRequest = utils.NewAPIError("duplicate_request", http.StatusConflict, "a request for this dashboard is already pending")
+var ErrRequestDecided = utils.NewAPIError("request_decided", http.StatusConflict, "request was already decided")
+var ErrInvalidEvent = utils.NewAPIError("invalid_event", http.StatusBadRequest, "unknown event type")
+var ErrInvalidWebhookURL = utils.NewAPIError("invalid_webhook_url", http.StatusBadRequest, "webhook url must be an absolute http or https url")
+var ErrInvalidNotificationType = utils.NewAPIError("invalid_notification_type", http.StatusBadRequest, "unknown notification type")
+var ErrStreamingUnsupported = utils.NewAPIError("streaming_unsupported", http.StatusInternalServerError, "streaming is not supported")
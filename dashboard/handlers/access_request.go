@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type AccessRequestHandler struct {
+	l logrus.FieldLogger
+	s *services.AccessRequestService
+}
+
+// NewAccessRequest creates a new access request handler with the given logger and service
+func NewAccessRequest(l logrus.FieldLogger, s *services.AccessRequestService) *AccessRequestHandler {
+	return &AccessRequestHandler{l, s}
+}
+
+type requestAccessRequest struct {
+	Role          string `json:"role" validate:"required,role"`
+	Justification string `json:"justification" validate:"required,max=2000"`
+}
+
+type approveAccessRequest struct {
+	Role      string     `json:"role" validate:"omitempty,role"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Note      *string    `json:"note" validate:"omitempty,max=2000"`
+}
+
+type denyAccessRequest struct {
+	Note *string `json:"note" validate:"omitempty,max=2000"`
+}
+
+/**
+ * @api {post} /dashboard/:id/access-requests Request access
+ * @apiName Ask for a role on a dashboard. Users who can manage access to it are notified.
+ * @apiGroup AccessRequest
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiBody {String} role Role asked for
+ * @apiBody {String} justification Why access is needed
+ */
+
+func (h *AccessRequestHandler) RequestAccess(w http.ResponseWriter, r *http.Request) {
+	dashId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &requestAccessRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	accessReq, err := h.s.RequestAccess(r.Context(), userId, dashId, req.Role, req.Justification)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not request access: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, accessReq)
+}
+
+/**
+ * @api {get} /dashboard/:id/access-requests Get access requests
+ * @apiName Get access requests for a dashboard. Needs edit_access.
+ * @apiGroup AccessRequest
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiQuery {String="pending","approved","denied"} [status=pending] Only requests in this state, all when empty
+ */
+
+func (h *AccessRequestHandler) GetAccessRequests(w http.ResponseWriter, r *http.Request) {
+	dashId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	status := models.AccessRequestPending
+	if q := r.URL.Query(); q.Has("status") {
+		status = q.Get("status")
+	}
+	if status != "" && status != models.AccessRequestPending && status != models.AccessRequestApproved && status != models.AccessRequestDenied {
+		utils.WriteError(w, r, utils.ErrInvalidQuery.WithDetail("status must be pending, approved or denied"))
+		return
+	}
+
+	reqs, err := h.s.GetAccessRequests(r.Context(), userId, dashId, status)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get access requests: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, reqs)
+}
+
+/**
+ * @api {get} /access-requests Get my access requests
+ * @apiName Get the access requests you made
+ * @apiGroup AccessRequest
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *AccessRequestHandler) GetMyAccessRequests(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	reqs, err := h.s.GetMyAccessRequests(r.Context(), userId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get access requests: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, reqs)
+}
+
+/**
+ * @api {post} /access-requests/:id/approve Approve access request
+ * @apiName Grant the requester a role on the dashboard. Needs edit_access.
+ * @apiGroup AccessRequest
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Access request ID
+ * @apiBody {String} [role] Role to grant instead of the requested one
+ * @apiBody {String} [expiresAt] RFC 3339 time at which the role lapses
+ * @apiBody {String} [note] Note to the requester
+ */
+
+func (h *AccessRequestHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	requestId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &approveAccessRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	accessReq, err := h.s.Approve(r.Context(), userId, requestId, req.Role, req.ExpiresAt, req.Note)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not approve access request: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, accessReq)
+}
+
+/**
+ * @api {post} /access-requests/:id/deny Deny access request
+ * @apiName Turn down a request for access. Needs edit_access.
+ * @apiGroup AccessRequest
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Access request ID
+ * @apiBody {String} [note] Note to the requester
+ */
+
+func (h *AccessRequestHandler) Deny(w http.ResponseWriter, r *http.Request) {
+	requestId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &denyAccessRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	accessReq, err := h.s.Deny(r.Context(), userId, requestId, req.Note)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not deny access request: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, accessReq)
+}
+
+func (h *AccessRequestHandler) parseIds(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, userId, true
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type cloneDashRequest struct {
+	Name          string `json:"name" validate:"omitempty,name"`
+	IncludeGrants bool   `json:"includeGrants"`
+}
+
+/**
+ * @api {post} /dashboard/:id/clone Clone dashboard
+ * @apiName Deep copy a dashboard with all views you can read
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiBody {String} [name] Name of the copy. Defaults to "Copy of <name>"
+ * @apiBody {Boolean} [includeGrants] Copy roles of other users too. Requires edit_access on the source.
+ */
+
+func (h *DashHandler) CloneDash(w http.ResponseWriter, r *http.Request) {
+	dashId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
+		return
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	// an empty body clones with defaults
+	req := &cloneDashRequest{}
+	if r.ContentLength != 0 && !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	clone, err := h.s.CloneDash(r.Context(), userId, dashId, req.Name, req.IncludeGrants)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not clone dashboard: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, clone)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type CommentHandler struct {
+	l logrus.FieldLogger
+	s *services.CommentService
+}
+
+// NewComment creates a new comment handler with the given logger and service
+func NewComment(l logrus.FieldLogger, s *services.CommentService) *CommentHandler {
+	return &CommentHandler{l, s}
+}
+
+type addCommentRequest struct {
+	Body     string     `json:"body" validate:"required,max=10000"`
+	ParentID *uuid.UUID `json:"parentId"`
+}
+
+type updateCommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+/**
+ * @api {get} /dashboard/:id/comments Get dashboard comments
+ * @apiName Get comment threads on a dashboard
+ * @apiGroup Comment
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ */
+
+func (h *CommentHandler) GetDashComments(w http.ResponseWriter, r *http.Request) {
+	h.getComments(w, r, h.s.GetDashComments)
+}
+
+/**
+ * @api {get} /view/:id/comments Get view comments
+ * @apiName Get comment threads on a view
+ * @apiGroup Comment
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id View ID
+ */
+
+func (h *CommentHandler) GetViewComments(w http.ResponseWriter, r *http.Request) {
+	h.getComments(w, r, h.s.GetViewComments)
+}
+
+/**
+ * @api {post} /dashboard/:id/comments Comment on dashboard
+ * @apiName Start a thread or reply on a dashboard. Mention users with @email.
+ * @apiGroup Comment
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiBody {String} body Text of the comment
+ * @apiBody {String} [parentId] Comment to reply to
+ */
+
+func (h *CommentHandler) AddDashComment(w http.ResponseWriter, r *http.Request) {
+	h.addComment(w, r, h.s.AddDashComment)
+}
+
+/**
+ * @api {post} /view/:id/comments Comment on view
+ * @apiName Start a thread or reply on a view. Mention users with @email.
+ * @apiGroup Comment
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id View ID
+ * @apiBody {String} body Text of the comment
+ * @apiBody {String} [parentId] Comment to reply to
+ */
+
+func (h *CommentHandler) AddViewComment(w http.ResponseWriter, r *http.Request) {
+	h.addComment(w, r, h.s.AddViewComment)
+}
+
+/**
+ * @api {put} /comments/:id Edit comment
+ * @apiName Edit your own comment
+ * @apiGroup Comment
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Comment ID
+ * @apiBody {String} body New text of the comment
+ */
+
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	commentId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &updateCommentRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	comment, err := h.s.UpdateComment(r.Context(), userId, commentId, req.Body)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not update comment: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, comment)
+}
+
+/**
+ * @api {delete} /comments/:id Delete comment
+ * @apiName Delete your own comment along with its replies
+ * @apiGroup Comment
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Comment ID
+ */
+
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	commentId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.s.DeleteComment(r.Context(), userId, commentId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not delete comment: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Comment deleted")
+}
+
+/**
+ * @api {post} /comments/:id/resolve Resolve thread
+ * @apiName Resolve the thread a comment belongs to
+ * @apiGroup Comment
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Comment ID
+ */
+
+func (h *CommentHandler) ResolveComment(w http.ResponseWriter, r *http.Request) {
+	h.setResolved(w, r, true)
+}
+
+/**
+ * @api {delete} /comments/:id/resolve Unresolve thread
+ * @apiName Reopen the thread a comment belongs to
+ * @apiGroup Comment
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Comment ID
+ */
+
+func (h *CommentHandler) UnresolveComment(w http.ResponseWriter, r *http.Request) {
+	h.setResolved(w, r, false)
+}
+
+func (h *CommentHandler) setResolved(w http.ResponseWriter, r *http.Request, resolved bool) {
+	commentId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.s.SetResolved(r.Context(), userId, commentId, resolved)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not change thread resolution: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	if resolved {
+		utils.WriteSuccessResponseMsg(w, http.StatusOK, "Thread resolved")
+	} else {
+		utils.WriteSuccessResponseMsg(w, http.StatusOK, "Thread reopened")
+	}
+}
+
+func (h *CommentHandler) getComments(w http.ResponseWriter, r *http.Request, get func(ctx context.Context, userId, id uuid.UUID) ([]*models.Comment, error)) {
+	id, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := get(r.Context(), userId, id)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get comments: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, comments)
+}
+
+func (h *CommentHandler) addComment(w http.ResponseWriter, r *http.Request, add func(ctx context.Context, userId, id uuid.UUID, parentId *uuid.UUID, body string) (*models.Comment, error)) {
+	id, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &addCommentRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	comment, err := add(r.Context(), userId, id, req.ParentID, req.Body)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not add comment: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, comment)
+}
+
+// parses the id from the path and the user id from the token
+func (h *CommentHandler) parseIds(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, userId, true
+}
@@ -2,27 +2,20 @@ package handlers
 
 import (
 	"backend/dashboard/models"
+	"backend/logging"
 	"backend/middlewares"
 	"backend/utils"
-	"encoding/json"
-	"io"
 	"net/http"
 
-	"github.com/go-playground/validator"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type createDashRequest struct {
-	Name        string `json:"name" validate:"required"`
+	Name        string `json:"name" validate:"required,name"`
 	Description string `json:"description"`
 }
 
-func (req *createDashRequest) fromJSON(r io.Reader) error {
-	e := json.NewDecoder(r)
-	return e.Decode(req)
-}
-
 /**
  * @api {post} /dashboard Create dashboard
  * @apiName Create dashboard
@@ -36,33 +29,23 @@ func (req *createDashRequest) fromJSON(r io.Reader) error {
 func (dash *DashHandler) CreateDash(rw http.ResponseWriter, r *http.Request) {
 	// decoding payload to createDashRequest
 	d := &createDashRequest{}
-	err := d.fromJSON(r.Body)
-	if err != nil {
-		utils.WriteFailureResponse(rw, http.StatusBadRequest, "Invalid request payload")
-		return
-	}
-
-	// validating payload
-	validate := validator.New()
-	err = validate.Struct(d)
-	if err != nil {
-		utils.WriteFailureResponse(rw, http.StatusBadRequest, err.Error())
+	if !utils.DecodeAndValidate(rw, r, d) {
 		return
 	}
 
 	// extracting user id
 	userId, err := uuid.Parse(mux.Vars(r)[middlewares.KeyUser])
 	if err != nil {
-		utils.WriteFailureResponse(rw, http.StatusInternalServerError, "failed to parse user id from jwt.")
+		utils.WriteError(rw, r, utils.ErrInvalidToken)
 		return
 	}
 
 	// creating dashboard
 	newdash := &models.Dash{Name: d.Name, Description: d.Description}
-	dash.l.Println("Creating new dashboard with name: ", newdash.Name, " and description: ", newdash.Description)
-	err = dash.s.AddDash(newdash, userId)
+	logging.ForRequest(r, dash.l).Println("Creating new dashboard with name: ", newdash.Name, " and description: ", newdash.Description)
+	err = dash.s.AddDash(r.Context(), newdash, userId)
 	if err != nil {
-		utils.WriteFailureResponse(rw, http.StatusInternalServerError, err.Error())
+		utils.WriteError(rw, r, err)
 		return
 	}
 
@@ -5,7 +5,6 @@ import (
 	"backend/middlewares"
 	"backend/utils"
 	"encoding/json"
-	"io"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -20,40 +19,35 @@ import (
  * @apiBody {String} name Name of the view
  * @apiBody {uuid} dashboardId of the dashboard to which the view belongs
  * @apiBody {String} [description] Description of the view
+ * @apiBody {Object} [config] Widget configuration of the view
  */
 
 type createViewRequest struct {
-	DashboardId uuid.UUID `json:"dashboardId" validate:"required,uuid4"`
-	Name        string    `json:"name" validate:"required"`
-	Description string    `json:"description"`
-}
-
-func (req *createViewRequest) fromJSON(r io.Reader) error {
-	e := json.NewDecoder(r)
-	return e.Decode(req)
+	DashboardId uuid.UUID       `json:"dashboardId" validate:"required,uuid4"`
+	Name        string          `json:"name" validate:"required,name"`
+	Description string          `json:"description"`
+	Config      json.RawMessage `json:"config"`
 }
 
 func (h *DashHandler) CreateView(w http.ResponseWriter, r *http.Request) {
 	// decoding payload to createViewRequest
 	v := &createViewRequest{}
-	err := v.fromJSON(r.Body)
-	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid request payload")
+	if !utils.DecodeAndValidate(w, r, v) {
 		return
 	}
 
 	// extracting user id
 	userId, err := uuid.Parse(mux.Vars(r)[middlewares.KeyUser])
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, "failed to parse user id from jwt.")
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
 	// creating view
-	newview := &models.View{DashID: v.DashboardId, Name: v.Name, Description: v.Description}
-	err = h.s.Vs.AddView(newview, userId)
+	newview := &models.View{DashID: v.DashboardId, Name: v.Name, Description: v.Description, Config: v.Config}
+	err = h.s.Vs.AddView(r.Context(), newview, userId)
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, r, err)
 		return
 	}
 
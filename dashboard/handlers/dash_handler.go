@@ -2,15 +2,33 @@ package handlers
 
 import (
 	"backend/dashboard/services"
-	"log"
+	"backend/logging"
+	"backend/utils"
+	"context"
+
+	"github.com/go-playground/validator"
+	"github.com/sirupsen/logrus"
 )
 
 type DashHandler struct {
-	l *log.Logger
+	l logrus.FieldLogger
 	s *services.DashService
 }
 
 // NewDash creates a new dashboard handler with the given logger and repository
-func NewDash(l *log.Logger, s *services.DashService) *DashHandler {
+func NewDash(l logrus.FieldLogger, s *services.DashService) *DashHandler {
 	return &DashHandler{l, s}
 }
+
+// RegisterValidations adds the request rules backed by services. Call once before serving.
+func RegisterValidations(l logrus.FieldLogger, rs *services.RoleService) {
+	utils.RegisterValidation("role", func(ctx context.Context, fl validator.FieldLevel) bool {
+		ok, err := rs.RoleExists(ctx, fl.Field().String())
+		if err != nil {
+			//the grant would fail on the role as well, reject it rather than guess
+			logging.FromContext(ctx, l).WithError(err).Error("Could not look up roles")
+			return false
+		}
+		return ok
+	}, "must be a known role")
+}
@@ -1,25 +1,21 @@
 package handlers
 
 import (
+	er "backend/dashboard/errors"
 	"backend/dashboard/perms"
 	"backend/middlewares"
 	"backend/utils"
-	"encoding/json"
-	"io"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type addUserToDashRequest struct {
-	UserId uuid.UUID `json:"userId" validate:"required,uuid4"`
-	Role   string    `json:"role" validate:"required"`
-}
-
-func (r *addUserToDashRequest) fromJSON(body io.Reader) error {
-	e := json.NewDecoder(body)
-	return e.Decode(r)
+	UserId    uuid.UUID  `json:"userId" validate:"required,uuid4"`
+	Role      string     `json:"role" validate:"required,role"`
+	ExpiresAt *time.Time `json:"expiresAt"`
 }
 
 /**
@@ -30,46 +26,45 @@ func (r *addUserToDashRequest) fromJSON(body io.Reader) error {
  * @apiParam {String} dashboardId Dashboard ID
  * @apiBody {String} userId User ID for which the role is to be added
  * @apiBody {String} role Role to be added
+ * @apiBody {String} [expiresAt] RFC 3339 time at which the role lapses. Admin roles cannot lapse.
  */
 
 func (h *DashHandler) AddUserToDash(w http.ResponseWriter, r *http.Request) {
 	//get dashId from mux.Vars
 	dashId, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid dashboard id")
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
 		return
 	}
 
 	// decoding payload to addUserToDashRequest
 	req := &addUserToDashRequest{}
-	err = req.fromJSON(r.Body)
-	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid request payload")
+	if !utils.DecodeAndValidate(w, r, req) {
 		return
 	}
 
 	// extracting user id
 	userId, err := uuid.Parse(mux.Vars(r)[middlewares.KeyUser])
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, "failed to parse user id from jwt.")
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
-	can, err := h.s.Rs.ExistsPermissionForUserForDashboard(userId, dashId, perms.ACCESS_MOD)
+	can, err := h.s.Rs.ExistsPermissionForUserForDashboard(r.Context(), userId, dashId, perms.ACCESS_MOD)
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	if !can {
-		utils.WriteFailureResponse(w, http.StatusUnauthorized, "You are not authorized to add users to this dashboard")
+		utils.WriteError(w, r, er.ErrNoPerm)
 		return
 	}
 
 	// adding user to dashboard
-	err = h.s.Rs.AddUserToDash(dashId, req.UserId, req.Role)
+	err = h.s.Rs.AddUserToDash(r.Context(), dashId, req.UserId, req.Role, req.ExpiresAt, userId)
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -88,32 +83,32 @@ func (h *DashHandler) GetUsersFromDash(w http.ResponseWriter, r *http.Request) {
 	//get dashId from mux.Vars
 	dashId, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid dashboard id")
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
 		return
 	}
 
 	// extracting user id
 	userId, err := uuid.Parse(mux.Vars(r)[middlewares.KeyUser])
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, "failed to parse user id from jwt.")
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
-	can, err := h.s.Rs.ExistsPermissionForUserForDashboard(userId, dashId, perms.READ_PERM)
+	can, err := h.s.Rs.ExistsPermissionForUserForDashboard(r.Context(), userId, dashId, perms.READ_PERM)
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	if !can {
-		utils.WriteFailureResponse(w, http.StatusUnauthorized, "You are not authorized to view this dashboard")
+		utils.WriteError(w, r, er.ErrNoPerm)
 		return
 	}
 
 	// getting users from dashboard
-	users, err := h.s.Rs.GetRolesForUsersForDashboard(dashId)
+	users, err := h.s.Rs.GetRolesForUsersForDashboard(r.Context(), dashId)
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, r, err)
 		return
 	}
 
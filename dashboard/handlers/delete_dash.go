@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"backend/logging"
 	"backend/middlewares"
 	"backend/utils"
 	"net/http"
@@ -9,28 +10,36 @@ import (
 	"github.com/gorilla/mux"
 )
 
+/**
+ * @api {delete} /dashboard/:id Delete dashboard
+ * @apiName Move a dashboard to trash. It can be restored until the retention window expires.
+ * @apiGroup Trash
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ */
+
 func (h *DashHandler) DeleteDash(w http.ResponseWriter, r *http.Request) {
 	// Get the dashboard id from the request
 	id, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid dashboard id")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
 		return
 	}
 
 	// Get the user id from the request
 	userId, err := middlewares.GetUserIDFromVars(r)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, "Failed to decode id")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
-	// Delete the dashboard from the database
-	err = h.s.DeleteDashById(userId, id)
+	// Move the dashboard to trash
+	err = h.s.DeleteDashById(r.Context(), userId, id)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusForbidden, "Failed to delete dashboard")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, err)
 		return
 	}
 
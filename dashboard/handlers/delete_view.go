@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"backend/logging"
 	"backend/middlewares"
 	"backend/utils"
 	"net/http"
@@ -9,28 +10,36 @@ import (
 	"github.com/gorilla/mux"
 )
 
+/**
+ * @api {delete} /view/:id Delete view
+ * @apiName Move a view to trash. It can be restored until the retention window expires.
+ * @apiGroup Trash
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id View ID
+ */
+
 func (h *DashHandler) DeleteView(w http.ResponseWriter, r *http.Request) {
 	// Get the view id from the request
 	id, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid view id")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid view id"))
 		return
 	}
 
 	// Get the user id from the request
 	userId, err := middlewares.GetUserIDFromVars(r)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, "Failed to decode id")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
-	// Delete the view from the database
-	err = h.s.Vs.DeleteView(id, userId)
+	// Move the view to trash
+	err = h.s.Vs.DeleteView(r.Context(), id, userId)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusForbidden, "Failed to delete view")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, err)
 		return
 	}
 
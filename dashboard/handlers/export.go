@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type ExportHandler struct {
+	l logrus.FieldLogger
+	s *services.ExportService
+}
+
+// NewExport creates a new export handler with the given logger and service
+func NewExport(l logrus.FieldLogger, s *services.ExportService) *ExportHandler {
+	return &ExportHandler{l, s}
+}
+
+/**
+ * @api {get} /dashboard/:id/export Export dashboard
+ * @apiName Export a dashboard with its views and layout as a portable json document. The document is returned as is, not wrapped in the usual response.
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiQuery {Boolean} [includeRoles] Include role assignments by email. Requires edit_access.
+ */
+
+func (h *ExportHandler) ExportDash(w http.ResponseWriter, r *http.Request) {
+	dashId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
+		return
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	includeRoles, _ := strconv.ParseBool(r.URL.Query().Get("includeRoles"))
+
+	doc, err := h.s.ExportDash(r.Context(), userId, dashId, includeRoles)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not export dashboard: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"dashboard-%s.json\"", dashId))
+	w.WriteHeader(http.StatusOK)
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	e.Encode(doc)
+}
+
+/**
+ * @api {post} /dashboard/import Import dashboard
+ * @apiName Create a dashboard from an export document
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiQuery {Boolean} [dryRun] Only validate the document and report what would be created
+ * @apiBody {Object} document Document as produced by the export endpoint
+ */
+
+func (h *ExportHandler) ImportDash(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	doc := &models.DashExport{}
+	if !utils.DecodeAndValidate(w, r, doc) {
+		return
+	}
+
+	report, err := h.s.ImportDash(r.Context(), userId, doc, dryRun)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not import dashboard: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	if dryRun {
+		utils.WriteSuccessResponse(w, http.StatusOK, report)
+		return
+	}
+	utils.WriteSuccessResponse(w, http.StatusCreated, report)
+}
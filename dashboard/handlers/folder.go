@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type FolderHandler struct {
+	l logrus.FieldLogger
+	s *services.FolderService
+}
+
+// NewFolder creates a new folder handler with the given logger and service
+func NewFolder(l logrus.FieldLogger, s *services.FolderService) *FolderHandler {
+	return &FolderHandler{l, s}
+}
+
+type folderRequest struct {
+	Name     string     `json:"name" validate:"required,name"`
+	ParentID *uuid.UUID `json:"parentId"`
+}
+
+type addUserToFolderRequest struct {
+	UserID uuid.UUID `json:"userId" validate:"required,uuid4"`
+	Role   string    `json:"role" validate:"required,role"`
+}
+
+type deleteUserFromFolderRequest struct {
+	UserID uuid.UUID `json:"userId" validate:"required,uuid4"`
+}
+
+/**
+ * @api {post} /folders Create folder
+ * @apiName Create a folder. You become its admin.
+ * @apiGroup Folder
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiBody {String} name Name of the folder
+ * @apiBody {String} [parentId] Folder to create it in, needs edit permission on it
+ */
+
+func (h *FolderHandler) CreateFolder(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	req := &folderRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	folder := &models.Folder{Name: req.Name, ParentID: req.ParentID}
+	err = h.s.CreateFolder(r.Context(), userId, folder)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not create folder: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, folder)
+}
+
+/**
+ * @api {get} /folders Get folders
+ * @apiName Get all folders you can read
+ * @apiGroup Folder
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *FolderHandler) GetFolders(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	folders, err := h.s.GetFoldersForUser(r.Context(), userId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get folders: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, folders)
+}
+
+/**
+ * @api {get} /folders/:id Get folder
+ * @apiName Get a folder you can read. List its dashboards with GET /dashboard?folder=:id
+ * @apiGroup Folder
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Folder ID
+ */
+
+func (h *FolderHandler) GetFolder(w http.ResponseWriter, r *http.Request) {
+	folderId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	folder, err := h.s.GetFolder(r.Context(), userId, folderId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get folder: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, folder)
+}
+
+/**
+ * @api {put} /folders/:id Update folder
+ * @apiName Rename a folder or move it. Moving needs edit_access on the folder and edit on the destination.
+ * @apiGroup Folder
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Folder ID
+ * @apiBody {String} name Name of the folder
+ * @apiBody {String} [parentId] Folder to place it in, top level when omitted
+ */
+
+func (h *FolderHandler) UpdateFolder(w http.ResponseWriter, r *http.Request) {
+	folderId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &folderRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	folder, err := h.s.UpdateFolder(r.Context(), userId, folderId, req.Name, req.ParentID)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not update folder: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, folder)
+}
+
+/**
+ * @api {delete} /folders/:id Delete folder
+ * @apiName Delete a folder that holds no folders or dashboards
+ * @apiGroup Folder
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Folder ID
+ */
+
+func (h *FolderHandler) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	folderId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.s.DeleteFolder(r.Context(), userId, folderId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not delete folder: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Folder deleted")
+}
+
+/**
+ * @api {get} /folders/:id/users Get users for folder
+ * @apiName Get users with a role on a folder, including roles inherited from folders above it
+ * @apiGroup Folder
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Folder ID
+ */
+
+func (h *FolderHandler) GetUsersFromFolder(w http.ResponseWriter, r *http.Request) {
+	folderId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := h.s.GetUsersFromFolder(r.Context(), userId, folderId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get folder users: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, users)
+}
+
+/**
+ * @api {post} /folders/:id/users Add user to folder
+ * @apiName Upsert a user with a role to a folder. The role applies to every dashboard below the folder.
+ * @apiGroup Folder
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Folder ID
+ * @apiBody {String} userId User ID for which the role is to be added
+ * @apiBody {String} role Role to be added
+ */
+
+func (h *FolderHandler) AddUserToFolder(w http.ResponseWriter, r *http.Request) {
+	folderId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &addUserToFolderRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	err := h.s.AddUserToFolder(r.Context(), userId, folderId, req.UserID, req.Role)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not add user to folder: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, nil)
+}
+
+/**
+ * @api {delete} /folders/:id/users Remove user from folder
+ * @apiName Revoke the role of a user on a folder
+ * @apiGroup Folder
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Folder ID
+ * @apiBody {String} userId User ID whose role is revoked
+ */
+
+func (h *FolderHandler) DeleteUserFromFolder(w http.ResponseWriter, r *http.Request) {
+	folderId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &deleteUserFromFolderRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	err := h.s.DeleteUserFromFolder(r.Context(), userId, folderId, req.UserID)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not remove user from folder: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "User removed from folder")
+}
+
+func (h *FolderHandler) parseIds(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid folder id"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, userId, true
+}
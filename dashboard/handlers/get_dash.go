@@ -2,9 +2,14 @@ package handlers
 
 import (
 	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/logging"
 	"backend/middlewares"
 	"backend/utils"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -23,64 +28,150 @@ func (h *DashHandler) GetDash(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	dashId, err := uuid.Parse(vars["id"])
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusBadRequest, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidID)
 		return
 	}
 
 	//getting user id from mux.Vars
 	userId, err := middlewares.GetUserIDFromVars(r)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
 	//getting dashboard from database
-	dash, err := h.s.GetDashByIdForUser(userId, dashId)
+	dash, err := h.s.GetDashByIdForUser(r.Context(), userId, dashId)
 	if err != nil {
-		h.l.Printf("Could not get dashboard: %v", err)
-		if err == er.ErrNoPerm {
-			utils.WriteFailureResponse(w, http.StatusForbidden, err.Error())
-		} else {
-			utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
-		}
+		logging.ForRequest(r, h.l).Printf("Could not get dashboard: %v", err)
+		utils.WriteError(w, r, err)
 		return
 	}
 
+	//remember the visit for the recently viewed list
+	visitErr := h.s.RecordVisit(r.Context(), userId, dashId)
+	if visitErr != nil {
+		logging.ForRequest(r, h.l).Printf("Could not record visit: %v", visitErr)
+	}
+
 	//sending dashboard to client
 	utils.WriteSuccessResponse(w, http.StatusOK, dash)
-	if err != nil {
-		h.l.Printf("Could not encode dashboard: %v", err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
-		return
-	}
 }
 
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
 /**
  * @api {get} /dashboard Get all dashboards
- * @apiName Get all dashboards you have access to
+ * @apiName Get a page of dashboards you have access to
  * @apiGroup Dashboard
  * @apiHeader {String} Authorization JWT Authorization token
+ * @apiQuery {String} [q] Only dashboards whose name or description contains this text
+ * @apiQuery {String} [tag] Only dashboards carrying this tag. Repeat to require several tags.
+ * @apiQuery {String} [folder] Only dashboards placed directly in this folder
+ * @apiQuery {String="name","created","updated"} [sort=name] Column to sort by
+ * @apiQuery {String="asc","desc"} [order=asc] Sort order
+ * @apiQuery {Number} [limit=50] Page size, at most 200
+ * @apiQuery {String} [cursor] next_cursor from the previous page
+ * @apiQuery {Boolean} [views=true] Include the views of each dashboard
  */
 
 func (h *DashHandler) GetDashs(w http.ResponseWriter, r *http.Request) {
 	//getting user id from mux.Vars
 	userId, err := middlewares.GetUserIDFromVars(r)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	opts, includeViews, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	//getting dashboards from database
-	dashs, err := h.s.GetAllDashboardsForUser(userId)
+	page, err := h.s.ListDashboardsForUser(r.Context(), userId, opts, includeViews)
 	if err != nil {
-		h.l.Printf("Could not get dashboards: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		logging.ForRequest(r, h.l).Printf("Could not get dashboards: %v", err)
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	//sending dashboards to client
-	utils.WriteSuccessResponse(w, http.StatusOK, dashs)
+	utils.WriteSuccessResponseWithMeta(w, http.StatusOK, page.Items, utils.PageMeta{NextCursor: page.NextCursor, Total: page.Total})
+}
+
+// reads paging, filtering and sorting options of a dashboard listing from the query string
+func parseListOptions(q url.Values) (*models.DashListOptions, bool, error) {
+	opts := &models.DashListOptions{
+		Query: strings.TrimSpace(q.Get("q")),
+		Sort:  models.SortByName,
+		Limit: defaultPageSize,
+	}
+
+	//every tag must match once, so blank and repeated ones would empty the list
+	seen := map[string]bool{}
+	for _, tag := range q["tag"] {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		opts.Tags = append(opts.Tags, tag)
+	}
+
+	if folder := q.Get("folder"); folder != "" {
+		folderId, err := uuid.Parse(folder)
+		if err != nil {
+			return nil, false, utils.ErrInvalidQuery.WithDetail("folder must be a folder id")
+		}
+		opts.FolderID = &folderId
+	}
+
+	if sort := q.Get("sort"); sort != "" {
+		if sort != models.SortByName && sort != models.SortByCreated && sort != models.SortByUpdated {
+			return nil, false, utils.ErrInvalidQuery.WithDetail("sort must be one of name, created, updated")
+		}
+		opts.Sort = sort
+	}
+
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return nil, false, utils.ErrInvalidQuery.WithDetail("order must be asc or desc")
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageSize {
+			return nil, false, utils.ErrInvalidQuery.WithDetail("limit must be between 1 and %d", maxPageSize)
+		}
+		opts.Limit = n
+	}
+
+	if cursor := q.Get("cursor"); cursor != "" {
+		after, err := models.DecodePageCursor(cursor)
+		if err != nil {
+			return nil, false, er.ErrInvalidCursor
+		}
+		opts.After = after
+	}
+
+	includeViews := true
+	if views := q.Get("views"); views != "" {
+		b, err := strconv.ParseBool(views)
+		if err != nil {
+			return nil, false, utils.ErrInvalidQuery.WithDetail("views must be true or false")
+		}
+		includeViews = b
+	}
+
+	return opts, includeViews, nil
 }
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseListOptionsTags(t *testing.T) {
+	q := url.Values{"tag": {"Sales", "sales ", " ", "ops", "", "OPS"}}
+	opts, _, err := parseListOptions(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(opts.Tags, ","); got != "sales,ops" {
+		t.Errorf("tags %q, want sales,ops", got)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"backend/logging"
 	"backend/utils"
 	"net/http"
 )
@@ -13,10 +14,10 @@ import (
  */
 
 func (h *DashHandler) GetAllRoles(w http.ResponseWriter, r *http.Request) {
-	roles, err := h.s.Rs.GetAllRoles()
+	roles, err := h.s.Rs.GetAllRoles(r.Context())
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, "Failed to get roles")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"backend/logging"
 	"backend/middlewares"
 	"backend/utils"
 	"net/http"
@@ -21,24 +22,24 @@ func (h *DashHandler) GetView(w http.ResponseWriter, r *http.Request) {
 	// Get the view id from the request
 	id, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid view id")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid view id"))
 		return
 	}
 
 	// Get the user id from the request
 	userId, err := middlewares.GetUserIDFromVars(r)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, "Failed to decode id")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
 	// Get the view from the database
-	view, err := h.s.Vs.GetView(id, userId)
+	view, err := h.s.Vs.GetView(r.Context(), id, userId)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusForbidden, "Failed to get view")
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"backend/dashboard/models"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type updateLayoutRequest struct {
+	Layout []*models.LayoutItem `json:"layout" validate:"dive,required"`
+}
+
+/**
+ * @api {put} /dashboard/:id/layout Update layout
+ * @apiName Replace the grid placement of views on a dashboard
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiBody {Object[]} layout Placement of views
+ * @apiBody {String} layout.viewId View ID
+ * @apiBody {Number} layout.x Column
+ * @apiBody {Number} layout.y Row
+ * @apiBody {Number} layout.w Width in columns
+ * @apiBody {Number} layout.h Height in rows
+ */
+
+func (h *DashHandler) UpdateLayout(w http.ResponseWriter, r *http.Request) {
+	dashId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
+		return
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	req := &updateLayoutRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	err = h.s.UpdateLayout(r.Context(), userId, dashId, req.Layout)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not update layout: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, req.Layout)
+}
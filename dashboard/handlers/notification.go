@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type NotificationHandler struct {
+	l logrus.FieldLogger
+	s *services.NotificationService
+}
+
+// NewNotification creates a new notification handler with the given logger and service
+func NewNotification(l logrus.FieldLogger, s *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{l, s}
+}
+
+// paging of the notification feed along with the unread count
+type notificationMeta struct {
+	Total  int `json:"total"`
+	Unread int `json:"unread"`
+}
+
+type unreadCountResponse struct {
+	Unread int `json:"unread"`
+}
+
+type markReadResponse struct {
+	Marked int64 `json:"marked"`
+}
+
+type markReadRequest struct {
+	IDs []uuid.UUID `json:"ids" validate:"required,min=1,max=200"`
+}
+
+type setPreferencesRequest struct {
+	Preferences map[string]bool `json:"preferences" validate:"required"`
+}
+
+/**
+ * @api {get} /notifications Get notifications
+ * @apiName Get your notifications, newest first. The meta carries the total and unread counts.
+ * @apiGroup Notification
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiQuery {Boolean} [unread=false] Only unread notifications
+ * @apiQuery {Number{1-200}} [limit=50] Page size
+ * @apiQuery {Number} [offset=0] Notifications to skip
+ */
+
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.parseUserId(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	limit, offset, err := parseLimitOffset(q)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	page, err := h.s.GetNotifications(r.Context(), userId, q.Get("unread") == "true", limit, offset)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get notifications: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseWithMeta(w, http.StatusOK, page.Items, notificationMeta{Total: page.Total, Unread: page.Unread})
+}
+
+/**
+ * @api {get} /notifications/unread-count Get unread count
+ * @apiName Get how many of your notifications are unread
+ * @apiGroup Notification
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.parseUserId(w, r)
+	if !ok {
+		return
+	}
+
+	cnt, err := h.s.CountUnread(r.Context(), userId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not count notifications: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, unreadCountResponse{Unread: cnt})
+}
+
+/**
+ * @api {post} /notifications/read Mark notifications read
+ * @apiName Mark the given notifications as read
+ * @apiGroup Notification
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiBody {String[]} ids IDs of the notifications
+ */
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.parseUserId(w, r)
+	if !ok {
+		return
+	}
+
+	req := &markReadRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	h.markRead(w, r, userId, req.IDs)
+}
+
+/**
+ * @api {post} /notifications/read-all Mark all notifications read
+ * @apiName Mark all your notifications as read
+ * @apiGroup Notification
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.parseUserId(w, r)
+	if !ok {
+		return
+	}
+
+	h.markRead(w, r, userId, nil)
+}
+
+/**
+ * @api {post} /notifications/:id/read Mark notification read
+ * @apiName Mark one notification as read
+ * @apiGroup Notification
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Notification ID
+ */
+
+func (h *NotificationHandler) MarkOneRead(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid notification id"))
+		return
+	}
+	userId, ok := h.parseUserId(w, r)
+	if !ok {
+		return
+	}
+
+	h.markRead(w, r, userId, []uuid.UUID{id})
+}
+
+/**
+ * @api {get} /notifications/preferences Get notification preferences
+ * @apiName Get whether you get each kind of notification. Every kind is on by default.
+ * @apiGroup Notification
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.parseUserId(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.s.GetPreferences(r.Context(), userId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get notification preferences: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, prefs)
+}
+
+/**
+ * @api {put} /notifications/preferences Set notification preferences
+ * @apiName Turn kinds of notification on or off. Kinds left out keep their setting.
+ * @apiGroup Notification
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiBody {Object} preferences Map of notification kind to whether it is on, e.g. {"mentioned": false}.
+ * Kinds are access_granted, access_revoked, mentioned, grant_expiring, access_requested and access_denied.
+ */
+
+func (h *NotificationHandler) SetPreferences(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.parseUserId(w, r)
+	if !ok {
+		return
+	}
+
+	req := &setPreferencesRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	prefs, err := h.s.SetPreferences(r.Context(), userId, req.Preferences)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not set notification preferences: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, prefs)
+}
+
+func (h *NotificationHandler) markRead(w http.ResponseWriter, r *http.Request, userId uuid.UUID, ids []uuid.UUID) {
+	n, err := h.s.MarkRead(r.Context(), userId, ids)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not mark notifications read: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, markReadResponse{Marked: n})
+}
+
+func (h *NotificationHandler) parseUserId(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return uuid.Nil, false
+	}
+	return userId, true
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"backend/dashboard/models"
+	"backend/openapi"
+	"backend/utils"
+	"net/http"
+)
+
+// Routes of the dashboard service as described in its OpenAPI document. Keep in step with
+// the router, a test fails when a routed path is missing here.
+func Routes() []openapi.Route {
+	var routes []openapi.Route
+	for _, group := range [][]openapi.Route{dashRoutes, viewRoutes, roleRoutes, templateRoutes, trashRoutes,
+		commentRoutes, folderRoutes, shareRoutes, accessRequestRoutes, webhookRoutes, notificationRoutes, searchRoutes} {
+		routes = append(routes, group...)
+	}
+	return routes
+}
+
+var pageQuery = []openapi.Param{
+	{Name: "limit", Type: "integer", Description: "Page size, at most 200"},
+	{Name: "offset", Type: "integer", Description: "Items to skip"},
+}
+
+var dashRoutes = []openapi.Route{
+	{Method: http.MethodPost, Path: "/dashboard", ID: "CreateDash", Tag: "Dashboard", Summary: "Create dashboard",
+		Body: createDashRequest{}, Status: http.StatusCreated, Data: models.Dash{}},
+	{Method: http.MethodGet, Path: "/dashboard", ID: "GetDashs", Tag: "Dashboard", Summary: "Get a page of dashboards you have access to",
+		Query: []openapi.Param{
+			{Name: "q", Description: "Only dashboards whose name or description contains this text"},
+			{Name: "tag", Description: "Only dashboards carrying this tag. Repeat to require several tags."},
+			{Name: "folder", Description: "Only dashboards placed directly in this folder"},
+			{Name: "sort", Description: "Column to sort by", Enum: []string{models.SortByName, models.SortByCreated, models.SortByUpdated}},
+			{Name: "order", Description: "Sort order", Enum: []string{"asc", "desc"}},
+			{Name: "limit", Type: "integer", Description: "Page size, at most 200"},
+			{Name: "cursor", Description: "next_cursor from the previous page"},
+			{Name: "views", Type: "boolean", Description: "Include the views of each dashboard, true by default"},
+		},
+		Data: []*models.Dash{}, Meta: utils.PageMeta{}},
+	{Method: http.MethodGet, Path: "/dashboard/starred", ID: "GetStarredDashs", Tag: "Dashboard", Summary: "Get your starred dashboards that you can still read",
+		Data: []*models.Dash{}},
+	{Method: http.MethodGet, Path: "/dashboard/recent", ID: "GetRecentDashs", Tag: "Dashboard", Summary: "Get dashboards you opened recently, latest first",
+		Query: []openapi.Param{{Name: "limit", Type: "integer", Description: "Number of dashboards, at most 50"}},
+		Data:  []*models.Dash{}},
+	{Method: http.MethodGet, Path: "/dashboard/{id}", ID: "GetDash", Tag: "Dashboard", Summary: "Get dashboard by its id you have access to",
+		Data: models.Dash{}},
+	{Method: http.MethodPut, Path: "/dashboard/{id}", ID: "UpdateDash", Tag: "Dashboard", Summary: "Update dashboard",
+		Body: createDashRequest{}, Data: models.Dash{}},
+	{Method: http.MethodPost, Path: "/dashboard/{id}/clone", ID: "CloneDash", Tag: "Dashboard", Summary: "Deep copy a dashboard with all views you can read",
+		Body: cloneDashRequest{}, OptionalBody: true, Status: http.StatusCreated, Data: models.Dash{}},
+	{Method: http.MethodPut, Path: "/dashboard/{id}/layout", ID: "UpdateLayout", Tag: "Dashboard", Summary: "Replace the grid placement of views on a dashboard",
+		Body: updateLayoutRequest{}, Data: []*models.LayoutItem{}},
+	{Method: http.MethodPut, Path: "/dashboard/{id}/tags", ID: "SetTags", Tag: "Dashboard", Summary: "Replace the tags of a dashboard",
+		Body: setTagsRequest{}, Data: []string{}},
+	{Method: http.MethodPut, Path: "/dashboard/{id}/folder", ID: "MoveDash", Tag: "Dashboard", Summary: "Move a dashboard into a folder, or out of it when folderId is null",
+		Body: moveDashRequest{}},
+	{Method: http.MethodPut, Path: "/dashboard/{id}/star", ID: "StarDash", Tag: "Dashboard", Summary: "Star a dashboard you can read"},
+	{Method: http.MethodDelete, Path: "/dashboard/{id}/star", ID: "UnstarDash", Tag: "Dashboard", Summary: "Remove your star from a dashboard"},
+	{Method: http.MethodGet, Path: "/dashboard/{id}/export", ID: "ExportDash", Tag: "Dashboard", Summary: "Export a dashboard with its views and layout as a portable document",
+		Query: []openapi.Param{{Name: "includeRoles", Type: "boolean", Description: "Include role assignments by email. Requires edit_access."}},
+		Data:  models.DashExport{}, Raw: true},
+	{Method: http.MethodPost, Path: "/dashboard/import", ID: "ImportDash", Tag: "Dashboard", Summary: "Create a dashboard from an export document",
+		Query: []openapi.Param{{Name: "dryRun", Type: "boolean", Description: "Only validate the document and report what would be created, answered with 200"}},
+		Body:  models.DashExport{}, Status: http.StatusCreated, Data: models.ImportReport{}},
+	{Method: http.MethodGet, Path: "/dashboard/{id}/events", ID: "Events", Tag: "Dashboard", Summary: "Server-sent events of changes to a dashboard, its views, layout and grants",
+		Query:   []openapi.Param{{Name: "lastEventId", Description: "Same as the Last-Event-ID header"}},
+		Headers: []openapi.Param{{Name: "Last-Event-ID", Description: "Id of the last event received, the events missed since are sent first"}},
+		Raw:     true, ContentType: "text/event-stream"},
+	{Method: http.MethodGet, Path: "/tags", ID: "GetTags", Tag: "Dashboard", Summary: "Get tags in use on dashboards you can read, with the number of dashboards carrying each",
+		Data: []*models.TagCount{}},
+}
+
+var viewRoutes = []openapi.Route{
+	{Method: http.MethodPost, Path: "/view", ID: "CreateView", Tag: "View", Summary: "Create view",
+		Body: createViewRequest{}, Status: http.StatusCreated, Data: models.View{}},
+	{Method: http.MethodGet, Path: "/view/{id}", ID: "GetView", Tag: "View", Summary: "Get data for a view by its id",
+		Data: models.View{}},
+	{Method: http.MethodPut, Path: "/view/{id}", ID: "UpdateView", Tag: "View", Summary: "Update view. Not implemented yet, always fails with not_implemented."},
+}
+
+var roleRoutes = []openapi.Route{
+	{Method: http.MethodGet, Path: "/roles", ID: "GetAllRoles", Tag: "Role", Summary: "Get all roles",
+		Data: []*models.Role{}},
+	{Method: http.MethodGet, Path: "/dashboard/{id}/users", ID: "GetUsersFromDash", Tag: "Role", Summary: "Get members of a dashboard with their roles and permissions",
+		Data: []*models.Role{}},
+	{Method: http.MethodPost, Path: "/dashboard/{id}/users", ID: "AddUserToDash", Tag: "Role", Summary: "Upsert a user with a role to a dashboard",
+		Body: addUserToDashRequest{}, Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/dashboard/{id}/users", ID: "DeleteUserFromDash", Tag: "Role", Summary: "Remove user from dashboard",
+		Body: DeleteUserFromDash{}},
+	{Method: http.MethodPost, Path: "/view/{id}/users", ID: "AddUserToView", Tag: "Role", Summary: "Upsert a user with a role to a view",
+		Body: addUserToViewRequest{}},
+	{Method: http.MethodDelete, Path: "/view/{id}/users", ID: "DeleteUserFromView", Tag: "Role", Summary: "Remove user from view",
+		Body: deleteUserFromViewRequest{}},
+}
+
+var templateRoutes = []openapi.Route{
+	{Method: http.MethodGet, Path: "/templates", ID: "GetTemplates", Tag: "Template", Summary: "Get all template dashboards you have access to",
+		Data: []*models.Dash{}},
+	{Method: http.MethodPut, Path: "/dashboard/{id}/template", ID: "MarkTemplate", Tag: "Template", Summary: "Mark a dashboard as template with declared variables",
+		Body: markTemplateRequest{}},
+	{Method: http.MethodDelete, Path: "/dashboard/{id}/template", ID: "UnmarkTemplate", Tag: "Template", Summary: "Turn a template back into a regular dashboard"},
+	{Method: http.MethodPost, Path: "/templates/{id}/instantiate", ID: "InstantiateTemplate", Tag: "Template", Summary: "Create a dashboard from a template",
+		Body: instantiateTemplateRequest{}, Status: http.StatusCreated, Data: models.Dash{}},
+}
+
+var trashRoutes = []openapi.Route{
+	{Method: http.MethodGet, Path: "/trash", ID: "GetTrash", Tag: "Trash", Summary: "Get trashed dashboards and views you can restore",
+		Data: models.Trash{}},
+	{Method: http.MethodDelete, Path: "/dashboard/{id}", ID: "DeleteDash", Tag: "Trash", Summary: "Move a dashboard to trash. It can be restored until the retention window expires."},
+	{Method: http.MethodDelete, Path: "/view/{id}", ID: "DeleteView", Tag: "Trash", Summary: "Move a view to trash. It can be restored until the retention window expires."},
+	{Method: http.MethodPost, Path: "/dashboard/{id}/restore", ID: "RestoreDash", Tag: "Trash", Summary: "Restore a trashed dashboard along with its views"},
+	{Method: http.MethodPost, Path: "/view/{id}/restore", ID: "RestoreView", Tag: "Trash", Summary: "Restore a trashed view"},
+}
+
+var commentRoutes = []openapi.Route{
+	{Method: http.MethodGet, Path: "/dashboard/{id}/comments", ID: "GetDashComments", Tag: "Comment", Summary: "Get comment threads on a dashboard",
+		Data: []*models.Comment{}},
+	{Method: http.MethodPost, Path: "/dashboard/{id}/comments", ID: "AddDashComment", Tag: "Comment", Summary: "Start a thread or reply on a dashboard. Mention users with @email.",
+		Body: addCommentRequest{}, Status: http.StatusCreated, Data: models.Comment{}},
+	{Method: http.MethodGet, Path: "/view/{id}/comments", ID: "GetViewComments", Tag: "Comment", Summary: "Get comment threads on a view",
+		Data: []*models.Comment{}},
+	{Method: http.MethodPost, Path: "/view/{id}/comments", ID: "AddViewComment", Tag: "Comment", Summary: "Start a thread or reply on a view. Mention users with @email.",
+		Body: addCommentRequest{}, Status: http.StatusCreated, Data: models.Comment{}},
+	{Method: http.MethodPut, Path: "/comments/{id}", ID: "UpdateComment", Tag: "Comment", Summary: "Edit your own comment",
+		Body: updateCommentRequest{}, Data: models.Comment{}},
+	{Method: http.MethodDelete, Path: "/comments/{id}", ID: "DeleteComment", Tag: "Comment", Summary: "Delete your own comment along with its replies"},
+	{Method: http.MethodPost, Path: "/comments/{id}/resolve", ID: "ResolveComment", Tag: "Comment", Summary: "Resolve the thread a comment belongs to"},
+	{Method: http.MethodDelete, Path: "/comments/{id}/resolve", ID: "UnresolveComment", Tag: "Comment", Summary: "Reopen the thread a comment belongs to"},
+}
+
+var folderRoutes = []openapi.Route{
+	{Method: http.MethodPost, Path: "/folders", ID: "CreateFolder", Tag: "Folder", Summary: "Create a folder. You become its admin.",
+		Body: folderRequest{}, Status: http.StatusCreated, Data: models.Folder{}},
+	{Method: http.MethodGet, Path: "/folders", ID: "GetFolders", Tag: "Folder", Summary: "Get all folders you can read",
+		Data: []*models.Folder{}},
+	{Method: http.MethodGet, Path: "/folders/{id}", ID: "GetFolder", Tag: "Folder", Summary: "Get a folder you can read",
+		Data: models.Folder{}},
+	{Method: http.MethodPut, Path: "/folders/{id}", ID: "UpdateFolder", Tag: "Folder", Summary: "Rename a folder or move it",
+		Body: folderRequest{}, Data: models.Folder{}},
+	{Method: http.MethodDelete, Path: "/folders/{id}", ID: "DeleteFolder", Tag: "Folder", Summary: "Delete a folder that holds no folders or dashboards"},
+	{Method: http.MethodGet, Path: "/folders/{id}/users", ID: "GetUsersFromFolder", Tag: "Folder", Summary: "Get users with a role on a folder, including roles inherited from folders above it",
+		Data: []*models.Role{}},
+	{Method: http.MethodPost, Path: "/folders/{id}/users", ID: "AddUserToFolder", Tag: "Folder", Summary: "Upsert a user with a role to a folder",
+		Body: addUserToFolderRequest{}, Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/folders/{id}/users", ID: "DeleteUserFromFolder", Tag: "Folder", Summary: "Revoke the role of a user on a folder",
+		Body: deleteUserFromFolderRequest{}},
+}
+
+var shareRoutes = []openapi.Route{
+	{Method: http.MethodPost, Path: "/dashboard/{id}/shares", ID: "CreateShareLink", Tag: "Share", Summary: "Create a read-only public link to a dashboard or one of its views",
+		Body: createShareLinkRequest{}, Status: http.StatusCreated, Data: models.ShareLink{}},
+	{Method: http.MethodGet, Path: "/dashboard/{id}/shares", ID: "GetShareLinks", Tag: "Share", Summary: "Get share links of a dashboard and its views. Tokens are not included.",
+		Data: []*models.ShareLink{}},
+	{Method: http.MethodDelete, Path: "/shares/{id}", ID: "RevokeShareLink", Tag: "Share", Summary: "Revoke a share link so it stops working"},
+	{Method: http.MethodPost, Path: "/dashboard/{id}/embed-tokens", ID: "CreateEmbedToken", Tag: "Share", Summary: "Create a short-lived signed token to embed a dashboard or one of its views",
+		Body: createEmbedTokenRequest{}, Status: http.StatusCreated, Data: models.EmbedToken{}},
+	{Method: http.MethodGet, Path: "/public/shares/{token}", ID: "OpenShareLink", Tag: "Public", Public: true, Summary: "Read the dashboard or view behind a share link",
+		Headers: []openapi.Param{{Name: sharePasswordHeader, Description: "Password of a protected link"}},
+		Data:    models.SharedResource{}},
+	{Method: http.MethodGet, Path: "/public/embed", ID: "OpenEmbed", Tag: "Public", Public: true, Summary: "Read the dashboard or view an embed token grants access to",
+		Query: []openapi.Param{{Name: "token", Description: "Embed token", Required: true}},
+		Data:  models.SharedResource{}},
+}
+
+var accessRequestRoutes = []openapi.Route{
+	{Method: http.MethodPost, Path: "/dashboard/{id}/access-requests", ID: "RequestAccess", Tag: "AccessRequest", Summary: "Ask for a role on a dashboard",
+		Body: requestAccessRequest{}, Status: http.StatusCreated, Data: models.AccessRequest{}},
+	{Method: http.MethodGet, Path: "/dashboard/{id}/access-requests", ID: "GetAccessRequests", Tag: "AccessRequest", Summary: "Get access requests for a dashboard. Needs edit_access.",
+		Query: []openapi.Param{{Name: "status", Description: "Only requests in this state, pending by default",
+			Enum: []string{models.AccessRequestPending, models.AccessRequestApproved, models.AccessRequestDenied}}},
+		Data: []*models.AccessRequest{}},
+	{Method: http.MethodGet, Path: "/access-requests", ID: "GetMyAccessRequests", Tag: "AccessRequest", Summary: "Get the access requests you made",
+		Data: []*models.AccessRequest{}},
+	{Method: http.MethodPost, Path: "/access-requests/{id}/approve", ID: "Approve", Tag: "AccessRequest", Summary: "Grant the requester a role on the dashboard",
+		Body: approveAccessRequest{}, Data: models.AccessRequest{}},
+	{Method: http.MethodPost, Path: "/access-requests/{id}/deny", ID: "Deny", Tag: "AccessRequest", Summary: "Turn down a request for access",
+		Body: denyAccessRequest{}, Data: models.AccessRequest{}},
+}
+
+var webhookRoutes = []openapi.Route{
+	{Method: http.MethodPost, Path: "/webhooks", ID: "CreateWebhook", Tag: "Webhook", Summary: "Register an endpoint receiving events of a dashboard, or of every dashboard",
+		Body: createWebhookRequest{}, Status: http.StatusCreated, Data: models.Webhook{}},
+	{Method: http.MethodGet, Path: "/webhooks", ID: "GetWebhooks", Tag: "Webhook", Summary: "Get the webhooks you can manage. Secrets are not included.",
+		Data: []*models.Webhook{}},
+	{Method: http.MethodGet, Path: "/webhooks/{id}", ID: "GetWebhook", Tag: "Webhook", Summary: "Get a webhook you can manage",
+		Data: models.Webhook{}},
+	{Method: http.MethodPut, Path: "/webhooks/{id}", ID: "UpdateWebhook", Tag: "Webhook", Summary: "Change the url and events of a webhook, or pause it",
+		Body: updateWebhookRequest{}, Data: models.Webhook{}},
+	{Method: http.MethodDelete, Path: "/webhooks/{id}", ID: "DeleteWebhook", Tag: "Webhook", Summary: "Delete a webhook along with its delivery log"},
+	{Method: http.MethodGet, Path: "/webhooks/{id}/deliveries", ID: "GetDeliveries", Tag: "Webhook", Summary: "Get the delivery log of a webhook, newest first",
+		Query: pageQuery, Data: []*models.WebhookDelivery{}, Meta: utils.PageMeta{}},
+	{Method: http.MethodPost, Path: "/webhooks/{id}/deliveries/{deliveryId}/redeliver", ID: "Redeliver", Tag: "Webhook", Summary: "Queue the payload of a past delivery again as a new delivery",
+		Status: http.StatusAccepted, Data: models.WebhookDelivery{}},
+}
+
+var notificationRoutes = []openapi.Route{
+	{Method: http.MethodGet, Path: "/notifications", ID: "GetNotifications", Tag: "Notification", Summary: "Get your notifications, newest first",
+		Query: append([]openapi.Param{{Name: "unread", Type: "boolean", Description: "Only unread notifications"}}, pageQuery...),
+		Data:  []*models.Notification{}, Meta: notificationMeta{}},
+	{Method: http.MethodGet, Path: "/notifications/unread-count", ID: "GetUnreadCount", Tag: "Notification", Summary: "Get how many of your notifications are unread",
+		Data: unreadCountResponse{}},
+	{Method: http.MethodPost, Path: "/notifications/read", ID: "MarkRead", Tag: "Notification", Summary: "Mark the given notifications as read",
+		Body: markReadRequest{}, Data: markReadResponse{}},
+	{Method: http.MethodPost, Path: "/notifications/read-all", ID: "MarkAllRead", Tag: "Notification", Summary: "Mark all your notifications as read",
+		Data: markReadResponse{}},
+	{Method: http.MethodPost, Path: "/notifications/{id}/read", ID: "MarkOneRead", Tag: "Notification", Summary: "Mark one notification as read",
+		Data: markReadResponse{}},
+	{Method: http.MethodGet, Path: "/notifications/preferences", ID: "GetPreferences", Tag: "Notification", Summary: "Get whether you get each kind of notification",
+		Data: []*models.NotificationPreference{}},
+	{Method: http.MethodPut, Path: "/notifications/preferences", ID: "SetPreferences", Tag: "Notification", Summary: "Turn kinds of notification on or off. Kinds left out keep their setting.",
+		Body: setPreferencesRequest{}, Data: []*models.NotificationPreference{}},
+}
+
+var searchRoutes = []openapi.Route{
+	{Method: http.MethodGet, Path: "/search", ID: "Search", Tag: "Search", Summary: "Search dashboards and views you can read by name, description and widget titles",
+		Query: []openapi.Param{
+			{Name: "q", Description: `Search terms. Supports "quoted phrases", OR and -excluded words`, Required: true},
+			{Name: "type", Description: "Only return this kind of result", Enum: []string{models.SearchTypeDashboard, models.SearchTypeView}},
+			{Name: "limit", Type: "integer", Description: "Page size, at most 100"},
+			{Name: "offset", Type: "integer", Description: "Number of results to skip"},
+		},
+		Data: []*models.SearchResult{}, Meta: utils.PageMeta{}},
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type setTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type moveDashRequest struct {
+	FolderID *uuid.UUID `json:"folderId"`
+}
+
+/**
+ * @api {put} /dashboard/:id/tags Set tags
+ * @apiName Replace the tags of a dashboard
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiBody {String[]} tags Tags, lower cased and deduplicated. At most 20 of up to 50 characters.
+ */
+
+func (h *DashHandler) SetTags(w http.ResponseWriter, r *http.Request) {
+	dashId, userId, ok := h.parseDashIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &setTagsRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	tags, err := h.s.SetTags(r.Context(), userId, dashId, req.Tags)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not set tags: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, tags)
+}
+
+/**
+ * @api {get} /tags Get tags
+ * @apiName Get tags in use on dashboards you can read, with the number of dashboards carrying each
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *DashHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	tags, err := h.s.GetTagsForUser(r.Context(), userId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get tags: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, tags)
+}
+
+/**
+ * @api {put} /dashboard/:id/folder Move dashboard
+ * @apiName Move a dashboard into a folder. Needs edit_access on the dashboard and edit on the folder.
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiBody {String} [folderId] Destination folder, top level when omitted
+ */
+
+func (h *DashHandler) MoveDash(w http.ResponseWriter, r *http.Request) {
+	dashId, userId, ok := h.parseDashIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &moveDashRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	err := h.s.MoveDashToFolder(r.Context(), userId, dashId, req.FolderID)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not move dashboard: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Dashboard moved")
+}
+
+/**
+ * @api {put} /dashboard/:id/star Star dashboard
+ * @apiName Star a dashboard you can read
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ */
+
+func (h *DashHandler) StarDash(w http.ResponseWriter, r *http.Request) {
+	dashId, userId, ok := h.parseDashIds(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.s.StarDash(r.Context(), userId, dashId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not star dashboard: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Dashboard starred")
+}
+
+/**
+ * @api {delete} /dashboard/:id/star Unstar dashboard
+ * @apiName Remove your star from a dashboard
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ */
+
+func (h *DashHandler) UnstarDash(w http.ResponseWriter, r *http.Request) {
+	dashId, userId, ok := h.parseDashIds(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.s.UnstarDash(r.Context(), userId, dashId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not unstar dashboard: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Dashboard unstarred")
+}
+
+/**
+ * @api {get} /dashboard/starred Get starred dashboards
+ * @apiName Get your starred dashboards that you can still read
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *DashHandler) GetStarredDashs(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	dashs, err := h.s.GetStarredDashs(r.Context(), userId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get starred dashboards: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, dashs)
+}
+
+/**
+ * @api {get} /dashboard/recent Get recently viewed dashboards
+ * @apiName Get dashboards you opened recently, latest first
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiQuery {Number} [limit=50] Number of dashboards, at most 50
+ */
+
+func (h *DashHandler) GetRecentDashs(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	limit := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			utils.WriteError(w, r, utils.ErrInvalidQuery.WithDetail("limit must be a positive number"))
+			return
+		}
+	}
+
+	dashs, err := h.s.GetRecentDashs(r.Context(), userId, limit)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get recent dashboards: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, dashs)
+}
+
+func (h *DashHandler) parseDashIds(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	dashId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return dashId, userId, true
+}
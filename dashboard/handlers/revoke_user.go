@@ -3,33 +3,21 @@ package handlers
 import (
 	er "backend/dashboard/errors"
 	"backend/dashboard/perms"
+	"backend/logging"
 	"backend/middlewares"
 	"backend/utils"
-	"encoding/json"
-	"io"
 	"net/http"
 
-	"github.com/go-playground/validator"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type deleteUserFromViewRequest struct {
-	UserID uuid.UUID `json:"userId" validate:"required"`
-}
-
-func (d *deleteUserFromViewRequest) FromJSON(r io.Reader) error {
-	e := json.NewDecoder(r)
-	return e.Decode(d)
+	UserID uuid.UUID `json:"userId" validate:"required,uuid4"`
 }
 
 type DeleteUserFromDash struct {
-	UserID uuid.UUID `json:"userId" validate:"required"`
-}
-
-func (d *DeleteUserFromDash) FromJSON(r io.Reader) error {
-	e := json.NewDecoder(r)
-	return e.Decode(d)
+	UserID uuid.UUID `json:"userId" validate:"required,uuid4"`
 }
 
 /**
@@ -44,52 +32,38 @@ func (d *DeleteUserFromDash) FromJSON(r io.Reader) error {
 func (h *DashHandler) DeleteUserFromView(w http.ResponseWriter, r *http.Request) {
 	viewId, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusBadRequest, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidID)
 		return
 	}
 
 	userId, err := middlewares.GetUserIDFromVars(r)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
 	d := &deleteUserFromViewRequest{}
-	err = d.FromJSON(r.Body)
-	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid request payload")
-		return
-	}
-
-	// validating payload
-	validate := validator.New()
-	err = validate.Struct(d)
-	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, err.Error())
+	if !utils.DecodeAndValidate(w, r, d) {
 		return
 	}
 
-	can, err := h.s.Rs.ExistsPermissionForUserForView(userId, viewId, perms.ACCESS_MOD)
+	can, err := h.s.Rs.ExistsPermissionForUserForView(r.Context(), userId, viewId, perms.ACCESS_MOD)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, err)
 		return
 	}
 	if !can {
-		utils.WriteFailureResponse(w, http.StatusForbidden, "You don't have permission to remove users from this view")
+		utils.WriteError(w, r, er.ErrNoPerm)
 		return
 	}
 
-	err = h.s.Rs.RevokeViewLevelRoleFromUser(viewId, d.UserID)
+	err = h.s.Rs.RevokeViewLevelRoleFromUser(r.Context(), viewId, d.UserID, userId)
 	if err != nil {
-		h.l.Println(err)
-		if err == er.ErrCannotRevokeLastAdmin {
-			utils.WriteFailureResponse(w, http.StatusBadRequest, err.Error())
-		} else {
-			utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
-		}
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -108,52 +82,38 @@ func (h *DashHandler) DeleteUserFromView(w http.ResponseWriter, r *http.Request)
 func (h *DashHandler) DeleteUserFromDash(w http.ResponseWriter, r *http.Request) {
 	dashId, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusBadRequest, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidID)
 		return
 	}
 
 	userId, err := middlewares.GetUserIDFromVars(r)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
 	d := &DeleteUserFromDash{}
-	err = d.FromJSON(r.Body)
-	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid request payload")
-		return
-	}
-
-	// validating payload
-	validate := validator.New()
-	err = validate.Struct(d)
-	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, err.Error())
+	if !utils.DecodeAndValidate(w, r, d) {
 		return
 	}
 
-	can, err := h.s.Rs.ExistsPermissionForUserForDashboard(userId, dashId, perms.ACCESS_MOD)
+	can, err := h.s.Rs.ExistsPermissionForUserForDashboard(r.Context(), userId, dashId, perms.ACCESS_MOD)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, err)
 		return
 	}
 	if !can {
-		utils.WriteFailureResponse(w, http.StatusForbidden, "You don't have permission to remove users from this dashboard")
+		utils.WriteError(w, r, er.ErrNoPerm)
 		return
 	}
 
-	err = h.s.Rs.RevokeDashLevelRoleFromUser(dashId, d.UserID)
+	err = h.s.Rs.RevokeDashLevelRoleFromUser(r.Context(), dashId, d.UserID, userId)
 	if err != nil {
-		h.l.Println(err)
-		if err == er.ErrCannotRevokeLastAdmin {
-			utils.WriteFailureResponse(w, http.StatusBadRequest, err.Error())
-		} else {
-			utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
-		}
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchQuery     = 256
+)
+
+type SearchHandler struct {
+	l logrus.FieldLogger
+	s *services.SearchService
+}
+
+// NewSearch creates a new search handler with the given logger and service
+func NewSearch(l logrus.FieldLogger, s *services.SearchService) *SearchHandler {
+	return &SearchHandler{l, s}
+}
+
+/**
+ * @api {get} /search Search
+ * @apiName Search dashboards and views you can read by name, description and widget titles
+ * @apiGroup Search
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiQuery {String} q Search terms. Supports "quoted phrases", OR and -excluded words
+ * @apiQuery {String="dashboard","view"} [type] Only return this kind of result
+ * @apiQuery {Number} [limit=20] Page size, at most 100
+ * @apiQuery {Number} [offset=0] Number of results to skip
+ */
+
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	opts, err := parseSearchOptions(r.URL.Query())
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	page, err := h.s.Search(r.Context(), userId, opts)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not search: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseWithMeta(w, http.StatusOK, page.Items, utils.PageMeta{Total: page.Total})
+}
+
+// reads search terms, result type and paging from the query string
+func parseSearchOptions(q url.Values) (*models.SearchOptions, error) {
+	opts := &models.SearchOptions{
+		Query: strings.TrimSpace(q.Get("q")),
+		Type:  q.Get("type"),
+		Limit: defaultSearchLimit,
+	}
+
+	if opts.Query == "" {
+		return nil, utils.ErrInvalidQuery.WithDetail("q is required")
+	}
+	if len(opts.Query) > maxSearchQuery {
+		return nil, utils.ErrInvalidQuery.WithDetail("q must be at most %d characters", maxSearchQuery)
+	}
+	if opts.Type != "" && opts.Type != models.SearchTypeDashboard && opts.Type != models.SearchTypeView {
+		return nil, utils.ErrInvalidQuery.WithDetail("type must be dashboard or view")
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			return nil, utils.ErrInvalidQuery.WithDetail("limit must be between 1 and %d", maxSearchLimit)
+		}
+		opts.Limit = n
+	}
+	if offset := q.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return nil, utils.ErrInvalidQuery.WithDetail("offset must be a non-negative number")
+		}
+		opts.Offset = n
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// header carrying the password of a password protected share link
+const sharePasswordHeader = "X-Share-Password"
+
+type ShareHandler struct {
+	l logrus.FieldLogger
+	s *services.ShareService
+}
+
+// NewShare creates a new share handler with the given logger and service
+func NewShare(l logrus.FieldLogger, s *services.ShareService) *ShareHandler {
+	return &ShareHandler{l, s}
+}
+
+type createShareLinkRequest struct {
+	ViewID    *uuid.UUID `json:"viewId"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Password  string     `json:"password" validate:"max=200"`
+}
+
+type createEmbedTokenRequest struct {
+	ViewID     *uuid.UUID `json:"viewId"`
+	TTLSeconds int        `json:"ttlSeconds" validate:"min=0"`
+}
+
+/**
+ * @api {post} /dashboard/:id/shares Create share link
+ * @apiName Create a read-only public link to a dashboard or one of its views. Needs edit_access.
+ * @apiGroup Share
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiBody {String} [viewId] Share only this view of the dashboard
+ * @apiBody {String} [expiresAt] RFC 3339 time after which the link stops working
+ * @apiBody {String} [password] Password to send in the X-Share-Password header when opening the link
+ * @apiSuccess {String} token Token of the link, only returned here
+ */
+
+func (h *ShareHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	dashId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &createShareLinkRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	link, err := h.s.CreateShareLink(r.Context(), userId, dashId, req.ViewID, req.ExpiresAt, req.Password)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not create share link: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, link)
+}
+
+/**
+ * @api {get} /dashboard/:id/shares Get share links
+ * @apiName Get share links of a dashboard and its views. Tokens are not included.
+ * @apiGroup Share
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ */
+
+func (h *ShareHandler) GetShareLinks(w http.ResponseWriter, r *http.Request) {
+	dashId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	links, err := h.s.GetShareLinks(r.Context(), userId, dashId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get share links: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, links)
+}
+
+/**
+ * @api {delete} /shares/:id Revoke share link
+ * @apiName Revoke a share link so it stops working
+ * @apiGroup Share
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Share link ID
+ */
+
+func (h *ShareHandler) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	linkId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.s.RevokeShareLink(r.Context(), userId, linkId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not revoke share link: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Share link revoked")
+}
+
+/**
+ * @api {post} /dashboard/:id/embed-tokens Create embed token
+ * @apiName Create a short-lived signed token to embed a dashboard or one of its views. Needs edit_access.
+ * @apiGroup Share
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiBody {String} [viewId] Embed only this view of the dashboard
+ * @apiBody {Number} [ttlSeconds] Lifetime of the token, at most an hour
+ */
+
+func (h *ShareHandler) CreateEmbedToken(w http.ResponseWriter, r *http.Request) {
+	dashId, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &createEmbedTokenRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	token, err := h.s.CreateEmbedToken(r.Context(), userId, dashId, req.ViewID, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not create embed token: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, token)
+}
+
+/**
+ * @api {get} /public/shares/:token Open share link
+ * @apiName Read the dashboard or view behind a share link. No Authorization needed.
+ * @apiGroup Public
+ * @apiHeader {String} [X-Share-Password] Password of a protected link
+ * @apiParam {String} token Token of the share link
+ */
+
+func (h *ShareHandler) OpenShareLink(w http.ResponseWriter, r *http.Request) {
+	res, err := h.s.OpenShareLink(r.Context(), mux.Vars(r)["token"], r.Header.Get(sharePasswordHeader))
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not open share link: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, res)
+}
+
+/**
+ * @api {get} /public/embed Open embed token
+ * @apiName Read the dashboard or view an embed token grants access to. No Authorization needed.
+ * @apiGroup Public
+ * @apiQuery {String} token Embed token
+ */
+
+func (h *ShareHandler) OpenEmbed(w http.ResponseWriter, r *http.Request) {
+	res, err := h.s.OpenEmbedToken(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not open embed token: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, res)
+}
+
+func (h *ShareHandler) parseIds(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, userId, true
+}
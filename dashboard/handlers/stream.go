@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// comment sent when nothing happened for a while, keeps proxies from closing the stream
+	streamHeartbeat = 25 * time.Second
+	// time allowed for each write to the client
+	streamWriteTimeout = 10 * time.Second
+	// streams are closed after this long so clients reconnect with a fresh token
+	maxStreamDuration = time.Hour
+	// milliseconds clients wait before reconnecting
+	streamRetry = 3000
+)
+
+// events after which a subscriber may have lost access to the dashboard
+var accessEvents = map[string]bool{
+	models.EventGrantRevoked: true,
+	models.EventDashDeleted:  true,
+	models.EventDashUpdated:  true,
+}
+
+type StreamHandler struct {
+	l logrus.FieldLogger
+	s *services.StreamService
+}
+
+// NewStream creates a new stream handler with the given logger and service
+func NewStream(l logrus.FieldLogger, s *services.StreamService) *StreamHandler {
+	return &StreamHandler{l, s}
+}
+
+/**
+ * @api {get} /dashboard/:id/events Stream dashboard events
+ * @apiName Server-sent events of changes to a dashboard, its views, layout and grants. Needs read.
+ * Every event carries an id; reconnect with the Last-Event-ID header, or the lastEventId query
+ * parameter, to get the events missed in between. A "reset" event means they are no longer
+ * kept and the dashboard should be reloaded. A "revoked" event ends the stream when read
+ * access is lost. Events about a view you cannot read carry its ids only.
+ * @apiGroup Dashboard
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiHeader {String} [Last-Event-ID] Id of the last event received
+ * @apiParam {String} id Dashboard ID
+ * @apiQuery {String} [lastEventId] Same as the Last-Event-ID header
+ */
+
+func (h *StreamHandler) Events(w http.ResponseWriter, r *http.Request) {
+	dashId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
+		return
+	}
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.WriteError(w, r, er.ErrStreamingUnsupported)
+		return
+	}
+
+	lastId := r.Header.Get("Last-Event-ID")
+	if lastId == "" {
+		lastId = r.URL.Query().Get("lastEventId")
+	}
+
+	sub, err := h.s.Subscribe(r.Context(), userId, dashId, lastId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not subscribe to dashboard events: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// nginx would otherwise buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(format string, args ...interface{}) bool {
+		middlewares.ExtendWriteDeadline(r, streamWriteTimeout)
+		_, err := fmt.Fprintf(w, format, args...)
+		if err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	if !send("retry: %d\n\n", streamRetry) {
+		return
+	}
+	if sub.Reset && !send("event: reset\ndata: {}\n\n") {
+		return
+	}
+	for _, ev := range sub.Backlog {
+		data, err := h.s.DataFor(r.Context(), userId, ev)
+		if err != nil {
+			logging.ForRequest(r, h.l).Printf("Could not check access to event %s: %v", ev.ID, err)
+			return
+		}
+		if !send("id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data) {
+			return
+		}
+		lastId = ev.ID
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	deadline := time.NewTimer(maxStreamDuration)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case <-heartbeat.C:
+			if !send(": ping\n\n") {
+				return
+			}
+		case ev, ok := <-sub.Events:
+			if !ok {
+				// fell behind, the client resumes from the last event it got
+				return
+			}
+			// already sent from the backlog
+			if lastId != "" && services.CompareStreamIDs(ev.ID, lastId) <= 0 {
+				continue
+			}
+			if accessEvents[ev.Type] {
+				can, err := h.s.CanRead(r.Context(), userId, dashId)
+				if err != nil {
+					logging.ForRequest(r, h.l).Printf("Could not check access to dashboard %s: %v", dashId, err)
+					return
+				}
+				if !can {
+					send("event: revoked\ndata: {}\n\n")
+					return
+				}
+			}
+			data, err := h.s.DataFor(r.Context(), userId, ev)
+			if err != nil {
+				logging.ForRequest(r, h.l).Printf("Could not check access to event %s: %v", ev.ID, err)
+				return
+			}
+			if !send("id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data) {
+				return
+			}
+			lastId = ev.ID
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"backend/dashboard/models"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type markTemplateRequest struct {
+	Variables []*models.TemplateVariable `json:"variables" validate:"dive,required"`
+}
+
+type instantiateTemplateRequest struct {
+	Name        string            `json:"name" validate:"omitempty,name"`
+	Description string            `json:"description"`
+	Values      map[string]string `json:"values"`
+}
+
+/**
+ * @api {put} /dashboard/:id/template Mark dashboard as template
+ * @apiName Mark a dashboard as template with declared variables
+ * @apiGroup Template
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ * @apiBody {Object[]} variables Variables referenced as {{name}} in names, descriptions and view configs
+ * @apiBody {String} variables.name Name of the variable
+ * @apiBody {String} [variables.description] Description of the variable
+ * @apiBody {String} [variables.default] Value used when none is given on instantiation
+ */
+
+func (h *DashHandler) MarkTemplate(w http.ResponseWriter, r *http.Request) {
+	dashId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
+		return
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	req := &markTemplateRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	err = h.s.MarkTemplate(r.Context(), userId, dashId, req.Variables)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not mark template: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Dashboard marked as template")
+}
+
+/**
+ * @api {delete} /dashboard/:id/template Unmark template
+ * @apiName Turn a template back into a regular dashboard
+ * @apiGroup Template
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ */
+
+func (h *DashHandler) UnmarkTemplate(w http.ResponseWriter, r *http.Request) {
+	dashId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
+		return
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	err = h.s.UnmarkTemplate(r.Context(), userId, dashId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not unmark template: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Dashboard is no longer a template")
+}
+
+/**
+ * @api {get} /templates Get templates
+ * @apiName Get all template dashboards you have access to
+ * @apiGroup Template
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *DashHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	templates, err := h.s.GetTemplatesForUser(r.Context(), userId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get templates: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, templates)
+}
+
+/**
+ * @api {post} /templates/:id/instantiate Instantiate template
+ * @apiName Create a dashboard from a template
+ * @apiGroup Template
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Template dashboard ID
+ * @apiBody {String} [name] Name of the new dashboard. Defaults to the template name
+ * @apiBody {String} [description] Description of the new dashboard
+ * @apiBody {Object} [values] Map of variable name to value
+ */
+
+func (h *DashHandler) InstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	templateId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid template id"))
+		return
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	req := &instantiateTemplateRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	dash, err := h.s.InstantiateTemplate(r.Context(), userId, templateId, req.Name, req.Description, req.Values)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not instantiate template: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, dash)
+}
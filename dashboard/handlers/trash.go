@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type TrashHandler struct {
+	l logrus.FieldLogger
+	s *services.TrashService
+}
+
+// NewTrash creates a new trash handler with the given logger and service
+func NewTrash(l logrus.FieldLogger, s *services.TrashService) *TrashHandler {
+	return &TrashHandler{l, s}
+}
+
+/**
+ * @api {get} /trash Get trash
+ * @apiName Get trashed dashboards and views you can restore
+ * @apiGroup Trash
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	trash, err := h.s.GetTrashForUser(r.Context(), userId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get trash: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, trash)
+}
+
+/**
+ * @api {post} /dashboard/:id/restore Restore dashboard
+ * @apiName Restore a trashed dashboard along with its views
+ * @apiGroup Trash
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Dashboard ID
+ */
+
+func (h *TrashHandler) RestoreDash(w http.ResponseWriter, r *http.Request) {
+	dashId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid dashboard id"))
+		return
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	err = h.s.RestoreDash(r.Context(), userId, dashId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not restore dashboard: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Dashboard restored")
+}
+
+/**
+ * @api {post} /view/:id/restore Restore view
+ * @apiName Restore a trashed view
+ * @apiGroup Trash
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id View ID
+ */
+
+func (h *TrashHandler) RestoreView(w http.ResponseWriter, r *http.Request) {
+	viewId, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid view id"))
+		return
+	}
+
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return
+	}
+
+	err = h.s.RestoreView(r.Context(), userId, viewId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not restore view: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "View restored")
+}
@@ -2,11 +2,11 @@ package handlers
 
 import (
 	"backend/dashboard/models"
+	"backend/logging"
 	"backend/middlewares"
 	"backend/utils"
 	"net/http"
 
-	"github.com/go-playground/validator"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
@@ -25,40 +25,30 @@ func (h *DashHandler) UpdateDash(w http.ResponseWriter, r *http.Request) {
 	// get id from mux.Vars
 	dashId, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusBadRequest, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidID)
 		return
 	}
 
 	// get user id from mux.Vars
 	userId, err := middlewares.GetUserIDFromVars(r)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
 	d := &createDashRequest{}
-	err = d.fromJSON(r.Body)
-	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid request payload")
-		return
-	}
-
-	// validating payload
-	validate := validator.New()
-	err = validate.Struct(d)
-	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, err.Error())
+	if !utils.DecodeAndValidate(w, r, d) {
 		return
 	}
 
 	// updating dashboard
 	newdash := &models.Dash{ID: dashId, Name: d.Name, Description: d.Description}
-	err = h.s.UpdateDash(userId, dashId, newdash)
+	err = h.s.UpdateDash(r.Context(), userId, dashId, newdash)
 	if err != nil {
-		h.l.Println(err)
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, err)
 		return
 	}
 
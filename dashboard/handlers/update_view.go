@@ -16,5 +16,5 @@ import (
  */
 
 func (h *DashHandler) UpdateView(w http.ResponseWriter, r *http.Request) {
-	utils.WriteFailureResponse(w, http.StatusNotImplemented, "Not implemented")
+	utils.WriteError(w, r, utils.ErrNotImplemented)
 }
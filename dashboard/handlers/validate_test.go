@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"backend/middlewares"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Requests with null items in lists are turned away before reaching the services, which
+// are left nil here
+func TestNullItemsRejected(t *testing.T) {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	dash := NewDash(l, nil)
+	export := NewExport(l, nil)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"layout", dash.UpdateLayout, `{"layout":[null]}`},
+		{"template variables", dash.MarkTemplate, `{"variables":[null]}`},
+		{"import views", export.ImportDash, `{"version":1,"dashboard":{"name":"sales"},"views":[null]}`},
+		{"import layout", export.ImportDash, `{"version":1,"dashboard":{"name":"sales"},"layout":[null]}`},
+		{"import variables", export.ImportDash, `{"version":1,"dashboard":{"name":"sales","variables":[null]}}`},
+		{"import roles", export.ImportDash, `{"version":1,"dashboard":{"name":"sales"},"roles":{"dashboard":[null],"views":[]}}`},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", "application/json")
+		r = mux.SetURLVars(r, map[string]string{"id": uuid.NewString(), middlewares.KeyUser: uuid.NewString()})
+		w := httptest.NewRecorder()
+		tt.handler(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, http.StatusBadRequest, w.Body)
+		}
+	}
+}
@@ -1,25 +1,22 @@
 package handlers
 
 import (
+	er "backend/dashboard/errors"
 	"backend/dashboard/perms"
+	"backend/logging"
 	"backend/middlewares"
 	"backend/utils"
-	"encoding/json"
-	"io"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type addUserToViewRequest struct {
-	UserId uuid.UUID `json:"userId" validate:"required,uuid4"`
-	Role   string    `json:"role" validate:"required"`
-}
-
-func (r *addUserToViewRequest) fromJSON(body io.Reader) error {
-	e := json.NewDecoder(body)
-	return e.Decode(r)
+	UserId    uuid.UUID  `json:"userId" validate:"required,uuid4"`
+	Role      string     `json:"role" validate:"required,role"`
+	ExpiresAt *time.Time `json:"expiresAt"`
 }
 
 /**
@@ -30,47 +27,46 @@ func (r *addUserToViewRequest) fromJSON(body io.Reader) error {
  * @apiParam {String} viewId View ID
  * @apiBody {String} userId User ID for which the role is to be added
  * @apiBody {String} role Role to be added
+ * @apiBody {String} [expiresAt] RFC 3339 time at which the role lapses. Admin roles cannot lapse.
  */
 
 func (h *DashHandler) AddUserToView(w http.ResponseWriter, r *http.Request) {
 	//get viewId from mux.Vars
 	viewId, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid view id")
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid view id"))
 		return
 	}
 
 	// extracting user id
 	userId, err := uuid.Parse(mux.Vars(r)[middlewares.KeyUser])
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, "failed to parse user id from jwt.")
+		utils.WriteError(w, r, utils.ErrInvalidToken)
 		return
 	}
 
 	//extracting request body
 	req := &addUserToViewRequest{}
-	err = req.fromJSON(r.Body)
-	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusBadRequest, "Invalid request payload")
+	if !utils.DecodeAndValidate(w, r, req) {
 		return
 	}
 
-	can, err := h.s.Rs.ExistsPermissionForUserForView(userId, viewId, perms.ACCESS_MOD)
+	can, err := h.s.Rs.ExistsPermissionForUserForView(r.Context(), userId, viewId, perms.ACCESS_MOD)
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	if !can {
-		utils.WriteFailureResponse(w, http.StatusUnauthorized, "You are not authorized to add users to this view")
+		utils.WriteError(w, r, er.ErrNoPerm)
 		return
 	}
 
 	// adding user to view
-	h.l.Println("Adding user ", req.UserId, " to view ", viewId, " with role ", req.Role)
-	err = h.s.Rs.AddUserToView(viewId, req.UserId, req.Role)
+	logging.ForRequest(r, h.l).Println("Adding user ", req.UserId, " to view ", viewId, " with role ", req.Role)
+	err = h.s.Rs.AddUserToView(r.Context(), viewId, req.UserId, req.Role, req.ExpiresAt, userId)
 	if err != nil {
-		utils.WriteFailureResponse(w, http.StatusInternalServerError, err.Error())
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+type WebhookHandler struct {
+	l logrus.FieldLogger
+	s *services.WebhookService
+}
+
+// NewWebhook creates a new webhook handler with the given logger and service
+func NewWebhook(l logrus.FieldLogger, s *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{l, s}
+}
+
+type createWebhookRequest struct {
+	DashID *uuid.UUID `json:"dashId"`
+	URL    string     `json:"url" validate:"required,url,max=2048"`
+	Events []string   `json:"events" validate:"required,min=1"`
+	Secret string     `json:"secret" validate:"omitempty,min=16,max=256"`
+}
+
+type updateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1"`
+	Active *bool    `json:"active" validate:"required"`
+}
+
+/**
+ * @api {post} /webhooks Create webhook
+ * @apiName Register an endpoint receiving events of a dashboard, or of every dashboard when dashId is left out.
+ * Webhooks on a dashboard need edit_access on it, global ones are reserved to system admins.
+ * Deliveries are POSTed as JSON and signed in the X-Webhook-Signature header with
+ * sha256=HMAC-SHA256(secret, X-Webhook-Timestamp + "." + body). Failed deliveries are retried with exponential backoff.
+ * @apiGroup Webhook
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiBody {String} [dashId] Dashboard whose events are sent
+ * @apiBody {String} url http or https url receiving deliveries
+ * @apiBody {String[]} events Event types to send: dashboard.created, dashboard.updated, dashboard.deleted,
+ * view.created, view.deleted, grant.created, grant.revoked, access.requested, access.approved, access.denied
+ * @apiBody {String} [secret] Secret signing deliveries, generated when left out
+ * @apiSuccess {String} secret Secret signing deliveries, only returned here
+ */
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.parseUserId(w, r)
+	if !ok {
+		return
+	}
+
+	req := &createWebhookRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	hook, err := h.s.CreateWebhook(r.Context(), userId, &models.Webhook{DashID: req.DashID, URL: req.URL, Events: req.Events, Secret: req.Secret})
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not create webhook: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, hook)
+}
+
+/**
+ * @api {get} /webhooks Get webhooks
+ * @apiName Get the webhooks you can manage. Secrets are not included.
+ * @apiGroup Webhook
+ * @apiHeader {String} Authorization JWT Authorization token
+ */
+
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userId, ok := h.parseUserId(w, r)
+	if !ok {
+		return
+	}
+
+	hooks, err := h.s.GetWebhooks(r.Context(), userId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get webhooks: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, hooks)
+}
+
+/**
+ * @api {get} /webhooks/:id Get webhook
+ * @apiName Get a webhook you can manage
+ * @apiGroup Webhook
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Webhook ID
+ */
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	hook, err := h.s.GetWebhook(r.Context(), userId, id)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get webhook: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, hook)
+}
+
+/**
+ * @api {put} /webhooks/:id Update webhook
+ * @apiName Change the url and events of a webhook, or pause it
+ * @apiGroup Webhook
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Webhook ID
+ * @apiBody {String} url http or https url receiving deliveries
+ * @apiBody {String[]} events Event types to send
+ * @apiBody {Boolean} active Whether events are sent. Deliveries of paused webhooks wait until it is resumed.
+ */
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	req := &updateWebhookRequest{}
+	if !utils.DecodeAndValidate(w, r, req) {
+		return
+	}
+
+	hook, err := h.s.UpdateWebhook(r.Context(), userId, id, &models.Webhook{URL: req.URL, Events: req.Events, Active: *req.Active})
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not update webhook: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, hook)
+}
+
+/**
+ * @api {delete} /webhooks/:id Delete webhook
+ * @apiName Delete a webhook along with its delivery log
+ * @apiGroup Webhook
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Webhook ID
+ */
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.s.DeleteWebhook(r.Context(), userId, id)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not delete webhook: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseMsg(w, http.StatusOK, "Webhook deleted")
+}
+
+/**
+ * @api {get} /webhooks/:id/deliveries Get deliveries
+ * @apiName Get the delivery log of a webhook, newest first
+ * @apiGroup Webhook
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Webhook ID
+ * @apiQuery {Number{1-200}} [limit=50] Page size
+ * @apiQuery {Number} [offset=0] Deliveries to skip
+ */
+
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parseLimitOffset(r.URL.Query())
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	deliveries, total, err := h.s.GetDeliveries(r.Context(), userId, id, limit, offset)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not get webhook deliveries: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponseWithMeta(w, http.StatusOK, deliveries, utils.PageMeta{Total: total})
+}
+
+/**
+ * @api {post} /webhooks/:id/deliveries/:deliveryId/redeliver Redeliver
+ * @apiName Queue the payload of a past delivery again as a new delivery
+ * @apiGroup Webhook
+ * @apiHeader {String} Authorization JWT Authorization token
+ * @apiParam {String} id Webhook ID
+ * @apiParam {String} deliveryId Delivery ID
+ */
+
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, userId, ok := h.parseIds(w, r)
+	if !ok {
+		return
+	}
+	deliveryId, err := uuid.Parse(mux.Vars(r)["deliveryId"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid delivery id"))
+		return
+	}
+
+	d, err := h.s.Redeliver(r.Context(), userId, id, deliveryId)
+	if err != nil {
+		logging.ForRequest(r, h.l).Printf("Could not redeliver: %v", err)
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusAccepted, d)
+}
+
+func (h *WebhookHandler) parseUserId(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userId, err := middlewares.GetUserIDFromVars(r)
+	if err != nil {
+		logging.ForRequest(r, h.l).Println(err)
+		utils.WriteError(w, r, utils.ErrInvalidToken)
+		return uuid.Nil, false
+	}
+	return userId, true
+}
+
+func (h *WebhookHandler) parseIds(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, utils.ErrInvalidID.WithDetail("invalid webhook id"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	userId, ok := h.parseUserId(w, r)
+	return id, userId, ok
+}
+
+// reads limit and offset paging from the query string
+func parseLimitOffset(q url.Values) (int, int, error) {
+	limit, offset := defaultPageSize, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, 0, utils.ErrInvalidQuery.WithDetail("limit must be between 1 and %d", maxPageSize)
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, utils.ErrInvalidQuery.WithDetail("offset must be a non-negative number")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
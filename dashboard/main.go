@@ -1,14 +1,20 @@
 package main
 
 import (
+	"backend/config"
 	db "backend/dashboard/db"
 	"backend/dashboard/handlers"
 	"backend/dashboard/repository"
 	"backend/dashboard/services"
+	"backend/health"
+	"backend/logging"
+	"backend/metrics"
 	mw "backend/middlewares"
+	"backend/tracing"
+	"backend/utils"
 	"context"
+	"flag"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,23 +22,41 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
+// wait between attempts to reach redis at startup
+var redisBackoff = utils.Backoff{Initial: 500 * time.Millisecond, Max: 5 * time.Second}
+
 func main() {
-	//getting environment variables
-	port := os.Getenv("PORT")
-	dbUser, dbPassword, dbName :=
-		os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB")
+	//settings from the config file, env and flags
+	cfg := config.DefaultDashboard()
+	err := config.Load(&cfg, os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		logging.New("dash-service", logrus.InfoLevel).Fatalf("Could not load config: %v", err)
+	}
 
 	//logger to inject to use across services
-	logger := log.New(os.Stdout, "dash-service ", log.LstdFlags)
-	bindAddress := fmt.Sprintf(":%s", port)
+	logger := logging.New("dash-service", cfg.Telemetry.LogLevel)
+	logger.WithField("config", config.Redacted(cfg)).Info("Loaded config")
+	bindAddress := fmt.Sprintf(":%d", cfg.Server.Port)
+	utils.ConfigureJWT(cfg.JWT.Secret, cfg.JWT.Expiry)
+
+	//tracing, spans are exported as set in config
+	shutdownTracing, err := tracing.Init(context.Background(), "dash-service", cfg.Telemetry.TracesExporter)
+	if err != nil {
+		logger.Fatalf("Could not set up tracing: %v", err)
+	}
+
+	//postgres and redis may still be starting, both are retried for up to the startup timeout
+	startupCtx, cancelStartup := context.WithTimeout(context.Background(), cfg.Lifecycle.StartupTimeout)
+	defer cancelStartup()
 
 	//database init
-	database, err := db.Initialize(dbUser, dbPassword, dbName)
+	database, err := db.Initialize(startupCtx, cfg.Database, logger)
 	if err != nil {
 		logger.Fatalf("Could not set up database: %v", err)
 	}
@@ -41,73 +65,130 @@ func main() {
 
 	//redis init
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "redis:6379",
-		Password: "",
-		DB:       0,
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
 	})
 	defer rdb.Close()
+	rdb.AddHook(tracing.RedisHook{})
 
 	//ping client
-	_, err = rdb.Ping(context.Background()).Result()
+	err = utils.Retry(startupCtx, redisBackoff, func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	}, func(err error, wait time.Duration) {
+		logger.Printf("Redis not reachable, retrying in %s: %v", wait, err)
+	})
 	if err != nil {
 		logger.Fatalf("Could not set up redis: %v", err)
 	}
+	cancelStartup()
 	logger.Println("Connected to redis")
 
+	//rate limits are shared through redis, each replica limits on its own while redis is down
+	rateLimiter := mw.NewRateLimiter(mw.NewFallbackRateLimitStore(mw.NewRedisRateLimitStore(rdb), mw.NewLocalRateLimitStore()), cfg.RateLimit.Default)
+	err = rateLimiter.ParseOverrides(cfg.RateLimit.Overrides)
+	if err != nil {
+		logger.Fatalf("Invalid RATE_LIMIT_OVERRIDES: %v", err)
+	}
+
 	//create handlers
 	dashRepo := repository.NewDashRepository(&database, logger)
 	viewRepo := repository.NewViewRepository(&database, logger)
 	roleRepo := repository.NewRoleRepository(&database, logger)
-	roleService := services.NewRoleService(roleRepo, rdb, logger)
-	viewService := services.NewViewService(viewRepo, roleService, logger)
-	dashService := services.NewDashService(dashRepo, viewService, roleService, rdb, logger)
-
+	commentRepo := repository.NewCommentRepository(&database, logger)
+	searchRepo := repository.NewSearchRepository(&database, logger)
+	folderRepo := repository.NewFolderRepository(&database, logger)
+	shareRepo := repository.NewShareRepository(&database, logger)
+	accessRequestRepo := repository.NewAccessRequestRepository(&database, logger)
+	webhookRepo := repository.NewWebhookRepository(&database, logger)
+	notificationRepo := repository.NewNotificationRepository(&database, logger)
+	events := services.NewEventBus(logger)
+	roleService := services.NewRoleService(roleRepo, rdb, events, logger)
+	viewService := services.NewViewService(viewRepo, roleService, events, logger)
+	dashService := services.NewDashService(dashRepo, viewService, roleService, rdb, events, logger)
+	trashService := services.NewTrashService(dashRepo, viewRepo, roleRepo, cfg.Trash.Retention, logger)
+	userDirectory := services.NewUserDirectory(cfg.AuthServiceURL, logger)
+	exportService := services.NewExportService(dashService, dashRepo, userDirectory, logger)
+	notificationService := services.NewNotificationService(notificationRepo, dashRepo, logger)
+	events.Subscribe(notificationService.HandleEvent)
+	commentService := services.NewCommentService(commentRepo, viewService, roleService, userDirectory, notificationService, logger)
+	searchService := services.NewSearchService(searchRepo, logger)
+	folderService := services.NewFolderService(folderRepo, roleService, logger)
+	grantExpiryService := services.NewGrantExpiryService(roleRepo, notificationService, events, cfg.Grants.ExpiryNotice, logger)
+	shareService := services.NewShareService(shareRepo, dashRepo, viewRepo, roleService, cfg.EmbedTokenTTL, logger)
+	accessRequestService := services.NewAccessRequestService(accessRequestRepo, dashRepo, roleService, notificationService, events, logger)
+	webhookService := services.NewWebhookService(webhookRepo, roleService, cfg.SystemAdminIDs, cfg.Webhooks.AllowPrivateTargets, logger)
+	events.Subscribe(webhookService.Enqueue)
+	streamService := services.NewStreamService(roleService, rdb, logger)
+	events.Subscribe(streamService.Publish)
+
+	//prometheus metrics, with business gauges queried on every scrape
+	registry := metrics.NewRegistry(database.Conn, cfg.Database.Name)
+	registry.MustRegister(
+		metrics.CacheRequests,
+		metrics.PermissionChecks,
+		metrics.NewQueryGauge("dashboards", "Dashboards not in trash, by kind.", "kind", dashRepo.CountDashboards),
+		metrics.NewQueryGauge("active_grants", "Grants that have not lapsed, by scope.", "scope", roleRepo.CountActiveGrants),
+	)
+
+	//readiness covers every dependency needed to serve requests
+	checker := health.NewChecker(cfg.Lifecycle.HealthCheckTimeout)
+	checker.Add("postgres", health.Postgres(database.Conn))
+	checker.Add("migrations", health.Migrations(database.Conn, database.Version))
+	checker.Add("redis", health.Redis(rdb))
+
+	handlers.RegisterValidations(logger, roleService)
 	dashHandler := handlers.NewDash(logger, dashService)
-
-	//serve mux
-	serveMux := mux.NewRouter()
-	serveMux.Use(mw.LoggerMiddleware)
-	serveMux.Use(mw.JSONContentHeaders) //adding content type to all responses
-	serveMux.Use(mw.AuthMiddleware)
-
-	//subrouter for post requests
-	postR := serveMux.Methods(http.MethodPost).Subrouter()
-	postR.HandleFunc("/dashboard", dashHandler.CreateDash)
-	postR.HandleFunc("/view", dashHandler.CreateView)
-	postR.HandleFunc("/dashboard/{id}/users", dashHandler.AddUserToDash)
-	postR.HandleFunc("/view/{id}/users", dashHandler.AddUserToView)
-
-	//subrouter for delete requests
-	deleteR := serveMux.Methods(http.MethodDelete).Subrouter()
-	deleteR.HandleFunc("/dashboard/{id}/users", dashHandler.DeleteUserFromDash)
-	deleteR.HandleFunc("/view/{id}/users", dashHandler.DeleteUserFromView)
-
-	//subrouter for patch requests
-	patchR := serveMux.Methods(http.MethodPut).Subrouter()
-	patchR.HandleFunc("/dashboard/{id}", dashHandler.UpdateDash)
-	patchR.HandleFunc("/view/{id}", dashHandler.UpdateView)
-
-	//subrouter for get requests
-	getR := serveMux.Methods(http.MethodGet).Subrouter()
-	getR.HandleFunc("/dashboard", dashHandler.GetDashs)
-	getR.HandleFunc("/dashboard/{id}", dashHandler.GetDash)
-	getR.HandleFunc("/dashboard/{id}/users", dashHandler.GetUsersFromDash)
-	getR.HandleFunc("/view/{id}", dashHandler.GetView)
-	getR.HandleFunc("/roles", dashHandler.GetAllRoles)
+	trashHandler := handlers.NewTrash(logger, trashService)
+	exportHandler := handlers.NewExport(logger, exportService)
+	commentHandler := handlers.NewComment(logger, commentService)
+	searchHandler := handlers.NewSearch(logger, searchService)
+	folderHandler := handlers.NewFolder(logger, folderService)
+	shareHandler := handlers.NewShare(logger, shareService)
+	accessRequestHandler := handlers.NewAccessRequest(logger, accessRequestService)
+	webhookHandler := handlers.NewWebhook(logger, webhookService)
+	streamHandler := handlers.NewStream(logger, streamService)
+	notificationHandler := handlers.NewNotification(logger, notificationService)
+
+	//background jobs stop when the server shuts down
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go trashService.RunPurger(jobCtx, cfg.Trash.PurgeInterval)
+	go grantExpiryService.RunSweeper(jobCtx, cfg.Grants.SweepInterval)
+	go webhookService.RunDispatcher(jobCtx, cfg.Webhooks.DispatchInterval)
+	go streamService.Run(jobCtx)
+
+	router := newRouter(logger, cfg.Server.TrustedProxies, cfg.Server.WriteTimeout, routeHandlers{
+		checker:       checker,
+		metrics:       metrics.Handler(registry),
+		rateLimiter:   rateLimiter,
+		dash:          dashHandler,
+		trash:         trashHandler,
+		export:        exportHandler,
+		comment:       commentHandler,
+		search:        searchHandler,
+		folder:        folderHandler,
+		share:         shareHandler,
+		accessRequest: accessRequestHandler,
+		webhook:       webhookHandler,
+		stream:        streamHandler,
+		notification:  notificationHandler,
+	})
 
 	// create a new server
 	server := http.Server{
-		Addr:         bindAddress,       // configure the bind address
-		Handler:      serveMux,          // set the default handler
-		ErrorLog:     logger,            // set the logger for the server
-		ReadTimeout:  5 * time.Second,   // max time to read request from the client
-		WriteTimeout: 10 * time.Second,  // max time to write response to the client
-		IdleTimeout:  120 * time.Second, // max time for connections using TCP Keep-Alive
+		Addr:        bindAddress,               // configure the bind address
+		Handler:     router,                    // set the default handler
+		ErrorLog:    logging.StdLogger(logger), // set the logger for the server
+		ReadTimeout: cfg.Server.ReadTimeout,    // max time to read request from the client
+		IdleTimeout: cfg.Server.IdleTimeout,    // max time for connections using TCP Keep-Alive
+		// write timeouts are set per request by the WriteDeadline middleware, event streams extend theirs
+		ConnContext: mw.ConnContext,
 	}
 
 	// start the server
 	go func() {
-		logger.Printf("Starting server on port %s", port)
+		logger.Printf("Starting server on port %d", cfg.Server.Port)
 
 		err := server.ListenAndServe()
 		if err != nil {
@@ -123,11 +204,14 @@ func main() {
 
 	// Block until a signal is received.
 	sig := <-stopChannel
-	log.Println("Got signal:", sig)
-	log.Println("Shutting down gracefully.")
+	logger.Println("Got signal:", sig)
+	logger.Println("Shutting down gracefully.")
+	stopJobs()
 
-	// gracefully shutdown the server, waiting max 30 seconds for current operations to complete
-	ctx, cancelFunc := context.WithTimeout(context.Background(), 30*time.Second)
+	// gracefully shutdown the server, waiting for current operations to complete
+	ctx, cancelFunc := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	server.Shutdown(ctx)
+	//flush spans still waiting to be exported
+	shutdownTracing(ctx)
 	cancelFunc()
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// States of an access request
+const (
+	AccessRequestPending  = "pending"
+	AccessRequestApproved = "approved"
+	AccessRequestDenied   = "denied"
+)
+
+// Request by a user for a role on a dashboard. GrantedRole is the role given on approval,
+// which may differ from the requested one.
+type AccessRequest struct {
+	ID            uuid.UUID  `json:"id"`
+	DashID        uuid.UUID  `json:"dashId"`
+	RequesterID   uuid.UUID  `json:"requesterId"`
+	Role          string     `json:"role"`
+	Justification string     `json:"justification"`
+	Status        string     `json:"status"`
+	DecidedBy     *uuid.UUID `json:"decidedBy,omitempty"`
+	GrantedRole   *string    `json:"grantedRole,omitempty"`
+	DecisionNote  *string    `json:"decisionNote,omitempty"`
+	CreatedAt     *time.Time `json:"createdAt,omitempty"`
+	DecidedAt     *time.Time `json:"decidedAt,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Comment on a dashboard, or on one of its views when ViewID is set.
+// Root comments start a thread and carry its replies.
+type Comment struct {
+	ID         uuid.UUID   `json:"id"`
+	DashID     uuid.UUID   `json:"dashboardId"`
+	ViewID     *uuid.UUID  `json:"viewId,omitempty"`
+	ParentID   *uuid.UUID  `json:"parentId,omitempty"`
+	AuthorID   uuid.UUID   `json:"authorId"`
+	Body       string      `json:"body"`
+	Mentions   []uuid.UUID `json:"mentions"`
+	ResolvedAt *time.Time  `json:"resolvedAt,omitempty"`
+	ResolvedBy *uuid.UUID  `json:"resolvedBy,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  *time.Time  `json:"updatedAt,omitempty"`
+	Replies    []*Comment  `json:"replies,omitempty"`
+}
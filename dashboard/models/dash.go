@@ -3,21 +3,25 @@ package models
 import (
 	"encoding/json"
 	"io"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Dash struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Views       []*View   `json:"views,omitempty"`
-	CreatedAt   string    `json:"-"`
-}
-
-func (d *Dash) FromJSON(r io.Reader) error {
-	e := json.NewDecoder(r)
-	return e.Decode(d)
+	ID          uuid.UUID           `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Views       []*View             `json:"views,omitempty"`
+	IsTemplate  bool                `json:"isTemplate"`
+	Variables   []*TemplateVariable `json:"variables,omitempty"`
+	Layout      []*LayoutItem       `json:"layout,omitempty"`
+	FolderID    *uuid.UUID          `json:"folderId,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	CreatedAt   *time.Time          `json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time          `json:"updatedAt,omitempty"`
+	DeletedAt   *time.Time          `json:"deletedAt,omitempty"`
+	DeletedBy   *uuid.UUID          `json:"deletedBy,omitempty"`
 }
 
 func (d *Dash) ToJSON(w io.Writer) error {
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of events published when dashboards, views or access to them change
+const (
+	EventDashCreated     = "dashboard.created"
+	EventDashUpdated     = "dashboard.updated"
+	EventDashDeleted     = "dashboard.deleted"
+	EventViewCreated     = "view.created"
+	EventViewDeleted     = "view.deleted"
+	EventGrantCreated    = "grant.created"
+	EventGrantRevoked    = "grant.revoked"
+	EventAccessRequested = "access.requested"
+	EventAccessApproved  = "access.approved"
+	EventAccessDenied    = "access.denied"
+)
+
+// Every event type, in the order they are documented
+var EventTypes = []string{
+	EventDashCreated, EventDashUpdated, EventDashDeleted,
+	EventViewCreated, EventViewDeleted,
+	EventGrantCreated, EventGrantRevoked,
+	EventAccessRequested, EventAccessApproved, EventAccessDenied,
+}
+
+// Something that happened to a dashboard or one of its views. ActorID is the user who
+// caused it, when known.
+type Event struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       string      `json:"type"`
+	DashID     uuid.UUID   `json:"dashId"`
+	ViewID     *uuid.UUID  `json:"viewId,omitempty"`
+	ActorID    *uuid.UUID  `json:"actorId,omitempty"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// Copy of the event without its data, for receivers that may not read the view it is about
+func (e *Event) WithoutData() *Event {
+	c := *e
+	c.Data = nil
+	return &c
+}
+
+// Payload of grant events
+type GrantEvent struct {
+	UserID    uuid.UUID  `json:"userId"`
+	Role      string     `json:"role,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Event as sent on the stream of a dashboard. IDs order the events of a dashboard and are
+// handed back as Last-Event-ID to resume a stream.
+type StreamEvent struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+	// view the event is about, and the event without its data for those who cannot read the view
+	ViewID  *uuid.UUID      `json:"viewId,omitempty"`
+	IDsOnly json.RawMessage `json:"idsOnly,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Version of the export document written by this service. Imports of other versions are rejected.
+const ExportVersion = 1
+
+// Self-contained, portable representation of a dashboard. Views are referenced by
+// document local refs instead of ids so the document can be imported anywhere.
+type DashExport struct {
+	Version    int                   `json:"version" validate:"required"`
+	ExportedAt time.Time             `json:"exportedAt"`
+	Dashboard  *ExportedDash         `json:"dashboard" validate:"required"`
+	Views      []*ExportedView       `json:"views" validate:"dive,required"`
+	Layout     []*ExportedLayoutItem `json:"layout" validate:"dive,required"`
+	Roles      *ExportedRoles        `json:"roles,omitempty"`
+}
+
+type ExportedDash struct {
+	Name        string              `json:"name" validate:"required,name"`
+	Description string              `json:"description"`
+	IsTemplate  bool                `json:"isTemplate,omitempty"`
+	Variables   []*TemplateVariable `json:"variables,omitempty" validate:"dive,required"`
+}
+
+type ExportedView struct {
+	Ref         string          `json:"ref" validate:"required"`
+	Name        string          `json:"name" validate:"required,name"`
+	Description string          `json:"description"`
+	Config      json.RawMessage `json:"config,omitempty"`
+}
+
+type ExportedLayoutItem struct {
+	ViewRef string `json:"viewRef" validate:"required"`
+	X       int    `json:"x" validate:"min=0"`
+	Y       int    `json:"y" validate:"min=0"`
+	W       int    `json:"w" validate:"min=1"`
+	H       int    `json:"h" validate:"min=1"`
+}
+
+// Role assignments identified by email, as user ids differ between environments
+type ExportedRoles struct {
+	Dashboard []*ExportedGrant `json:"dashboard" validate:"dive,required"`
+	Views     []*ExportedGrant `json:"views" validate:"dive,required"`
+}
+
+type ExportedGrant struct {
+	ViewRef string `json:"viewRef,omitempty"`
+	Email   string `json:"email" validate:"required,email"`
+	Role    string `json:"role" validate:"required"`
+}
+
+// Outcome of an import. On a dry run nothing is created and Dashboard is nil.
+type ImportReport struct {
+	DryRun           bool             `json:"dryRun"`
+	Dashboard        *Dash            `json:"dashboard,omitempty"`
+	DashboardName    string           `json:"dashboardName"`
+	Views            int              `json:"views"`
+	Grants           []*ExportedGrant `json:"grants"`
+	UnresolvedEmails []string         `json:"unresolvedEmails"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "github.com/google/uuid"
+
+// Placement of a view on the dashboard grid
+type LayoutItem struct {
+	ViewID uuid.UUID `json:"viewId" validate:"required"`
+	X      int       `json:"x" validate:"min=0"`
+	Y      int       `json:"y" validate:"min=0"`
+	W      int       `json:"w" validate:"min=1"`
+	H      int       `json:"h" validate:"min=1"`
+}
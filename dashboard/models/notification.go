@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of notification
+const (
+	NotifyGrantExpiring   = "grant_expiring"
+	NotifyAccessRequested = "access_requested"
+	NotifyAccessDenied    = "access_denied"
+	NotifyAccessGranted   = "access_granted"
+	NotifyAccessRevoked   = "access_revoked"
+	NotifyMentioned       = "mentioned"
+)
+
+// Every kind of notification, in the order they are listed in preferences
+var NotificationTypes = []string{
+	NotifyAccessGranted, NotifyAccessRevoked, NotifyMentioned, NotifyGrantExpiring,
+	NotifyAccessRequested, NotifyAccessDenied,
+}
+
+// Something a user should be told about. DashID and ViewID point at what it concerns.
+type Notification struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	UserID    uuid.UUID   `json:"userId"`
+	DashID    *uuid.UUID  `json:"dashId,omitempty"`
+	ViewID    *uuid.UUID  `json:"viewId,omitempty"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+	CreatedAt *time.Time  `json:"createdAt,omitempty"`
+	ReadAt    *time.Time  `json:"readAt,omitempty"`
+}
+
+// Whether a user gets notifications of a kind
+type NotificationPreference struct {
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+}
+
+// A page of the notifications of a user along with how many are unread overall
+type NotificationPage struct {
+	Items  []*Notification
+	Total  int
+	Unread int
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Columns dashboards can be listed by
+const (
+	SortByName    = "name"
+	SortByCreated = "created"
+	SortByUpdated = "updated"
+)
+
+// Options for listing dashboards a page at a time. Only dashboards carrying all Tags
+// and, when FolderID is set, placed directly in that folder are listed.
+type DashListOptions struct {
+	Query    string
+	Tags     []string
+	FolderID *uuid.UUID
+	Sort     string
+	Desc     bool
+	Limit    int
+	After    *PageCursor
+}
+
+// Position after the last item of a page: the value of the sort column and the id of the item
+type PageCursor struct {
+	Sort  string    `json:"s"`
+	Desc  bool      `json:"d"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// Encodes the cursor as an opaque url safe string
+func (c *PageCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decodes a cursor produced by Encode
+func DecodePageCursor(s string) (*PageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	c := &PageCursor{}
+	err = json.Unmarshal(raw, c)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// A page of dashboards along with the cursor of the next page, empty on the last page
+type DashPage struct {
+	Items      []*Dash
+	NextCursor string
+	Total      int
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPageCursorRoundTrip(t *testing.T) {
+	c := &PageCursor{Sort: SortByName, Desc: true, Value: "sales & ops/2022", ID: uuid.New()}
+	s := c.Encode()
+	for _, r := range s {
+		if r == '+' || r == '/' || r == '=' {
+			t.Fatalf("cursor %q is not url safe", s)
+		}
+	}
+	got, err := DecodePageCursor(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *c {
+		t.Errorf("decoded %+v, want %+v", got, c)
+	}
+}
+
+func TestDecodePageCursorInvalid(t *testing.T) {
+	for _, s := range []string{
+		"not a cursor!",
+		base64.RawURLEncoding.EncodeToString([]byte("not json")),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"id":"42"}`)),
+		//padding is not part of cursors
+		base64.RawURLEncoding.EncodeToString([]byte(`{"s":"name"}`)) + "==",
+	} {
+		if _, err := DecodePageCursor(s); err == nil {
+			t.Errorf("%q decoded without error", s)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Permission struct {
 	ID   int    `json:"permId"`
@@ -12,4 +16,23 @@ type Role struct {
 	Name        string        `json:"roleName"`
 	Permissions []*Permission `json:"permissions"`
 	UserId      uuid.UUID     `json:"userId"`
+	FolderID    *uuid.UUID    `json:"inheritedFrom,omitempty"`
+	ExpiresAt   *time.Time    `json:"expiresAt,omitempty"`
+}
+
+// Role of a user on a dashboard, or on one of its views when ViewID is set
+type Grant struct {
+	UserID    uuid.UUID  `json:"userId"`
+	RoleName  string     `json:"role"`
+	ViewID    *uuid.UUID `json:"viewId,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Grant that lapses soon
+type ExpiringGrant struct {
+	UserID    uuid.UUID  `json:"userId"`
+	RoleName  string     `json:"role"`
+	DashID    uuid.UUID  `json:"dashId"`
+	ViewID    *uuid.UUID `json:"viewId,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt"`
 }
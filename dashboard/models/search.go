@@ -0,0 +1,34 @@
+package models
+
+import "github.com/google/uuid"
+
+const (
+	SearchTypeDashboard = "dashboard"
+	SearchTypeView      = "view"
+)
+
+// Options of a full-text search. Type limits results to dashboards or views when set.
+type SearchOptions struct {
+	Query  string
+	Type   string
+	Limit  int
+	Offset int
+}
+
+// A dashboard or view matching a search. Headline and Snippet are HTML escaped, with
+// matched words wrapped in <mark> tags.
+type SearchResult struct {
+	Type     string    `json:"type"`
+	ID       uuid.UUID `json:"id"`
+	DashID   uuid.UUID `json:"dash_id"`
+	Name     string    `json:"name"`
+	Headline string    `json:"headline"`
+	Snippet  string    `json:"snippet"`
+	Rank     float32   `json:"rank"`
+}
+
+// A page of search results with the total number of matches
+type SearchPage struct {
+	Items []*SearchResult
+	Total int
+}
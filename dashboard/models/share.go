@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Read-only link to a dashboard, or to one of its views when ViewID is set.
+// Token is only filled in when the link is created.
+type ShareLink struct {
+	ID          uuid.UUID  `json:"id"`
+	DashID      uuid.UUID  `json:"dashId"`
+	ViewID      *uuid.UUID `json:"viewId,omitempty"`
+	Token       string     `json:"token,omitempty"`
+	HasPassword bool       `json:"hasPassword"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	CreatedBy   uuid.UUID  `json:"createdBy"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// Short-lived token granting read access to a single dashboard or view
+type EmbedToken struct {
+	Token     string     `json:"token"`
+	DashID    uuid.UUID  `json:"dashId"`
+	ViewID    *uuid.UUID `json:"viewId,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+}
+
+// A dashboard or view reached through a share link or embed token.
+// Exactly one of Dashboard and View is set.
+type SharedResource struct {
+	Dashboard *Dash `json:"dashboard,omitempty"`
+	View      *View `json:"view,omitempty"`
+}
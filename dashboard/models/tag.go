@@ -0,0 +1,7 @@
+package models
+
+// A tag and the number of dashboards carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
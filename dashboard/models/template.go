@@ -0,0 +1,9 @@
+package models
+
+// Variable declared by a template dashboard. Occurrences of {{name}} in the
+// dashboard and its views are replaced when the template is instantiated.
+type TemplateVariable struct {
+	Name        string  `json:"name" validate:"required"`
+	Description string  `json:"description,omitempty"`
+	Default     *string `json:"default,omitempty"`
+}
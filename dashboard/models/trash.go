@@ -0,0 +1,8 @@
+package models
+
+// Trashed dashboards and views a user is allowed to restore
+type Trash struct {
+	Dashboards []*Dash `json:"dashboards"`
+	Views      []*View `json:"views"`
+	Retention  string  `json:"retention"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "github.com/google/uuid"
+
+// User as known to the auth service
+type User struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+}
@@ -3,21 +3,20 @@ package models
 import (
 	"encoding/json"
 	"io"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type View struct {
-	ID          uuid.UUID `json:"id"`
-	DashID      uuid.UUID `json:"dash_id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   string    `json:"-"`
-}
-
-func (v *View) FromJSON(r io.Reader) error {
-	e := json.NewDecoder(r)
-	return e.Decode(v)
+	ID          uuid.UUID       `json:"id"`
+	DashID      uuid.UUID       `json:"dash_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Config      json.RawMessage `json:"config,omitempty"`
+	CreatedAt   string          `json:"-"`
+	DeletedAt   *time.Time      `json:"deletedAt,omitempty"`
+	DeletedBy   *uuid.UUID      `json:"deletedBy,omitempty"`
 }
 
 func (v *View) ToJSON(w io.Writer) error {
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// States of a webhook delivery
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Endpoint receiving events of the given types. Webhooks without a dashboard receive
+// events of every dashboard. Secret is only returned when the webhook is created.
+type Webhook struct {
+	ID        uuid.UUID  `json:"id"`
+	DashID    *uuid.UUID `json:"dashId,omitempty"`
+	URL       string     `json:"url"`
+	Events    []string   `json:"events"`
+	Secret    string     `json:"secret,omitempty"`
+	Active    bool       `json:"active"`
+	CreatedBy uuid.UUID  `json:"createdBy"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// One event queued for one webhook, along with the outcome of the latest attempt
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhookId"`
+	EventID        uuid.UUID       `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	LastStatusCode *int            `json:"lastStatusCode,omitempty"`
+	LastError      *string         `json:"lastError,omitempty"`
+	CreatedAt      *time.Time      `json:"createdAt,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+
+	// target of the delivery, filled in when it is claimed for sending
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"backend/dashboard/db"
+	"backend/dashboard/models"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// Contains methods for requests for access to dashboards
+type AccessRequestRepository struct {
+	Conn *db.DashboardDb
+	L    logrus.FieldLogger
+}
+
+// Returns a new instance of AccessRequestRepository
+func NewAccessRequestRepository(conn *db.DashboardDb, l logrus.FieldLogger) *AccessRequestRepository {
+	return &AccessRequestRepository{conn, l}
+}
+
+const accessRequestColumns = `a.id, a.dashboard_id, a.requester_id, r.name, a.justification, a.status, a.decided_by,
+	(SELECT g.name FROM roles g WHERE g.id = a.granted_role_id), a.decision_note, a.created_at, a.decided_at`
+
+const accessRequestFrom = " FROM access_request a JOIN roles r ON r.id = a.role_id"
+
+func scanAccessRequest(row rowScanner) (*models.AccessRequest, error) {
+	req := &models.AccessRequest{}
+	err := row.Scan(&req.ID, &req.DashID, &req.RequesterID, &req.Role, &req.Justification, &req.Status, &req.DecidedBy,
+		&req.GrantedRole, &req.DecisionNote, &req.CreatedAt, &req.DecidedAt)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Add a pending request. Returns false if the user already has a pending request for the dashboard.
+func (repo *AccessRequestRepository) AddAccessRequest(ctx context.Context, req *models.AccessRequest) (bool, error) {
+	err := repo.Conn.Conn.QueryRowContext(ctx, `INSERT INTO access_request (dashboard_id, requester_id, role_id, justification)
+		SELECT $1, $2, roles.id, $4 FROM roles WHERE roles.name = $3
+		RETURNING id, status, created_at`, req.DashID, req.RequesterID, req.Role, req.Justification).
+		Scan(&req.ID, &req.Status, &req.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get an access request by id
+func (repo *AccessRequestRepository) GetAccessRequest(ctx context.Context, id uuid.UUID) (*models.AccessRequest, error) {
+	return scanAccessRequest(repo.Conn.Conn.QueryRowContext(ctx, "SELECT "+accessRequestColumns+accessRequestFrom+" WHERE a.id = $1", id))
+}
+
+// Get access requests for a dashboard, all of them when status is empty, oldest first
+func (repo *AccessRequestRepository) GetAccessRequestsForDashboard(ctx context.Context, dashId uuid.UUID, status string) ([]*models.AccessRequest, error) {
+	return repo.query(ctx, "SELECT "+accessRequestColumns+accessRequestFrom+" WHERE a.dashboard_id = $1 AND ($2 = '' OR a.status = $2) ORDER BY a.created_at", dashId, status)
+}
+
+// Get access requests made by a user, newest first
+func (repo *AccessRequestRepository) GetAccessRequestsByRequester(ctx context.Context, userId uuid.UUID) ([]*models.AccessRequest, error) {
+	return repo.query(ctx, "SELECT "+accessRequestColumns+accessRequestFrom+" WHERE a.requester_id = $1 ORDER BY a.created_at DESC", userId)
+}
+
+func (repo *AccessRequestRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.AccessRequest, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reqs := make([]*models.AccessRequest, 0)
+	for rows.Next() {
+		req, err := scanAccessRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, rows.Err()
+}
+
+// Record the decision on a pending request. grantedRole is empty for denials.
+// Returns sql.ErrNoRows if the request is not pending anymore.
+func (repo *AccessRequestRepository) DecideAccessRequest(ctx context.Context, id uuid.UUID, status string, deciderId uuid.UUID, grantedRole string, note *string) error {
+	res, err := repo.Conn.Conn.ExecContext(ctx, `UPDATE access_request SET status = $2, decided_by = $3, decided_at = NOW(), decision_note = $5,
+		granted_role_id = (SELECT id FROM roles WHERE name = $4)
+		WHERE id = $1 AND status = 'pending'`, id, status, deciderId, grantedRole, note)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Put back to pending a request approved by deciderId whose grant could not be made
+func (repo *AccessRequestRepository) ReopenAccessRequest(ctx context.Context, id, deciderId uuid.UUID) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, `UPDATE access_request SET status = 'pending', decided_by = NULL, decided_at = NULL,
+		decision_note = NULL, granted_role_id = NULL
+		WHERE id = $1 AND status = 'approved' AND decided_by = $2`, id, deciderId)
+	return err
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"backend/dashboard/db"
+	"backend/dashboard/models"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// Contains methods for comment threads on dashboards and views
+type CommentRepository struct {
+	Conn *db.DashboardDb
+	L    logrus.FieldLogger
+}
+
+// Returns a new instance of CommentRepository
+func NewCommentRepository(conn *db.DashboardDb, l logrus.FieldLogger) *CommentRepository {
+	return &CommentRepository{conn, l}
+}
+
+const commentColumns = `c.id, c.dashboard_id, c.view_id, c.parent_id, c.author_id, c.body, c.resolved_at, c.resolved_by, c.created_at, c.updated_at,
+	COALESCE((SELECT array_agg(m.user_id::text) FROM comment_mention m WHERE m.comment_id = c.id), '{}')`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanComment(row rowScanner) (*models.Comment, error) {
+	c := &models.Comment{}
+	var mentions []string
+	err := row.Scan(&c.ID, &c.DashID, &c.ViewID, &c.ParentID, &c.AuthorID, &c.Body, &c.ResolvedAt, &c.ResolvedBy, &c.CreatedAt, &c.UpdatedAt, pq.Array(&mentions))
+	if err != nil {
+		return nil, err
+	}
+	c.Mentions = make([]uuid.UUID, 0, len(mentions))
+	for _, m := range mentions {
+		id, err := uuid.Parse(m)
+		if err != nil {
+			return nil, err
+		}
+		c.Mentions = append(c.Mentions, id)
+	}
+	return c, nil
+}
+
+// Add a comment along with its mentions
+func (repo *CommentRepository) AddComment(ctx context.Context, c *models.Comment) error {
+	tx, err := repo.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO comment (dashboard_id, view_id, parent_id, author_id, body) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+		c.DashID, c.ViewID, c.ParentID, c.AuthorID, c.Body).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = insertMentions(ctx, tx, c.ID, c.Mentions)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get comment by id
+func (repo *CommentRepository) GetComment(ctx context.Context, id uuid.UUID) (*models.Comment, error) {
+	row := repo.Conn.Conn.QueryRowContext(ctx, "SELECT "+commentColumns+" FROM comment c WHERE c.id = $1", id)
+	return scanComment(row)
+}
+
+// Get all comments on a dashboard itself, oldest first. Comments on its views are not included.
+func (repo *CommentRepository) GetCommentsForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.Comment, error) {
+	return repo.queryComments(ctx, "SELECT "+commentColumns+" FROM comment c WHERE c.dashboard_id = $1 AND c.view_id IS NULL ORDER BY c.created_at", dashId)
+}
+
+// Get all comments on a view, oldest first
+func (repo *CommentRepository) GetCommentsForView(ctx context.Context, viewId uuid.UUID) ([]*models.Comment, error) {
+	return repo.queryComments(ctx, "SELECT "+commentColumns+" FROM comment c WHERE c.view_id = $1 ORDER BY c.created_at", viewId)
+}
+
+func (repo *CommentRepository) queryComments(ctx context.Context, query string, args ...interface{}) ([]*models.Comment, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	comments := []*models.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// Update body and mentions of a comment
+func (repo *CommentRepository) UpdateComment(ctx context.Context, c *models.Comment) error {
+	tx, err := repo.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "UPDATE comment SET body = $1, updated_at = NOW() WHERE id = $2 RETURNING updated_at", c.Body, c.ID).Scan(&c.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM comment_mention WHERE comment_id = $1", c.ID)
+	if err != nil {
+		return err
+	}
+	err = insertMentions(ctx, tx, c.ID, c.Mentions)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete comment by id. Replies are deleted along with it.
+func (repo *CommentRepository) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	res, err := repo.Conn.Conn.ExecContext(ctx, "DELETE FROM comment WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Mark a thread as resolved by the user, or as unresolved when userId is nil
+func (repo *CommentRepository) SetResolved(ctx context.Context, id uuid.UUID, userId *uuid.UUID) error {
+	var err error
+	if userId != nil {
+		_, err = repo.Conn.Conn.ExecContext(ctx, "UPDATE comment SET resolved_at = NOW(), resolved_by = $1 WHERE id = $2", *userId, id)
+	} else {
+		_, err = repo.Conn.Conn.ExecContext(ctx, "UPDATE comment SET resolved_at = NULL, resolved_by = NULL WHERE id = $1", id)
+	}
+	return err
+}
+
+func insertMentions(ctx context.Context, tx *sql.Tx, commentId uuid.UUID, mentions []uuid.UUID) error {
+	for _, userId := range mentions {
+		_, err := tx.ExecContext(ctx, "INSERT INTO comment_mention (comment_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", commentId, userId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
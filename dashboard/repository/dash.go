@@ -4,17 +4,27 @@ import (
 	"backend/dashboard/db"
 	"backend/dashboard/models"
 	"context"
-	"log"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
 )
 
+// tags of the dashboard aliased d, sorted
+const dashTagsColumn = "COALESCE((SELECT array_agg(t.tag ORDER BY t.tag) FROM dashboard_tags t WHERE t.dashboard_id = d.id), '{}')"
+
 type DashRepository struct {
 	Conn *db.DashboardDb
-	L    *log.Logger
+	L    logrus.FieldLogger
 }
 
-func NewDashRepository(conn *db.DashboardDb, l *log.Logger) *DashRepository {
+func NewDashRepository(conn *db.DashboardDb, l logrus.FieldLogger) *DashRepository {
 	return &DashRepository{conn, l}
 }
 
@@ -27,14 +37,14 @@ func (repo *DashRepository) AddDash(ctx context.Context, dash *models.Dash, user
 	defer tx.Rollback()
 
 	var uuid uuid.UUID
-	err = tx.QueryRow("INSERT INTO dashboard (name, description) VALUES ($1, $2) RETURNING id", dash.Name, dash.Description).Scan(&uuid)
+	err = tx.QueryRowContext(ctx, "INSERT INTO dashboard (name, description) VALUES ($1, $2) RETURNING id", dash.Name, dash.Description).Scan(&uuid)
 	if err != nil {
 		return err
 	}
 	dash.ID = uuid
 
 	// assign the user as admin on new dashboard
-	_, err = tx.Exec("INSERT INTO user_role_dashboard (dashboard_id, user_id, role_id) VALUES ($1, $2, $3)", dash.ID, userId, 1)
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_role_dashboard (dashboard_id, user_id, role_id) VALUES ($1, $2, $3)", dash.ID, userId, 1)
 	if err != nil {
 		return err
 	}
@@ -43,17 +53,382 @@ func (repo *DashRepository) AddDash(ctx context.Context, dash *models.Dash, user
 }
 
 // Get dashboard by id
-func (repo *DashRepository) GetDash(id uuid.UUID) (*models.Dash, error) {
+func (repo *DashRepository) GetDash(ctx context.Context, id uuid.UUID) (*models.Dash, error) {
+	dash := &models.Dash{}
+	var vars, layout []byte
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT d.id, d.name, d.description, d.is_template, d.template_variables, d.layout, d.folder_id, "+dashTagsColumn+" FROM dashboard d WHERE d.id = $1 AND d.deleted_at IS NULL", id).
+		Scan(&dash.ID, &dash.Name, &dash.Description, &dash.IsTemplate, &vars, &layout, &dash.FolderID, pq.Array(&dash.Tags))
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(layout, &dash.Layout)
+	if err != nil {
+		return nil, err
+	}
+	if dash.IsTemplate {
+		err = json.Unmarshal(vars, &dash.Variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dash, nil
+}
+
+// Copy a dashboard along with the given views in a single transaction. Each view in dash.Views
+// must carry the id of the view it was copied from; it is replaced with the id of the copy.
+// The user becomes admin of the new dashboard and its views. With includeGrants, roles of other
+// users on the source dashboard and views are copied too.
+func (repo *DashRepository) CloneDash(ctx context.Context, srcId uuid.UUID, dash *models.Dash, userId uuid.UUID, includeGrants bool) error {
+	tx, err := repo.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var newId uuid.UUID
+	err = tx.QueryRowContext(ctx, "INSERT INTO dashboard (name, description) VALUES ($1, $2) RETURNING id", dash.Name, dash.Description).Scan(&newId)
+	if err != nil {
+		return err
+	}
+
+	// assign the user as admin on new dashboard
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_role_dashboard (dashboard_id, user_id, role_id) VALUES ($1, $2, $3)", newId, userId, 1)
+	if err != nil {
+		return err
+	}
+
+	if includeGrants {
+		_, err = tx.ExecContext(ctx, `INSERT INTO user_role_dashboard (dashboard_id, user_id, role_id, expires_at) SELECT $1, user_id, role_id, expires_at FROM user_role_dashboard
+			WHERE dashboard_id = $2 AND user_id <> $3 AND (expires_at IS NULL OR expires_at > NOW())`, newId, srcId, userId)
+		if err != nil {
+			return err
+		}
+	}
+
+	viewIds := map[uuid.UUID]uuid.UUID{}
+	for _, view := range dash.Views {
+		srcViewId := view.ID
+		var newViewId uuid.UUID
+		err = tx.QueryRowContext(ctx, "INSERT INTO view (dashboard_id, name, description, config) VALUES ($1, $2, $3, $4) RETURNING id", newId, view.Name, view.Description, configOrEmpty(view.Config)).Scan(&newViewId)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO user_role_view (view_id, user_id, role_id) VALUES ($1, $2, $3)", newViewId, userId, 1)
+		if err != nil {
+			return err
+		}
+
+		if includeGrants {
+			_, err = tx.ExecContext(ctx, `INSERT INTO user_role_view (view_id, user_id, role_id, expires_at) SELECT $1, user_id, role_id, expires_at FROM user_role_view
+				WHERE view_id = $2 AND user_id <> $3 AND (expires_at IS NULL OR expires_at > NOW())`, newViewId, srcViewId, userId)
+			if err != nil {
+				return err
+			}
+		}
+
+		viewIds[srcViewId] = newViewId
+		view.ID = newViewId
+		view.DashID = newId
+	}
+
+	// layout of the copy only places the copied views
+	layout := []*models.LayoutItem{}
+	for _, item := range dash.Layout {
+		if newViewId, ok := viewIds[item.ViewID]; ok {
+			layout = append(layout, &models.LayoutItem{ViewID: newViewId, X: item.X, Y: item.Y, W: item.W, H: item.H})
+		}
+	}
+	raw, err := json.Marshal(layout)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE dashboard SET layout = $1 WHERE id = $2", string(raw), newId)
+	if err != nil {
+		return err
+	}
+	dash.Layout = layout
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	dash.ID = newId
+	return nil
+}
+
+// Create a dashboard with its views, layout and grants in a single transaction. Ids of the
+// dashboard and views are taken from the model so layout and grants can refer to them.
+// The user becomes admin of the dashboard and all of its views.
+func (repo *DashRepository) ImportDash(ctx context.Context, dash *models.Dash, userId uuid.UUID, grants []*models.Grant) error {
+	tx, err := repo.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	vars := dash.Variables
+	if vars == nil {
+		vars = []*models.TemplateVariable{}
+	}
+	rawVars, err := json.Marshal(vars)
+	if err != nil {
+		return err
+	}
+	layout := dash.Layout
+	if layout == nil {
+		layout = []*models.LayoutItem{}
+	}
+	rawLayout, err := json.Marshal(layout)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO dashboard (id, name, description, is_template, template_variables, layout) VALUES ($1, $2, $3, $4, $5, $6)",
+		dash.ID, dash.Name, dash.Description, dash.IsTemplate, string(rawVars), string(rawLayout))
+	if err != nil {
+		return err
+	}
+
+	// assign the user as admin on new dashboard
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_role_dashboard (dashboard_id, user_id, role_id) VALUES ($1, $2, $3)", dash.ID, userId, 1)
+	if err != nil {
+		return err
+	}
+
+	for _, view := range dash.Views {
+		_, err = tx.ExecContext(ctx, "INSERT INTO view (id, dashboard_id, name, description, config) VALUES ($1, $2, $3, $4, $5)", view.ID, dash.ID, view.Name, view.Description, configOrEmpty(view.Config))
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO user_role_view (view_id, user_id, role_id) VALUES ($1, $2, $3)", view.ID, userId, 1)
+		if err != nil {
+			return err
+		}
+		view.DashID = dash.ID
+	}
+
+	// role names are validated by the caller, duplicate grants are skipped
+	for _, grant := range grants {
+		if grant.ViewID == nil {
+			_, err = tx.ExecContext(ctx, "INSERT INTO user_role_dashboard (user_id, dashboard_id, role_id) SELECT $1, $2, roles.id FROM roles WHERE roles.name=$3 ON CONFLICT DO NOTHING", grant.UserID, dash.ID, grant.RoleName)
+		} else {
+			_, err = tx.ExecContext(ctx, "INSERT INTO user_role_view (user_id, view_id, role_id) SELECT $1, $2, roles.id FROM roles WHERE roles.name=$3 ON CONFLICT DO NOTHING", grant.UserID, *grant.ViewID, grant.RoleName)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Replace the layout of a dashboard
+func (repo *DashRepository) UpdateLayout(ctx context.Context, id uuid.UUID, layout []*models.LayoutItem) error {
+	if layout == nil {
+		layout = []*models.LayoutItem{}
+	}
+	raw, err := json.Marshal(layout)
+	if err != nil {
+		return err
+	}
+	res, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE dashboard SET layout = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL", string(raw), id)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Mark or unmark a dashboard as template with the given variables
+func (repo *DashRepository) SetTemplate(ctx context.Context, id uuid.UUID, isTemplate bool, vars []*models.TemplateVariable) error {
+	if vars == nil {
+		vars = []*models.TemplateVariable{}
+	}
+	raw, err := json.Marshal(vars)
+	if err != nil {
+		return err
+	}
+	res, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE dashboard SET is_template = $1, template_variables = $2, updated_at = NOW() WHERE id = $3 AND deleted_at IS NULL", isTemplate, string(raw), id)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Get all template dashboards the user can read
+func (repo *DashRepository) GetTemplatesForUser(ctx context.Context, userId uuid.UUID) ([]*models.Dash, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, `SELECT d.id, d.name, d.description, d.template_variables FROM dashboard d
+		WHERE d.is_template AND d.deleted_at IS NULL
+		AND EXISTS (SELECT 1 FROM dashboard_perms p WHERE p.dash_id = d.id AND p.user_id = $1 AND p.perm_name = 'read')
+		ORDER BY d.name`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dashboards := make([]*models.Dash, 0)
+	for rows.Next() {
+		dash := &models.Dash{IsTemplate: true}
+		var vars []byte
+		err := rows.Scan(&dash.ID, &dash.Name, &dash.Description, &vars)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(vars, &dash.Variables)
+		if err != nil {
+			return nil, err
+		}
+		dashboards = append(dashboards, dash)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return dashboards, nil
+}
+
+// sort columns of dashboard listings
+var dashSortColumns = map[string]string{
+	models.SortByName:    "d.name",
+	models.SortByCreated: "d.created_at",
+	models.SortByUpdated: "d.updated_at",
+}
+
+// timestamps in cursors are formatted to sort the same as in the database
+const cursorTimeFormat = "2006-01-02 15:04:05.999999"
+
+// List a page of the dashboards a user can read, optionally filtered by name or description.
+// Returns the page, which holds one extra item when there are more pages, and the total count.
+func (repo *DashRepository) ListDashboardsForUser(ctx context.Context, userId uuid.UUID, opts *models.DashListOptions) ([]*models.Dash, int, error) {
+	column, ok := dashSortColumns[opts.Sort]
+	if !ok {
+		return nil, 0, errors.New("invalid sort column")
+	}
+
+	where := "d.deleted_at IS NULL AND EXISTS (SELECT 1 FROM dashboard_perms p WHERE p.dash_id = d.id AND p.user_id = $1 AND p.perm_name = 'read')"
+	args := []interface{}{userId}
+	if opts.Query != "" {
+		args = append(args, "%"+escapeLike(opts.Query)+"%")
+		where += fmt.Sprintf(" AND (d.name ILIKE $%d OR d.description ILIKE $%d)", len(args), len(args))
+	}
+	if len(opts.Tags) > 0 {
+		args = append(args, pq.Array(opts.Tags), len(opts.Tags))
+		where += fmt.Sprintf(" AND d.id IN (SELECT t.dashboard_id FROM dashboard_tags t WHERE t.tag = ANY($%d) GROUP BY t.dashboard_id HAVING COUNT(*) = $%d)", len(args)-1, len(args))
+	}
+	if opts.FolderID != nil {
+		args = append(args, *opts.FolderID)
+		where += fmt.Sprintf(" AND d.folder_id = $%d", len(args))
+	}
+
+	var total int
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM dashboard d WHERE "+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cmp, dir := ">", "ASC"
+	if opts.Desc {
+		cmp, dir = "<", "DESC"
+	}
+	if opts.After != nil {
+		cast := "text"
+		if opts.Sort != models.SortByName {
+			cast = "timestamp"
+		}
+		args = append(args, opts.After.Value, opts.After.ID)
+		where += fmt.Sprintf(" AND (%s, d.id) %s ($%d::%s, $%d)", column, cmp, len(args)-1, cast, len(args))
+	}
+	args = append(args, opts.Limit+1)
+	query := fmt.Sprintf("SELECT d.id, d.name, d.description, d.is_template, d.created_at, d.updated_at, d.folder_id, %s FROM dashboard d WHERE %s ORDER BY %s %s, d.id %s LIMIT $%d",
+		dashTagsColumn, where, column, dir, dir, len(args))
+
+	rows, err := repo.Conn.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	dashboards := make([]*models.Dash, 0)
+	for rows.Next() {
+		dash := &models.Dash{}
+		err := rows.Scan(&dash.ID, &dash.Name, &dash.Description, &dash.IsTemplate, &dash.CreatedAt, &dash.UpdatedAt, &dash.FolderID, pq.Array(&dash.Tags))
+		if err != nil {
+			return nil, 0, err
+		}
+		dashboards = append(dashboards, dash)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return dashboards, total, nil
+}
+
+// Returns the cursor pointing after the given dashboard
+func DashCursor(dash *models.Dash, opts *models.DashListOptions) *models.PageCursor {
+	c := &models.PageCursor{Sort: opts.Sort, Desc: opts.Desc, ID: dash.ID}
+	switch opts.Sort {
+	case models.SortByName:
+		c.Value = dash.Name
+	case models.SortByCreated:
+		c.Value = dash.CreatedAt.Format(cursorTimeFormat)
+	case models.SortByUpdated:
+		c.Value = dash.UpdatedAt.Format(cursorTimeFormat)
+	}
+	return c
+}
+
+// escapes wildcards so user input matches literally in LIKE patterns
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Move dashboard with given id to trash. Its views and grants are kept until purged.
+func (repo *DashRepository) TrashDash(ctx context.Context, id, userId uuid.UUID) error {
+	res, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE dashboard SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL", id, userId)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Get a trashed dashboard by id
+func (repo *DashRepository) GetTrashedDash(ctx context.Context, id uuid.UUID) (*models.Dash, error) {
 	dash := &models.Dash{}
-	err := repo.Conn.Conn.QueryRow("SELECT id, name, description FROM dashboard WHERE id = $1", id).Scan(&dash.ID, &dash.Name, &dash.Description)
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT id, name, description, deleted_at, deleted_by FROM dashboard WHERE id = $1 AND deleted_at IS NOT NULL", id).
+		Scan(&dash.ID, &dash.Name, &dash.Description, &dash.DeletedAt, &dash.DeletedBy)
 	if err != nil {
 		return nil, err
 	}
 	return dash, nil
 }
 
-func (repo *DashRepository) GetAllDashboardsForUser(id uuid.UUID) ([]*models.Dash, error) {
-	rows, err := repo.Conn.Conn.Query("SELECT dash_id, dash_name, dash_description FROM dashboard_perms WHERE perm_name='read' AND user_id=$1", id)
+// Get all trashed dashboards on which the user holds the given permission
+func (repo *DashRepository) GetTrashedDashsForUser(ctx context.Context, userId uuid.UUID, permName string) ([]*models.Dash, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, `SELECT d.id, d.name, d.description, d.deleted_at, d.deleted_by FROM dashboard d
+		WHERE d.deleted_at IS NOT NULL AND EXISTS (
+			SELECT 1 FROM user_role_dashboard urd
+			JOIN role_has_permissions rhp ON rhp.role_id = urd.role_id
+			JOIN permissions p ON p.id = rhp.permission_id
+			WHERE urd.dashboard_id = d.id AND urd.user_id = $1 AND p.name = $2)
+		ORDER BY d.deleted_at DESC`, userId, permName)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +436,7 @@ func (repo *DashRepository) GetAllDashboardsForUser(id uuid.UUID) ([]*models.Das
 	dashboards := make([]*models.Dash, 0)
 	for rows.Next() {
 		dash := &models.Dash{}
-		err := rows.Scan(&dash.ID, &dash.Name, &dash.Description)
+		err := rows.Scan(&dash.ID, &dash.Name, &dash.Description, &dash.DeletedAt, &dash.DeletedBy)
 		if err != nil {
 			return nil, err
 		}
@@ -73,20 +448,201 @@ func (repo *DashRepository) GetAllDashboardsForUser(id uuid.UUID) ([]*models.Das
 	return dashboards, nil
 }
 
-// Delete dashboard by id
-func (repo *DashRepository) DeleteDash(id uuid.UUID) error {
-	_, err := repo.Conn.Conn.Exec("DELETE FROM dashboard WHERE id = $1", id)
+// Take dashboard with given id out of trash
+func (repo *DashRepository) RestoreDash(ctx context.Context, id uuid.UUID) error {
+	res, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE dashboard SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
 
+// Permanently delete dashboards and views that have been in trash for longer than olderThan.
+// Views and grants of purged dashboards are removed by cascade.
+func (repo *DashRepository) PurgeTrash(ctx context.Context, olderThan time.Duration) (int64, int64, error) {
+	tx, err := repo.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM view WHERE deleted_at < NOW() - $1 * INTERVAL '1 second'", olderThan.Seconds())
+	if err != nil {
+		return 0, 0, err
+	}
+	views, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	res, err = tx.ExecContext(ctx, "DELETE FROM dashboard WHERE deleted_at < NOW() - $1 * INTERVAL '1 second'", olderThan.Seconds())
+	if err != nil {
+		return 0, 0, err
+	}
+	dashs, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return dashs, views, tx.Commit()
+}
+
 // Update dashboard by id
-func (repo *DashRepository) UpdateDash(dash *models.Dash) error {
-	_, err := repo.Conn.Conn.Exec("UPDATE dashboard SET name = $1, description = $2 WHERE id = $3", dash.Name, dash.Description, dash.ID)
+func (repo *DashRepository) UpdateDash(ctx context.Context, dash *models.Dash) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE dashboard SET name = $1, description = $2, updated_at = NOW() WHERE id = $3 AND deleted_at IS NULL", dash.Name, dash.Description, dash.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Replace the tags of a dashboard
+func (repo *DashRepository) SetTags(ctx context.Context, id uuid.UUID, tags []string) error {
+	tx, err := repo.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE dashboard SET updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM dashboard_tags WHERE dashboard_id = $1", id)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		_, err = tx.ExecContext(ctx, "INSERT INTO dashboard_tags (dashboard_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get all tags on dashboards the user can read along with how many dashboards carry each
+func (repo *DashRepository) GetTagsForUser(ctx context.Context, userId uuid.UUID) ([]*models.TagCount, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, `SELECT t.tag, COUNT(*) FROM dashboard_tags t
+		WHERE EXISTS (SELECT 1 FROM dashboard_perms p WHERE p.dash_id = t.dashboard_id AND p.user_id = $1 AND p.perm_name = 'read')
+		GROUP BY t.tag ORDER BY t.tag`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := make([]*models.TagCount, 0)
+	for rows.Next() {
+		tag := &models.TagCount{}
+		err := rows.Scan(&tag.Tag, &tag.Count)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Place a dashboard in a folder, or at the top level when folderId is nil
+func (repo *DashRepository) SetFolder(ctx context.Context, id uuid.UUID, folderId *uuid.UUID) error {
+	res, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE dashboard SET folder_id = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL", folderId, id)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
+
+// Star a dashboard for a user. Starring twice is a no-op.
+func (repo *DashRepository) StarDash(ctx context.Context, id, userId uuid.UUID) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, "INSERT INTO dashboard_star (user_id, dashboard_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", userId, id)
+	return err
+}
+
+// Remove the star of a user from a dashboard
+func (repo *DashRepository) UnstarDash(ctx context.Context, id, userId uuid.UUID) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, "DELETE FROM dashboard_star WHERE user_id = $1 AND dashboard_id = $2", userId, id)
+	return err
+}
+
+// Get dashboards starred by the user that the user can still read, most recently starred first
+func (repo *DashRepository) GetStarredDashsForUser(ctx context.Context, userId uuid.UUID) ([]*models.Dash, error) {
+	return repo.queryDashs(ctx, `SELECT d.id, d.name, d.description, d.folder_id, `+dashTagsColumn+` FROM dashboard_star s JOIN dashboard d ON d.id = s.dashboard_id
+		WHERE s.user_id = $1 AND d.deleted_at IS NULL
+		AND EXISTS (SELECT 1 FROM dashboard_perms p WHERE p.dash_id = d.id AND p.user_id = $1 AND p.perm_name = 'read')
+		ORDER BY s.created_at DESC`, userId)
+}
+
+// Record that a user opened a dashboard, keeping only the given number of latest visits per user
+func (repo *DashRepository) RecordVisit(ctx context.Context, id, userId uuid.UUID, keep int) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, `INSERT INTO dashboard_visit (user_id, dashboard_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, dashboard_id) DO UPDATE SET visited_at = NOW()`, userId, id)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Conn.Conn.ExecContext(ctx, `DELETE FROM dashboard_visit WHERE user_id = $1 AND dashboard_id NOT IN
+		(SELECT dashboard_id FROM dashboard_visit WHERE user_id = $1 ORDER BY visited_at DESC LIMIT $2)`, userId, keep)
+	return err
+}
+
+// Get dashboards recently opened by the user that the user can still read, latest first
+func (repo *DashRepository) GetRecentDashsForUser(ctx context.Context, userId uuid.UUID, limit int) ([]*models.Dash, error) {
+	return repo.queryDashs(ctx, `SELECT d.id, d.name, d.description, d.folder_id, `+dashTagsColumn+` FROM dashboard_visit dv JOIN dashboard d ON d.id = dv.dashboard_id
+		WHERE dv.user_id = $1 AND d.deleted_at IS NULL
+		AND EXISTS (SELECT 1 FROM dashboard_perms p WHERE p.dash_id = d.id AND p.user_id = $1 AND p.perm_name = 'read')
+		ORDER BY dv.visited_at DESC LIMIT $2`, userId, limit)
+}
+
+// runs a query selecting id, name, description, folder id and tags of dashboards
+func (repo *DashRepository) queryDashs(ctx context.Context, query string, args ...interface{}) ([]*models.Dash, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dashboards := make([]*models.Dash, 0)
+	for rows.Next() {
+		dash := &models.Dash{}
+		err := rows.Scan(&dash.ID, &dash.Name, &dash.Description, &dash.FolderID, pq.Array(&dash.Tags))
+		if err != nil {
+			return nil, err
+		}
+		dashboards = append(dashboards, dash)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return dashboards, nil
+}
+
+// Count dashboards not in trash, keyed by kind: dashboard or template
+func (repo *DashRepository) CountDashboards(ctx context.Context) (map[string]int64, error) {
+	var dashs, templates int64
+	err := repo.Conn.Conn.QueryRowContext(ctx, `SELECT COUNT(*) FILTER (WHERE NOT is_template), COUNT(*) FILTER (WHERE is_template)
+		FROM dashboard WHERE deleted_at IS NULL`).Scan(&dashs, &templates)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int64{"dashboard": dashs, "template": templates}, nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"backend/dashboard/db"
+	"backend/dashboard/models"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Contains methods for folders that organise dashboards
+type FolderRepository struct {
+	Conn *db.DashboardDb
+	L    logrus.FieldLogger
+}
+
+// Returns a new instance of FolderRepository
+func NewFolderRepository(conn *db.DashboardDb, l logrus.FieldLogger) *FolderRepository {
+	return &FolderRepository{conn, l}
+}
+
+// Add folder to database. The user becomes admin of the new folder.
+func (repo *FolderRepository) AddFolder(ctx context.Context, folder *models.Folder, userId uuid.UUID) error {
+	tx, err := repo.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO folder (parent_id, name) VALUES ($1, $2) RETURNING id, created_at", folder.ParentID, folder.Name).Scan(&folder.ID, &folder.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	// assign the user as admin on new folder
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_role_folder (folder_id, user_id, role_id) VALUES ($1, $2, $3)", folder.ID, userId, 1)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get folder by id
+func (repo *FolderRepository) GetFolder(ctx context.Context, id uuid.UUID) (*models.Folder, error) {
+	folder := &models.Folder{}
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT id, parent_id, name, created_at FROM folder WHERE id = $1", id).
+		Scan(&folder.ID, &folder.ParentID, &folder.Name, &folder.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// Get all folders the user can read
+func (repo *FolderRepository) GetFoldersForUser(ctx context.Context, userId uuid.UUID) ([]*models.Folder, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, `SELECT f.id, f.parent_id, f.name, f.created_at FROM folder f
+		WHERE EXISTS (SELECT 1 FROM folder_perms p WHERE p.folder_id = f.id AND p.user_id = $1 AND p.perm_name = 'read')
+		ORDER BY f.name, f.id`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	folders := make([]*models.Folder, 0)
+	for rows.Next() {
+		folder := &models.Folder{}
+		err := rows.Scan(&folder.ID, &folder.ParentID, &folder.Name, &folder.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// Rename a folder and move it under another folder, or to the top level when parent is nil
+func (repo *FolderRepository) UpdateFolder(ctx context.Context, folder *models.Folder) error {
+	res, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE folder SET name = $1, parent_id = $2 WHERE id = $3", folder.Name, folder.ParentID, folder.ID)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Returns true if folder is ancestor or lies below it
+func (repo *FolderRepository) IsWithin(ctx context.Context, folder, ancestor uuid.UUID) (bool, error) {
+	var within bool
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM folder_ancestors WHERE folder_id = $1 AND ancestor_id = $2)", folder, ancestor).Scan(&within)
+	if err != nil {
+		return false, err
+	}
+	return within, nil
+}
+
+// Returns true if the folder holds no folders and no dashboards outside of trash
+func (repo *FolderRepository) IsEmpty(ctx context.Context, id uuid.UUID) (bool, error) {
+	var empty bool
+	err := repo.Conn.Conn.QueryRowContext(ctx, `SELECT NOT EXISTS(SELECT 1 FROM folder WHERE parent_id = $1)
+		AND NOT EXISTS(SELECT 1 FROM dashboard WHERE folder_id = $1 AND deleted_at IS NULL)`, id).Scan(&empty)
+	if err != nil {
+		return false, err
+	}
+	return empty, nil
+}
+
+// Delete a folder. Trashed dashboards still in it are moved to the top level.
+func (repo *FolderRepository) DeleteFolder(ctx context.Context, id uuid.UUID) error {
+	tx, err := repo.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE dashboard SET folder_id = NULL WHERE folder_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM folder WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
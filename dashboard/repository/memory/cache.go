@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"backend/dashboard/services"
+	"context"
+	"encoding"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var _ services.Cache = (*Cache)(nil)
+
+// Cache keeps values in memory the way redis does: as strings, with no expiry
+type Cache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// Returns an empty cache
+func NewCache() *Cache {
+	return &Cache{values: map[string]string{}}
+}
+
+// Get the value of key, redis.Nil when it is not set
+func (c *Cache) Get(ctx context.Context, key string) *redis.StringCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(v, nil)
+}
+
+// Set key to value. Like redis, values other than strings, bytes, numbers and
+// encoding.BinaryMarshaler are refused.
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	var v string
+	switch value := value.(type) {
+	case string:
+		v = value
+	case []byte:
+		v = string(value)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		v = fmt.Sprint(value)
+	case bool:
+		v = "0"
+		if value {
+			v = "1"
+		}
+	case encoding.BinaryMarshaler:
+		raw, err := value.MarshalBinary()
+		if err != nil {
+			return redis.NewStatusResult("", err)
+		}
+		v = string(raw)
+	default:
+		return redis.NewStatusResult("", fmt.Errorf("redis: can't marshal %T (implement encoding.BinaryMarshaler)", value))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = v
+	return redis.NewStatusResult("OK", nil)
+}
@@ -0,0 +1,423 @@
+package memory
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var _ services.DashStore = (*DashRepository)(nil)
+
+// Dashboards kept in a Store, the counterpart of repository.DashRepository
+type DashRepository struct {
+	s *Store
+}
+
+// Returns a new instance of DashRepository over the store
+func NewDashRepository(s *Store) *DashRepository {
+	return &DashRepository{s}
+}
+
+// Add dashboard to the store. The user becomes admin of the new dashboard.
+func (repo *DashRepository) AddDash(ctx context.Context, dash *models.Dash, userId uuid.UUID) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dash.ID = s.insertDash(dash.Name, dash.Description)
+	s.dashGrants = append(s.dashGrants, &grant{userId: userId, on: dash.ID, roleId: adminRoleId})
+	return nil
+}
+
+func (s *Store) insertDash(name, description string) uuid.UUID {
+	now := s.timestamp()
+	d := &dashRow{}
+	d.dash = models.Dash{ID: uuid.New(), Name: name, Description: description, Layout: []*models.LayoutItem{}, CreatedAt: &now, UpdatedAt: copyTime(&now)}
+	s.dashs[d.dash.ID] = d
+	return d.dash.ID
+}
+
+// Get dashboard by id, sql.ErrNoRows when it does not exist or is in trash
+func (repo *DashRepository) GetDash(ctx context.Context, id uuid.UUID) (*models.Dash, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.liveDash(id)
+	if d == nil {
+		return nil, sql.ErrNoRows
+	}
+	dash := s.dashSummary(d)
+	dash.IsTemplate = d.dash.IsTemplate
+	dash.Layout = copyLayout(d.dash.Layout)
+	if dash.IsTemplate {
+		dash.Variables = copyVariables(d.dash.Variables)
+	}
+	return dash, nil
+}
+
+// Copy a dashboard along with the given views, as repository.DashRepository.CloneDash
+func (repo *DashRepository) CloneDash(ctx context.Context, srcId uuid.UUID, dash *models.Dash, userId uuid.UUID, includeGrants bool) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	newId := s.insertDash(dash.Name, dash.Description)
+	s.dashGrants = append(s.dashGrants, &grant{userId: userId, on: newId, roleId: adminRoleId})
+	if includeGrants {
+		s.dashGrants = append(s.dashGrants, s.copyGrants(s.dashGrants, srcId, newId, userId)...)
+	}
+
+	viewIds := map[uuid.UUID]uuid.UUID{}
+	for _, view := range dash.Views {
+		srcViewId := view.ID
+		v := &viewRow{view: *copyView(view), seq: s.next()}
+		v.view.ID = uuid.New()
+		v.view.DashID = newId
+		s.views[v.view.ID] = v
+		s.viewGrants = append(s.viewGrants, &grant{userId: userId, on: v.view.ID, roleId: adminRoleId})
+		if includeGrants {
+			s.viewGrants = append(s.viewGrants, s.copyGrants(s.viewGrants, srcViewId, v.view.ID, userId)...)
+		}
+
+		viewIds[srcViewId] = v.view.ID
+		view.ID = v.view.ID
+		view.DashID = newId
+	}
+
+	// layout of the copy only places the copied views
+	layout := []*models.LayoutItem{}
+	for _, item := range dash.Layout {
+		if newViewId, ok := viewIds[item.ViewID]; ok {
+			layout = append(layout, &models.LayoutItem{ViewID: newViewId, X: item.X, Y: item.Y, W: item.W, H: item.H})
+		}
+	}
+	s.dashs[newId].dash.Layout = copyLayout(layout)
+	dash.Layout = layout
+	dash.ID = newId
+	return nil
+}
+
+// active grants on src of users other than userId, moved to dst
+func (s *Store) copyGrants(grants []*grant, src, dst, userId uuid.UUID) []*grant {
+	res := []*grant{}
+	for _, g := range grants {
+		if g.on == src && g.userId != userId && s.active(g) {
+			res = append(res, &grant{userId: g.userId, on: dst, roleId: g.roleId, expiresAt: copyTime(g.expiresAt)})
+		}
+	}
+	return res
+}
+
+// Update name and description of a dashboard that is not in trash
+func (repo *DashRepository) UpdateDash(ctx context.Context, dash *models.Dash) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d := s.liveDash(dash.ID); d != nil {
+		d.dash.Name, d.dash.Description = dash.Name, dash.Description
+		s.touch(d)
+	}
+	return nil
+}
+
+func (s *Store) touch(d *dashRow) {
+	now := s.timestamp()
+	d.dash.UpdatedAt = &now
+}
+
+// Replace the layout of a dashboard
+func (repo *DashRepository) UpdateLayout(ctx context.Context, id uuid.UUID, layout []*models.LayoutItem) error {
+	return repo.update(id, func(d *dashRow) {
+		d.dash.Layout = copyLayout(layout)
+	})
+}
+
+// Mark or unmark a dashboard as template with the given variables
+func (repo *DashRepository) SetTemplate(ctx context.Context, id uuid.UUID, isTemplate bool, vars []*models.TemplateVariable) error {
+	return repo.update(id, func(d *dashRow) {
+		d.dash.IsTemplate = isTemplate
+		d.dash.Variables = copyVariables(vars)
+	})
+}
+
+// Place a dashboard in a folder, or at the top level when folderId is nil
+func (repo *DashRepository) SetFolder(ctx context.Context, id uuid.UUID, folderId *uuid.UUID) error {
+	return repo.update(id, func(d *dashRow) {
+		d.dash.FolderID = copyID(folderId)
+	})
+}
+
+// Replace the tags of a dashboard
+func (repo *DashRepository) SetTags(ctx context.Context, id uuid.UUID, tags []string) error {
+	return repo.update(id, func(d *dashRow) {
+		set := map[string]bool{}
+		for _, tag := range tags {
+			set[tag] = true
+		}
+		repo.s.tags[id] = set
+	})
+}
+
+// applies change to a dashboard that is not in trash, sql.ErrNoRows otherwise
+func (repo *DashRepository) update(id uuid.UUID, change func(d *dashRow)) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.liveDash(id)
+	if d == nil {
+		return sql.ErrNoRows
+	}
+	change(d)
+	s.touch(d)
+	return nil
+}
+
+// Move dashboard with given id to trash
+func (repo *DashRepository) TrashDash(ctx context.Context, id, userId uuid.UUID) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.liveDash(id)
+	if d == nil {
+		return sql.ErrNoRows
+	}
+	now := s.timestamp()
+	d.deletedAt = &now
+	d.dash.DeletedAt, d.dash.DeletedBy = &now, &userId
+	return nil
+}
+
+// readable dashboards in the given order
+func (s *Store) readable(userId uuid.UUID, ids []uuid.UUID) []*models.Dash {
+	res := make([]*models.Dash, 0)
+	for _, id := range ids {
+		if d := s.liveDash(id); d != nil && s.dashPermission(userId, id, "read") {
+			res = append(res, s.dashSummary(d))
+		}
+	}
+	return res
+}
+
+// Get all tags on dashboards the user can read along with how many dashboards carry each
+func (repo *DashRepository) GetTagsForUser(ctx context.Context, userId uuid.UUID) ([]*models.TagCount, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := map[string]int{}
+	for id := range s.tags {
+		if !s.dashPermission(userId, id, "read") {
+			continue
+		}
+		for tag := range s.tags[id] {
+			counts[tag]++
+		}
+	}
+	tags := make([]*models.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, &models.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+	return tags, nil
+}
+
+// Star a dashboard for a user. Starring twice is a no-op.
+func (repo *DashRepository) StarDash(ctx context.Context, id, userId uuid.UUID) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dashs[id]; !ok {
+		return errors.New("dashboard does not exist")
+	}
+	for _, m := range s.stars {
+		if m.userId == userId && m.dashId == id {
+			return nil
+		}
+	}
+	s.stars = append(s.stars, &mark{userId: userId, dashId: id})
+	return nil
+}
+
+// Remove the star of a user from a dashboard
+func (repo *DashRepository) UnstarDash(ctx context.Context, id, userId uuid.UUID) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stars = removeMarks(s.stars, func(m *mark) bool { return m.userId == userId && m.dashId == id })
+	return nil
+}
+
+// Get dashboards starred by the user that the user can still read, most recently starred first
+func (repo *DashRepository) GetStarredDashsForUser(ctx context.Context, userId uuid.UUID) ([]*models.Dash, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readable(userId, latestMarks(s.stars, userId, len(s.stars))), nil
+}
+
+// Record that a user opened a dashboard, keeping only the given number of latest visits per user
+func (repo *DashRepository) RecordVisit(ctx context.Context, id, userId uuid.UUID, keep int) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dashs[id]; !ok {
+		return errors.New("dashboard does not exist")
+	}
+	s.visits = removeMarks(s.visits, func(m *mark) bool { return m.userId == userId && m.dashId == id })
+	s.visits = append(s.visits, &mark{userId: userId, dashId: id})
+
+	kept := map[uuid.UUID]bool{}
+	for _, dashId := range latestMarks(s.visits, userId, keep) {
+		kept[dashId] = true
+	}
+	s.visits = removeMarks(s.visits, func(m *mark) bool { return m.userId == userId && !kept[m.dashId] })
+	return nil
+}
+
+// Get dashboards recently opened by the user that the user can still read, latest first
+func (repo *DashRepository) GetRecentDashsForUser(ctx context.Context, userId uuid.UUID, limit int) ([]*models.Dash, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dashs := s.readable(userId, latestMarks(s.visits, userId, len(s.visits)))
+	if len(dashs) > limit {
+		dashs = dashs[:limit]
+	}
+	return dashs, nil
+}
+
+func removeMarks(marks []*mark, drop func(m *mark) bool) []*mark {
+	kept := marks[:0]
+	for _, m := range marks {
+		if !drop(m) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// ids of the dashboards marked by the user, latest first
+func latestMarks(marks []*mark, userId uuid.UUID, limit int) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for i := len(marks) - 1; i >= 0 && len(ids) < limit; i-- {
+		if marks[i].userId == userId {
+			ids = append(ids, marks[i].dashId)
+		}
+	}
+	return ids
+}
+
+// Get all template dashboards the user can read
+func (repo *DashRepository) GetTemplatesForUser(ctx context.Context, userId uuid.UUID) ([]*models.Dash, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dashs := make([]*models.Dash, 0)
+	for id, d := range s.dashs {
+		if d.deletedAt == nil && d.dash.IsTemplate && s.dashPermission(userId, id, "read") {
+			dashs = append(dashs, &models.Dash{ID: id, Name: d.dash.Name, Description: d.dash.Description, IsTemplate: true, Variables: copyVariables(d.dash.Variables)})
+		}
+	}
+	sort.Slice(dashs, func(i, j int) bool { return dashs[i].Name < dashs[j].Name })
+	return dashs, nil
+}
+
+// timestamps in cursors are formatted to sort the same as in the database
+const cursorTimeFormat = "2006-01-02 15:04:05.999999"
+
+// List a page of the dashboards a user can read, as repository.DashRepository.ListDashboardsForUser
+func (repo *DashRepository) ListDashboardsForUser(ctx context.Context, userId uuid.UUID, opts *models.DashListOptions) ([]*models.Dash, int, error) {
+	var compare func(a, b *models.Dash) int
+	switch opts.Sort {
+	case models.SortByName:
+		compare = func(a, b *models.Dash) int { return strings.Compare(a.Name, b.Name) }
+	case models.SortByCreated:
+		compare = func(a, b *models.Dash) int { return compareTimes(*a.CreatedAt, *b.CreatedAt) }
+	case models.SortByUpdated:
+		compare = func(a, b *models.Dash) int { return compareTimes(*a.UpdatedAt, *b.UpdatedAt) }
+	default:
+		return nil, 0, errors.New("invalid sort column")
+	}
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := strings.ToLower(opts.Query)
+	dashs := []*models.Dash{}
+	for id, d := range s.dashs {
+		switch {
+		case d.deletedAt != nil, !s.dashPermission(userId, id, "read"):
+			continue
+		case query != "" && !strings.Contains(strings.ToLower(d.dash.Name), query) && !strings.Contains(strings.ToLower(d.dash.Description), query):
+			continue
+		case opts.FolderID != nil && (d.dash.FolderID == nil || *d.dash.FolderID != *opts.FolderID):
+			continue
+		case !hasTags(s.tags[id], opts.Tags):
+			continue
+		}
+		dash := s.dashSummary(d)
+		dash.IsTemplate = d.dash.IsTemplate
+		dash.CreatedAt, dash.UpdatedAt = copyTime(d.dash.CreatedAt), copyTime(d.dash.UpdatedAt)
+		dashs = append(dashs, dash)
+	}
+	total := len(dashs)
+
+	less := func(a, b *models.Dash) bool {
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+		return a.ID.String() < b.ID.String()
+	}
+	sort.Slice(dashs, func(i, j int) bool {
+		if opts.Desc {
+			return less(dashs[j], dashs[i])
+		}
+		return less(dashs[i], dashs[j])
+	})
+
+	if opts.After != nil {
+		after := &models.Dash{ID: opts.After.ID, Name: opts.After.Value}
+		if opts.Sort != models.SortByName {
+			t, err := time.ParseInLocation(cursorTimeFormat, opts.After.Value, time.Local)
+			if err != nil {
+				return nil, 0, err
+			}
+			after.CreatedAt, after.UpdatedAt = &t, &t
+		}
+		page := []*models.Dash{}
+		for _, d := range dashs {
+			if (!opts.Desc && less(after, d)) || (opts.Desc && less(d, after)) {
+				page = append(page, d)
+			}
+		}
+		dashs = page
+	}
+	if len(dashs) > opts.Limit+1 {
+		dashs = dashs[:opts.Limit+1]
+	}
+	return dashs, total, nil
+}
+
+func hasTags(set map[string]bool, tags []string) bool {
+	for _, tag := range tags {
+		if !set[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
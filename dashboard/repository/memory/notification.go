@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+var _ services.NotificationStore = (*NotificationRepository)(nil)
+
+// Notification inboxes and preferences kept in a Store, the counterpart of
+// repository.NotificationRepository
+type NotificationRepository struct {
+	s *Store
+}
+
+// Returns a new instance of NotificationRepository over the store
+func NewNotificationRepository(s *Store) *NotificationRepository {
+	return &NotificationRepository{s}
+}
+
+// Add a notification unless the user turned its kind off. Returns false when it was not added.
+// Data is kept encoded, as it is read back from the database.
+func (repo *NotificationRepository) AddNotification(ctx context.Context, n *models.Notification) (bool, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if enabled, ok := s.preferences[n.UserID][n.Type]; ok && !enabled {
+		return false, nil
+	}
+	c := *n
+	c.ID = uuid.New()
+	now := s.timestamp()
+	c.CreatedAt = &now
+	c.DashID, c.ViewID = copyID(n.DashID), copyID(n.ViewID)
+	if n.Data != nil {
+		data, err := json.Marshal(n.Data)
+		if err != nil {
+			return false, err
+		}
+		c.Data = json.RawMessage(data)
+	}
+	s.inbox = append(s.inbox, &c)
+	return true, nil
+}
+
+// Get a page of the notifications of a user, newest first, along with the total count of
+// the listed notifications and of unread ones
+func (repo *NotificationRepository) GetNotifications(ctx context.Context, userId uuid.UUID, unreadOnly bool, limit, offset int) (*models.NotificationPage, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page := &models.NotificationPage{Items: make([]*models.Notification, 0)}
+	listed := []*models.Notification{}
+	for i := len(s.inbox) - 1; i >= 0; i-- {
+		n := s.inbox[i]
+		if n.UserID != userId {
+			continue
+		}
+		if n.ReadAt == nil {
+			page.Unread++
+		}
+		if !unreadOnly || n.ReadAt == nil {
+			listed = append(listed, n)
+		}
+	}
+	page.Total = len(listed)
+	for i := offset; i < len(listed) && len(page.Items) < limit; i++ {
+		c := *listed[i]
+		c.ReadAt = copyTime(listed[i].ReadAt)
+		page.Items = append(page.Items, &c)
+	}
+	return page, nil
+}
+
+// Count the unread notifications of a user
+func (repo *NotificationRepository) CountUnread(ctx context.Context, userId uuid.UUID) (int, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cnt := 0
+	for _, n := range s.inbox {
+		if n.UserID == userId && n.ReadAt == nil {
+			cnt++
+		}
+	}
+	return cnt, nil
+}
+
+// Mark the given notifications of a user as read, or all of them when ids is nil.
+// Returns the number of notifications marked.
+func (repo *NotificationRepository) MarkRead(ctx context.Context, userId uuid.UUID, ids []uuid.UUID) (int64, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wanted := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	var cnt int64
+	now := s.timestamp()
+	for _, n := range s.inbox {
+		if n.UserID == userId && n.ReadAt == nil && (ids == nil || wanted[n.ID]) {
+			readAt := now
+			n.ReadAt = &readAt
+			cnt++
+		}
+	}
+	return cnt, nil
+}
+
+// Get the kinds of notification a user set a preference for
+func (repo *NotificationRepository) GetPreferences(ctx context.Context, userId uuid.UUID) (map[string]bool, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prefs := map[string]bool{}
+	for t, enabled := range s.preferences[userId] {
+		prefs[t] = enabled
+	}
+	return prefs, nil
+}
+
+// Store the preferences of a user for the given kinds of notification
+func (repo *NotificationRepository) SetPreferences(ctx context.Context, userId uuid.UUID, prefs map[string]bool) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.preferences[userId] == nil {
+		s.preferences[userId] = map[string]bool{}
+	}
+	for t, enabled := range prefs {
+		s.preferences[userId][t] = enabled
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package memory
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	_ services.RoleStore        = (*RoleRepository)(nil)
+	_ services.GrantExpiryStore = (*RoleRepository)(nil)
+)
+
+// Roles and grants kept in a Store, the counterpart of repository.RoleRepository
+type RoleRepository struct {
+	s *Store
+}
+
+// Returns a new instance of RoleRepository over the store
+func NewRoleRepository(s *Store) *RoleRepository {
+	return &RoleRepository{s}
+}
+
+// Get list of all roles
+func (r *RoleRepository) GetAllRoles(ctx context.Context) ([]*models.Role, error) {
+	res := []*models.Role{}
+	for _, role := range roles {
+		res = append(res, &models.Role{ID: role.ID, Name: role.Name})
+	}
+	return res, nil
+}
+
+// Get all permissions for a role with given id
+func (r *RoleRepository) GetPermissionsForRoleId(ctx context.Context, roleId int) ([]*models.Permission, error) {
+	perms := []*models.Permission{}
+	for _, id := range rolePermissions[roleId] {
+		p := *permissions[id-1]
+		perms = append(perms, &p)
+	}
+	return perms, nil
+}
+
+// gives the user the named role on a dashboard, view or folder, replacing the one they had
+func (s *Store) grant(grants []*grant, userId uuid.UUID, roleName string, on uuid.UUID, expiresAt *time.Time) ([]*grant, error) {
+	role := roleByName(roleName)
+	if role == nil {
+		return grants, errors.New("invalid role name")
+	}
+	if g := findGrant(grants, userId, on); g != nil {
+		g.roleId, g.expiresAt, g.expiryNotified = role.ID, copyTime(expiresAt), false
+		return grants, nil
+	}
+	return append(grants, &grant{userId: userId, on: on, roleId: role.ID, expiresAt: copyTime(expiresAt)}), nil
+}
+
+// Grant role to a user with given id. The grant lapses at expiresAt when set.
+func (r *RoleRepository) GrantDashLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, dashId uuid.UUID, expiresAt *time.Time) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	s.dashGrants, err = s.grant(s.dashGrants, userId, roleName, dashId, expiresAt)
+	return err
+}
+
+// Revoke role from a user with given id, along with the roles of the user on its views
+func (r *RoleRepository) RevokeDashLevelRoleFromUser(ctx context.Context, userId uuid.UUID, dashId uuid.UUID) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if findGrant(s.dashGrants, userId, dashId) == nil {
+		return errors.New("user does not have this role")
+	}
+	s.dashGrants = removeGrants(s.dashGrants, func(g *grant) bool { return g.userId != userId || g.on != dashId })
+
+	//remove user from all views of this dashboard
+	s.viewGrants = removeGrants(s.viewGrants, func(g *grant) bool {
+		v, ok := s.views[g.on]
+		return g.userId != userId || !ok || v.view.DashID != dashId
+	})
+	return nil
+}
+
+// Grant role for a view to a user. The grant lapses at expiresAt when set.
+func (r *RoleRepository) GrantViewLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, viewId uuid.UUID, expiresAt *time.Time) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	s.viewGrants, err = s.grant(s.viewGrants, userId, roleName, viewId, expiresAt)
+	return err
+}
+
+// Revoke role for a view from a user
+func (r *RoleRepository) RevokeViewLevelRoleFromUser(ctx context.Context, userId uuid.UUID, viewId uuid.UUID) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if findGrant(s.viewGrants, userId, viewId) == nil {
+		return errors.New("user does not have this role")
+	}
+	s.viewGrants = removeGrants(s.viewGrants, func(g *grant) bool { return g.userId != userId || g.on != viewId })
+	return nil
+}
+
+// Grant role for a folder to a user, replacing any role the user has on it
+func (r *RoleRepository) GrantFolderLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, folderId uuid.UUID) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	s.folderGrants, err = s.grant(s.folderGrants, userId, roleName, folderId, nil)
+	return err
+}
+
+// Revoke role for a folder from a user
+func (r *RoleRepository) RevokeFolderLevelRoleFromUser(ctx context.Context, userId uuid.UUID, folderId uuid.UUID) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if findGrant(s.folderGrants, userId, folderId) == nil {
+		return errors.New("user does not have this role")
+	}
+	s.folderGrants = removeGrants(s.folderGrants, func(g *grant) bool { return g.userId != userId || g.on != folderId })
+	return nil
+}
+
+// Get the id of the dashboard a view is on
+func (r *RoleRepository) GetDashIdForView(ctx context.Context, viewId uuid.UUID) (uuid.UUID, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.views[viewId]
+	if !ok {
+		return uuid.Nil, sql.ErrNoRows
+	}
+	return v.view.DashID, nil
+}
+
+// Get the roles granted to a user directly on a dashboard
+func (r *RoleRepository) GetRolesForUserForDashboard(ctx context.Context, userId uuid.UUID, dashId uuid.UUID) ([]*models.Role, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := []*models.Role{}
+	if g := findGrant(s.dashGrants, userId, dashId); g != nil {
+		res = append(res, &models.Role{ID: g.roleId, Name: roleName(g.roleId)})
+	}
+	return res, nil
+}
+
+// Get the roles users hold on a dashboard, directly or through a folder
+func (r *RoleRepository) GetRolesForUsersForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.Role, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := []*models.Role{}
+	for _, dr := range s.dashRoles(dashId) {
+		if roleHasPermission(dr.roleId, "read") {
+			res = append(res, &models.Role{UserId: dr.userId, ID: dr.roleId, Name: roleName(dr.roleId), FolderID: copyID(dr.folderId), ExpiresAt: copyTime(dr.expiresAt)})
+		}
+	}
+	return res, nil
+}
+
+// Get users with a role on a folder, including roles inherited from folders above it
+func (r *RoleRepository) GetRolesForUsersForFolder(ctx context.Context, folderId uuid.UUID) ([]*models.Role, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := []*models.Role{}
+	for _, fr := range s.folderRoles(folderId) {
+		if !roleHasPermission(fr.roleId, "read") {
+			continue
+		}
+		role := &models.Role{UserId: fr.userId, ID: fr.roleId, Name: roleName(fr.roleId)}
+		if *fr.folderId != folderId {
+			role.FolderID = copyID(fr.folderId)
+		}
+		res = append(res, role)
+	}
+	return res, nil
+}
+
+// Get all grants on a dashboard and on its views that are not in trash and have not lapsed
+func (r *RoleRepository) GetGrantsForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.Grant, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grants := []*models.Grant{}
+	for _, g := range s.dashGrants {
+		if g.on == dashId && s.active(g) {
+			grants = append(grants, &models.Grant{UserID: g.userId, RoleName: roleName(g.roleId), ExpiresAt: copyTime(g.expiresAt)})
+		}
+	}
+	for _, v := range s.dashViews(dashId) {
+		for _, g := range s.viewGrants {
+			if g.on == v.view.ID && s.active(g) {
+				viewId := v.view.ID
+				grants = append(grants, &models.Grant{UserID: g.userId, RoleName: roleName(g.roleId), ViewID: &viewId, ExpiresAt: copyTime(g.expiresAt)})
+			}
+		}
+	}
+	return grants, nil
+}
+
+// Get users holding a permission on a dashboard, directly or through a folder
+func (r *RoleRepository) GetUsersWithPermissionForDashboard(ctx context.Context, dashId uuid.UUID, permName string) ([]uuid.UUID, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, dr := range s.dashRoles(dashId) {
+		if !seen[dr.userId] && roleHasPermission(dr.roleId, permName) {
+			seen[dr.userId] = true
+			ids = append(ids, dr.userId)
+		}
+	}
+	return ids, nil
+}
+
+// Get users holding a permission on a view
+func (r *RoleRepository) GetUsersWithPermissionForView(ctx context.Context, viewId uuid.UUID, permName string) ([]uuid.UUID, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []uuid.UUID{}
+	for _, g := range s.viewGrantsFor(viewId) {
+		if roleHasPermission(g.roleId, permName) {
+			ids = append(ids, g.userId)
+		}
+	}
+	return ids, nil
+}
+
+func (r *RoleRepository) ExistsPermissionForUserForDashboard(ctx context.Context, userId uuid.UUID, dashID uuid.UUID, permName string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.dashPermission(userId, dashID, permName), nil
+}
+
+func (r *RoleRepository) ExistsPermissionForUserForView(ctx context.Context, userId uuid.UUID, viewID uuid.UUID, permName string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.viewPermission(userId, viewID, permName), nil
+}
+
+// Returns true if the user holds the permission on a folder directly or through a folder above it
+func (r *RoleRepository) ExistsPermissionForUserForFolder(ctx context.Context, userId uuid.UUID, folderId uuid.UUID, permName string) (bool, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, fr := range s.folderRoles(folderId) {
+		if fr.userId == userId && roleHasPermission(fr.roleId, permName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// admins among grants on a dashboard or view, lapsed or not
+func admins(grants []*grant, on uuid.UUID) []*grant {
+	res := []*grant{}
+	for _, g := range grants {
+		if g.on == on && g.roleId == adminRoleId {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+func (r *RoleRepository) IsOnlyAdminForDashboard(ctx context.Context, userId uuid.UUID, dashID uuid.UUID) (bool, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := admins(s.dashGrants, dashID)
+	if len(found) == 0 {
+		return false, errors.New("no admin role found for dashboard!!! this should not happen")
+	}
+	return len(found) == 1 && found[0].userId == userId, nil
+}
+
+func (r *RoleRepository) IsOnlyAdminForView(ctx context.Context, userId uuid.UUID, viewID uuid.UUID) (bool, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := admins(s.viewGrants, viewID)
+	if len(found) == 0 {
+		return false, errors.New("no admin role found for view!!! this should not happen")
+	}
+	if len(found) > 1 {
+		return false, nil
+	}
+	//the admin is looked up in view_perms, which leaves out views in trash
+	for _, g := range s.viewGrantsFor(viewID) {
+		if g.roleId == adminRoleId {
+			return g.userId == userId, nil
+		}
+	}
+	return false, sql.ErrNoRows
+}
+
+// Returns true if the user is the only one holding the admin role directly on the folder
+func (r *RoleRepository) IsOnlyAdminForFolder(ctx context.Context, userId uuid.UUID, folderId uuid.UUID) (bool, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var others, own int
+	for _, g := range admins(s.folderGrants, folderId) {
+		if g.userId == userId {
+			own++
+		} else {
+			others++
+		}
+	}
+	return own == 1 && others == 0, nil
+}
+
+// Get grants lapsing within the given window that admins were not told about yet
+func (r *RoleRepository) GetGrantsExpiringWithin(ctx context.Context, window time.Duration) ([]*models.ExpiringGrant, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	lapsing := func(g *grant) bool {
+		return !g.expiryNotified && g.expiresAt != nil && g.expiresAt.After(now) && !g.expiresAt.After(now.Add(window))
+	}
+	grants := []*models.ExpiringGrant{}
+	for _, g := range s.dashGrants {
+		if lapsing(g) && s.liveDash(g.on) != nil {
+			grants = append(grants, &models.ExpiringGrant{UserID: g.userId, RoleName: roleName(g.roleId), DashID: g.on, ExpiresAt: *g.expiresAt})
+		}
+	}
+	for _, g := range s.viewGrants {
+		if v, ok := s.views[g.on]; ok && lapsing(g) && v.deletedAt == nil {
+			viewId := g.on
+			grants = append(grants, &models.ExpiringGrant{UserID: g.userId, RoleName: roleName(g.roleId), DashID: v.view.DashID, ViewID: &viewId, ExpiresAt: *g.expiresAt})
+		}
+	}
+	return grants, nil
+}
+
+// Remember that admins were told about a lapsing grant
+func (r *RoleRepository) MarkExpiryNotified(ctx context.Context, expiring *models.ExpiringGrant) error {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var g *grant
+	if expiring.ViewID == nil {
+		g = findGrant(s.dashGrants, expiring.UserID, expiring.DashID)
+	} else {
+		g = findGrant(s.viewGrants, expiring.UserID, *expiring.ViewID)
+	}
+	if g != nil {
+		g.expiryNotified = true
+	}
+	return nil
+}
+
+// Delete lapsed grants. Like revoking, a lapsed dashboard grant takes the roles of the user
+// on the views of that dashboard with it. Returns the lapsed grants, without the view roles
+// taken along. The store keeps no access audit.
+func (r *RoleRepository) DeleteExpiredGrants(ctx context.Context) ([]*models.ExpiringGrant, error) {
+	s := r.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lapsed := []*models.ExpiringGrant{}
+	s.viewGrants = removeGrants(s.viewGrants, func(g *grant) bool {
+		v, ok := s.views[g.on]
+		if ok && !s.active(g) {
+			viewId := g.on
+			lapsed = append(lapsed, &models.ExpiringGrant{UserID: g.userId, RoleName: roleName(g.roleId), DashID: v.view.DashID, ViewID: &viewId, ExpiresAt: *g.expiresAt})
+			return false
+		}
+		return true
+	})
+
+	s.dashGrants = removeGrants(s.dashGrants, func(g *grant) bool {
+		if s.active(g) {
+			return true
+		}
+		lapsed = append(lapsed, &models.ExpiringGrant{UserID: g.userId, RoleName: roleName(g.roleId), DashID: g.on, ExpiresAt: *g.expiresAt})
+		s.viewGrants = removeGrants(s.viewGrants, func(vg *grant) bool {
+			v, ok := s.views[vg.on]
+			return !ok || vg.userId != g.userId || v.view.DashID != g.on
+		})
+		return false
+	})
+	return lapsed, nil
+}
@@ -0,0 +1,363 @@
+// Package memory keeps dashboards, views, folders and grants in memory for tests of the
+// services. Permissions follow the dashboard_perms, view_perms and folder_perms views of the
+// database: roles on a dashboard do not reach its views, roles on a folder reach every
+// dashboard below it, lapsed grants and items in trash grant nothing.
+package memory
+
+import (
+	"backend/dashboard/models"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ids of the roles seeded by the migrations
+const (
+	adminRoleId = 1
+)
+
+// roles and permissions as seeded by the migrations
+var (
+	roles = []*models.Role{
+		{ID: 1, Name: "admin"},
+		{ID: 2, Name: "editor"},
+		{ID: 3, Name: "viewer"},
+		{ID: 4, Name: "commenter"},
+		{ID: 5, Name: "manager"},
+	}
+	permissions = []*models.Permission{
+		{ID: 1, Name: "edit_access"},
+		{ID: 2, Name: "delete"},
+		{ID: 3, Name: "edit"},
+		{ID: 4, Name: "comment"},
+		{ID: 5, Name: "read"},
+	}
+	rolePermissions = map[int][]int{
+		1: {1, 2, 3, 4, 5},
+		2: {3, 4, 5},
+		3: {5},
+		4: {4, 5},
+		5: {1, 2, 3, 4, 5},
+	}
+)
+
+// Store holds the rows shared by the repositories of this package. Create it with NewStore.
+type Store struct {
+	//clock grants lapse by, time.Now unless set
+	Now func() time.Time
+
+	mu           sync.Mutex
+	seq          int
+	dashs        map[uuid.UUID]*dashRow
+	views        map[uuid.UUID]*viewRow
+	folders      map[uuid.UUID]*models.Folder
+	dashGrants   []*grant
+	viewGrants   []*grant
+	folderGrants []*grant
+	tags         map[uuid.UUID]map[string]bool
+	stars        []*mark
+	visits       []*mark
+	webhooks     []*models.Webhook
+	deliveries   []*models.WebhookDelivery
+	inbox        []*models.Notification
+	preferences  map[uuid.UUID]map[string]bool
+}
+
+type dashRow struct {
+	dash      models.Dash
+	deletedAt *time.Time
+}
+
+type viewRow struct {
+	view      models.View
+	seq       int
+	deletedAt *time.Time
+}
+
+// role of a user on a dashboard, view or folder, kept in the order granted
+type grant struct {
+	userId    uuid.UUID
+	on        uuid.UUID
+	roleId    int
+	expiresAt *time.Time
+	//admins were told the grant is about to lapse
+	expiryNotified bool
+}
+
+// star or visit of a dashboard by a user, kept oldest first
+type mark struct {
+	userId uuid.UUID
+	dashId uuid.UUID
+}
+
+// a role a user holds on a dashboard, either directly or through the folder it was granted on
+type dashRole struct {
+	userId    uuid.UUID
+	roleId    int
+	folderId  *uuid.UUID
+	expiresAt *time.Time
+}
+
+// Returns an empty store with the roles and permissions of the migrations
+func NewStore() *Store {
+	return &Store{
+		Now:     time.Now,
+		dashs:   map[uuid.UUID]*dashRow{},
+		views:   map[uuid.UUID]*viewRow{},
+		folders: map[uuid.UUID]*models.Folder{},
+		tags:    map[uuid.UUID]map[string]bool{},
+
+		preferences: map[uuid.UUID]map[string]bool{},
+	}
+}
+
+// Add a folder, below ParentID when set. The user becomes admin of the new folder.
+func (s *Store) AddFolder(folder *models.Folder, userId uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	folder.ID = uuid.New()
+	now := s.timestamp()
+	folder.CreatedAt = &now
+	f := *folder
+	s.folders[f.ID] = &f
+	s.folderGrants = append(s.folderGrants, &grant{userId: userId, on: f.ID, roleId: adminRoleId})
+}
+
+// next sequence number, orders views the way they were added
+func (s *Store) next() int {
+	s.seq++
+	return s.seq
+}
+
+// current time to the microsecond, as timestamps are kept in the database
+func (s *Store) timestamp() time.Time {
+	return s.Now().Round(0).Truncate(time.Microsecond)
+}
+
+func (s *Store) active(g *grant) bool {
+	return g.expiresAt == nil || g.expiresAt.After(s.Now())
+}
+
+func findGrant(grants []*grant, userId, on uuid.UUID) *grant {
+	for _, g := range grants {
+		if g.userId == userId && g.on == on {
+			return g
+		}
+	}
+	return nil
+}
+
+func removeGrants(grants []*grant, keep func(g *grant) bool) []*grant {
+	kept := grants[:0]
+	for _, g := range grants {
+		if keep(g) {
+			kept = append(kept, g)
+		}
+	}
+	return kept
+}
+
+func roleByName(name string) *models.Role {
+	for _, r := range roles {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func roleName(id int) string {
+	for _, r := range roles {
+		if r.ID == id {
+			return r.Name
+		}
+	}
+	return ""
+}
+
+func roleHasPermission(roleId int, permName string) bool {
+	for _, id := range rolePermissions[roleId] {
+		if permissions[id-1].Name == permName {
+			return true
+		}
+	}
+	return false
+}
+
+// dashboard if it is not in trash
+func (s *Store) liveDash(id uuid.UUID) *dashRow {
+	d, ok := s.dashs[id]
+	if !ok || d.deletedAt != nil {
+		return nil
+	}
+	return d
+}
+
+// view if neither it nor its dashboard is in trash
+func (s *Store) liveView(id uuid.UUID) *viewRow {
+	v, ok := s.views[id]
+	if !ok || v.deletedAt != nil || s.liveDash(v.view.DashID) == nil {
+		return nil
+	}
+	return v
+}
+
+// the folder and all folders above it, as folder_ancestors
+func (s *Store) ancestors(folderId uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for id := &folderId; id != nil; {
+		f, ok := s.folders[*id]
+		if !ok {
+			break
+		}
+		ids = append(ids, f.ID)
+		id = f.ParentID
+	}
+	return ids
+}
+
+// roles a user holds on a folder through it or a folder above it, as folder_perms
+func (s *Store) folderRoles(folderId uuid.UUID) []*dashRole {
+	res := []*dashRole{}
+	for _, ancestor := range s.ancestors(folderId) {
+		for _, g := range s.folderGrants {
+			if g.on == ancestor {
+				grantedOn := ancestor
+				res = append(res, &dashRole{userId: g.userId, roleId: g.roleId, folderId: &grantedOn})
+			}
+		}
+	}
+	return res
+}
+
+// rows of dashboard_perms for a dashboard, one per role rather than per permission
+func (s *Store) dashRoles(dashId uuid.UUID) []*dashRole {
+	d := s.liveDash(dashId)
+	if d == nil {
+		return nil
+	}
+	res := []*dashRole{}
+	for _, g := range s.dashGrants {
+		if g.on == dashId && s.active(g) {
+			res = append(res, &dashRole{userId: g.userId, roleId: g.roleId, expiresAt: copyTime(g.expiresAt)})
+		}
+	}
+	if d.dash.FolderID != nil {
+		res = append(res, s.folderRoles(*d.dash.FolderID)...)
+	}
+	return res
+}
+
+func (s *Store) dashPermission(userId, dashId uuid.UUID, permName string) bool {
+	for _, r := range s.dashRoles(dashId) {
+		if r.userId == userId && roleHasPermission(r.roleId, permName) {
+			return true
+		}
+	}
+	return false
+}
+
+// rows of view_perms for a view, one per role rather than per permission
+func (s *Store) viewGrantsFor(viewId uuid.UUID) []*grant {
+	if s.liveView(viewId) == nil {
+		return nil
+	}
+	res := []*grant{}
+	for _, g := range s.viewGrants {
+		if g.on == viewId && s.active(g) {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+func (s *Store) viewPermission(userId, viewId uuid.UUID, permName string) bool {
+	for _, g := range s.viewGrantsFor(viewId) {
+		if g.userId == userId && roleHasPermission(g.roleId, permName) {
+			return true
+		}
+	}
+	return false
+}
+
+// views of a dashboard that are not in trash, in the order they were added
+func (s *Store) dashViews(dashId uuid.UUID) []*viewRow {
+	res := []*viewRow{}
+	for _, v := range s.views {
+		if v.view.DashID == dashId && v.deletedAt == nil {
+			res = append(res, v)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].seq < res[j].seq })
+	return res
+}
+
+func (s *Store) dashTags(dashId uuid.UUID) []string {
+	tags := []string{}
+	for tag := range s.tags[dashId] {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// the columns of dashboard listings: id, name, description, folder and tags
+func (s *Store) dashSummary(d *dashRow) *models.Dash {
+	return &models.Dash{
+		ID:          d.dash.ID,
+		Name:        d.dash.Name,
+		Description: d.dash.Description,
+		FolderID:    copyID(d.dash.FolderID),
+		Tags:        s.dashTags(d.dash.ID),
+	}
+}
+
+func copyView(v *models.View) *models.View {
+	c := &models.View{ID: v.ID, DashID: v.DashID, Name: v.Name, Description: v.Description}
+	c.Config = append(json.RawMessage(nil), configOrEmpty(v.Config)...)
+	return c
+}
+
+func configOrEmpty(config json.RawMessage) json.RawMessage {
+	if len(config) == 0 {
+		return json.RawMessage("{}")
+	}
+	return config
+}
+
+func copyLayout(layout []*models.LayoutItem) []*models.LayoutItem {
+	res := make([]*models.LayoutItem, 0, len(layout))
+	for _, item := range layout {
+		c := *item
+		res = append(res, &c)
+	}
+	return res
+}
+
+func copyVariables(vars []*models.TemplateVariable) []*models.TemplateVariable {
+	res := make([]*models.TemplateVariable, 0, len(vars))
+	for _, v := range vars {
+		c := *v
+		res = append(res, &c)
+	}
+	return res
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func copyID(id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	c := *id
+	return &c
+}
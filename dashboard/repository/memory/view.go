@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var _ services.ViewStore = (*ViewRepository)(nil)
+
+// Views kept in a Store, the counterpart of repository.ViewRepository
+type ViewRepository struct {
+	s *Store
+}
+
+// Returns a new instance of ViewRepository over the store
+func NewViewRepository(s *Store) *ViewRepository {
+	return &ViewRepository{s}
+}
+
+// Add view attached to a particular dashboard. The user becomes admin of the new view.
+func (repo *ViewRepository) AddView(ctx context.Context, view *models.View, userId uuid.UUID) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dashs[view.DashID]; !ok {
+		return errors.New("dashboard does not exist")
+	}
+	v := &viewRow{view: *copyView(view), seq: s.next()}
+	v.view.ID = uuid.New()
+	s.views[v.view.ID] = v
+	s.viewGrants = append(s.viewGrants, &grant{userId: userId, on: v.view.ID, roleId: adminRoleId})
+	view.ID = v.view.ID
+	return nil
+}
+
+// Get a view the user can read, sql.ErrNoRows otherwise
+func (repo *ViewRepository) GetView(ctx context.Context, viewId, userId uuid.UUID) (*models.View, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.viewPermission(userId, viewId, "read") {
+		return nil, sql.ErrNoRows
+	}
+	return copyView(&s.views[viewId].view), nil
+}
+
+// Get a view that is not in trash by id, regardless of the user
+func (repo *ViewRepository) GetViewById(ctx context.Context, viewId uuid.UUID) (*models.View, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.liveView(viewId)
+	if v == nil {
+		return nil, sql.ErrNoRows
+	}
+	return copyView(&v.view), nil
+}
+
+// Get all views attached to a particular dashboard
+func (repo *ViewRepository) GetViewsByDashId(ctx context.Context, dashId uuid.UUID) ([]*models.View, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	views := []*models.View{}
+	for _, v := range s.dashViews(dashId) {
+		views = append(views, copyView(&v.view))
+	}
+	return views, nil
+}
+
+// Get all views attached to a particular dashboard the user can read
+func (repo *ViewRepository) GetViewsByDashIdForUser(ctx context.Context, dashId, userId uuid.UUID) ([]*models.View, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	views := []*models.View{}
+	for _, v := range s.dashViews(dashId) {
+		if s.viewPermission(userId, v.view.ID, "read") {
+			views = append(views, copyView(&v.view))
+		}
+	}
+	return views, nil
+}
+
+// Get views of many dashboards a user can read, grouped by dashboard id
+func (repo *ViewRepository) GetViewsByDashIdsForUser(ctx context.Context, dashIds []uuid.UUID, userId uuid.UUID) (map[uuid.UUID][]*models.View, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	views := map[uuid.UUID][]*models.View{}
+	for _, dashId := range dashIds {
+		for _, v := range s.dashViews(dashId) {
+			if s.viewPermission(userId, v.view.ID, "read") {
+				views[dashId] = append(views[dashId], copyView(&v.view))
+			}
+		}
+	}
+	return views, nil
+}
+
+// Move view with given id to trash
+func (repo *ViewRepository) TrashView(ctx context.Context, id, userId uuid.UUID) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.views[id]
+	if !ok || v.deletedAt != nil {
+		return sql.ErrNoRows
+	}
+	now := s.timestamp()
+	v.deletedAt = &now
+	return nil
+}
@@ -0,0 +1,284 @@
+package memory
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var _ services.WebhookStore = (*WebhookRepository)(nil)
+
+// Webhooks and their deliveries kept in a Store, the counterpart of repository.WebhookRepository
+type WebhookRepository struct {
+	s *Store
+}
+
+// Returns a new instance of WebhookRepository over the store
+func NewWebhookRepository(s *Store) *WebhookRepository {
+	return &WebhookRepository{s}
+}
+
+// Add a webhook, filling in its id and creation time
+func (repo *WebhookRepository) AddWebhook(ctx context.Context, hook *models.Webhook) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hook.ID = uuid.New()
+	now := s.timestamp()
+	hook.CreatedAt = &now
+	c := copyWebhook(hook)
+	c.Secret = hook.Secret
+	s.webhooks = append(s.webhooks, c)
+	return nil
+}
+
+func (s *Store) findWebhook(id uuid.UUID) *models.Webhook {
+	for _, hook := range s.webhooks {
+		if hook.ID == id {
+			return hook
+		}
+	}
+	return nil
+}
+
+// Get a webhook by id, without its secret
+func (repo *WebhookRepository) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hook := s.findWebhook(id)
+	if hook == nil {
+		return nil, sql.ErrNoRows
+	}
+	return copyWebhook(hook), nil
+}
+
+// Get webhooks of dashboards on which the user has the permission, and the global ones
+// when includeGlobal is set, newest first
+func (repo *WebhookRepository) GetWebhooksForUser(ctx context.Context, userId uuid.UUID, permName string, includeGlobal bool) ([]*models.Webhook, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hooks := []*models.Webhook{}
+	for i := len(s.webhooks) - 1; i >= 0; i-- {
+		hook := s.webhooks[i]
+		if (hook.DashID == nil && includeGlobal) || (hook.DashID != nil && s.dashPermission(userId, *hook.DashID, permName)) {
+			hooks = append(hooks, copyWebhook(hook))
+		}
+	}
+	return hooks, nil
+}
+
+// Update the url, events and state of a webhook
+func (repo *WebhookRepository) UpdateWebhook(ctx context.Context, hook *models.Webhook) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h := s.findWebhook(hook.ID); h != nil {
+		h.URL, h.Events, h.Active = hook.URL, append([]string(nil), hook.Events...), hook.Active
+	}
+	return nil
+}
+
+// Delete a webhook along with its deliveries
+func (repo *WebhookRepository) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hooks := s.webhooks[:0]
+	for _, hook := range s.webhooks {
+		if hook.ID != id {
+			hooks = append(hooks, hook)
+		}
+	}
+	s.webhooks = hooks
+	deliveries := s.deliveries[:0]
+	for _, d := range s.deliveries {
+		if d.WebhookID != id {
+			deliveries = append(deliveries, d)
+		}
+	}
+	s.deliveries = deliveries
+	return nil
+}
+
+// Queue the event for every active webhook subscribed to its type, as
+// repository.WebhookRepository.EnqueueDeliveries
+func (repo *WebhookRepository) EnqueueDeliveries(ctx context.Context, e *models.Event, payload, idsOnly []byte) (int64, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, hook := range s.webhooks {
+		if !hook.Active || !contains(hook.Events, e.Type) || (hook.DashID != nil && *hook.DashID != e.DashID) {
+			continue
+		}
+		if hook.DashID != nil && e.Type != models.EventDashDeleted && !s.dashPermission(hook.CreatedBy, e.DashID, "read") {
+			continue
+		}
+		body := payload
+		if hook.DashID != nil && e.ViewID != nil && !s.viewPermission(hook.CreatedBy, *e.ViewID, "read") {
+			body = idsOnly
+		}
+		s.deliveries = append(s.deliveries, s.newDelivery(hook.ID, e.ID, e.Type, body))
+		n++
+	}
+	return n, nil
+}
+
+func (s *Store) newDelivery(webhookId, eventId uuid.UUID, eventType string, payload []byte) *models.WebhookDelivery {
+	now := s.timestamp()
+	next := now
+	return &models.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     webhookId,
+		EventID:       eventId,
+		EventType:     eventType,
+		Payload:       append(json.RawMessage(nil), payload...),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &next,
+		CreatedAt:     &now,
+	}
+}
+
+// Claim up to limit pending deliveries that are due, counting an attempt on each. Claimed
+// deliveries are not due again until lease passes.
+func (repo *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.timestamp()
+	due := []*models.WebhookDelivery{}
+	for _, d := range s.deliveries {
+		hook := s.findWebhook(d.WebhookID)
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) && hook != nil && hook.Active {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		d.Attempts++
+		next := now.Add(lease)
+		d.NextAttemptAt = &next
+		c := copyDelivery(d)
+		hook := s.findWebhook(d.WebhookID)
+		c.URL, c.Secret = hook.URL, hook.Secret
+		claimed = append(claimed, c)
+	}
+	return claimed, nil
+}
+
+// Record the outcome of an attempt. A pending delivery is retried after retryIn.
+func (repo *WebhookRepository) FinishDelivery(ctx context.Context, id uuid.UUID, status string, statusCode *int, lastErr *string, retryIn time.Duration) error {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.deliveries {
+		if d.ID != id {
+			continue
+		}
+		now := s.timestamp()
+		next := now.Add(retryIn)
+		d.Status, d.NextAttemptAt = status, &next
+		d.LastStatusCode, d.LastError = copyInt(statusCode), copyString(lastErr)
+		d.DeliveredAt = nil
+		if status == models.DeliverySucceeded {
+			d.DeliveredAt = &now
+		}
+	}
+	return nil
+}
+
+// Get a page of deliveries of a webhook, newest first, along with their total count
+func (repo *WebhookRepository) GetDeliveries(ctx context.Context, webhookId uuid.UUID, limit, offset int) ([]*models.WebhookDelivery, int, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := []*models.WebhookDelivery{}
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if s.deliveries[i].WebhookID == webhookId {
+			all = append(all, copyDelivery(s.deliveries[i]))
+		}
+	}
+	total := len(all)
+	if offset > total {
+		offset = total
+	}
+	all = all[offset:]
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	return all, total, nil
+}
+
+// Queue the payload of a past delivery of the webhook again as a new delivery
+func (repo *WebhookRepository) Redeliver(ctx context.Context, webhookId, deliveryId uuid.UUID) (*models.WebhookDelivery, error) {
+	s := repo.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.deliveries {
+		if d.ID == deliveryId && d.WebhookID == webhookId {
+			again := s.newDelivery(webhookId, d.EventID, d.EventType, d.Payload)
+			s.deliveries = append(s.deliveries, again)
+			return copyDelivery(again), nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// webhook without its secret
+func copyWebhook(hook *models.Webhook) *models.Webhook {
+	c := *hook
+	c.DashID = copyID(hook.DashID)
+	c.Events = append([]string(nil), hook.Events...)
+	c.CreatedAt = copyTime(hook.CreatedAt)
+	c.Secret = ""
+	return &c
+}
+
+func copyDelivery(d *models.WebhookDelivery) *models.WebhookDelivery {
+	c := *d
+	c.Payload = append(json.RawMessage(nil), d.Payload...)
+	c.NextAttemptAt = copyTime(d.NextAttemptAt)
+	c.LastStatusCode = copyInt(d.LastStatusCode)
+	c.LastError = copyString(d.LastError)
+	c.CreatedAt = copyTime(d.CreatedAt)
+	c.DeliveredAt = copyTime(d.DeliveredAt)
+	return &c
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func copyInt(i *int) *int {
+	if i == nil {
+		return nil
+	}
+	c := *i
+	return &c
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
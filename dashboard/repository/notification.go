@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"backend/dashboard/db"
+	"backend/dashboard/models"
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// Contains methods for the notification inbox of users and their preferences
+type NotificationRepository struct {
+	Conn *db.DashboardDb
+	L    logrus.FieldLogger
+}
+
+// Returns a new instance of NotificationRepository
+func NewNotificationRepository(conn *db.DashboardDb, l logrus.FieldLogger) *NotificationRepository {
+	return &NotificationRepository{conn, l}
+}
+
+const notificationColumns = "id, user_id, type, dashboard_id, view_id, message, data, created_at, read_at"
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	n := &models.Notification{}
+	var data []byte
+	err := row.Scan(&n.ID, &n.UserID, &n.Type, &n.DashID, &n.ViewID, &n.Message, &data, &n.CreatedAt, &n.ReadAt)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		n.Data = json.RawMessage(data)
+	}
+	return n, nil
+}
+
+// Add a notification unless the user turned its kind off. Returns false when it was not added.
+func (repo *NotificationRepository) AddNotification(ctx context.Context, n *models.Notification) (bool, error) {
+	var data []byte
+	if n.Data != nil {
+		var err error
+		data, err = json.Marshal(n.Data)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	res, err := repo.Conn.Conn.ExecContext(ctx, `INSERT INTO notification (user_id, type, dashboard_id, view_id, message, data)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE NOT EXISTS (SELECT 1 FROM notification_preference WHERE user_id = $1 AND type = $2 AND NOT enabled)`,
+		n.UserID, n.Type, n.DashID, n.ViewID, n.Message, data)
+	if err != nil {
+		return false, err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return cnt > 0, nil
+}
+
+// Get a page of the notifications of a user, newest first, along with the total count of
+// the listed notifications and of unread ones
+func (repo *NotificationRepository) GetNotifications(ctx context.Context, userId uuid.UUID, unreadOnly bool, limit, offset int) (*models.NotificationPage, error) {
+	page := &models.NotificationPage{Items: make([]*models.Notification, 0)}
+	err := repo.Conn.Conn.QueryRowContext(ctx, `SELECT COUNT(*) FILTER (WHERE NOT $2 OR read_at IS NULL), COUNT(*) FILTER (WHERE read_at IS NULL)
+		FROM notification WHERE user_id = $1`, userId, unreadOnly).Scan(&page.Total, &page.Unread)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.Conn.Conn.QueryContext(ctx, "SELECT "+notificationColumns+` FROM notification
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, id LIMIT $3 OFFSET $4`, userId, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, n)
+	}
+	return page, rows.Err()
+}
+
+// Count the unread notifications of a user
+func (repo *NotificationRepository) CountUnread(ctx context.Context, userId uuid.UUID) (int, error) {
+	var cnt int
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM notification WHERE user_id = $1 AND read_at IS NULL", userId).Scan(&cnt)
+	return cnt, err
+}
+
+// Mark the given notifications of a user as read, or all of them when ids is nil.
+// Returns the number of notifications marked.
+func (repo *NotificationRepository) MarkRead(ctx context.Context, userId uuid.UUID, ids []uuid.UUID) (int64, error) {
+	var idArr interface{}
+	if ids != nil {
+		strs := make([]string, 0, len(ids))
+		for _, id := range ids {
+			strs = append(strs, id.String())
+		}
+		idArr = pq.Array(strs)
+	}
+
+	res, err := repo.Conn.Conn.ExecContext(ctx, `UPDATE notification SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL AND ($2::uuid[] IS NULL OR id = ANY($2::uuid[]))`, userId, idArr)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Get the kinds of notification a user set a preference for
+func (repo *NotificationRepository) GetPreferences(ctx context.Context, userId uuid.UUID) (map[string]bool, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, "SELECT type, enabled FROM notification_preference WHERE user_id = $1", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	prefs := map[string]bool{}
+	for rows.Next() {
+		var t string
+		var enabled bool
+		err := rows.Scan(&t, &enabled)
+		if err != nil {
+			return nil, err
+		}
+		prefs[t] = enabled
+	}
+	return prefs, rows.Err()
+}
+
+// Store the preferences of a user for the given kinds of notification
+func (repo *NotificationRepository) SetPreferences(ctx context.Context, userId uuid.UUID, prefs map[string]bool) error {
+	tx, err := repo.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for t, enabled := range prefs {
+		_, err = tx.ExecContext(ctx, `INSERT INTO notification_preference (user_id, type, enabled) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled`, userId, t, enabled)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -6,26 +6,27 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 )
 
 // Contains methods for access management for dashboards and its views
 type RoleRepository struct {
 	Conn *db.DashboardDb
-	L    *log.Logger
+	L    logrus.FieldLogger
 }
 
 // Returns a new RoleRepository instance
-func NewRoleRepository(conn *db.DashboardDb, l *log.Logger) *RoleRepository {
+func NewRoleRepository(conn *db.DashboardDb, l logrus.FieldLogger) *RoleRepository {
 	return &RoleRepository{conn, l}
 }
 
 // Add a new kind of permission to database
-func (r *RoleRepository) AddPermission(perm *models.Permission) error {
+func (r *RoleRepository) AddPermission(ctx context.Context, perm *models.Permission) error {
 	var id int
-	err := r.Conn.Conn.QueryRow("INSERT INTO permissions (permission) VALUES ($1) RETURNING id", perm.Name).Scan(&id)
+	err := r.Conn.Conn.QueryRowContext(ctx, "INSERT INTO permissions (permission) VALUES ($1) RETURNING id", perm.Name).Scan(&id)
 	if err != nil {
 		return err
 	}
@@ -34,8 +35,8 @@ func (r *RoleRepository) AddPermission(perm *models.Permission) error {
 }
 
 // Delete a permission with given id from database
-func (r *RoleRepository) DeletePermission(id int) error {
-	_, err := r.Conn.Conn.Exec("DELETE FROM permissions WHERE id = $1", id)
+func (r *RoleRepository) DeletePermission(ctx context.Context, id int) error {
+	_, err := r.Conn.Conn.ExecContext(ctx, "DELETE FROM permissions WHERE id = $1", id)
 	if err != nil {
 		return err
 	}
@@ -43,9 +44,9 @@ func (r *RoleRepository) DeletePermission(id int) error {
 }
 
 // Get a permission with given id from database
-func (r *RoleRepository) GetPermission(id int) (*models.Permission, error) {
+func (r *RoleRepository) GetPermission(ctx context.Context, id int) (*models.Permission, error) {
 	perm := &models.Permission{}
-	err := r.Conn.Conn.QueryRow("SELECT id, permission FROM permissions WHERE id = $1", id).Scan(&perm.ID, &perm.Name)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT id, permission FROM permissions WHERE id = $1", id).Scan(&perm.ID, &perm.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -53,9 +54,9 @@ func (r *RoleRepository) GetPermission(id int) (*models.Permission, error) {
 }
 
 // Get a permission with given name from database. Names are unique to each permission.
-func (r *RoleRepository) GetPermissionByName(name string) (*models.Permission, error) {
+func (r *RoleRepository) GetPermissionByName(ctx context.Context, name string) (*models.Permission, error) {
 	perm := &models.Permission{}
-	err := r.Conn.Conn.QueryRow("SELECT id, permission FROM permissions WHERE permission = $1", name).Scan(&perm.ID, &perm.Name)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT id, permission FROM permissions WHERE permission = $1", name).Scan(&perm.ID, &perm.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -63,8 +64,8 @@ func (r *RoleRepository) GetPermissionByName(name string) (*models.Permission, e
 }
 
 // Update permissions details for an id
-func (r *RoleRepository) UpdatePermission(perm *models.Permission) error {
-	_, err := r.Conn.Conn.Exec("UPDATE permissions SET permission = $1 WHERE id = $2", perm.Name, perm.ID)
+func (r *RoleRepository) UpdatePermission(ctx context.Context, perm *models.Permission) error {
+	_, err := r.Conn.Conn.ExecContext(ctx, "UPDATE permissions SET permission = $1 WHERE id = $2", perm.Name, perm.ID)
 	if err != nil {
 		return err
 	}
@@ -72,8 +73,8 @@ func (r *RoleRepository) UpdatePermission(perm *models.Permission) error {
 }
 
 // Get list of all types of permissions from database
-func (r *RoleRepository) GetAllPermissions() ([]*models.Permission, error) {
-	rows, err := r.Conn.Conn.Query("SELECT id, permission FROM permissions")
+func (r *RoleRepository) GetAllPermissions(ctx context.Context) ([]*models.Permission, error) {
+	rows, err := r.Conn.Conn.QueryContext(ctx, "SELECT id, permission FROM permissions")
 	if err != nil {
 		return nil, err
 	}
@@ -91,9 +92,9 @@ func (r *RoleRepository) GetAllPermissions() ([]*models.Permission, error) {
 }
 
 // Add a new role to database. Created role will have no permissions by default.
-func (r *RoleRepository) AddRole(role *models.Role) error {
+func (r *RoleRepository) AddRole(ctx context.Context, role *models.Role) error {
 	var id int
-	err := r.Conn.Conn.QueryRow("INSERT INTO roles (name) VALUES ($1) RETURNING id", role.Name).Scan(&id)
+	err := r.Conn.Conn.QueryRowContext(ctx, "INSERT INTO roles (name) VALUES ($1) RETURNING id", role.Name).Scan(&id)
 	if err != nil {
 		return err
 	}
@@ -102,8 +103,8 @@ func (r *RoleRepository) AddRole(role *models.Role) error {
 }
 
 // Delete a role with given id from database
-func (r *RoleRepository) DeleteRole(id int) error {
-	_, err := r.Conn.Conn.Exec("DELETE FROM roles WHERE id = $1", id)
+func (r *RoleRepository) DeleteRole(ctx context.Context, id int) error {
+	_, err := r.Conn.Conn.ExecContext(ctx, "DELETE FROM roles WHERE id = $1", id)
 	if err != nil {
 		return err
 	}
@@ -111,9 +112,9 @@ func (r *RoleRepository) DeleteRole(id int) error {
 }
 
 // Get a role with given id from database
-func (r *RoleRepository) GetRole(id int) (*models.Role, error) {
+func (r *RoleRepository) GetRole(ctx context.Context, id int) (*models.Role, error) {
 	role := &models.Role{}
-	err := r.Conn.Conn.QueryRow("SELECT id, name FROM roles WHERE id = $1", id).Scan(&role.ID, &role.Name)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT id, name FROM roles WHERE id = $1", id).Scan(&role.ID, &role.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -121,9 +122,9 @@ func (r *RoleRepository) GetRole(id int) (*models.Role, error) {
 }
 
 // Get a role with given name from database. Names are unique to each role.
-func (r *RoleRepository) GetRoleByName(name string) (*models.Role, error) {
+func (r *RoleRepository) GetRoleByName(ctx context.Context, name string) (*models.Role, error) {
 	role := &models.Role{}
-	err := r.Conn.Conn.QueryRow("SELECT id, name FROM roles WHERE name = $1", name).Scan(&role.ID, &role.Name)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT id, name FROM roles WHERE name = $1", name).Scan(&role.ID, &role.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -131,8 +132,8 @@ func (r *RoleRepository) GetRoleByName(name string) (*models.Role, error) {
 }
 
 // Update role details for an id
-func (r *RoleRepository) UpdateRole(role *models.Role) error {
-	_, err := r.Conn.Conn.Exec("UPDATE roles SET name = $1 WHERE id = $2", role.Name, role.ID)
+func (r *RoleRepository) UpdateRole(ctx context.Context, role *models.Role) error {
+	_, err := r.Conn.Conn.ExecContext(ctx, "UPDATE roles SET name = $1 WHERE id = $2", role.Name, role.ID)
 	if err != nil {
 		return err
 	}
@@ -140,8 +141,8 @@ func (r *RoleRepository) UpdateRole(role *models.Role) error {
 }
 
 // Get list of all roles from database
-func (r *RoleRepository) GetAllRoles() ([]*models.Role, error) {
-	rows, err := r.Conn.Conn.Query("SELECT id, name FROM roles")
+func (r *RoleRepository) GetAllRoles(ctx context.Context) ([]*models.Role, error) {
+	rows, err := r.Conn.Conn.QueryContext(ctx, "SELECT id, name FROM roles")
 	if err != nil {
 		return nil, err
 	}
@@ -159,8 +160,8 @@ func (r *RoleRepository) GetAllRoles() ([]*models.Role, error) {
 }
 
 // Get all permissions for a role with given id
-func (r *RoleRepository) GetPermissionsForRoleId(roleId int) ([]*models.Permission, error) {
-	rows, err := r.Conn.Conn.Query("SELECT p.id, p.name FROM permissions p, role_has_permissions rhp WHERE rhp.role_id = $1 AND rhp.permission_id = p.id", roleId)
+func (r *RoleRepository) GetPermissionsForRoleId(ctx context.Context, roleId int) ([]*models.Permission, error) {
+	rows, err := r.Conn.Conn.QueryContext(ctx, "SELECT p.id, p.name FROM permissions p, role_has_permissions rhp WHERE rhp.role_id = $1 AND rhp.permission_id = p.id", roleId)
 	if err != nil {
 		return nil, err
 	}
@@ -186,7 +187,7 @@ func (r *RoleRepository) GivePermissionsToRole(ctx context.Context, roleId int,
 	defer tx.Rollback()
 
 	for _, perm := range perms {
-		_, err := tx.Exec("INSERT INTO role_has_permissions (role_id, permission_id) VALUES ($1, $2)", roleId, perm)
+		_, err := tx.ExecContext(ctx, "INSERT INTO role_has_permissions (role_id, permission_id) VALUES ($1, $2)", roleId, perm)
 		if err != nil {
 			return err
 		}
@@ -204,7 +205,7 @@ func (r *RoleRepository) RevokePermissionsFromRole(ctx context.Context, roleId i
 	defer tx.Rollback()
 
 	for _, perm := range perms {
-		_, err := tx.Exec("DELETE FROM role_has_permissions WHERE role_id = $1 AND permission_id = $2", roleId, perm)
+		_, err := tx.ExecContext(ctx, "DELETE FROM role_has_permissions WHERE role_id = $1 AND permission_id = $2", roleId, perm)
 		if err != nil {
 			return err
 		}
@@ -213,22 +214,22 @@ func (r *RoleRepository) RevokePermissionsFromRole(ctx context.Context, roleId i
 	return tx.Commit()
 }
 
-// Grant role to a user with given id
-func (r *RoleRepository) GrantDashLevelRoleToUser(userId uuid.UUID, roleName string, dashId uuid.UUID) error {
+// Grant role to a user with given id. The grant lapses at expiresAt when set.
+func (r *RoleRepository) GrantDashLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, dashId uuid.UUID, expiresAt *time.Time) error {
 	var exists bool
-	err := r.Conn.Conn.QueryRow("SELECT EXISTS (SELECT * FROM user_role_dashboard WHERE user_id = $1 AND dashboard_id = $2)", userId, dashId).Scan(&exists)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT * FROM user_role_dashboard WHERE user_id = $1 AND dashboard_id = $2)", userId, dashId).Scan(&exists)
 	if err != nil {
 		return err
 	}
 
 	var res sql.Result
 	if !exists {
-		res, err = r.Conn.Conn.Exec("INSERT INTO user_role_dashboard (user_id, dashboard_id, role_id) SELECT $1, $2, roles.id FROM roles WHERE roles.name=$3", userId, dashId, roleName)
+		res, err = r.Conn.Conn.ExecContext(ctx, "INSERT INTO user_role_dashboard (user_id, dashboard_id, role_id, expires_at) SELECT $1, $2, roles.id, $4 FROM roles WHERE roles.name=$3", userId, dashId, roleName, expiresAt)
 		if err != nil {
 			return err
 		}
 	} else {
-		res, err = r.Conn.Conn.Exec("UPDATE user_role_dashboard SET role_id = roles.id FROM roles WHERE roles.name=$1 AND user_role_dashboard.user_id=$2 AND user_role_dashboard.dashboard_id=$3;", roleName, userId, dashId)
+		res, err = r.Conn.Conn.ExecContext(ctx, "UPDATE user_role_dashboard SET role_id = roles.id, expires_at = $4, expiry_notified_at = NULL FROM roles WHERE roles.name=$1 AND user_role_dashboard.user_id=$2 AND user_role_dashboard.dashboard_id=$3;", roleName, userId, dashId, expiresAt)
 		if err != nil {
 			return err
 		}
@@ -247,14 +248,14 @@ func (r *RoleRepository) GrantDashLevelRoleToUser(userId uuid.UUID, roleName str
 }
 
 // Revoke role from a user with given id
-func (r *RoleRepository) RevokeDashLevelRoleFromUser(context context.Context, userId uuid.UUID, dashId uuid.UUID) error {
-	tx, err := r.Conn.Conn.BeginTx(context, nil)
+func (r *RoleRepository) RevokeDashLevelRoleFromUser(ctx context.Context, userId uuid.UUID, dashId uuid.UUID) error {
+	tx, err := r.Conn.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	res, err := tx.Exec("DELETE FROM user_role_dashboard WHERE user_id = $1 AND dashboard_id = $2", userId, dashId)
+	res, err := tx.ExecContext(ctx, "DELETE FROM user_role_dashboard WHERE user_id = $1 AND dashboard_id = $2", userId, dashId)
 	if err != nil {
 		return err
 	}
@@ -267,7 +268,7 @@ func (r *RoleRepository) RevokeDashLevelRoleFromUser(context context.Context, us
 	}
 
 	//remove user from all views of this dashboard
-	_, err = tx.Exec("DELETE FROM user_role_view urv USING view v WHERE urv.user_id = $1 AND urv.view_id = v.id AND v.dashboard_id=$2", userId, dashId)
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_role_view urv USING view v WHERE urv.user_id = $1 AND urv.view_id = v.id AND v.dashboard_id=$2", userId, dashId)
 	if err != nil {
 		return err
 	}
@@ -275,10 +276,10 @@ func (r *RoleRepository) RevokeDashLevelRoleFromUser(context context.Context, us
 	return tx.Commit()
 }
 
-// Grant role for a view to a user
-func (r *RoleRepository) GrantViewLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, viewId uuid.UUID) error {
+// Grant role for a view to a user. The grant lapses at expiresAt when set.
+func (r *RoleRepository) GrantViewLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, viewId uuid.UUID, expiresAt *time.Time) error {
 	var exists bool
-	err := r.Conn.Conn.QueryRow("SELECT EXISTS (SELECT * FROM user_role_view WHERE user_id = $1 AND view_id = $2)", userId, viewId).Scan(&exists)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT * FROM user_role_view WHERE user_id = $1 AND view_id = $2)", userId, viewId).Scan(&exists)
 	if err != nil {
 		return err
 	}
@@ -286,13 +287,13 @@ func (r *RoleRepository) GrantViewLevelRoleToUser(ctx context.Context, userId uu
 	var res sql.Result
 	if !exists {
 		r.L.Println("Inserting role " + roleName + " for user " + userId.String() + " for view " + viewId.String())
-		res, err = r.Conn.Conn.Exec("INSERT INTO user_role_view (user_id, view_id, role_id) SELECT $1, $2, roles.id FROM roles WHERE roles.name=$3", userId, viewId, roleName)
+		res, err = r.Conn.Conn.ExecContext(ctx, "INSERT INTO user_role_view (user_id, view_id, role_id, expires_at) SELECT $1, $2, roles.id, $4 FROM roles WHERE roles.name=$3", userId, viewId, roleName, expiresAt)
 		if err != nil {
 			return err
 		}
 	} else {
 		r.L.Println("Updating role " + roleName + " for user " + userId.String() + " for view " + viewId.String())
-		res, err = r.Conn.Conn.Exec("UPDATE user_role_view SET role_id = roles.id FROM roles WHERE roles.name=$1 AND user_role_view.user_id=$2 AND user_role_view.view_id=$3;", roleName, userId, viewId)
+		res, err = r.Conn.Conn.ExecContext(ctx, "UPDATE user_role_view SET role_id = roles.id, expires_at = $4, expiry_notified_at = NULL FROM roles WHERE roles.name=$1 AND user_role_view.user_id=$2 AND user_role_view.view_id=$3;", roleName, userId, viewId, expiresAt)
 		if err != nil {
 			return err
 		}
@@ -310,8 +311,8 @@ func (r *RoleRepository) GrantViewLevelRoleToUser(ctx context.Context, userId uu
 }
 
 // Revoke role for a view from a user
-func (r *RoleRepository) RevokeViewLevelRoleFromUser(userId uuid.UUID, viewId uuid.UUID) error {
-	res, err := r.Conn.Conn.Exec("DELETE FROM user_role_view WHERE user_id = $1 AND view_id = $2", userId, viewId)
+func (r *RoleRepository) RevokeViewLevelRoleFromUser(ctx context.Context, userId uuid.UUID, viewId uuid.UUID) error {
+	res, err := r.Conn.Conn.ExecContext(ctx, "DELETE FROM user_role_view WHERE user_id = $1 AND view_id = $2", userId, viewId)
 	if err != nil {
 		return err
 	}
@@ -325,9 +326,16 @@ func (r *RoleRepository) RevokeViewLevelRoleFromUser(userId uuid.UUID, viewId uu
 	return nil
 }
 
+// Get the id of the dashboard a view is on
+func (r *RoleRepository) GetDashIdForView(ctx context.Context, viewId uuid.UUID) (uuid.UUID, error) {
+	var dashId uuid.UUID
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT dashboard_id FROM view WHERE id = $1", viewId).Scan(&dashId)
+	return dashId, err
+}
+
 // Get all roles for a user for a dashboard
-func (r *RoleRepository) GetRolesForUserForDashboard(userId uuid.UUID, dashId uuid.UUID) ([]*models.Role, error) {
-	rows, err := r.Conn.Conn.Query("SELECT r.id, r.name FROM roles r JOIN user_role_dashboard urd ON r.id = urd.role_id WHERE urd.user_id = $1 AND urd.dashboard_id = $2", userId, dashId)
+func (r *RoleRepository) GetRolesForUserForDashboard(ctx context.Context, userId uuid.UUID, dashId uuid.UUID) ([]*models.Role, error) {
+	rows, err := r.Conn.Conn.QueryContext(ctx, "SELECT r.id, r.name FROM roles r JOIN user_role_dashboard urd ON r.id = urd.role_id WHERE urd.user_id = $1 AND urd.dashboard_id = $2", userId, dashId)
 	if err != nil {
 		return nil, err
 	}
@@ -344,8 +352,8 @@ func (r *RoleRepository) GetRolesForUserForDashboard(userId uuid.UUID, dashId uu
 	return roles, nil
 }
 
-func (r *RoleRepository) GetRolesForUsersForDashboard(dashId uuid.UUID) ([]*models.Role, error) {
-	rows, err := r.Conn.Conn.Query("SELECT r.user_id, r.role_id, r.role_name FROM dashboard_perms r WHERE dash_id=$1 AND perm_name='read'", dashId)
+func (r *RoleRepository) GetRolesForUsersForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.Role, error) {
+	rows, err := r.Conn.Conn.QueryContext(ctx, "SELECT r.user_id, r.role_id, r.role_name, r.folder_id, r.expires_at FROM dashboard_perms r WHERE dash_id=$1 AND perm_name='read'", dashId)
 	if err != nil {
 		return nil, err
 	}
@@ -353,7 +361,7 @@ func (r *RoleRepository) GetRolesForUsersForDashboard(dashId uuid.UUID) ([]*mode
 	roles := []*models.Role{}
 	for rows.Next() {
 		role := &models.Role{}
-		err := rows.Scan(&role.UserId, &role.ID, &role.Name)
+		err := rows.Scan(&role.UserId, &role.ID, &role.Name, &role.FolderID, &role.ExpiresAt)
 		if err != nil {
 			return nil, err
 		}
@@ -362,27 +370,76 @@ func (r *RoleRepository) GetRolesForUsersForDashboard(dashId uuid.UUID) ([]*mode
 	return roles, nil
 }
 
-func (r *RoleRepository) ExistsPermissionForUserForDashboard(userId uuid.UUID, dashID uuid.UUID, permName string) (bool, error) {
+func (r *RoleRepository) ExistsPermissionForUserForDashboard(ctx context.Context, userId uuid.UUID, dashID uuid.UUID, permName string) (bool, error) {
 	var count bool
-	err := r.Conn.Conn.QueryRow("SELECT EXISTS(SELECT * FROM dashboard_perms WHERE user_id=$1 AND dash_id=$2 AND perm_name=$3)", userId, dashID, permName).Scan(&count)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT EXISTS(SELECT * FROM dashboard_perms WHERE user_id=$1 AND dash_id=$2 AND perm_name=$3)", userId, dashID, permName).Scan(&count)
 	if err != nil {
 		return count, err
 	}
 	return count, nil
 }
 
-func (r *RoleRepository) ExistsPermissionForUserForView(userId uuid.UUID, viewID uuid.UUID, permName string) (bool, error) {
+func (r *RoleRepository) ExistsPermissionForUserForView(ctx context.Context, userId uuid.UUID, viewID uuid.UUID, permName string) (bool, error) {
 	var count bool
-	err := r.Conn.Conn.QueryRow("SELECT EXISTS(SELECT * FROM view_perms WHERE user_id=$1 AND view_Id=$2 AND perm_name=$3)", userId, viewID, permName).Scan(&count)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT EXISTS(SELECT * FROM view_perms WHERE user_id=$1 AND view_Id=$2 AND perm_name=$3)", userId, viewID, permName).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count, nil
 }
 
-func (r *RoleRepository) IsOnlyAdminForDashboard(userId uuid.UUID, dashID uuid.UUID) (bool, error) {
+// Get all grants on a dashboard and on its views that are not in trash and have not lapsed
+func (r *RoleRepository) GetGrantsForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.Grant, error) {
+	rows, err := r.Conn.Conn.QueryContext(ctx, `SELECT urd.user_id, ro.name, NULL::uuid, urd.expires_at FROM user_role_dashboard urd JOIN roles ro ON ro.id = urd.role_id
+		WHERE urd.dashboard_id = $1 AND (urd.expires_at IS NULL OR urd.expires_at > NOW())
+		UNION ALL
+		SELECT urv.user_id, ro.name, v.id, urv.expires_at FROM user_role_view urv JOIN roles ro ON ro.id = urv.role_id JOIN view v ON v.id = urv.view_id
+		WHERE v.dashboard_id = $1 AND v.deleted_at IS NULL AND (urv.expires_at IS NULL OR urv.expires_at > NOW())`, dashId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	grants := []*models.Grant{}
+	for rows.Next() {
+		grant := &models.Grant{}
+		err := rows.Scan(&grant.UserID, &grant.RoleName, &grant.ViewID, &grant.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
+
+// Returns true if the user holds the permission on a dashboard regardless of it being in trash
+func (r *RoleRepository) ExistsPermissionForUserForTrashedDashboard(ctx context.Context, userId uuid.UUID, dashID uuid.UUID, permName string) (bool, error) {
+	var exists bool
+	err := r.Conn.Conn.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM user_role_dashboard urd
+		JOIN role_has_permissions rhp ON rhp.role_id = urd.role_id
+		JOIN permissions p ON p.id = rhp.permission_id
+		WHERE urd.user_id = $1 AND urd.dashboard_id = $2 AND p.name = $3 AND (urd.expires_at IS NULL OR urd.expires_at > NOW()))`, userId, dashID, permName).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// Returns true if the user holds the permission on a view regardless of it being in trash
+func (r *RoleRepository) ExistsPermissionForUserForTrashedView(ctx context.Context, userId uuid.UUID, viewID uuid.UUID, permName string) (bool, error) {
+	var exists bool
+	err := r.Conn.Conn.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM user_role_view urv
+		JOIN role_has_permissions rhp ON rhp.role_id = urv.role_id
+		JOIN permissions p ON p.id = rhp.permission_id
+		WHERE urv.user_id = $1 AND urv.view_id = $2 AND p.name = $3 AND (urv.expires_at IS NULL OR urv.expires_at > NOW()))`, userId, viewID, permName).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *RoleRepository) IsOnlyAdminForDashboard(ctx context.Context, userId uuid.UUID, dashID uuid.UUID) (bool, error) {
 	var count int
-	err := r.Conn.Conn.QueryRow("SELECT COUNT(*) FROM user_role_dashboard WHERE dashboard_id=$1 AND role_id=1", dashID).Scan(&count)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_role_dashboard WHERE dashboard_id=$1 AND role_id=1", dashID).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -392,7 +449,7 @@ func (r *RoleRepository) IsOnlyAdminForDashboard(userId uuid.UUID, dashID uuid.U
 	}
 	if count == 1 {
 		var adminID uuid.UUID
-		err = r.Conn.Conn.QueryRow("SELECT user_id FROM dashboard_perms WHERE dash_id=$1 AND role_name='admin'", dashID).Scan(&adminID)
+		err = r.Conn.Conn.QueryRowContext(ctx, "SELECT user_id FROM user_role_dashboard WHERE dashboard_id=$1 AND role_id=1", dashID).Scan(&adminID)
 		if err != nil {
 			return false, err
 		}
@@ -404,9 +461,9 @@ func (r *RoleRepository) IsOnlyAdminForDashboard(userId uuid.UUID, dashID uuid.U
 	return false, nil
 }
 
-func (r *RoleRepository) IsOnlyAdminForView(userId uuid.UUID, viewID uuid.UUID) (bool, error) {
+func (r *RoleRepository) IsOnlyAdminForView(ctx context.Context, userId uuid.UUID, viewID uuid.UUID) (bool, error) {
 	var count int
-	err := r.Conn.Conn.QueryRow("SELECT COUNT(*) FROM user_role_view WHERE view_id=$1 AND role_id=1", viewID).Scan(&count)
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_role_view WHERE view_id=$1 AND role_id=1", viewID).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -415,7 +472,7 @@ func (r *RoleRepository) IsOnlyAdminForView(userId uuid.UUID, viewID uuid.UUID)
 	}
 	if count == 1 {
 		var adminID uuid.UUID
-		err = r.Conn.Conn.QueryRow("SELECT user_id FROM view_perms WHERE view_id=$1 AND role_name='admin'", viewID).Scan(&adminID)
+		err = r.Conn.Conn.QueryRowContext(ctx, "SELECT user_id FROM view_perms WHERE view_id=$1 AND role_name='admin'", viewID).Scan(&adminID)
 		if err != nil {
 			return false, err
 		}
@@ -425,3 +482,226 @@ func (r *RoleRepository) IsOnlyAdminForView(userId uuid.UUID, viewID uuid.UUID)
 	}
 	return false, nil
 }
+
+// Returns true if the user holds the permission on a folder directly or through a folder above it
+func (r *RoleRepository) ExistsPermissionForUserForFolder(ctx context.Context, userId uuid.UUID, folderId uuid.UUID, permName string) (bool, error) {
+	var exists bool
+	err := r.Conn.Conn.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM folder_perms WHERE user_id=$1 AND folder_id=$2 AND perm_name=$3)", userId, folderId, permName).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// Grant role for a folder to a user, replacing any role the user has on it
+func (r *RoleRepository) GrantFolderLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, folderId uuid.UUID) error {
+	res, err := r.Conn.Conn.ExecContext(ctx, `INSERT INTO user_role_folder (user_id, folder_id, role_id) SELECT $1, $2, roles.id FROM roles WHERE roles.name=$3
+		ON CONFLICT (user_id, folder_id) DO UPDATE SET role_id = EXCLUDED.role_id`, userId, folderId, roleName)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("invalid role name")
+	}
+	return nil
+}
+
+// Revoke role for a folder from a user
+func (r *RoleRepository) RevokeFolderLevelRoleFromUser(ctx context.Context, userId uuid.UUID, folderId uuid.UUID) error {
+	res, err := r.Conn.Conn.ExecContext(ctx, "DELETE FROM user_role_folder WHERE user_id = $1 AND folder_id = $2", userId, folderId)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return errors.New("user does not have this role")
+	}
+	return nil
+}
+
+// Get users with a role on a folder, including roles inherited from folders above it
+func (r *RoleRepository) GetRolesForUsersForFolder(ctx context.Context, folderId uuid.UUID) ([]*models.Role, error) {
+	rows, err := r.Conn.Conn.QueryContext(ctx, "SELECT user_id, role_id, role_name, NULLIF(granted_on, folder_id) FROM folder_perms WHERE folder_id=$1 AND perm_name='read'", folderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	roles := []*models.Role{}
+	for rows.Next() {
+		role := &models.Role{}
+		err := rows.Scan(&role.UserId, &role.ID, &role.Name, &role.FolderID)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// Returns true if the user is the only one holding the admin role directly on the folder
+func (r *RoleRepository) IsOnlyAdminForFolder(ctx context.Context, userId uuid.UUID, folderId uuid.UUID) (bool, error) {
+	var others, own int
+	err := r.Conn.Conn.QueryRowContext(ctx, `SELECT COUNT(*) FILTER (WHERE user_id <> $2), COUNT(*) FILTER (WHERE user_id = $2)
+		FROM user_role_folder WHERE folder_id=$1 AND role_id=1`, folderId, userId).Scan(&others, &own)
+	if err != nil {
+		return false, err
+	}
+	return own == 1 && others == 0, nil
+}
+
+// Get users holding a permission on a dashboard, directly or through a folder
+func (r *RoleRepository) GetUsersWithPermissionForDashboard(ctx context.Context, dashId uuid.UUID, permName string) ([]uuid.UUID, error) {
+	return r.queryUserIds(ctx, "SELECT DISTINCT user_id FROM dashboard_perms WHERE dash_id=$1 AND perm_name=$2", dashId, permName)
+}
+
+// Get users holding a permission on a view
+func (r *RoleRepository) GetUsersWithPermissionForView(ctx context.Context, viewId uuid.UUID, permName string) ([]uuid.UUID, error) {
+	return r.queryUserIds(ctx, "SELECT DISTINCT user_id FROM view_perms WHERE view_id=$1 AND perm_name=$2", viewId, permName)
+}
+
+func (r *RoleRepository) queryUserIds(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := r.Conn.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Get grants lapsing within the given window that admins were not told about yet
+func (r *RoleRepository) GetGrantsExpiringWithin(ctx context.Context, window time.Duration) ([]*models.ExpiringGrant, error) {
+	rows, err := r.Conn.Conn.QueryContext(ctx, `SELECT urd.user_id, ro.name, urd.dashboard_id, NULL::uuid, urd.expires_at
+		FROM user_role_dashboard urd JOIN roles ro ON ro.id = urd.role_id JOIN dashboard d ON d.id = urd.dashboard_id
+		WHERE urd.expiry_notified_at IS NULL AND urd.expires_at > NOW() AND urd.expires_at <= NOW() + $1 * INTERVAL '1 second' AND d.deleted_at IS NULL
+		UNION ALL
+		SELECT urv.user_id, ro.name, v.dashboard_id, urv.view_id, urv.expires_at
+		FROM user_role_view urv JOIN roles ro ON ro.id = urv.role_id JOIN view v ON v.id = urv.view_id
+		WHERE urv.expiry_notified_at IS NULL AND urv.expires_at > NOW() AND urv.expires_at <= NOW() + $1 * INTERVAL '1 second' AND v.deleted_at IS NULL`, window.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	grants := []*models.ExpiringGrant{}
+	for rows.Next() {
+		grant := &models.ExpiringGrant{}
+		err := rows.Scan(&grant.UserID, &grant.RoleName, &grant.DashID, &grant.ViewID, &grant.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
+
+// Remember that admins were told about a lapsing grant
+func (r *RoleRepository) MarkExpiryNotified(ctx context.Context, grant *models.ExpiringGrant) error {
+	var err error
+	if grant.ViewID == nil {
+		_, err = r.Conn.Conn.ExecContext(ctx, "UPDATE user_role_dashboard SET expiry_notified_at = NOW() WHERE user_id = $1 AND dashboard_id = $2", grant.UserID, grant.DashID)
+	} else {
+		_, err = r.Conn.Conn.ExecContext(ctx, "UPDATE user_role_view SET expiry_notified_at = NOW() WHERE user_id = $1 AND view_id = $2", grant.UserID, *grant.ViewID)
+	}
+	return err
+}
+
+// Delete lapsed grants and record each in the access audit. Like revoking, a lapsed dashboard
+// grant takes the roles of the user on the views of that dashboard with it. Returns the lapsed
+// grants, without the view roles taken along.
+func (r *RoleRepository) DeleteExpiredGrants(ctx context.Context) ([]*models.ExpiringGrant, error) {
+	tx, err := r.Conn.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	grants := []*models.ExpiringGrant{}
+	rows, err := tx.QueryContext(ctx, `WITH lapsed AS (
+			DELETE FROM user_role_view urv USING view v WHERE urv.view_id = v.id AND urv.expires_at <= NOW()
+			RETURNING urv.user_id, urv.role_id, v.dashboard_id, urv.view_id, urv.expires_at
+		), audited AS (
+			INSERT INTO access_audit (action, user_id, role_id, dashboard_id, view_id, expires_at)
+			SELECT 'grant_expired', user_id, role_id, dashboard_id, view_id, expires_at FROM lapsed
+			RETURNING action, user_id, role_id, dashboard_id, view_id, expires_at
+		)
+		SELECT a.user_id, ro.name, a.dashboard_id, a.view_id, a.expires_at FROM audited a JOIN roles ro ON ro.id = a.role_id`)
+	if err != nil {
+		return nil, err
+	}
+	grants, err = scanLapsedGrants(rows, grants)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `WITH lapsed AS (
+			DELETE FROM user_role_dashboard WHERE expires_at <= NOW()
+			RETURNING user_id, role_id, dashboard_id, expires_at
+		), cascaded AS (
+			DELETE FROM user_role_view urv USING view v, lapsed l
+			WHERE urv.view_id = v.id AND v.dashboard_id = l.dashboard_id AND urv.user_id = l.user_id
+			RETURNING urv.user_id, urv.role_id, v.dashboard_id, urv.view_id, urv.expires_at
+		), audited AS (
+			INSERT INTO access_audit (action, user_id, role_id, dashboard_id, view_id, expires_at)
+			SELECT 'grant_expired', user_id, role_id, dashboard_id, NULL, expires_at FROM lapsed
+			UNION ALL
+			SELECT 'dashboard_grant_expired', user_id, role_id, dashboard_id, view_id, expires_at FROM cascaded
+			RETURNING action, user_id, role_id, dashboard_id, view_id, expires_at
+		)
+		SELECT a.user_id, ro.name, a.dashboard_id, a.view_id, a.expires_at FROM audited a JOIN roles ro ON ro.id = a.role_id
+		WHERE a.action = 'grant_expired'`)
+	if err != nil {
+		return nil, err
+	}
+	grants, err = scanLapsedGrants(rows, grants)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// appends the grants read from rows, closing them
+func scanLapsedGrants(rows *sql.Rows, grants []*models.ExpiringGrant) ([]*models.ExpiringGrant, error) {
+	defer rows.Close()
+	for rows.Next() {
+		grant := &models.ExpiringGrant{}
+		err := rows.Scan(&grant.UserID, &grant.RoleName, &grant.DashID, &grant.ViewID, &grant.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
+
+// Count grants that have not lapsed, keyed by scope: dashboard, view or folder
+func (r *RoleRepository) CountActiveGrants(ctx context.Context) (map[string]int64, error) {
+	var dashs, views, folders int64
+	err := r.Conn.Conn.QueryRowContext(ctx, `SELECT
+		(SELECT COUNT(*) FROM user_role_dashboard WHERE expires_at IS NULL OR expires_at > NOW()),
+		(SELECT COUNT(*) FROM user_role_view WHERE expires_at IS NULL OR expires_at > NOW()),
+		(SELECT COUNT(*) FROM user_role_folder)`).Scan(&dashs, &views, &folders)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int64{"dashboard": dashs, "view": views, "folder": folders}, nil
+}
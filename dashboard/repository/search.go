@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"backend/dashboard/db"
+	"backend/dashboard/models"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Contains full-text search over dashboards and views
+type SearchRepository struct {
+	Conn *db.DashboardDb
+	L    logrus.FieldLogger
+}
+
+// Returns a new instance of SearchRepository
+func NewSearchRepository(conn *db.DashboardDb, l logrus.FieldLogger) *SearchRepository {
+	return &SearchRepository{conn, l}
+}
+
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5"
+
+// dashboards and views the user can read that match the query $2, of type $3 unless empty.
+// Readability goes through dashboard_perms and view_perms, so trashed items and items
+// without a grant never match.
+const searchMatches = `WITH q AS (SELECT websearch_to_tsquery('english', $2) AS query),
+matches AS (
+	SELECT 'dashboard' AS type, d.id, d.id AS dash_id, d.name, d.description, ts_rank(d.search_vector, q.query) AS rank
+	FROM dashboard d, q
+	WHERE d.search_vector @@ q.query
+		AND EXISTS (SELECT 1 FROM dashboard_perms p WHERE p.dash_id = d.id AND p.user_id = $1 AND p.perm_name = 'read')
+	UNION ALL
+	SELECT 'view' AS type, v.id, v.dashboard_id, v.name, v.description, ts_rank(v.search_vector, q.query) AS rank
+	FROM view v, q
+	WHERE v.search_vector @@ q.query
+		AND EXISTS (SELECT 1 FROM view_perms p WHERE p.view_id = v.id AND p.user_id = $1 AND p.perm_name = 'read')
+)
+`
+
+// a page of matches with highlighted names and descriptions. Both are HTML escaped before
+// the marks go in, the query as well so it reads the text the same way; the parser takes
+// the entities for single tokens so marks never split them.
+var searchQuery = searchMatches + `SELECT m.type, m.id, m.dash_id, m.name,
+	ts_headline('english', ` + escapeHTML("m.name") + `, h.query, $6),
+	ts_headline('english', ` + escapeHTML("m.description") + `, h.query, $6),
+	m.rank
+FROM matches m, (SELECT websearch_to_tsquery('english', ` + escapeHTML("$2") + `) AS query) h
+WHERE $3 = '' OR m.type = $3
+ORDER BY m.rank DESC, m.name, m.id
+LIMIT $4 OFFSET $5`
+
+// the number of matches, counted apart from the page so an offset past the end keeps it
+const searchCountQuery = searchMatches + `SELECT COUNT(*) FROM matches m WHERE $3 = '' OR m.type = $3`
+
+// SQL escaping the text expr for HTML
+func escapeHTML(expr string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&quot;"}, {"'", "&#39;"}} {
+		expr = fmt.Sprintf("replace(%s, '%s', '%s')", expr, strings.ReplaceAll(r[0], "'", "''"), r[1])
+	}
+	return expr
+}
+
+// Search dashboards and views readable by the user, best matches first.
+// Returns the requested page and the total number of matches.
+func (repo *SearchRepository) Search(ctx context.Context, userId uuid.UUID, opts *models.SearchOptions) ([]*models.SearchResult, int, error) {
+	var total int
+	err := repo.Conn.Conn.QueryRowContext(ctx, searchCountQuery, userId, opts.Query, opts.Type).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repo.Conn.Conn.QueryContext(ctx, searchQuery, userId, opts.Query, opts.Type, opts.Limit, opts.Offset, headlineOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := make([]*models.SearchResult, 0)
+	for rows.Next() {
+		res := &models.SearchResult{}
+		err := rows.Scan(&res.Type, &res.ID, &res.DashID, &res.Name, &res.Headline, &res.Snippet, &res.Rank)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, res)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"backend/dashboard/db"
+	"backend/dashboard/models"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Contains methods for public share links of dashboards and views
+type ShareRepository struct {
+	Conn *db.DashboardDb
+	L    logrus.FieldLogger
+}
+
+// Returns a new instance of ShareRepository
+func NewShareRepository(conn *db.DashboardDb, l logrus.FieldLogger) *ShareRepository {
+	return &ShareRepository{conn, l}
+}
+
+const shareLinkColumns = "id, dashboard_id, view_id, password_hash IS NOT NULL, expires_at, created_by, created_at, revoked_at, last_used_at"
+
+func scanShareLink(row rowScanner) (*models.ShareLink, error) {
+	link := &models.ShareLink{}
+	err := row.Scan(&link.ID, &link.DashID, &link.ViewID, &link.HasPassword, &link.ExpiresAt, &link.CreatedBy, &link.CreatedAt, &link.RevokedAt, &link.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// Add a share link with the hash of its token and of its password, if any
+func (repo *ShareRepository) AddShareLink(ctx context.Context, link *models.ShareLink, tokenHash string, passwordHash *string) error {
+	return repo.Conn.Conn.QueryRowContext(ctx, `INSERT INTO share_link (dashboard_id, view_id, token_hash, password_hash, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`, link.DashID, link.ViewID, tokenHash, passwordHash, link.ExpiresAt, link.CreatedBy).
+		Scan(&link.ID, &link.CreatedAt)
+}
+
+// Get a share link by id
+func (repo *ShareRepository) GetShareLink(ctx context.Context, id uuid.UUID) (*models.ShareLink, error) {
+	return scanShareLink(repo.Conn.Conn.QueryRowContext(ctx, "SELECT "+shareLinkColumns+" FROM share_link WHERE id = $1", id))
+}
+
+// Get all share links of a dashboard and its views, newest first
+func (repo *ShareRepository) GetShareLinksForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.ShareLink, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, "SELECT "+shareLinkColumns+" FROM share_link WHERE dashboard_id = $1 ORDER BY created_at DESC", dashId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	links := make([]*models.ShareLink, 0)
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// Get a usable share link by the hash of its token, along with its password hash.
+// Revoked and expired links and links to trashed dashboards or views are not found.
+func (repo *ShareRepository) GetActiveShareLinkByToken(ctx context.Context, tokenHash string) (*models.ShareLink, *string, error) {
+	link := &models.ShareLink{}
+	var passwordHash *string
+	err := repo.Conn.Conn.QueryRowContext(ctx, `SELECT s.id, s.dashboard_id, s.view_id, s.password_hash, s.expires_at, s.created_by, s.created_at
+		FROM share_link s
+		JOIN dashboard d ON d.id = s.dashboard_id
+		LEFT JOIN view v ON v.id = s.view_id
+		WHERE s.token_hash = $1 AND s.revoked_at IS NULL AND (s.expires_at IS NULL OR s.expires_at > NOW())
+		AND d.deleted_at IS NULL AND v.deleted_at IS NULL`, tokenHash).
+		Scan(&link.ID, &link.DashID, &link.ViewID, &passwordHash, &link.ExpiresAt, &link.CreatedBy, &link.CreatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	link.HasPassword = passwordHash != nil
+	return link, passwordHash, nil
+}
+
+// Record that a share link was used
+func (repo *ShareRepository) TouchShareLink(ctx context.Context, id uuid.UUID) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE share_link SET last_used_at = NOW() WHERE id = $1", id)
+	return err
+}
+
+// Revoke a share link. Revoking twice is a no-op.
+func (repo *ShareRepository) RevokeShareLink(ctx context.Context, id uuid.UUID) error {
+	res, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE share_link SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -4,19 +4,22 @@ import (
 	"backend/dashboard/db"
 	"backend/dashboard/models"
 	"context"
-	"log"
+	"database/sql"
+	"encoding/json"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
 )
 
 // Contains methods for interacting with the dashaboard in database
 type ViewRepository struct {
 	Conn *db.DashboardDb
-	L    *log.Logger
+	L    logrus.FieldLogger
 }
 
 // Returns a new instance of ViewRepository
-func NewViewRepository(conn *db.DashboardDb, l *log.Logger) *ViewRepository {
+func NewViewRepository(conn *db.DashboardDb, l logrus.FieldLogger) *ViewRepository {
 	return &ViewRepository{conn, l}
 }
 
@@ -29,14 +32,14 @@ func (repo *ViewRepository) AddView(ctx context.Context, view *models.View, user
 	defer tx.Rollback()
 
 	var uuid uuid.UUID
-	err = tx.QueryRow("INSERT INTO view (dashboard_id, name, description) VALUES ($1, $2, $3) RETURNING id", view.DashID, view.Name, view.Description).Scan(&uuid)
+	err = tx.QueryRowContext(ctx, "INSERT INTO view (dashboard_id, name, description, config) VALUES ($1, $2, $3, $4) RETURNING id", view.DashID, view.Name, view.Description, configOrEmpty(view.Config)).Scan(&uuid)
 	if err != nil {
 		return err
 	}
 	view.ID = uuid
 
 	// assign the user as admin on new view
-	_, err = tx.Exec("INSERT INTO user_role_view (view_id, user_id, role_id) VALUES ($1, $2, $3)", view.ID, userId, 1)
+	_, err = tx.ExecContext(ctx, "INSERT INTO user_role_view (view_id, user_id, role_id) VALUES ($1, $2, $3)", view.ID, userId, 1)
 	if err != nil {
 		return err
 	}
@@ -45,9 +48,21 @@ func (repo *ViewRepository) AddView(ctx context.Context, view *models.View, user
 }
 
 // Get view by id
-func (repo *ViewRepository) GetView(viewId, userId uuid.UUID) (*models.View, error) {
+func (repo *ViewRepository) GetView(ctx context.Context, viewId, userId uuid.UUID) (*models.View, error) {
 	view := &models.View{}
-	err := repo.Conn.Conn.QueryRow("SELECT view_id, view_name, view_desc FROM view_pemrs WHERE view_id = $1 AND user_id=$2 AND perm_name='read'", viewId, userId).Scan(&view.ID, &view.Name, &view.Description)
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT vp.view_id, vp.dash_id, vp.view_name, vp.view_desc, v.config FROM view_perms vp JOIN view v ON v.id = vp.view_id WHERE vp.view_id = $1 AND vp.user_id=$2 AND vp.perm_name='read'", viewId, userId).
+		Scan(&view.ID, &view.DashID, &view.Name, &view.Description, &view.Config)
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// Get a view that is not in trash by id, regardless of the user
+func (repo *ViewRepository) GetViewById(ctx context.Context, viewId uuid.UUID) (*models.View, error) {
+	view := &models.View{}
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT v.id, v.dashboard_id, v.name, v.description, v.config FROM view v JOIN dashboard d ON d.id = v.dashboard_id WHERE v.id = $1 AND v.deleted_at IS NULL AND d.deleted_at IS NULL", viewId).
+		Scan(&view.ID, &view.DashID, &view.Name, &view.Description, &view.Config)
 	if err != nil {
 		return nil, err
 	}
@@ -55,8 +70,8 @@ func (repo *ViewRepository) GetView(viewId, userId uuid.UUID) (*models.View, err
 }
 
 // Get all views attached to a particular dashboard
-func (repo *ViewRepository) GetViewsByDashId(dashId uuid.UUID) ([]*models.View, error) {
-	rows, err := repo.Conn.Conn.Query("SELECT id, name, description FROM views WHERE dashboard_id = $1", dashId)
+func (repo *ViewRepository) GetViewsByDashId(ctx context.Context, dashId uuid.UUID) ([]*models.View, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, "SELECT id, dashboard_id, name, description, config FROM view WHERE dashboard_id = $1 AND deleted_at IS NULL", dashId)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +79,7 @@ func (repo *ViewRepository) GetViewsByDashId(dashId uuid.UUID) ([]*models.View,
 	views := []*models.View{}
 	for rows.Next() {
 		view := &models.View{}
-		err := rows.Scan(&view.ID, &view.Name, &view.Description)
+		err := rows.Scan(&view.ID, &view.DashID, &view.Name, &view.Description, &view.Config)
 		if err != nil {
 			return nil, err
 		}
@@ -74,8 +89,8 @@ func (repo *ViewRepository) GetViewsByDashId(dashId uuid.UUID) ([]*models.View,
 }
 
 // Get all views attached to a particular dashboard for a particular user
-func (repo *ViewRepository) GetViewsByDashIdForUser(dashId, userId uuid.UUID) ([]*models.View, error) {
-	rows, err := repo.Conn.Conn.Query("SELECT view_id, view_name, view_desc FROM view_perms WHERE dash_id = $1 AND user_id = $2 AND perm_name='read'", dashId, userId)
+func (repo *ViewRepository) GetViewsByDashIdForUser(ctx context.Context, dashId, userId uuid.UUID) ([]*models.View, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, "SELECT vp.view_id, vp.dash_id, vp.view_name, vp.view_desc, v.config FROM view_perms vp JOIN view v ON v.id = vp.view_id WHERE vp.dash_id = $1 AND vp.user_id = $2 AND vp.perm_name='read'", dashId, userId)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +98,7 @@ func (repo *ViewRepository) GetViewsByDashIdForUser(dashId, userId uuid.UUID) ([
 	views := []*models.View{}
 	for rows.Next() {
 		view := &models.View{}
-		err := rows.Scan(&view.ID, &view.Name, &view.Description)
+		err := rows.Scan(&view.ID, &view.DashID, &view.Name, &view.Description, &view.Config)
 		if err != nil {
 			return nil, err
 		}
@@ -92,20 +107,118 @@ func (repo *ViewRepository) GetViewsByDashIdForUser(dashId, userId uuid.UUID) ([
 	return views, nil
 }
 
+// Get views of many dashboards a user can read in one query, grouped by dashboard id
+func (repo *ViewRepository) GetViewsByDashIdsForUser(ctx context.Context, dashIds []uuid.UUID, userId uuid.UUID) (map[uuid.UUID][]*models.View, error) {
+	views := map[uuid.UUID][]*models.View{}
+	if len(dashIds) == 0 {
+		return views, nil
+	}
+	ids := make([]string, 0, len(dashIds))
+	for _, id := range dashIds {
+		ids = append(ids, id.String())
+	}
+	rows, err := repo.Conn.Conn.QueryContext(ctx, "SELECT vp.view_id, vp.dash_id, vp.view_name, vp.view_desc, v.config FROM view_perms vp JOIN view v ON v.id = vp.view_id WHERE vp.dash_id = ANY($1::uuid[]) AND vp.user_id = $2 AND vp.perm_name='read' ORDER BY v.created_at", pq.Array(ids), userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		view := &models.View{}
+		err := rows.Scan(&view.ID, &view.DashID, &view.Name, &view.Description, &view.Config)
+		if err != nil {
+			return nil, err
+		}
+		views[view.DashID] = append(views[view.DashID], view)
+	}
+	return views, rows.Err()
+}
+
 // Update view content by id
-func (repo *ViewRepository) UpdateView(view *models.View) error {
-	_, err := repo.Conn.Conn.Exec("UPDATE views SET name = $1, description = $2 WHERE id = $3", view.Name, view.Description, view.ID)
+func (repo *ViewRepository) UpdateView(ctx context.Context, view *models.View) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE view SET name = $1, description = $2 WHERE id = $3 AND deleted_at IS NULL", view.Name, view.Description, view.ID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// Delete view by id
-func (repo *ViewRepository) DeleteView(id uuid.UUID) error {
-	_, err := repo.Conn.Conn.Exec("DELETE FROM views WHERE id = $1", id)
+// Move view with given id to trash
+func (repo *ViewRepository) TrashView(ctx context.Context, id, userId uuid.UUID) error {
+	res, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE view SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL", id, userId)
+	if err != nil {
+		return err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Get a trashed view by id
+func (repo *ViewRepository) GetTrashedView(ctx context.Context, id uuid.UUID) (*models.View, error) {
+	view := &models.View{}
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT id, dashboard_id, name, description, deleted_at, deleted_by FROM view WHERE id = $1 AND deleted_at IS NOT NULL", id).
+		Scan(&view.ID, &view.DashID, &view.Name, &view.Description, &view.DeletedAt, &view.DeletedBy)
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// Get all trashed views on which the user holds the given permission.
+// Views of trashed dashboards are restored along with their dashboard and are not listed.
+func (repo *ViewRepository) GetTrashedViewsForUser(ctx context.Context, userId uuid.UUID, permName string) ([]*models.View, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, `SELECT v.id, v.dashboard_id, v.name, v.description, v.deleted_at, v.deleted_by FROM view v
+		JOIN dashboard d ON d.id = v.dashboard_id
+		WHERE v.deleted_at IS NOT NULL AND d.deleted_at IS NULL AND EXISTS (
+			SELECT 1 FROM user_role_view urv
+			JOIN role_has_permissions rhp ON rhp.role_id = urv.role_id
+			JOIN permissions p ON p.id = rhp.permission_id
+			WHERE urv.view_id = v.id AND urv.user_id = $1 AND p.name = $2)
+		ORDER BY v.deleted_at DESC`, userId, permName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	views := []*models.View{}
+	for rows.Next() {
+		view := &models.View{}
+		err := rows.Scan(&view.ID, &view.DashID, &view.Name, &view.Description, &view.DeletedAt, &view.DeletedBy)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return views, nil
+}
+
+// Take view with given id out of trash
+func (repo *ViewRepository) RestoreView(ctx context.Context, id uuid.UUID) error {
+	res, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE view SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL", id)
 	if err != nil {
 		return err
 	}
+	cnt, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if cnt == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
+
+// view config is stored as jsonb and defaults to an empty object
+func configOrEmpty(config json.RawMessage) string {
+	if len(config) == 0 {
+		return "{}"
+	}
+	return string(config)
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"backend/dashboard/db"
+	"backend/dashboard/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// Contains methods for webhooks and the queue of deliveries to them
+type WebhookRepository struct {
+	Conn *db.DashboardDb
+	L    logrus.FieldLogger
+}
+
+// Returns a new instance of WebhookRepository
+func NewWebhookRepository(conn *db.DashboardDb, l logrus.FieldLogger) *WebhookRepository {
+	return &WebhookRepository{conn, l}
+}
+
+const webhookColumns = "id, dashboard_id, url, events, active, created_by, created_at"
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	err := row.Scan(&hook.ID, &hook.DashID, &hook.URL, pq.Array(&hook.Events), &hook.Active, &hook.CreatedBy, &hook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, created_at, delivered_at`
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Add a webhook, filling in its id and creation time
+func (repo *WebhookRepository) AddWebhook(ctx context.Context, hook *models.Webhook) error {
+	return repo.Conn.Conn.QueryRowContext(ctx, `INSERT INTO webhook (dashboard_id, url, secret, events, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		hook.DashID, hook.URL, hook.Secret, pq.Array(hook.Events), hook.Active, hook.CreatedBy).
+		Scan(&hook.ID, &hook.CreatedAt)
+}
+
+// Get a webhook by id, without its secret
+func (repo *WebhookRepository) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	return scanWebhook(repo.Conn.Conn.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE id = $1", id))
+}
+
+// Get webhooks of dashboards on which the user has the permission, and the global ones
+// when includeGlobal is set, newest first
+func (repo *WebhookRepository) GetWebhooksForUser(ctx context.Context, userId uuid.UUID, permName string, includeGlobal bool) ([]*models.Webhook, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, "SELECT "+webhookColumns+` FROM webhook w
+		WHERE (w.dashboard_id IS NULL AND $3)
+		OR EXISTS (SELECT 1 FROM dashboard_perms p WHERE p.dash_id = w.dashboard_id AND p.user_id = $1 AND p.perm_name = $2)
+		ORDER BY w.created_at DESC`, userId, permName, includeGlobal)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hooks := make([]*models.Webhook, 0)
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// Update the url, events and state of a webhook
+func (repo *WebhookRepository) UpdateWebhook(ctx context.Context, hook *models.Webhook) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, "UPDATE webhook SET url = $2, events = $3, active = $4 WHERE id = $1",
+		hook.ID, hook.URL, pq.Array(hook.Events), hook.Active)
+	return err
+}
+
+// Delete a webhook along with its deliveries
+func (repo *WebhookRepository) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, "DELETE FROM webhook WHERE id = $1", id)
+	return err
+}
+
+// Queue the event for every active webhook subscribed to its type, either on its dashboard
+// or globally. Webhooks on the dashboard are skipped once their creator cannot read it,
+// save for its deletion as trashed dashboards leave dashboard_perms, and get idsOnly instead of payload for events about a view their
+// creator cannot read. Returns the number of deliveries queued.
+func (repo *WebhookRepository) EnqueueDeliveries(ctx context.Context, e *models.Event, payload, idsOnly []byte) (int64, error) {
+	res, err := repo.Conn.Conn.ExecContext(ctx, `INSERT INTO webhook_delivery (webhook_id, event_id, event_type, payload)
+		SELECT w.id, $1, $2, CASE WHEN w.dashboard_id IS NULL OR $5::uuid IS NULL
+			OR EXISTS (SELECT 1 FROM view_perms p WHERE p.view_id = $5 AND p.user_id = w.created_by AND p.perm_name = 'read')
+			THEN $3::jsonb ELSE $6::jsonb END
+		FROM webhook w
+		WHERE w.active AND $2 = ANY(w.events) AND (w.dashboard_id IS NULL OR (w.dashboard_id = $4
+			AND ($2 = $7 OR EXISTS (SELECT 1 FROM dashboard_perms p WHERE p.dash_id = w.dashboard_id AND p.user_id = w.created_by AND p.perm_name = 'read'))))`,
+		e.ID, e.Type, payload, e.DashID, e.ViewID, idsOnly, models.EventDashDeleted)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Claim up to limit pending deliveries that are due, counting an attempt on each. Claimed
+// deliveries are not due again until lease passes, so other instances skip them while
+// they are being sent.
+func (repo *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	rows, err := repo.Conn.Conn.QueryContext(ctx, `UPDATE webhook_delivery d
+		SET attempts = d.attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM webhook w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT q.id FROM webhook_delivery q JOIN webhook qw ON qw.id = q.webhook_id
+			WHERE q.status = 'pending' AND q.next_attempt_at <= NOW() AND qw.active
+			ORDER BY q.next_attempt_at LIMIT $1
+			FOR UPDATE OF q SKIP LOCKED)
+		RETURNING d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+			d.last_status_code, d.last_error, d.created_at, d.delivered_at, w.url, w.secret`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		d := &models.WebhookDelivery{}
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Record the outcome of an attempt. A pending delivery is retried after retryIn.
+func (repo *WebhookRepository) FinishDelivery(ctx context.Context, id uuid.UUID, status string, statusCode *int, lastErr *string, retryIn time.Duration) error {
+	_, err := repo.Conn.Conn.ExecContext(ctx, `UPDATE webhook_delivery SET status = $2, last_status_code = $3, last_error = $4,
+		next_attempt_at = NOW() + make_interval(secs => $5),
+		delivered_at = CASE WHEN $2 = 'succeeded' THEN NOW() END
+		WHERE id = $1`, id, status, statusCode, lastErr, retryIn.Seconds())
+	return err
+}
+
+// Get a page of deliveries of a webhook, newest first, along with their total count
+func (repo *WebhookRepository) GetDeliveries(ctx context.Context, webhookId uuid.UUID, limit, offset int) ([]*models.WebhookDelivery, int, error) {
+	var total int
+	err := repo.Conn.Conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_delivery WHERE webhook_id = $1", webhookId).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repo.Conn.Conn.QueryContext(ctx, "SELECT "+deliveryColumns+` FROM webhook_delivery WHERE webhook_id = $1
+		ORDER BY created_at DESC, id LIMIT $2 OFFSET $3`, webhookId, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, total, rows.Err()
+}
+
+// Queue the payload of a past delivery of the webhook again as a new delivery
+func (repo *WebhookRepository) Redeliver(ctx context.Context, webhookId, deliveryId uuid.UUID) (*models.WebhookDelivery, error) {
+	return scanDelivery(repo.Conn.Conn.QueryRowContext(ctx, `INSERT INTO webhook_delivery (webhook_id, event_id, event_type, payload)
+		SELECT webhook_id, event_id, event_type, payload FROM webhook_delivery WHERE id = $1 AND webhook_id = $2
+		RETURNING `+deliveryColumns, deliveryId, webhookId))
+}
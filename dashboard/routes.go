@@ -0,0 +1,162 @@
+package main
+
+import (
+	"backend/dashboard/handlers"
+	"backend/health"
+	mw "backend/middlewares"
+	"backend/openapi"
+	"backend/tracing"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Everything the routes of the service are served by
+type routeHandlers struct {
+	checker       *health.Checker
+	metrics       http.Handler
+	rateLimiter   *mw.RateLimiter
+	dash          *handlers.DashHandler
+	trash         *handlers.TrashHandler
+	export        *handlers.ExportHandler
+	comment       *handlers.CommentHandler
+	search        *handlers.SearchHandler
+	folder        *handlers.FolderHandler
+	share         *handlers.ShareHandler
+	accessRequest *handlers.AccessRequestHandler
+	webhook       *handlers.WebhookHandler
+	stream        *handlers.StreamHandler
+	notification  *handlers.NotificationHandler
+}
+
+// Routes of the service. Every route must also be in apiSpec.
+func newRouter(logger logrus.FieldLogger, trustedProxies []mw.IPNet, writeTimeout time.Duration, h routeHandlers) *mux.Router {
+	serveMux := mux.NewRouter()
+	serveMux.Use(tracing.Middleware("dash-service"))
+	serveMux.Use(mw.RealIP(trustedProxies)) //client address from nginx
+	serveMux.Use(mw.RequestID(logger))
+	serveMux.Use(mw.AccessLog)
+	serveMux.Use(mw.Metrics)
+	serveMux.Use(mw.JSONContentHeaders)          //adding content type to all responses
+	serveMux.Use(mw.WriteDeadline(writeTimeout)) //max time to write response to the client
+
+	//probed by docker-compose and orchestrators, without a token
+	serveMux.HandleFunc("/healthz", h.checker.Live).Methods(http.MethodGet)
+	serveMux.HandleFunc("/readyz", h.checker.Ready).Methods(http.MethodGet)
+
+	//scraped by prometheus from inside the network, not proxied by nginx
+	serveMux.Handle("/metrics", h.metrics).Methods(http.MethodGet)
+
+	//description of every route, kept in step by routes_test.go
+	serveMux.Handle("/openapi.json", apiSpec().Handler()).Methods(http.MethodGet)
+
+	//read only routes for share links and embed tokens, reached without logging in
+	publicR := serveMux.PathPrefix("/public").Methods(http.MethodGet).Subrouter()
+	publicR.HandleFunc("/shares/{token}", h.share.OpenShareLink)
+	publicR.HandleFunc("/embed", h.share.OpenEmbed)
+	publicR.Use(h.rateLimiter.Middleware)
+
+	//every other route needs a valid user token
+	apiR := serveMux.NewRoute().Subrouter()
+	apiR.Use(mw.AuthMiddleware)
+	apiR.Use(h.rateLimiter.Middleware)
+
+	//subrouter for post requests
+	postR := apiR.Methods(http.MethodPost).Subrouter()
+	postR.HandleFunc("/dashboard", h.dash.CreateDash)
+	postR.HandleFunc("/view", h.dash.CreateView)
+	postR.HandleFunc("/dashboard/{id}/users", h.dash.AddUserToDash)
+	postR.HandleFunc("/view/{id}/users", h.dash.AddUserToView)
+	postR.HandleFunc("/dashboard/{id}/restore", h.trash.RestoreDash)
+	postR.HandleFunc("/view/{id}/restore", h.trash.RestoreView)
+	postR.HandleFunc("/dashboard/{id}/clone", h.dash.CloneDash)
+	postR.HandleFunc("/templates/{id}/instantiate", h.dash.InstantiateTemplate)
+	postR.HandleFunc("/dashboard/import", h.export.ImportDash)
+	postR.HandleFunc("/dashboard/{id}/comments", h.comment.AddDashComment)
+	postR.HandleFunc("/view/{id}/comments", h.comment.AddViewComment)
+	postR.HandleFunc("/comments/{id}/resolve", h.comment.ResolveComment)
+	postR.HandleFunc("/folders", h.folder.CreateFolder)
+	postR.HandleFunc("/folders/{id}/users", h.folder.AddUserToFolder)
+	postR.HandleFunc("/dashboard/{id}/shares", h.share.CreateShareLink)
+	postR.HandleFunc("/dashboard/{id}/embed-tokens", h.share.CreateEmbedToken)
+	postR.HandleFunc("/dashboard/{id}/access-requests", h.accessRequest.RequestAccess)
+	postR.HandleFunc("/access-requests/{id}/approve", h.accessRequest.Approve)
+	postR.HandleFunc("/access-requests/{id}/deny", h.accessRequest.Deny)
+	postR.HandleFunc("/webhooks", h.webhook.CreateWebhook)
+	postR.HandleFunc("/notifications/read", h.notification.MarkRead)
+	postR.HandleFunc("/notifications/read-all", h.notification.MarkAllRead)
+	postR.HandleFunc("/notifications/{id}/read", h.notification.MarkOneRead)
+	postR.HandleFunc("/webhooks/{id}/deliveries/{deliveryId}/redeliver", h.webhook.Redeliver)
+
+	//subrouter for delete requests
+	deleteR := apiR.Methods(http.MethodDelete).Subrouter()
+	deleteR.HandleFunc("/dashboard/{id}/users", h.dash.DeleteUserFromDash)
+	deleteR.HandleFunc("/view/{id}/users", h.dash.DeleteUserFromView)
+	deleteR.HandleFunc("/dashboard/{id}", h.dash.DeleteDash)
+	deleteR.HandleFunc("/view/{id}", h.dash.DeleteView)
+	deleteR.HandleFunc("/dashboard/{id}/template", h.dash.UnmarkTemplate)
+	deleteR.HandleFunc("/comments/{id}", h.comment.DeleteComment)
+	deleteR.HandleFunc("/comments/{id}/resolve", h.comment.UnresolveComment)
+	deleteR.HandleFunc("/folders/{id}", h.folder.DeleteFolder)
+	deleteR.HandleFunc("/folders/{id}/users", h.folder.DeleteUserFromFolder)
+	deleteR.HandleFunc("/dashboard/{id}/star", h.dash.UnstarDash)
+	deleteR.HandleFunc("/shares/{id}", h.share.RevokeShareLink)
+	deleteR.HandleFunc("/webhooks/{id}", h.webhook.DeleteWebhook)
+
+	//subrouter for patch requests
+	patchR := apiR.Methods(http.MethodPut).Subrouter()
+	patchR.HandleFunc("/dashboard/{id}", h.dash.UpdateDash)
+	patchR.HandleFunc("/view/{id}", h.dash.UpdateView)
+	patchR.HandleFunc("/dashboard/{id}/template", h.dash.MarkTemplate)
+	patchR.HandleFunc("/dashboard/{id}/layout", h.dash.UpdateLayout)
+	patchR.HandleFunc("/comments/{id}", h.comment.UpdateComment)
+	patchR.HandleFunc("/folders/{id}", h.folder.UpdateFolder)
+	patchR.HandleFunc("/dashboard/{id}/tags", h.dash.SetTags)
+	patchR.HandleFunc("/dashboard/{id}/folder", h.dash.MoveDash)
+	patchR.HandleFunc("/dashboard/{id}/star", h.dash.StarDash)
+	patchR.HandleFunc("/webhooks/{id}", h.webhook.UpdateWebhook)
+	patchR.HandleFunc("/notifications/preferences", h.notification.SetPreferences)
+
+	//subrouter for get requests
+	getR := apiR.Methods(http.MethodGet).Subrouter()
+	getR.HandleFunc("/dashboard", h.dash.GetDashs)
+	//fixed paths before /dashboard/{id} so they are not taken as ids
+	getR.HandleFunc("/dashboard/starred", h.dash.GetStarredDashs)
+	getR.HandleFunc("/dashboard/recent", h.dash.GetRecentDashs)
+	getR.HandleFunc("/dashboard/{id}", h.dash.GetDash)
+	getR.HandleFunc("/dashboard/{id}/users", h.dash.GetUsersFromDash)
+	getR.HandleFunc("/dashboard/{id}/export", h.export.ExportDash)
+	getR.HandleFunc("/dashboard/{id}/comments", h.comment.GetDashComments)
+	getR.HandleFunc("/dashboard/{id}/shares", h.share.GetShareLinks)
+	getR.HandleFunc("/dashboard/{id}/access-requests", h.accessRequest.GetAccessRequests)
+	getR.HandleFunc("/dashboard/{id}/events", h.stream.Events)
+	getR.HandleFunc("/access-requests", h.accessRequest.GetMyAccessRequests)
+	getR.HandleFunc("/view/{id}/comments", h.comment.GetViewComments)
+	getR.HandleFunc("/view/{id}", h.dash.GetView)
+	getR.HandleFunc("/roles", h.dash.GetAllRoles)
+	getR.HandleFunc("/trash", h.trash.GetTrash)
+	getR.HandleFunc("/templates", h.dash.GetTemplates)
+	getR.HandleFunc("/search", h.search.Search)
+	getR.HandleFunc("/tags", h.dash.GetTags)
+	getR.HandleFunc("/folders", h.folder.GetFolders)
+	getR.HandleFunc("/folders/{id}", h.folder.GetFolder)
+	getR.HandleFunc("/folders/{id}/users", h.folder.GetUsersFromFolder)
+	getR.HandleFunc("/webhooks", h.webhook.GetWebhooks)
+	getR.HandleFunc("/webhooks/{id}", h.webhook.GetWebhook)
+	getR.HandleFunc("/webhooks/{id}/deliveries", h.webhook.GetDeliveries)
+	getR.HandleFunc("/notifications", h.notification.GetNotifications)
+	getR.HandleFunc("/notifications/unread-count", h.notification.GetUnreadCount)
+	getR.HandleFunc("/notifications/preferences", h.notification.GetPreferences)
+
+	return serveMux
+}
+
+// OpenAPI document of the service
+func apiSpec() *openapi.Document {
+	doc := openapi.New("Dashboard service", "1.0.0", "Dashboards, views and who can access them. Errors carry a stable code, send Accept: application/problem+json to get them as RFC 7807 problem details.")
+	doc.Add(openapi.ServiceRoutes()...)
+	doc.Add(handlers.Routes()...)
+	return doc
+}
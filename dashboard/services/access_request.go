@@ -0,0 +1,216 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"backend/dashboard/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// service for requesting access to dashboards and deciding on requests
+type AccessRequestService struct {
+	ar       *repository.AccessRequestRepository
+	ds       *repository.DashRepository
+	rs       *RoleService
+	notifier Notifier
+	events   *EventBus
+	L        logrus.FieldLogger
+}
+
+// Creates a new instance of AccessRequestService
+func NewAccessRequestService(ar *repository.AccessRequestRepository, d *repository.DashRepository, rs *RoleService, n Notifier, events *EventBus, l logrus.FieldLogger) *AccessRequestService {
+	return &AccessRequestService{ar, d, rs, n, events, l}
+}
+
+// Ask for a role on a dashboard. Everyone who can manage access to it is notified.
+func (s *AccessRequestService) RequestAccess(ctx context.Context, userId, dashId uuid.UUID, role, justification string) (*models.AccessRequest, error) {
+	dash, err := s.ds.GetDash(ctx, dashId)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkRole(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &models.AccessRequest{DashID: dashId, RequesterID: userId, Role: role, Justification: justification}
+	created, err := s.ar.AddAccessRequest(ctx, req)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrInvalidRole
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, er.ErrDuplicateRequest
+	}
+	s.events.Publish(ctx, &models.Event{Type: models.EventAccessRequested, DashID: dashId, ActorID: &userId, Data: req})
+
+	managers, err := s.rs.GetUsersWithPermissionForDashboard(ctx, dashId, perms.ACCESS_MOD)
+	if err != nil {
+		s.L.Printf("Could not find who to notify of access request %s: %v", req.ID, err)
+		return req, nil
+	}
+	for _, manager := range managers {
+		s.notify(ctx, &models.Notification{
+			Type:    models.NotifyAccessRequested,
+			UserID:  manager,
+			DashID:  &dashId,
+			Message: fmt.Sprintf("User %s requested the %s role on %s", userId, role, dash.Name),
+			Data:    req,
+		})
+	}
+	return req, nil
+}
+
+// Get the access requests of a dashboard, filtered by status when given. Needs edit_access.
+func (s *AccessRequestService) GetAccessRequests(ctx context.Context, userId, dashId uuid.UUID, status string) ([]*models.AccessRequest, error) {
+	err := s.checkCanDecide(ctx, userId, dashId)
+	if err != nil {
+		return nil, err
+	}
+	return s.ar.GetAccessRequestsForDashboard(ctx, dashId, status)
+}
+
+// Get the access requests made by a user
+func (s *AccessRequestService) GetMyAccessRequests(ctx context.Context, userId uuid.UUID) ([]*models.AccessRequest, error) {
+	return s.ar.GetAccessRequestsByRequester(ctx, userId)
+}
+
+// Approve a pending request, granting the requested role or role when given.
+// The grant lapses at expiresAt when set. The requester hears of it through the grant.
+func (s *AccessRequestService) Approve(ctx context.Context, userId, requestId uuid.UUID, role string, expiresAt *time.Time, note *string) (*models.AccessRequest, error) {
+	req, err := s.pendingRequest(ctx, userId, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		role = req.Role
+	}
+	err = s.checkRole(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	_, err = checkGrantExpiry(role, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// claim the request before granting so a concurrent decision cannot leave a grant behind
+	err = s.decide(ctx, req, models.AccessRequestApproved, userId, role, note)
+	if err != nil {
+		return nil, err
+	}
+	err = s.rs.AddUserToDash(ctx, req.DashID, req.RequesterID, role, expiresAt, userId)
+	if err != nil {
+		if err := s.ar.ReopenAccessRequest(ctx, req.ID, userId); err != nil {
+			s.L.Printf("Could not reopen access request %s after a failed grant: %v", req.ID, err)
+		}
+		return nil, err
+	}
+	s.events.Publish(ctx, &models.Event{Type: models.EventAccessApproved, DashID: req.DashID, ActorID: &userId, Data: req})
+	return req, nil
+}
+
+// Deny a pending request. The requester is notified.
+func (s *AccessRequestService) Deny(ctx context.Context, userId, requestId uuid.UUID, note *string) (*models.AccessRequest, error) {
+	req, err := s.pendingRequest(ctx, userId, requestId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.decide(ctx, req, models.AccessRequestDenied, userId, "", note)
+	if err != nil {
+		return nil, err
+	}
+	s.events.Publish(ctx, &models.Event{Type: models.EventAccessDenied, DashID: req.DashID, ActorID: &userId, Data: req})
+
+	s.notify(ctx, &models.Notification{
+		Type:    models.NotifyAccessDenied,
+		UserID:  req.RequesterID,
+		DashID:  &req.DashID,
+		Message: "Your request for access was denied",
+		Data:    req,
+	})
+	return req, nil
+}
+
+// loads a request the user may decide on, which must still be pending
+func (s *AccessRequestService) pendingRequest(ctx context.Context, userId, requestId uuid.UUID) (*models.AccessRequest, error) {
+	req, err := s.ar.GetAccessRequest(ctx, requestId)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkCanDecide(ctx, userId, req.DashID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != models.AccessRequestPending {
+		return nil, er.ErrRequestDecided
+	}
+	return req, nil
+}
+
+// records a decision and reflects it in req
+func (s *AccessRequestService) decide(ctx context.Context, req *models.AccessRequest, status string, userId uuid.UUID, role string, note *string) error {
+	err := s.ar.DecideAccessRequest(ctx, req.ID, status, userId, role, note)
+	if err == sql.ErrNoRows {
+		return er.ErrRequestDecided
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	req.Status = status
+	req.DecidedBy = &userId
+	req.DecidedAt = &now
+	req.DecisionNote = note
+	if role != "" {
+		req.GrantedRole = &role
+	}
+	return nil
+}
+
+func (s *AccessRequestService) checkRole(ctx context.Context, role string) error {
+	exists, err := s.rs.RoleExists(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return er.ErrInvalidRole
+	}
+	return nil
+}
+
+func (s *AccessRequestService) checkCanDecide(ctx context.Context, userId, dashId uuid.UUID) error {
+	can, err := s.rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.ACCESS_MOD)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+	return nil
+}
+
+// notifications are best effort, a failure does not undo the action
+func (s *AccessRequestService) notify(ctx context.Context, n *models.Notification) {
+	err := s.notifier.Notify(ctx, n)
+	if err != nil {
+		s.L.Printf("Could not notify %s of %s: %v", n.UserID, n.Type, err)
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"backend/dashboard/repository"
+	"context"
+	"database/sql"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// mentions are written as @ followed by the email of the user, e.g. @jane@example.com
+var mentionRegex = regexp.MustCompile(`(?:^|\s)@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// service to manage comment threads on dashboards and views
+type CommentService struct {
+	cr       *repository.CommentRepository
+	vs       *ViewService
+	rs       *RoleService
+	users    *UserDirectory
+	notifier Notifier
+	L        logrus.FieldLogger
+}
+
+// Creates a new instance of CommentService. Mentioned users are told through n.
+func NewCommentService(cr *repository.CommentRepository, vs *ViewService, rs *RoleService, users *UserDirectory, n Notifier, l logrus.FieldLogger) *CommentService {
+	return &CommentService{cr, vs, rs, users, n, l}
+}
+
+// Get comment threads on a dashboard. Requires read permission.
+func (s *CommentService) GetDashComments(ctx context.Context, userId, dashId uuid.UUID) ([]*models.Comment, error) {
+	can, err := s.rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.READ_PERM)
+	if err != nil {
+		return nil, err
+	}
+	if !can {
+		return nil, er.ErrNoPerm
+	}
+	comments, err := s.cr.GetCommentsForDashboard(ctx, dashId)
+	if err != nil {
+		return nil, err
+	}
+	return threads(comments), nil
+}
+
+// Get comment threads on a view. Requires read permission on the view.
+func (s *CommentService) GetViewComments(ctx context.Context, userId, viewId uuid.UUID) ([]*models.Comment, error) {
+	can, err := s.rs.ExistsPermissionForUserForView(ctx, userId, viewId, perms.READ_PERM)
+	if err != nil {
+		return nil, err
+	}
+	if !can {
+		return nil, er.ErrNoPerm
+	}
+	comments, err := s.cr.GetCommentsForView(ctx, viewId)
+	if err != nil {
+		return nil, err
+	}
+	return threads(comments), nil
+}
+
+// Post a comment on a dashboard, or a reply when parentId is set. Requires comment permission.
+func (s *CommentService) AddDashComment(ctx context.Context, userId, dashId uuid.UUID, parentId *uuid.UUID, body string) (*models.Comment, error) {
+	c := &models.Comment{DashID: dashId, AuthorID: userId, Body: body}
+	return c, s.addComment(ctx, c, parentId)
+}
+
+// Post a comment on a view, or a reply when parentId is set. Requires comment permission on the view.
+func (s *CommentService) AddViewComment(ctx context.Context, userId, viewId uuid.UUID, parentId *uuid.UUID, body string) (*models.Comment, error) {
+	view, err := s.vs.GetView(ctx, viewId, userId)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNoPerm
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &models.Comment{DashID: view.DashID, ViewID: &viewId, AuthorID: userId, Body: body}
+	return c, s.addComment(ctx, c, parentId)
+}
+
+func (s *CommentService) addComment(ctx context.Context, c *models.Comment, parentId *uuid.UUID) error {
+	err := s.checkPerm(ctx, c.AuthorID, c, perms.COMMENT_PERM)
+	if err != nil {
+		return err
+	}
+
+	if parentId != nil {
+		parent, err := s.cr.GetComment(ctx, *parentId)
+		if err == sql.ErrNoRows {
+			return er.ErrInvalidParent
+		}
+		if err != nil {
+			return err
+		}
+		if parent.DashID != c.DashID || !sameView(parent.ViewID, c.ViewID) {
+			return er.ErrInvalidParent
+		}
+		// threads are one level deep, replies to replies join the thread
+		if parent.ParentID != nil {
+			parentId = parent.ParentID
+		}
+		c.ParentID = parentId
+	}
+
+	c.Mentions, err = s.resolveMentions(ctx, c)
+	if err != nil {
+		return err
+	}
+	err = s.cr.AddComment(ctx, c)
+	if err != nil {
+		return err
+	}
+	s.notifyMentions(ctx, c, nil)
+	return nil
+}
+
+// Edit the body of a comment. Only the author can edit, and only while allowed to comment.
+func (s *CommentService) UpdateComment(ctx context.Context, userId, commentId uuid.UUID, body string) (*models.Comment, error) {
+	c, err := s.getOwnComment(ctx, userId, commentId)
+	if err != nil {
+		return nil, err
+	}
+	c.Body = body
+	before := c.Mentions
+	c.Mentions, err = s.resolveMentions(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	err = s.cr.UpdateComment(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyMentions(ctx, c, before)
+	return c, nil
+}
+
+// tells users mentioned in the comment, except the author and those already mentioned before
+func (s *CommentService) notifyMentions(ctx context.Context, c *models.Comment, before []uuid.UUID) {
+	told := map[uuid.UUID]bool{c.AuthorID: true}
+	for _, id := range before {
+		told[id] = true
+	}
+	for _, id := range c.Mentions {
+		if told[id] {
+			continue
+		}
+		told[id] = true
+		err := s.notifier.Notify(ctx, &models.Notification{
+			Type:    models.NotifyMentioned,
+			UserID:  id,
+			DashID:  &c.DashID,
+			ViewID:  c.ViewID,
+			Message: "You were mentioned in a comment",
+			Data:    c,
+		})
+		if err != nil {
+			s.L.Printf("Could not notify %s of mention: %v", id, err)
+		}
+	}
+}
+
+// Delete a comment along with its replies. Only the author can delete, and only while allowed to comment.
+func (s *CommentService) DeleteComment(ctx context.Context, userId, commentId uuid.UUID) error {
+	_, err := s.getOwnComment(ctx, userId, commentId)
+	if err != nil {
+		return err
+	}
+	return s.cr.DeleteComment(ctx, commentId)
+}
+
+// Resolve or reopen the thread a comment belongs to. Requires comment permission.
+func (s *CommentService) SetResolved(ctx context.Context, userId, commentId uuid.UUID, resolved bool) error {
+	c, err := s.cr.GetComment(ctx, commentId)
+	if err == sql.ErrNoRows {
+		return er.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	err = s.checkPerm(ctx, userId, c, perms.COMMENT_PERM)
+	if err != nil {
+		return err
+	}
+
+	threadId := c.ID
+	if c.ParentID != nil {
+		threadId = *c.ParentID
+	}
+	if resolved {
+		return s.cr.SetResolved(ctx, threadId, &userId)
+	}
+	return s.cr.SetResolved(ctx, threadId, nil)
+}
+
+func (s *CommentService) getOwnComment(ctx context.Context, userId, commentId uuid.UUID) (*models.Comment, error) {
+	c, err := s.cr.GetComment(ctx, commentId)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.AuthorID != userId {
+		return nil, er.ErrNotAuthor
+	}
+	err = s.checkPerm(ctx, userId, c, perms.COMMENT_PERM)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checks the permission on the view of the comment, or on its dashboard
+func (s *CommentService) checkPerm(ctx context.Context, userId uuid.UUID, c *models.Comment, perm string) error {
+	var can bool
+	var err error
+	if c.ViewID != nil {
+		can, err = s.rs.ExistsPermissionForUserForView(ctx, userId, *c.ViewID, perm)
+	} else {
+		can, err = s.rs.ExistsPermissionForUserForDashboard(ctx, userId, c.DashID, perm)
+	}
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+	return nil
+}
+
+// finds @email mentions in the body, keeping users that can read what is commented on
+func (s *CommentService) resolveMentions(ctx context.Context, c *models.Comment) ([]uuid.UUID, error) {
+	emails := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionRegex.FindAllStringSubmatch(c.Body, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			emails = append(emails, match[1])
+		}
+	}
+	if len(emails) == 0 {
+		return []uuid.UUID{}, nil
+	}
+
+	users, err := s.users.LookupByEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	mentions := []uuid.UUID{}
+	for _, u := range users {
+		err := s.checkPerm(ctx, u.ID, c, perms.READ_PERM)
+		if err == er.ErrNoPerm {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, u.ID)
+	}
+	return mentions, nil
+}
+
+// groups replies under their root comments, keeping order
+func threads(comments []*models.Comment) []*models.Comment {
+	roots := []*models.Comment{}
+	byId := map[uuid.UUID]*models.Comment{}
+	for _, c := range comments {
+		if c.ParentID == nil {
+			c.Replies = []*models.Comment{}
+			byId[c.ID] = c
+			roots = append(roots, c)
+		}
+	}
+	for _, c := range comments {
+		if c.ParentID != nil {
+			if root, ok := byId[*c.ParentID]; ok {
+				root.Replies = append(root.Replies, c)
+			}
+		}
+	}
+	return roots
+}
+
+func sameView(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -6,70 +6,101 @@ import (
 	"backend/dashboard/perms"
 	"backend/dashboard/repository"
 	"context"
-	"log"
 
-	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 )
 
 // service to manage dashboard, its views and access to them
 type DashService struct {
-	ds  *repository.DashRepository
-	Vs  *ViewService
-	Rs  *RoleService
-	rdb *redis.Client
-	L   *log.Logger
+	ds     DashStore
+	Vs     *ViewService
+	Rs     *RoleService
+	rdb    Cache
+	events *EventBus
+	L      logrus.FieldLogger
 }
 
-// Creates a new instance of DashService
-func NewDashService(r *repository.DashRepository, v *ViewService, rr *RoleService, rdb *redis.Client, l *log.Logger) *DashService {
-	return &DashService{r, v, rr, rdb, l}
+// Creates a new instance of DashService. Changes to dashboards are published on events.
+func NewDashService(r DashStore, v *ViewService, rr *RoleService, rdb Cache, events *EventBus, l logrus.FieldLogger) *DashService {
+	return &DashService{r, v, rr, rdb, events, l}
 }
 
 // Create a new dashboard witb new ID updated in the model
-func (s *DashService) AddDash(dash *models.Dash, userId uuid.UUID) error {
-	return s.ds.AddDash(context.Background(), dash, userId)
+func (s *DashService) AddDash(ctx context.Context, dash *models.Dash, userId uuid.UUID) error {
+	err := s.ds.AddDash(ctx, dash, userId)
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, models.EventDashCreated, dash.ID, userId, dash)
+	return nil
+}
+
+// publish an event about a dashboard caused by the user
+func (s *DashService) publish(ctx context.Context, eventType string, dashId, userId uuid.UUID, data interface{}) {
+	s.events.Publish(ctx, &models.Event{Type: eventType, DashID: dashId, ActorID: &userId, Data: data})
 }
 
 // Get a dashboard with given id
-func (s *DashService) GetDashByIdForUser(userId, dashId uuid.UUID) (*models.Dash, error) {
-	can, err := s.Rs.ExistsPermissionForUserForDashboard(userId, dashId, perms.READ_PERM)
-	s.L.Println("Permission: ", can, "for user: ", userId, "for dashboard: ", dashId)
+func (s *DashService) GetDashByIdForUser(ctx context.Context, userId, dashId uuid.UUID) (*models.Dash, error) {
+	can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.READ_PERM)
 	if err != nil {
 		return nil, err
 	}
 	if !can {
 		return nil, er.ErrNoPerm
 	}
-	dash, err := s.ds.GetDash(dashId)
+	dash, err := s.ds.GetDash(ctx, dashId)
 	if err != nil {
 		return nil, err
 	}
-	dash.Views, err = s.Vs.GetViewsByDashIdForUser(dashId, userId)
+	dash.Views, err = s.Vs.GetViewsByDashIdForUser(ctx, dashId, userId)
 	if err != nil {
 		return nil, err
 	}
 	return dash, err
 }
 
-// Get all dashboards for a user
-func (s *DashService) GetAllDashboardsForUser(userId uuid.UUID) ([]*models.Dash, error) {
-	dashs, err := s.ds.GetAllDashboardsForUser(userId)
+// List a page of dashboards a user can read. Views the user can read are loaded
+// for the whole page in a single query when includeViews is set.
+func (s *DashService) ListDashboardsForUser(ctx context.Context, userId uuid.UUID, opts *models.DashListOptions, includeViews bool) (*models.DashPage, error) {
+	if opts.After != nil && (opts.After.Sort != opts.Sort || opts.After.Desc != opts.Desc) {
+		return nil, er.ErrInvalidCursor
+	}
+
+	dashs, total, err := s.ds.ListDashboardsForUser(ctx, userId, opts)
 	if err != nil {
 		return nil, err
 	}
-	for _, dash := range dashs {
-		dash.Views, err = s.Vs.vR.GetViewsByDashIdForUser(dash.ID, userId)
+
+	page := &models.DashPage{Items: dashs, Total: total}
+	if len(dashs) > opts.Limit {
+		page.Items = dashs[:opts.Limit]
+		page.NextCursor = repository.DashCursor(page.Items[opts.Limit-1], opts).Encode()
+	}
+
+	if includeViews {
+		ids := make([]uuid.UUID, 0, len(page.Items))
+		for _, dash := range page.Items {
+			ids = append(ids, dash.ID)
+		}
+		views, err := s.Vs.vR.GetViewsByDashIdsForUser(ctx, ids, userId)
 		if err != nil {
 			return nil, err
 		}
+		for _, dash := range page.Items {
+			dash.Views = views[dash.ID]
+			if dash.Views == nil {
+				dash.Views = []*models.View{}
+			}
+		}
 	}
-	return dashs, nil
+	return page, nil
 }
 
-// Delete a dashboard with given id
-func (s *DashService) DeleteDashById(userId, dashId uuid.UUID) error {
-	can, err := s.Rs.ExistsPermissionForUserForDashboard(userId, dashId, perms.DELETE_PERM)
+// Move a dashboard with given id to trash
+func (s *DashService) DeleteDashById(ctx context.Context, userId, dashId uuid.UUID) error {
+	can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.DELETE_PERM)
 	if err != nil {
 		return err
 	}
@@ -77,11 +108,78 @@ func (s *DashService) DeleteDashById(userId, dashId uuid.UUID) error {
 		return er.ErrNoPerm
 	}
 
-	return s.ds.DeleteDash(dashId)
+	err = s.ds.TrashDash(ctx, dashId, userId)
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, models.EventDashDeleted, dashId, userId, nil)
+	return nil
 }
 
-func (s *DashService) UpdateDash(userId, dashId uuid.UUID, dash *models.Dash) error {
-	can, err := s.Rs.ExistsPermissionForUserForDashboard(userId, dashId, perms.WRITE_PERM)
+// Deep copy a dashboard and the views the user can read. The user becomes admin of the copy.
+// Copying grants of other users requires permission to manage access on the source.
+func (s *DashService) CloneDash(ctx context.Context, userId, dashId uuid.UUID, name string, includeGrants bool) (*models.Dash, error) {
+	src, err := s.GetDashByIdForUser(ctx, userId, dashId)
+	if err != nil {
+		return nil, err
+	}
+
+	if includeGrants {
+		can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.ACCESS_MOD)
+		if err != nil {
+			return nil, err
+		}
+		if !can {
+			return nil, er.ErrNoPerm
+		}
+	}
+
+	if name == "" {
+		name = "Copy of " + src.Name
+	}
+	clone := &models.Dash{Name: name, Description: src.Description, Views: src.Views, Layout: src.Layout}
+	err = s.ds.CloneDash(ctx, dashId, clone, userId, includeGrants)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, models.EventDashCreated, clone.ID, userId, clone)
+	return clone, nil
+}
+
+// Replace the layout of a dashboard. Every item must place a view of the dashboard.
+func (s *DashService) UpdateLayout(ctx context.Context, userId, dashId uuid.UUID, layout []*models.LayoutItem) error {
+	can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.WRITE_PERM)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+
+	views, err := s.Vs.vR.GetViewsByDashId(ctx, dashId)
+	if err != nil {
+		return err
+	}
+	known := map[uuid.UUID]bool{}
+	for _, v := range views {
+		known[v.ID] = true
+	}
+	for _, item := range layout {
+		if !known[item.ViewID] {
+			return er.ErrInvalidLayout
+		}
+	}
+
+	err = s.ds.UpdateLayout(ctx, dashId, layout)
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, models.EventDashUpdated, dashId, userId, map[string]interface{}{"layout": layout})
+	return nil
+}
+
+func (s *DashService) UpdateDash(ctx context.Context, userId, dashId uuid.UUID, dash *models.Dash) error {
+	can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.WRITE_PERM)
 	if err != nil {
 		return err
 	}
@@ -90,5 +188,10 @@ func (s *DashService) UpdateDash(userId, dashId uuid.UUID, dash *models.Dash) er
 	}
 
 	dash.ID = dashId
-	return s.ds.UpdateDash(dash)
+	err = s.ds.UpdateDash(ctx, dash)
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, models.EventDashUpdated, dashId, userId, dash)
+	return nil
 }
@@ -0,0 +1,356 @@
+package services_test
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"backend/dashboard/repository/memory"
+	"backend/dashboard/services"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Dashboard, view and role services over an in-memory store, recording the events they
+// publish. The store runs on a clock moved with advance.
+type fixture struct {
+	store  *memory.Store
+	cache  *memory.Cache
+	roles  *services.RoleService
+	views  *services.ViewService
+	dashs  *services.DashService
+	bus    *services.EventBus
+	events []*models.Event
+	now    time.Time
+}
+
+func newFixture() *fixture {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+
+	f := &fixture{store: memory.NewStore(), cache: memory.NewCache(), now: time.Now()}
+	f.store.Now = func() time.Time { return f.now }
+	f.bus = services.NewEventBus(l)
+	f.bus.Subscribe(func(ctx context.Context, e *models.Event) { f.events = append(f.events, e) })
+
+	f.roles = services.NewRoleService(memory.NewRoleRepository(f.store), f.cache, f.bus, l)
+	f.views = services.NewViewService(memory.NewViewRepository(f.store), f.roles, f.bus, l)
+	f.dashs = services.NewDashService(memory.NewDashRepository(f.store), f.views, f.roles, f.cache, f.bus, l)
+	return f
+}
+
+func (f *fixture) advance(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+// Dashboard created by owner, who becomes its admin
+func (f *fixture) addDash(t *testing.T, owner uuid.UUID, name string) *models.Dash {
+	t.Helper()
+	dash := &models.Dash{Name: name}
+	err := f.dashs.AddDash(context.Background(), dash, owner)
+	if err != nil {
+		t.Fatalf("AddDash: %v", err)
+	}
+	return dash
+}
+
+// View on a dashboard created by owner, who becomes its admin
+func (f *fixture) addView(t *testing.T, owner, dashId uuid.UUID, name string) *models.View {
+	t.Helper()
+	view := &models.View{DashID: dashId, Name: name}
+	err := f.views.AddView(context.Background(), view, owner)
+	if err != nil {
+		t.Fatalf("AddView: %v", err)
+	}
+	return view
+}
+
+func (f *fixture) canDash(t *testing.T, userId, dashId uuid.UUID, perm string) bool {
+	t.Helper()
+	can, err := f.roles.ExistsPermissionForUserForDashboard(context.Background(), userId, dashId, perm)
+	if err != nil {
+		t.Fatalf("ExistsPermissionForUserForDashboard: %v", err)
+	}
+	return can
+}
+
+// Types of the events published so far
+func (f *fixture) eventTypes() []string {
+	types := []string{}
+	for _, e := range f.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestAddDashMakesCreatorAdmin(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner := uuid.New()
+	dash := f.addDash(t, owner, "sales")
+
+	roles, err := f.roles.GetRolesForUserForDashboard(ctx, owner, dash.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 1 || roles[0].Name != "admin" || len(roles[0].Permissions) != 5 {
+		t.Fatalf("roles of the creator: %+v, want admin with every permission", roles)
+	}
+	if got := f.eventTypes(); len(got) != 1 || got[0] != models.EventDashCreated {
+		t.Errorf("events %v, want %s", got, models.EventDashCreated)
+	}
+
+	_, err = f.dashs.GetDashByIdForUser(ctx, uuid.New(), dash.ID)
+	if !errors.Is(err, er.ErrNoPerm) {
+		t.Errorf("reading as another user: got %v, want %v", err, er.ErrNoPerm)
+	}
+}
+
+func TestDeleteDash(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, editor := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	view := f.addView(t, owner, dash.ID, "revenue")
+	err := f.roles.AddUserToDash(ctx, dash.ID, editor, "editor", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.dashs.DeleteDashById(ctx, editor, dash.ID)
+	if !errors.Is(err, er.ErrNoPerm) {
+		t.Fatalf("deleting as editor: got %v, want %v", err, er.ErrNoPerm)
+	}
+	err = f.dashs.DeleteDashById(ctx, owner, dash.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//nothing in trash grants any permission, the views of the dashboard included
+	for _, perm := range []string{perms.READ_PERM, perms.DELETE_PERM} {
+		if f.canDash(t, owner, dash.ID, perm) {
+			t.Errorf("admin still holds %s on a dashboard in trash", perm)
+		}
+	}
+	can, err := f.roles.ExistsPermissionForUserForView(ctx, owner, view.ID, perms.READ_PERM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if can {
+		t.Error("admin can still read a view of a dashboard in trash")
+	}
+	page, err := f.dashs.ListDashboardsForUser(ctx, owner, &models.DashListOptions{Sort: models.SortByName, Limit: 10}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 0 {
+		t.Errorf("listed %d dashboards, want none", page.Total)
+	}
+}
+
+func TestListDashboardsPages(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	user, other := uuid.New(), uuid.New()
+	for _, name := range []string{"c", "a", "d", "b"} {
+		f.addDash(t, user, name)
+	}
+	f.addDash(t, other, "e")
+
+	names := []string{}
+	opts := &models.DashListOptions{Sort: models.SortByName, Limit: 3}
+	for {
+		page, err := f.dashs.ListDashboardsForUser(ctx, user, opts, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 4 {
+			t.Errorf("total %d, want 4", page.Total)
+		}
+		for _, dash := range page.Items {
+			names = append(names, dash.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.After, err = models.DecodePageCursor(page.NextCursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(names) != 4 || names[0] != "a" || names[1] != "b" || names[2] != "c" || names[3] != "d" {
+		t.Errorf("listed %v, want [a b c d]", names)
+	}
+}
+
+// Names of the dashboards on every page of the listing, following cursors
+func listAll(t *testing.T, f *fixture, userId uuid.UUID, opts *models.DashListOptions, between func()) []string {
+	t.Helper()
+	names := []string{}
+	for {
+		page, err := f.dashs.ListDashboardsForUser(context.Background(), userId, opts, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) > opts.Limit {
+			t.Fatalf("page of %d dashboards, limit %d", len(page.Items), opts.Limit)
+		}
+		for _, dash := range page.Items {
+			names = append(names, dash.Name)
+		}
+		if page.NextCursor == "" {
+			return names
+		}
+		opts.After, err = models.DecodePageCursor(page.NextCursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if between != nil {
+			between()
+			between = nil
+		}
+	}
+}
+
+func TestListDashboardsTiesAndDesc(t *testing.T) {
+	f := newFixture()
+	user := uuid.New()
+	//created at the same instant, the id breaks the tie
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		f.addDash(t, user, name)
+	}
+	f.advance(time.Minute)
+	f.addDash(t, user, "f")
+
+	names := listAll(t, f, user, &models.DashListOptions{Sort: models.SortByUpdated, Desc: true, Limit: 2}, nil)
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			t.Errorf("%s listed twice in %v", name, names)
+		}
+		seen[name] = true
+	}
+	if len(names) != 6 || names[0] != "f" {
+		t.Errorf("listed %v, want all six with f first", names)
+	}
+}
+
+func TestListDashboardsKeepsPlace(t *testing.T) {
+	f := newFixture()
+	user := uuid.New()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		f.addDash(t, user, name)
+	}
+
+	//dashboards created between pages show up only when they sort after the cursor
+	names := listAll(t, f, user, &models.DashListOptions{Sort: models.SortByName, Limit: 2}, func() {
+		f.addDash(t, user, "aa")
+		f.addDash(t, user, "bb")
+	})
+	if strings.Join(names, ",") != "a,b,bb,c,d" {
+		t.Errorf("listed %v, want [a b bb c d]", names)
+	}
+}
+
+func TestListDashboardsRejectsForeignCursor(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	user := uuid.New()
+	dash := f.addDash(t, user, "a")
+
+	opts := &models.DashListOptions{Sort: models.SortByName, Limit: 1}
+	cursors := []*models.PageCursor{
+		{Sort: models.SortByCreated, Value: "2020-01-01", ID: dash.ID},
+		{Sort: models.SortByName, Desc: true, Value: dash.Name, ID: dash.ID},
+	}
+	for _, c := range cursors {
+		opts.After = c
+		_, err := f.dashs.ListDashboardsForUser(ctx, user, opts, false)
+		if !errors.Is(err, er.ErrInvalidCursor) {
+			t.Errorf("cursor sorted by %s desc %v: got %v, want %v", c.Sort, c.Desc, err, er.ErrInvalidCursor)
+		}
+	}
+}
+
+func TestCloneDashGrants(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, viewer, temp, editor := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	expiresAt := f.now.Add(time.Hour)
+	for user, role := range map[uuid.UUID]string{viewer: "viewer", editor: "editor"} {
+		err := f.roles.AddUserToDash(ctx, dash.ID, user, role, nil, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := f.roles.AddUserToDash(ctx, dash.ID, temp, "viewer", &expiresAt, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.dashs.CloneDash(ctx, editor, dash.ID, "", true)
+	if !errors.Is(err, er.ErrNoPerm) {
+		t.Fatalf("cloning grants as editor: got %v, want %v", err, er.ErrNoPerm)
+	}
+
+	f.advance(2 * time.Hour)
+	clone, err := f.dashs.CloneDash(ctx, owner, dash.ID, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.Name != "Copy of sales" {
+		t.Errorf("clone is named %q", clone.Name)
+	}
+	if !f.canDash(t, viewer, clone.ID, perms.READ_PERM) {
+		t.Error("role of the viewer was not copied")
+	}
+	if f.canDash(t, temp, clone.ID, perms.READ_PERM) {
+		t.Error("lapsed role was copied")
+	}
+}
+
+func TestCopiesKeepLayout(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner := uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	view := f.addView(t, owner, dash.ID, "revenue")
+	err := f.dashs.UpdateLayout(ctx, owner, dash.ID, []*models.LayoutItem{{ViewID: view.ID, X: 1, Y: 2, W: 3, H: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.dashs.MarkTemplate(ctx, owner, dash.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clone, err := f.dashs.CloneDash(ctx, owner, dash.ID, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance, err := f.dashs.InstantiateTemplate(ctx, owner, dash.ID, "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, copied := range []*models.Dash{clone, instance} {
+		got, err := f.dashs.GetDashByIdForUser(ctx, owner, copied.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Views) != 1 || len(got.Layout) != 1 {
+			t.Fatalf("copy %s has %d views and layout %v", got.Name, len(got.Views), got.Layout)
+		}
+		item := got.Layout[0]
+		if item.ViewID != got.Views[0].ID || item.ViewID == view.ID || item.X != 1 || item.Y != 2 || item.W != 3 || item.H != 4 {
+			t.Errorf("copy %s has layout %+v, want the grid of the source on view %s", got.Name, item, got.Views[0].ID)
+		}
+	}
+}
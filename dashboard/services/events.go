@@ -0,0 +1,55 @@
+package services
+
+import (
+	"backend/dashboard/models"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Receives events published on an EventBus
+type Subscriber func(ctx context.Context, e *models.Event)
+
+// In process fan out of events to subscribers such as webhooks
+type EventBus struct {
+	mu   sync.RWMutex
+	subs []Subscriber
+	L    logrus.FieldLogger
+}
+
+// Creates a new instance of EventBus
+func NewEventBus(l logrus.FieldLogger) *EventBus {
+	return &EventBus{L: l}
+}
+
+// Add a subscriber receiving every event published from now on
+func (b *EventBus) Subscribe(sub Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, sub)
+}
+
+// Stamp the event with an id and time and hand it to every subscriber in turn.
+// Subscribers run on the caller's goroutine so they must not block for long.
+// Publishing on a nil bus does nothing.
+func (b *EventBus) Publish(ctx context.Context, e *models.Event) {
+	if b == nil {
+		return
+	}
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	for _, sub := range subs {
+		sub(ctx, e)
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"backend/dashboard/repository"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// service to move dashboards between environments as portable json documents
+type ExportService struct {
+	ds    *DashService
+	dr    *repository.DashRepository
+	users *UserDirectory
+	L     logrus.FieldLogger
+}
+
+// Creates a new instance of ExportService
+func NewExportService(ds *DashService, dr *repository.DashRepository, users *UserDirectory, l logrus.FieldLogger) *ExportService {
+	return &ExportService{ds, dr, users, l}
+}
+
+// Export a dashboard with the views the user can read. Role assignments are included by
+// email when requested, which requires permission to manage access on the dashboard.
+func (s *ExportService) ExportDash(ctx context.Context, userId, dashId uuid.UUID, includeRoles bool) (*models.DashExport, error) {
+	dash, err := s.ds.GetDashByIdForUser(ctx, userId, dashId)
+	if err != nil {
+		return nil, err
+	}
+
+	if includeRoles {
+		can, err := s.ds.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.ACCESS_MOD)
+		if err != nil {
+			return nil, err
+		}
+		if !can {
+			return nil, er.ErrNoPerm
+		}
+	}
+
+	doc := &models.DashExport{
+		Version:    models.ExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Dashboard: &models.ExportedDash{
+			Name:        dash.Name,
+			Description: dash.Description,
+			IsTemplate:  dash.IsTemplate,
+			Variables:   dash.Variables,
+		},
+		Views:  []*models.ExportedView{},
+		Layout: []*models.ExportedLayoutItem{},
+	}
+
+	refs := map[uuid.UUID]string{}
+	for i, v := range dash.Views {
+		ref := fmt.Sprintf("view-%d", i+1)
+		refs[v.ID] = ref
+		doc.Views = append(doc.Views, &models.ExportedView{Ref: ref, Name: v.Name, Description: v.Description, Config: v.Config})
+	}
+	for _, item := range dash.Layout {
+		if ref, ok := refs[item.ViewID]; ok {
+			doc.Layout = append(doc.Layout, &models.ExportedLayoutItem{ViewRef: ref, X: item.X, Y: item.Y, W: item.W, H: item.H})
+		}
+	}
+
+	if !includeRoles {
+		return doc, nil
+	}
+
+	grants, err := s.ds.Rs.GetGrantsForDashboard(ctx, dashId)
+	if err != nil {
+		return nil, err
+	}
+	ids := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, g := range grants {
+		if !seen[g.UserID] {
+			seen[g.UserID] = true
+			ids = append(ids, g.UserID)
+		}
+	}
+	users, err := s.users.LookupByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	emails := map[uuid.UUID]string{}
+	for _, u := range users {
+		emails[u.ID] = u.Email
+	}
+
+	doc.Roles = &models.ExportedRoles{Dashboard: []*models.ExportedGrant{}, Views: []*models.ExportedGrant{}}
+	for _, g := range grants {
+		email, ok := emails[g.UserID]
+		if !ok {
+			s.L.Println("Skipping grant of unknown user", g.UserID)
+			continue
+		}
+		if g.ViewID == nil {
+			doc.Roles.Dashboard = append(doc.Roles.Dashboard, &models.ExportedGrant{Email: email, Role: g.RoleName})
+		} else if ref, ok := refs[*g.ViewID]; ok {
+			doc.Roles.Views = append(doc.Roles.Views, &models.ExportedGrant{ViewRef: ref, Email: email, Role: g.RoleName})
+		}
+	}
+
+	return doc, nil
+}
+
+// Import a dashboard document with fresh ids. The user becomes admin of the dashboard and
+// all views; role assignments are applied for emails known to the auth service. On a dry
+// run the document is validated and the report describes what would be created.
+func (s *ExportService) ImportDash(ctx context.Context, userId uuid.UUID, doc *models.DashExport, dryRun bool) (*models.ImportReport, error) {
+	if doc.Version != models.ExportVersion {
+		return nil, fmt.Errorf("%w: got %d, supported %d", er.ErrUnsupportedExportVersion, doc.Version, models.ExportVersion)
+	}
+
+	dash := &models.Dash{
+		ID:          uuid.New(),
+		Name:        doc.Dashboard.Name,
+		Description: doc.Dashboard.Description,
+		IsTemplate:  doc.Dashboard.IsTemplate,
+		Variables:   doc.Dashboard.Variables,
+		Views:       []*models.View{},
+		Layout:      []*models.LayoutItem{},
+	}
+
+	viewIds := map[string]uuid.UUID{}
+	for _, v := range doc.Views {
+		if _, dup := viewIds[v.Ref]; dup {
+			return nil, fmt.Errorf("%w: duplicate view ref %q", er.ErrInvalidExport, v.Ref)
+		}
+		viewIds[v.Ref] = uuid.New()
+		dash.Views = append(dash.Views, &models.View{ID: viewIds[v.Ref], Name: v.Name, Description: v.Description, Config: v.Config})
+	}
+	for _, item := range doc.Layout {
+		id, ok := viewIds[item.ViewRef]
+		if !ok {
+			return nil, fmt.Errorf("%w: layout refers to unknown view %q", er.ErrInvalidExport, item.ViewRef)
+		}
+		dash.Layout = append(dash.Layout, &models.LayoutItem{ViewID: id, X: item.X, Y: item.Y, W: item.W, H: item.H})
+	}
+
+	report := &models.ImportReport{
+		DryRun:           dryRun,
+		DashboardName:    dash.Name,
+		Views:            len(dash.Views),
+		Grants:           []*models.ExportedGrant{},
+		UnresolvedEmails: []string{},
+	}
+
+	grants, err := s.resolveGrants(ctx, userId, doc.Roles, viewIds, report)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	err = s.dr.ImportDash(ctx, dash, userId, grants)
+	if err != nil {
+		return nil, err
+	}
+	s.ds.publish(ctx, models.EventDashCreated, dash.ID, userId, dash)
+	report.Dashboard = dash
+	return report, nil
+}
+
+// validates exported role assignments and maps them to users of this environment
+func (s *ExportService) resolveGrants(ctx context.Context, userId uuid.UUID, roles *models.ExportedRoles, viewIds map[string]uuid.UUID, report *models.ImportReport) ([]*models.Grant, error) {
+	grants := []*models.Grant{}
+	if roles == nil {
+		return grants, nil
+	}
+
+	all, err := s.ds.Rs.GetAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roleNames := map[string]bool{}
+	for _, r := range all {
+		roleNames[r.Name] = true
+	}
+
+	exported := append(append([]*models.ExportedGrant{}, roles.Dashboard...), roles.Views...)
+	emails := []string{}
+	seen := map[string]bool{}
+	for i, g := range exported {
+		if !roleNames[g.Role] {
+			return nil, fmt.Errorf("%w: unknown role %q", er.ErrInvalidExport, g.Role)
+		}
+		// grants listed under views must name their view
+		if i >= len(roles.Dashboard) {
+			if _, ok := viewIds[g.ViewRef]; !ok {
+				return nil, fmt.Errorf("%w: role refers to unknown view %q", er.ErrInvalidExport, g.ViewRef)
+			}
+		}
+		if !seen[g.Email] {
+			seen[g.Email] = true
+			emails = append(emails, g.Email)
+		}
+	}
+
+	users, err := s.users.LookupByEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]uuid.UUID{}
+	for _, u := range users {
+		ids[u.Email] = u.ID
+	}
+	for _, email := range emails {
+		if _, ok := ids[email]; !ok {
+			report.UnresolvedEmails = append(report.UnresolvedEmails, email)
+		}
+	}
+
+	for i, g := range exported {
+		id, ok := ids[g.Email]
+		// the importing user is admin of everything already
+		if !ok || id == userId {
+			continue
+		}
+		grant := &models.Grant{UserID: id, RoleName: g.Role}
+		if i >= len(roles.Dashboard) {
+			viewId := viewIds[g.ViewRef]
+			grant.ViewID = &viewId
+		}
+		grants = append(grants, grant)
+		report.Grants = append(report.Grants, g)
+	}
+	return grants, nil
+}
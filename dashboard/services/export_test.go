@@ -0,0 +1,117 @@
+package services_test
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"backend/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Export service whose user lookups are answered by a stand in for the auth service
+// knowing users, recording the emails asked for
+func newExport(t *testing.T, f *fixture, users map[string]uuid.UUID) (*services.ExportService, *[]string) {
+	t.Helper()
+	asked := &[]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Emails []string `json:"emails"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		*asked = append(*asked, body.Emails...)
+		found := []*models.User{}
+		for _, email := range body.Emails {
+			if id, ok := users[email]; ok {
+				found = append(found, &models.User{ID: id, Email: email})
+			}
+		}
+		utils.WriteSuccessResponse(w, http.StatusOK, found)
+	}))
+	t.Cleanup(srv.Close)
+
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	//dry runs never reach the repository
+	return services.NewExportService(f.dashs, nil, services.NewUserDirectory(srv.URL, l), l), asked
+}
+
+func exportDoc(roles *models.ExportedRoles) *models.DashExport {
+	return &models.DashExport{
+		Version:   models.ExportVersion,
+		Dashboard: &models.ExportedDash{Name: "sales"},
+		Views:     []*models.ExportedView{{Ref: "view-1", Name: "revenue"}},
+		Roles:     roles,
+	}
+}
+
+func TestImportResolvesGrants(t *testing.T) {
+	f := newFixture()
+	importer, ann := uuid.New(), uuid.New()
+	s, asked := newExport(t, f, map[string]uuid.UUID{"me@example.com": importer, "ann@example.com": ann})
+
+	report, err := s.ImportDash(context.Background(), importer, exportDoc(&models.ExportedRoles{
+		Dashboard: []*models.ExportedGrant{
+			{Email: "me@example.com", Role: "admin"},
+			{Email: "ann@example.com", Role: "viewer"},
+			{Email: "gone@example.com", Role: "editor"},
+		},
+		Views: []*models.ExportedGrant{
+			{ViewRef: "view-1", Email: "ann@example.com", Role: "editor"},
+		},
+	}), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the importer is admin already and unknown users are reported, not granted
+	got := []string{}
+	for _, g := range report.Grants {
+		got = append(got, g.ViewRef+":"+g.Email+":"+g.Role)
+	}
+	if strings.Join(got, ",") != ":ann@example.com:viewer,view-1:ann@example.com:editor" {
+		t.Errorf("grants %v", got)
+	}
+	if len(report.UnresolvedEmails) != 1 || report.UnresolvedEmails[0] != "gone@example.com" {
+		t.Errorf("unresolved emails %v, want [gone@example.com]", report.UnresolvedEmails)
+	}
+	if len(*asked) != 3 {
+		t.Errorf("looked up %v, want each email once", *asked)
+	}
+	if !report.DryRun || report.Dashboard != nil || report.Views != 1 {
+		t.Errorf("dry run report %+v", report)
+	}
+}
+
+func TestImportRejectsInvalidGrants(t *testing.T) {
+	f := newFixture()
+	importer := uuid.New()
+	s, asked := newExport(t, f, map[string]uuid.UUID{"ann@example.com": uuid.New()})
+
+	tests := []struct {
+		name  string
+		roles *models.ExportedRoles
+	}{
+		{"unknown role", &models.ExportedRoles{Dashboard: []*models.ExportedGrant{{Email: "ann@example.com", Role: "owner"}}}},
+		{"unknown view", &models.ExportedRoles{Views: []*models.ExportedGrant{{ViewRef: "view-2", Email: "ann@example.com", Role: "viewer"}}}},
+		{"view grant without view", &models.ExportedRoles{Views: []*models.ExportedGrant{{Email: "ann@example.com", Role: "viewer"}}}},
+	}
+	for _, tt := range tests {
+		_, err := s.ImportDash(context.Background(), importer, exportDoc(tt.roles), true)
+		if !errors.Is(err, er.ErrInvalidExport) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, er.ErrInvalidExport)
+		}
+	}
+	if len(*asked) != 0 {
+		t.Errorf("looked up %v for invalid documents", *asked)
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"backend/dashboard/repository"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// service to manage folders and access to them
+type FolderService struct {
+	fr *repository.FolderRepository
+	rs *RoleService
+	L  logrus.FieldLogger
+}
+
+// Creates a new instance of FolderService
+func NewFolderService(f *repository.FolderRepository, rs *RoleService, l logrus.FieldLogger) *FolderService {
+	return &FolderService{f, rs, l}
+}
+
+// requires the user to hold a permission on a folder
+func (s *FolderService) checkFolderPerm(ctx context.Context, userId, folderId uuid.UUID, permName string) error {
+	can, err := s.rs.ExistsPermissionForUserForFolder(ctx, userId, folderId, permName)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+	return nil
+}
+
+// Create a folder, at the top level or inside a folder the user can edit.
+// The user becomes admin of the new folder.
+func (s *FolderService) CreateFolder(ctx context.Context, userId uuid.UUID, folder *models.Folder) error {
+	if folder.ParentID != nil {
+		err := s.checkFolderPerm(ctx, userId, *folder.ParentID, perms.WRITE_PERM)
+		if err != nil {
+			return err
+		}
+	}
+	return s.fr.AddFolder(ctx, folder, userId)
+}
+
+// Get all folders the user can read
+func (s *FolderService) GetFoldersForUser(ctx context.Context, userId uuid.UUID) ([]*models.Folder, error) {
+	return s.fr.GetFoldersForUser(ctx, userId)
+}
+
+// Get a folder the user can read
+func (s *FolderService) GetFolder(ctx context.Context, userId, folderId uuid.UUID) (*models.Folder, error) {
+	err := s.checkFolderPerm(ctx, userId, folderId, perms.READ_PERM)
+	if err != nil {
+		return nil, err
+	}
+	return s.fr.GetFolder(ctx, folderId)
+}
+
+// Rename a folder and optionally move it. Moving changes who inherits access, so it needs
+// permission to manage access on the folder and to edit the destination.
+func (s *FolderService) UpdateFolder(ctx context.Context, userId, folderId uuid.UUID, name string, parentId *uuid.UUID) (*models.Folder, error) {
+	err := s.checkFolderPerm(ctx, userId, folderId, perms.WRITE_PERM)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := s.fr.GetFolder(ctx, folderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameFolder(folder.ParentID, parentId) {
+		err = s.checkFolderPerm(ctx, userId, folderId, perms.ACCESS_MOD)
+		if err != nil {
+			return nil, err
+		}
+		if parentId != nil {
+			err = s.checkFolderPerm(ctx, userId, *parentId, perms.WRITE_PERM)
+			if err != nil {
+				return nil, err
+			}
+			within, err := s.fr.IsWithin(ctx, *parentId, folderId)
+			if err != nil {
+				return nil, err
+			}
+			if within {
+				return nil, er.ErrFolderCycle
+			}
+		}
+	}
+
+	folder.Name = name
+	folder.ParentID = parentId
+	err = s.fr.UpdateFolder(ctx, folder)
+	if err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// Delete an empty folder
+func (s *FolderService) DeleteFolder(ctx context.Context, userId, folderId uuid.UUID) error {
+	err := s.checkFolderPerm(ctx, userId, folderId, perms.DELETE_PERM)
+	if err != nil {
+		return err
+	}
+	empty, err := s.fr.IsEmpty(ctx, folderId)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return er.ErrFolderNotEmpty
+	}
+	err = s.fr.DeleteFolder(ctx, folderId)
+	if err == sql.ErrNoRows {
+		return er.ErrNotFound
+	}
+	return err
+}
+
+// Get users with a role on a folder the user can read
+func (s *FolderService) GetUsersFromFolder(ctx context.Context, userId, folderId uuid.UUID) ([]*models.Role, error) {
+	err := s.checkFolderPerm(ctx, userId, folderId, perms.READ_PERM)
+	if err != nil {
+		return nil, err
+	}
+	return s.rs.GetRolesForUsersForFolder(ctx, folderId)
+}
+
+// Give another user a role on a folder
+func (s *FolderService) AddUserToFolder(ctx context.Context, userId, folderId, memberId uuid.UUID, roleName string) error {
+	err := s.checkFolderPerm(ctx, userId, folderId, perms.ACCESS_MOD)
+	if err != nil {
+		return err
+	}
+	return s.rs.AddUserToFolder(ctx, folderId, memberId, roleName)
+}
+
+// Revoke the role of a user on a folder
+func (s *FolderService) DeleteUserFromFolder(ctx context.Context, userId, folderId, memberId uuid.UUID) error {
+	err := s.checkFolderPerm(ctx, userId, folderId, perms.ACCESS_MOD)
+	if err != nil {
+		return err
+	}
+	return s.rs.RevokeFolderLevelRoleFromUser(ctx, folderId, memberId)
+}
+
+func sameFolder(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
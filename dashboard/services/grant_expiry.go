@@ -0,0 +1,111 @@
+package services
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// service removing lapsed grants and warning admins about grants about to lapse
+type GrantExpiryService struct {
+	rR       GrantExpiryStore
+	notifier Notifier
+	events   *EventBus
+	notice   time.Duration
+	L        logrus.FieldLogger
+}
+
+// Creates a new instance of GrantExpiryService. Admins are notified notice ahead of a grant
+// lapsing, and lapsed grants are published on events as revoked.
+func NewGrantExpiryService(r GrantExpiryStore, n Notifier, events *EventBus, notice time.Duration, l logrus.FieldLogger) *GrantExpiryService {
+	return &GrantExpiryService{r, n, events, notice, l}
+}
+
+// Remove lapsed grants, recording each in the access audit, and publish a revoke for each so
+// open streams of their users close. Returns the number of grants removed.
+func (s *GrantExpiryService) Sweep(ctx context.Context) (int, error) {
+	grants, err := s.rR.DeleteExpiredGrants(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, grant := range grants {
+		expiresAt := grant.ExpiresAt
+		s.events.Publish(ctx, &models.Event{
+			Type:   models.EventGrantRevoked,
+			DashID: grant.DashID,
+			ViewID: grant.ViewID,
+			Data:   &models.GrantEvent{UserID: grant.UserID, Role: grant.RoleName, ExpiresAt: &expiresAt},
+		})
+	}
+	return len(grants), nil
+}
+
+// Notify everyone who can manage access to a dashboard or view about grants lapsing within
+// the notice period. Each grant is notified about once.
+func (s *GrantExpiryService) NotifyExpiring(ctx context.Context) error {
+	grants, err := s.rR.GetGrantsExpiringWithin(ctx, s.notice)
+	if err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		var admins []uuid.UUID
+		if grant.ViewID == nil {
+			admins, err = s.rR.GetUsersWithPermissionForDashboard(ctx, grant.DashID, perms.ACCESS_MOD)
+		} else {
+			admins, err = s.rR.GetUsersWithPermissionForView(ctx, *grant.ViewID, perms.ACCESS_MOD)
+		}
+		if err != nil {
+			return err
+		}
+
+		dashId := grant.DashID
+		for _, admin := range admins {
+			err = s.notifier.Notify(ctx, &models.Notification{
+				Type:    models.NotifyGrantExpiring,
+				UserID:  admin,
+				DashID:  &dashId,
+				ViewID:  grant.ViewID,
+				Message: fmt.Sprintf("The %s role of user %s lapses at %s", grant.RoleName, grant.UserID, grant.ExpiresAt.Format(time.RFC3339)),
+				Data:    grant,
+			})
+			if err != nil {
+				s.L.Printf("Could not notify %s of lapsing grant: %v", admin, err)
+			}
+		}
+
+		err = s.rR.MarkExpiryNotified(ctx, grant)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sweep lapsed grants and send notices every interval until ctx is done
+func (s *GrantExpiryService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.Sweep(ctx)
+		if err != nil {
+			s.L.Printf("Could not remove lapsed grants: %v", err)
+		} else if n > 0 {
+			s.L.Printf("Removed %d lapsed grants", n)
+		}
+		err = s.NotifyExpiring(ctx)
+		if err != nil {
+			s.L.Printf("Could not notify about lapsing grants: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
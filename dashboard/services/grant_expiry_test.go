@@ -0,0 +1,156 @@
+package services_test
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"backend/dashboard/repository/memory"
+	"backend/dashboard/services"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Notifier keeping the notifications it was asked to send
+type recordingNotifier struct {
+	sent []*models.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification *models.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func newGrantExpiry(f *fixture, notice time.Duration) (*services.GrantExpiryService, *recordingNotifier) {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	n := &recordingNotifier{}
+	return services.NewGrantExpiryService(memory.NewRoleRepository(f.store), n, f.bus, notice, l), n
+}
+
+func TestSweepRemovesLapsedGrants(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	sweeper, _ := newGrantExpiry(f, time.Hour)
+	owner, temp, guest := uuid.New(), uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	view := f.addView(t, owner, dash.ID, "revenue")
+
+	soon, later := f.now.Add(time.Hour), f.now.Add(3*time.Hour)
+	for _, err := range []error{
+		f.roles.AddUserToDash(ctx, dash.ID, temp, "viewer", &soon, owner),
+		f.roles.AddUserToView(ctx, view.ID, temp, "viewer", nil, owner),
+		f.roles.AddUserToView(ctx, view.ID, guest, "viewer", &soon, owner),
+		f.roles.AddUserToDash(ctx, dash.ID, guest, "viewer", &later, owner),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := sweeper.Sweep(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("swept %d grants before any lapsed", n)
+	}
+
+	f.advance(2 * time.Hour)
+	published := len(f.events)
+	n, err = sweeper.Sweep(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the dashboard grant of temp and the view grant of guest, the view grant of temp goes along
+	if n != 2 {
+		t.Errorf("swept %d grants, want 2", n)
+	}
+	revoked := map[uuid.UUID]*models.Event{}
+	for _, e := range f.events[published:] {
+		if grant, ok := e.Data.(*models.GrantEvent); ok && e.Type == models.EventGrantRevoked {
+			revoked[grant.UserID] = e
+		}
+	}
+	if e := revoked[temp]; e == nil || e.DashID != dash.ID || e.ViewID != nil {
+		t.Errorf("revoke of the dashboard grant of temp published as %+v", e)
+	}
+	if e := revoked[guest]; e == nil || e.ViewID == nil || *e.ViewID != view.ID {
+		t.Errorf("revoke of the view grant of guest published as %+v", e)
+	}
+	if len(f.events) != published+2 {
+		t.Errorf("published %d events, want 2", len(f.events)-published)
+	}
+	can, err := f.roles.ExistsPermissionForUserForView(ctx, temp, view.ID, perms.READ_PERM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if can {
+		t.Error("view grant outlived the lapsed dashboard grant")
+	}
+	if !f.canDash(t, guest, dash.ID, perms.READ_PERM) {
+		t.Error("grant that has not lapsed was swept")
+	}
+	grants, err := f.roles.GetGrantsForDashboard(ctx, dash.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grants) != 3 {
+		t.Errorf("grants left %+v, want the admin of the dashboard and the view and guest", grants)
+	}
+}
+
+func TestNotifyExpiring(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	sweeper, notifier := newGrantExpiry(f, 24*time.Hour)
+	owner, temp, other := uuid.New(), uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+
+	soon, later := f.now.Add(12*time.Hour), f.now.Add(48*time.Hour)
+	err := f.roles.AddUserToDash(ctx, dash.ID, temp, "viewer", &soon, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.roles.AddUserToDash(ctx, dash.ID, other, "viewer", &later, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sweeper.NotifyExpiring(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 1 {
+		t.Fatalf("sent %d notifications, want one to the admin", len(notifier.sent))
+	}
+	sent := notifier.sent[0]
+	grant, _ := sent.Data.(*models.ExpiringGrant)
+	if sent.Type != models.NotifyGrantExpiring || sent.UserID != owner || grant == nil || grant.UserID != temp {
+		t.Errorf("sent %+v, want the admin told about the grant of temp", sent)
+	}
+
+	//each grant is notified about once, until it is granted again
+	err = sweeper.NotifyExpiring(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 1 {
+		t.Fatalf("sent %d notifications, want no more", len(notifier.sent))
+	}
+	soon = soon.Add(time.Hour)
+	err = f.roles.AddUserToDash(ctx, dash.ID, temp, "viewer", &soon, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sweeper.NotifyExpiring(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.sent) != 2 {
+		t.Errorf("sent %d notifications, want another for the renewed grant", len(notifier.sent))
+	}
+}
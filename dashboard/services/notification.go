@@ -0,0 +1,124 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// service keeping the notification inbox of users. It is the Notifier of other services.
+type NotificationService struct {
+	nr NotificationStore
+	ds DashStore
+	L  logrus.FieldLogger
+}
+
+// Creates a new instance of NotificationService
+func NewNotificationService(nr NotificationStore, d DashStore, l logrus.FieldLogger) *NotificationService {
+	return &NotificationService{nr, d, l}
+}
+
+// Add a notification to the inbox of its user, unless they turned its kind off
+func (s *NotificationService) Notify(ctx context.Context, n *models.Notification) error {
+	_, err := s.nr.AddNotification(ctx, n)
+	return err
+}
+
+// Tell users about roles given to or taken from them. Meant to be subscribed to the event bus.
+func (s *NotificationService) HandleEvent(ctx context.Context, e *models.Event) {
+	grant, ok := e.Data.(*models.GrantEvent)
+	if !ok {
+		return
+	}
+	// nothing to tell users about their own doing
+	if e.ActorID != nil && *e.ActorID == grant.UserID {
+		return
+	}
+
+	n := &models.Notification{UserID: grant.UserID, DashID: &e.DashID, ViewID: e.ViewID, Data: grant}
+	name := s.dashName(ctx, e.DashID)
+	switch e.Type {
+	case models.EventGrantCreated:
+		n.Type = models.NotifyAccessGranted
+		if e.ViewID != nil {
+			n.Message = fmt.Sprintf("You were given the %s role on a view of %s", grant.Role, name)
+		} else {
+			n.Message = fmt.Sprintf("You were given the %s role on %s", grant.Role, name)
+		}
+	case models.EventGrantRevoked:
+		n.Type = models.NotifyAccessRevoked
+		if e.ViewID != nil {
+			n.Message = fmt.Sprintf("Your access to a view of %s was removed", name)
+		} else {
+			n.Message = fmt.Sprintf("Your access to %s was removed", name)
+		}
+	default:
+		return
+	}
+
+	err := s.Notify(ctx, n)
+	if err != nil {
+		s.L.Printf("Could not notify %s of %s: %v", n.UserID, e.Type, err)
+	}
+}
+
+// Get a page of the notifications of a user, newest first
+func (s *NotificationService) GetNotifications(ctx context.Context, userId uuid.UUID, unreadOnly bool, limit, offset int) (*models.NotificationPage, error) {
+	return s.nr.GetNotifications(ctx, userId, unreadOnly, limit, offset)
+}
+
+// Count the unread notifications of a user
+func (s *NotificationService) CountUnread(ctx context.Context, userId uuid.UUID) (int, error) {
+	return s.nr.CountUnread(ctx, userId)
+}
+
+// Mark notifications of a user as read, all of them when ids is nil
+func (s *NotificationService) MarkRead(ctx context.Context, userId uuid.UUID, ids []uuid.UUID) (int64, error) {
+	return s.nr.MarkRead(ctx, userId, ids)
+}
+
+// Get whether the user gets each kind of notification
+func (s *NotificationService) GetPreferences(ctx context.Context, userId uuid.UUID) ([]*models.NotificationPreference, error) {
+	set, err := s.nr.GetPreferences(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	prefs := make([]*models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		enabled, ok := set[t]
+		prefs = append(prefs, &models.NotificationPreference{Type: t, Enabled: enabled || !ok})
+	}
+	return prefs, nil
+}
+
+// Turn kinds of notification on or off. Kinds left out keep their setting.
+func (s *NotificationService) SetPreferences(ctx context.Context, userId uuid.UUID, prefs map[string]bool) ([]*models.NotificationPreference, error) {
+	known := map[string]bool{}
+	for _, t := range models.NotificationTypes {
+		known[t] = true
+	}
+	for t := range prefs {
+		if !known[t] {
+			return nil, fmt.Errorf("%w: %q", er.ErrInvalidNotificationType, t)
+		}
+	}
+
+	err := s.nr.SetPreferences(ctx, userId, prefs)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPreferences(ctx, userId)
+}
+
+// name of a dashboard for messages, falling back to a generic one
+func (s *NotificationService) dashName(ctx context.Context, dashId uuid.UUID) string {
+	dash, err := s.ds.GetDash(ctx, dashId)
+	if err != nil {
+		return "a dashboard"
+	}
+	return dash.Name
+}
@@ -0,0 +1,80 @@
+package services_test
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/repository/memory"
+	"backend/dashboard/services"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Notification service over the store of the fixture, told about every event published
+func newNotifications(f *fixture) *services.NotificationService {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	s := services.NewNotificationService(memory.NewNotificationRepository(f.store), memory.NewDashRepository(f.store), l)
+	f.bus.Subscribe(s.HandleEvent)
+	return s
+}
+
+// Types of the notifications in the inbox of a user, oldest first
+func inbox(t *testing.T, s *services.NotificationService, userId uuid.UUID) []*models.Notification {
+	t.Helper()
+	page, err := s.GetNotifications(context.Background(), userId, false, 50, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := page.Items
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	return items
+}
+
+func TestNotifyGrants(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	notifications := newNotifications(f)
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+
+	err := f.roles.AddUserToDash(ctx, dash.ID, user, "manager", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := inbox(t, notifications, user)
+	if len(got) != 1 || got[0].Type != models.NotifyAccessGranted || !strings.Contains(got[0].Message, "manager role on sales") {
+		t.Fatalf("inbox after being given a role: %+v", got)
+	}
+
+	//nothing to tell users about their own doing
+	expiresAt := f.now.Add(time.Hour)
+	err = f.roles.AddUserToDash(ctx, dash.ID, user, "viewer", &expiresAt, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := inbox(t, notifications, user); len(got) != 1 {
+		t.Fatalf("notified about changing their own role: %+v", got[len(got)-1])
+	}
+
+	//a lapsed grant has no actor, its user is told
+	f.advance(2 * time.Hour)
+	sweeper, _ := newGrantExpiry(f, time.Hour)
+	_, err = sweeper.Sweep(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = inbox(t, notifications, user)
+	if len(got) != 2 || got[1].Type != models.NotifyAccessRevoked {
+		t.Errorf("inbox after the grant lapsed: %+v", got)
+	}
+	if got := inbox(t, notifications, owner); len(got) != 0 {
+		t.Errorf("owner notified about grants they made: %+v", got)
+	}
+}
@@ -0,0 +1,11 @@
+package services
+
+import (
+	"backend/dashboard/models"
+	"context"
+)
+
+// Delivers notifications to users
+type Notifier interface {
+	Notify(ctx context.Context, n *models.Notification) error
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxTags      = 20
+	maxTagLength = 50
+	// visits kept per user for the recently viewed list
+	maxRecentVisits = 50
+)
+
+// Replace the tags of a dashboard. Tags are trimmed, lower cased and deduplicated.
+func (s *DashService) SetTags(ctx context.Context, userId, dashId uuid.UUID, tags []string) ([]string, error) {
+	can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.WRITE_PERM)
+	if err != nil {
+		return nil, err
+	}
+	if !can {
+		return nil, er.ErrNoPerm
+	}
+
+	tags, err = normaliseTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	err = s.ds.SetTags(ctx, dashId, tags)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, models.EventDashUpdated, dashId, userId, map[string]interface{}{"tags": tags})
+	return tags, nil
+}
+
+// Get tags in use on the dashboards a user can read
+func (s *DashService) GetTagsForUser(ctx context.Context, userId uuid.UUID) ([]*models.TagCount, error) {
+	return s.ds.GetTagsForUser(ctx, userId)
+}
+
+// Move a dashboard into a folder, or to the top level when folderId is nil. Moving changes
+// who inherits access, so it needs permission to manage access on the dashboard and to
+// edit the destination folder.
+func (s *DashService) MoveDashToFolder(ctx context.Context, userId, dashId uuid.UUID, folderId *uuid.UUID) error {
+	can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.ACCESS_MOD)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+
+	if folderId != nil {
+		can, err = s.Rs.ExistsPermissionForUserForFolder(ctx, userId, *folderId, perms.WRITE_PERM)
+		if err != nil {
+			return err
+		}
+		if !can {
+			return er.ErrNoPerm
+		}
+	}
+
+	err = s.ds.SetFolder(ctx, dashId, folderId)
+	if err == sql.ErrNoRows {
+		return er.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, models.EventDashUpdated, dashId, userId, map[string]interface{}{"folderId": folderId})
+	return nil
+}
+
+// Star a dashboard the user can read
+func (s *DashService) StarDash(ctx context.Context, userId, dashId uuid.UUID) error {
+	can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.READ_PERM)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+	return s.ds.StarDash(ctx, dashId, userId)
+}
+
+// Remove the star of a user from a dashboard
+func (s *DashService) UnstarDash(ctx context.Context, userId, dashId uuid.UUID) error {
+	return s.ds.UnstarDash(ctx, dashId, userId)
+}
+
+// Get starred dashboards the user can still read
+func (s *DashService) GetStarredDashs(ctx context.Context, userId uuid.UUID) ([]*models.Dash, error) {
+	return s.ds.GetStarredDashsForUser(ctx, userId)
+}
+
+// Record that the user opened a dashboard
+func (s *DashService) RecordVisit(ctx context.Context, userId, dashId uuid.UUID) error {
+	return s.ds.RecordVisit(ctx, dashId, userId, maxRecentVisits)
+}
+
+// Get dashboards the user opened recently and can still read, latest first
+func (s *DashService) GetRecentDashs(ctx context.Context, userId uuid.UUID, limit int) ([]*models.Dash, error) {
+	if limit <= 0 || limit > maxRecentVisits {
+		limit = maxRecentVisits
+	}
+	return s.ds.GetRecentDashsForUser(ctx, userId, limit)
+}
+
+// trims, lower cases, deduplicates and sorts tags
+func normaliseTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("%w: tags must be 1 to %d characters", er.ErrInvalidTag, maxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	if len(out) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", er.ErrInvalidTag, maxTags)
+	}
+	sort.Strings(out)
+	return out, nil
+}
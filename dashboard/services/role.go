@@ -3,35 +3,40 @@ package services
 import (
 	er "backend/dashboard/errors"
 	"backend/dashboard/models"
-	"backend/dashboard/repository"
+	"backend/metrics"
 	"context"
-	"log"
+	"encoding/json"
+	"time"
 
-	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 )
 
+// name of the role holding every permission
+const adminRole = "admin"
+
 // Role service to manage roles and permissions to dashboards and views
 type RoleService struct {
-	r   *repository.RoleRepository
-	l   *log.Logger
-	rdb *redis.Client
+	r      RoleStore
+	l      logrus.FieldLogger
+	rdb    Cache
+	events *EventBus
 }
 
-// Creates a new instance of RoleService
-func NewRoleService(r *repository.RoleRepository, rdb *redis.Client, l *log.Logger) *RoleService {
-	return &RoleService{r, l, rdb}
+// Creates a new instance of RoleService. Grants and revocations are published on events.
+func NewRoleService(r RoleStore, rdb Cache, events *EventBus, l logrus.FieldLogger) *RoleService {
+	return &RoleService{r, l, rdb, events}
 }
 
 // Returns all roles for a user for a dashboard
-func (s *RoleService) GetRolesForUserForDashboard(userId, dashId uuid.UUID) ([]*models.Role, error) {
-	roles, err := s.r.GetRolesForUserForDashboard(userId, dashId)
+func (s *RoleService) GetRolesForUserForDashboard(ctx context.Context, userId, dashId uuid.UUID) ([]*models.Role, error) {
+	roles, err := s.r.GetRolesForUserForDashboard(ctx, userId, dashId)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, role := range roles {
-		role.Permissions, err = s.r.GetPermissionsForRoleId(role.ID)
+		role.Permissions, err = s.r.GetPermissionsForRoleId(ctx, role.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -41,47 +46,94 @@ func (s *RoleService) GetRolesForUserForDashboard(userId, dashId uuid.UUID) ([]*
 }
 
 // Returns true if the user has the specified permission for the dashboard
-func (s *RoleService) ExistsPermissionForUserForDashboard(userId, dashId uuid.UUID, permName string) (bool, error) {
-	return s.r.ExistsPermissionForUserForDashboard(userId, dashId, permName)
+func (s *RoleService) ExistsPermissionForUserForDashboard(ctx context.Context, userId, dashId uuid.UUID, permName string) (bool, error) {
+	can, err := s.r.ExistsPermissionForUserForDashboard(ctx, userId, dashId, permName)
+	metrics.ObservePermissionCheck("dashboard", can, err)
+	return can, err
 }
 
-// Add a user to dashboard with given role
-func (s *RoleService) AddUserToDash(dashId, userId uuid.UUID, roleName string) error {
-	return s.r.GrantDashLevelRoleToUser(userId, roleName, dashId)
+// Add a user to dashboard with given role on behalf of actorId. The grant lapses at expiresAt when set.
+func (s *RoleService) AddUserToDash(ctx context.Context, dashId, userId uuid.UUID, roleName string, expiresAt *time.Time, actorId uuid.UUID) error {
+	expiresAt, err := checkGrantExpiry(roleName, expiresAt)
+	if err != nil {
+		return err
+	}
+	err = s.r.GrantDashLevelRoleToUser(ctx, userId, roleName, dashId, expiresAt)
+	if err != nil {
+		return err
+	}
+	s.events.Publish(ctx, &models.Event{
+		Type:    models.EventGrantCreated,
+		DashID:  dashId,
+		ActorID: &actorId,
+		Data:    &models.GrantEvent{UserID: userId, Role: roleName, ExpiresAt: expiresAt},
+	})
+	return nil
 }
 
-func (s *RoleService) AddUserToView(viewId, userId uuid.UUID, roleName string) error {
-	return s.r.GrantViewLevelRoleToUser(context.Background(), userId, roleName, viewId)
+// Add a user to view with given role on behalf of actorId. The grant lapses at expiresAt when set.
+func (s *RoleService) AddUserToView(ctx context.Context, viewId, userId uuid.UUID, roleName string, expiresAt *time.Time, actorId uuid.UUID) error {
+	expiresAt, err := checkGrantExpiry(roleName, expiresAt)
+	if err != nil {
+		return err
+	}
+	err = s.r.GrantViewLevelRoleToUser(ctx, userId, roleName, viewId, expiresAt)
+	if err != nil {
+		return err
+	}
+	s.publishViewEvent(ctx, viewId, models.EventGrantCreated, actorId, &models.GrantEvent{UserID: userId, Role: roleName, ExpiresAt: expiresAt})
+	return nil
 }
 
-func (s *RoleService) GetAllRoles() ([]*models.Role, error) {
+// Expiry must lie in the future and admin grants cannot expire, so a dashboard or view
+// never loses its last admin to the sweeper. Returns the expiry in UTC as stored.
+func checkGrantExpiry(roleName string, expiresAt *time.Time) (*time.Time, error) {
+	if expiresAt == nil {
+		return nil, nil
+	}
+	if roleName == adminRole {
+		return nil, er.ErrAdminGrantCannotExpire
+	}
+	if !expiresAt.After(time.Now()) {
+		return nil, er.ErrInvalidExpiry
+	}
+	utc := expiresAt.UTC()
+	return &utc, nil
+}
+
+func (s *RoleService) GetAllRoles(ctx context.Context) ([]*models.Role, error) {
 	//can use redis here as roles and their permissions are not changing while running
 	//check if redis has this
-	res := s.rdb.Get(context.Background(), "all-roles")
+	res := s.rdb.Get(ctx, "all-roles")
 	if res.Err() == nil {
 		//if it does, return it
 		var roles []*models.Role
-		err := res.Scan(&roles)
+		err := json.Unmarshal([]byte(res.Val()), &roles)
 		if err != nil {
 			return nil, err
 		}
-		s.l.Println("Roles fetched from redis")
+		metrics.CacheRequests.WithLabelValues("roles", metrics.CacheHit).Inc()
 		return roles, nil
 	}
+	metrics.CacheRequests.WithLabelValues("roles", metrics.CacheMiss).Inc()
 
-	roles, err := s.r.GetAllRoles()
+	roles, err := s.r.GetAllRoles(ctx)
 	if err != nil {
 		return nil, err
 	}
 	for _, role := range roles {
-		role.Permissions, err = s.r.GetPermissionsForRoleId(role.ID)
+		role.Permissions, err = s.r.GetPermissionsForRoleId(ctx, role.ID)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	//set it in redis
-	err = s.rdb.Set(context.Background(), "all-roles", roles, 0).Err()
+	//set it in redis, encoded as redis cannot store a slice as is
+	raw, err := json.Marshal(roles)
+	if err != nil {
+		return nil, err
+	}
+	err = s.rdb.Set(ctx, "all-roles", raw, 0).Err()
 	if err != nil {
 		return nil, err
 	}
@@ -89,17 +141,24 @@ func (s *RoleService) GetAllRoles() ([]*models.Role, error) {
 	return roles, nil
 }
 
-func (s *RoleService) ExistsPermissionForUserForView(userId, viewId uuid.UUID, permName string) (bool, error) {
-	return s.r.ExistsPermissionForUserForView(userId, viewId, permName)
+func (s *RoleService) ExistsPermissionForUserForView(ctx context.Context, userId, viewId uuid.UUID, permName string) (bool, error) {
+	can, err := s.r.ExistsPermissionForUserForView(ctx, userId, viewId, permName)
+	metrics.ObservePermissionCheck("view", can, err)
+	return can, err
 }
 
-func (s *RoleService) GetRolesForUsersForDashboard(dashId uuid.UUID) ([]*models.Role, error) {
-	roles, err := s.r.GetRolesForUsersForDashboard(dashId)
+// Returns all grants on a dashboard and its views
+func (s *RoleService) GetGrantsForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.Grant, error) {
+	return s.r.GetGrantsForDashboard(ctx, dashId)
+}
+
+func (s *RoleService) GetRolesForUsersForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.Role, error) {
+	roles, err := s.r.GetRolesForUsersForDashboard(ctx, dashId)
 	if err != nil {
 		return nil, err
 	}
 	for _, role := range roles {
-		role.Permissions, err = s.r.GetPermissionsForRoleId(role.ID)
+		role.Permissions, err = s.r.GetPermissionsForRoleId(ctx, role.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -107,19 +166,31 @@ func (s *RoleService) GetRolesForUsersForDashboard(dashId uuid.UUID) ([]*models.
 	return roles, nil
 }
 
-func (r *RoleService) RevokeDashLevelRoleFromUser(dashId, userId uuid.UUID) error {
-	isonly, err := r.r.IsOnlyAdminForDashboard(userId, dashId)
+// Revoke the role of a user on a dashboard on behalf of actorId
+func (r *RoleService) RevokeDashLevelRoleFromUser(ctx context.Context, dashId, userId, actorId uuid.UUID) error {
+	isonly, err := r.r.IsOnlyAdminForDashboard(ctx, userId, dashId)
 	if err != nil {
 		return err
 	}
 	if isonly {
 		return er.ErrCannotRevokeLastAdmin
 	}
-	return r.r.RevokeDashLevelRoleFromUser(context.Background(), userId, dashId)
+	err = r.r.RevokeDashLevelRoleFromUser(ctx, userId, dashId)
+	if err != nil {
+		return err
+	}
+	r.events.Publish(ctx, &models.Event{
+		Type:    models.EventGrantRevoked,
+		DashID:  dashId,
+		ActorID: &actorId,
+		Data:    &models.GrantEvent{UserID: userId},
+	})
+	return nil
 }
 
-func (r *RoleService) RevokeViewLevelRoleFromUser(viewId, userId uuid.UUID) error {
-	isonly, err := r.r.IsOnlyAdminForView(userId, viewId)
+// Revoke the role of a user on a view on behalf of actorId
+func (r *RoleService) RevokeViewLevelRoleFromUser(ctx context.Context, viewId, userId, actorId uuid.UUID) error {
+	isonly, err := r.r.IsOnlyAdminForView(ctx, userId, viewId)
 	if err != nil {
 		return err
 	}
@@ -127,5 +198,77 @@ func (r *RoleService) RevokeViewLevelRoleFromUser(viewId, userId uuid.UUID) erro
 		return er.ErrCannotRevokeLastAdmin
 	}
 
-	return r.r.RevokeViewLevelRoleFromUser(userId, viewId)
+	err = r.r.RevokeViewLevelRoleFromUser(ctx, userId, viewId)
+	if err != nil {
+		return err
+	}
+	r.publishViewEvent(ctx, viewId, models.EventGrantRevoked, actorId, &models.GrantEvent{UserID: userId})
+	return nil
+}
+
+// publish an event about a view, which is keyed by the dashboard the view is on
+func (s *RoleService) publishViewEvent(ctx context.Context, viewId uuid.UUID, eventType string, actorId uuid.UUID, data interface{}) {
+	dashId, err := s.r.GetDashIdForView(ctx, viewId)
+	if err != nil {
+		s.l.Printf("Could not publish %s for view %s: %v", eventType, viewId, err)
+		return
+	}
+	s.events.Publish(ctx, &models.Event{Type: eventType, DashID: dashId, ViewID: &viewId, ActorID: &actorId, Data: data})
+}
+
+// Returns true if the user has the specified permission for the folder or a folder above it
+func (s *RoleService) ExistsPermissionForUserForFolder(ctx context.Context, userId, folderId uuid.UUID, permName string) (bool, error) {
+	can, err := s.r.ExistsPermissionForUserForFolder(ctx, userId, folderId, permName)
+	metrics.ObservePermissionCheck("folder", can, err)
+	return can, err
+}
+
+// Add a user to folder with given role. The role applies to every dashboard below the folder.
+func (s *RoleService) AddUserToFolder(ctx context.Context, folderId, userId uuid.UUID, roleName string) error {
+	return s.r.GrantFolderLevelRoleToUser(ctx, userId, roleName, folderId)
+}
+
+// Returns users with a role on a folder along with the permissions of each role
+func (s *RoleService) GetRolesForUsersForFolder(ctx context.Context, folderId uuid.UUID) ([]*models.Role, error) {
+	roles, err := s.r.GetRolesForUsersForFolder(ctx, folderId)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		role.Permissions, err = s.r.GetPermissionsForRoleId(ctx, role.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return roles, nil
+}
+
+func (s *RoleService) RevokeFolderLevelRoleFromUser(ctx context.Context, folderId, userId uuid.UUID) error {
+	isonly, err := s.r.IsOnlyAdminForFolder(ctx, userId, folderId)
+	if err != nil {
+		return err
+	}
+	if isonly {
+		return er.ErrCannotRevokeLastAdmin
+	}
+	return s.r.RevokeFolderLevelRoleFromUser(ctx, userId, folderId)
+}
+
+// Returns users holding a permission on a dashboard, directly or through a folder
+func (s *RoleService) GetUsersWithPermissionForDashboard(ctx context.Context, dashId uuid.UUID, permName string) ([]uuid.UUID, error) {
+	return s.r.GetUsersWithPermissionForDashboard(ctx, dashId, permName)
+}
+
+// Returns true if a role with the given name exists
+func (s *RoleService) RoleExists(ctx context.Context, name string) (bool, error) {
+	roles, err := s.GetAllRoles(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		if role.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
 }
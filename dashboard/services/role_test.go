@@ -0,0 +1,356 @@
+package services_test
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestShareDash(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+
+	err := f.roles.AddUserToDash(ctx, dash.ID, user, "viewer", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.dashs.GetDashByIdForUser(ctx, user, dash.ID)
+	if err != nil {
+		t.Fatalf("reading as viewer: %v", err)
+	}
+	err = f.dashs.UpdateDash(ctx, user, dash.ID, &models.Dash{Name: "renamed"})
+	if !errors.Is(err, er.ErrNoPerm) {
+		t.Fatalf("editing as viewer: got %v, want %v", err, er.ErrNoPerm)
+	}
+
+	//sharing again replaces the role, a user holds one role per dashboard
+	err = f.roles.AddUserToDash(ctx, dash.ID, user, "editor", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles, err := f.roles.GetRolesForUserForDashboard(ctx, user, dash.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 1 || roles[0].Name != "editor" {
+		t.Fatalf("roles after sharing twice: %+v, want editor only", roles)
+	}
+	err = f.dashs.UpdateDash(ctx, user, dash.ID, &models.Dash{Name: "renamed"})
+	if err != nil {
+		t.Fatalf("editing as editor: %v", err)
+	}
+	if f.canDash(t, user, dash.ID, perms.DELETE_PERM) || f.canDash(t, user, dash.ID, perms.ACCESS_MOD) {
+		t.Error("editor can delete or manage access")
+	}
+
+	grants := 0
+	for _, e := range f.events {
+		if e.Type == models.EventGrantCreated && e.DashID == dash.ID {
+			grants++
+			if e.ActorID == nil || *e.ActorID != owner {
+				t.Errorf("%s event names actor %v, want the owner", e.Type, e.ActorID)
+			}
+		}
+	}
+	if grants != 2 {
+		t.Errorf("published %d %s events, want 2", grants, models.EventGrantCreated)
+	}
+}
+
+func TestShareDashWithUnknownRole(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+
+	err := f.roles.AddUserToDash(ctx, dash.ID, user, "owner", nil, owner)
+	if err == nil {
+		t.Fatal("sharing with an unknown role succeeded")
+	}
+	if f.canDash(t, user, dash.ID, perms.READ_PERM) {
+		t.Error("user can read after a failed share")
+	}
+}
+
+func TestGrantExpiry(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+
+	later := f.now.Add(time.Hour)
+	err := f.roles.AddUserToDash(ctx, dash.ID, user, "admin", &later, owner)
+	if !errors.Is(err, er.ErrAdminGrantCannotExpire) {
+		t.Fatalf("expiring admin grant: got %v, want %v", err, er.ErrAdminGrantCannotExpire)
+	}
+	earlier := f.now.Add(-time.Hour)
+	err = f.roles.AddUserToDash(ctx, dash.ID, user, "viewer", &earlier, owner)
+	if !errors.Is(err, er.ErrInvalidExpiry) {
+		t.Fatalf("grant expiring in the past: got %v, want %v", err, er.ErrInvalidExpiry)
+	}
+
+	err = f.roles.AddUserToDash(ctx, dash.ID, user, "viewer", &later, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.canDash(t, user, dash.ID, perms.READ_PERM) {
+		t.Fatal("viewer cannot read before the grant lapses")
+	}
+
+	f.advance(2 * time.Hour)
+	if f.canDash(t, user, dash.ID, perms.READ_PERM) {
+		t.Error("viewer can read after the grant lapsed")
+	}
+	roles, err := f.roles.GetRolesForUsersForDashboard(ctx, dash.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, role := range roles {
+		if role.UserId == user {
+			t.Errorf("lapsed role listed: %+v", role)
+		}
+	}
+	grants, err := f.roles.GetGrantsForDashboard(ctx, dash.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grants) != 1 || grants[0].UserID != owner {
+		t.Errorf("grants %+v, want the admin only", grants)
+	}
+}
+
+func TestRevokeDashRole(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	view := f.addView(t, owner, dash.ID, "revenue")
+	err := f.roles.AddUserToDash(ctx, dash.ID, user, "viewer", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.roles.AddUserToView(ctx, view.ID, user, "viewer", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.roles.RevokeDashLevelRoleFromUser(ctx, dash.ID, user, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.dashs.GetDashByIdForUser(ctx, user, dash.ID)
+	if !errors.Is(err, er.ErrNoPerm) {
+		t.Errorf("reading after revoke: got %v, want %v", err, er.ErrNoPerm)
+	}
+	//revoking on the dashboard takes away the roles on its views too
+	can, err := f.roles.ExistsPermissionForUserForView(ctx, user, view.ID, perms.READ_PERM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if can {
+		t.Error("role on the view survived revoking the dashboard role")
+	}
+	last := f.events[len(f.events)-1]
+	if last.Type != models.EventGrantRevoked || last.DashID != dash.ID {
+		t.Errorf("last event %s on %s, want %s on %s", last.Type, last.DashID, models.EventGrantRevoked, dash.ID)
+	}
+	if last.ActorID == nil || *last.ActorID != owner {
+		t.Errorf("revoke names actor %v, want the owner", last.ActorID)
+	}
+
+	err = f.roles.RevokeDashLevelRoleFromUser(ctx, dash.ID, user, owner)
+	if err == nil {
+		t.Error("revoking a role the user does not have succeeded")
+	}
+}
+
+func TestRevokeLastDashAdmin(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+
+	err := f.roles.RevokeDashLevelRoleFromUser(ctx, dash.ID, owner, owner)
+	if !errors.Is(err, er.ErrCannotRevokeLastAdmin) {
+		t.Fatalf("revoking the only admin: got %v, want %v", err, er.ErrCannotRevokeLastAdmin)
+	}
+	if !f.canDash(t, owner, dash.ID, perms.ACCESS_MOD) {
+		t.Fatal("only admin lost access")
+	}
+
+	err = f.roles.AddUserToDash(ctx, dash.ID, user, "admin", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.roles.RevokeDashLevelRoleFromUser(ctx, dash.ID, owner, owner)
+	if err != nil {
+		t.Fatalf("revoking one of two admins: %v", err)
+	}
+	err = f.roles.RevokeDashLevelRoleFromUser(ctx, dash.ID, user, owner)
+	if !errors.Is(err, er.ErrCannotRevokeLastAdmin) {
+		t.Errorf("revoking the admin left: got %v, want %v", err, er.ErrCannotRevokeLastAdmin)
+	}
+}
+
+func TestRevokeLastViewAdmin(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	view := f.addView(t, owner, dash.ID, "revenue")
+
+	err := f.roles.RevokeViewLevelRoleFromUser(ctx, view.ID, owner, owner)
+	if !errors.Is(err, er.ErrCannotRevokeLastAdmin) {
+		t.Fatalf("revoking the only admin: got %v, want %v", err, er.ErrCannotRevokeLastAdmin)
+	}
+
+	err = f.roles.AddUserToView(ctx, view.ID, user, "admin", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.roles.RevokeViewLevelRoleFromUser(ctx, view.ID, owner, owner)
+	if err != nil {
+		t.Fatalf("revoking one of two admins: %v", err)
+	}
+	if last := f.events[len(f.events)-1]; last.Type != models.EventGrantRevoked || last.ViewID == nil || *last.ViewID != view.ID {
+		t.Errorf("last event %+v, want %s on the view", last, models.EventGrantRevoked)
+	}
+	//the dashboard role is untouched by revoking on one of its views
+	if !f.canDash(t, owner, dash.ID, perms.ACCESS_MOD) {
+		t.Error("revoking the view role took away the dashboard role")
+	}
+}
+
+func TestFolderRolesReachDashboards(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	parent := &models.Folder{Name: "team"}
+	f.store.AddFolder(parent, owner)
+	child := &models.Folder{Name: "reports", ParentID: &parent.ID}
+	f.store.AddFolder(child, owner)
+	dash := f.addDash(t, owner, "sales")
+	view := f.addView(t, owner, dash.ID, "revenue")
+	err := f.dashs.MoveDashToFolder(ctx, owner, dash.ID, &child.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.roles.AddUserToFolder(ctx, parent.ID, user, "viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.dashs.GetDashByIdForUser(ctx, user, dash.ID)
+	if err != nil {
+		t.Fatalf("reading through a role on a folder above: %v", err)
+	}
+	//roles on folders reach dashboards, not their views
+	if len(got.Views) != 0 {
+		t.Errorf("viewer through a folder sees %d views, want none", len(got.Views))
+	}
+	_, err = f.views.GetView(ctx, view.ID, user)
+	if err == nil {
+		t.Error("viewer through a folder can read a view")
+	}
+
+	roles, err := f.roles.GetRolesForUsersForDashboard(ctx, dash.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inherited := 0
+	for _, role := range roles {
+		if role.UserId == user {
+			inherited++
+			if role.FolderID == nil || *role.FolderID != parent.ID {
+				t.Errorf("role of the user %+v, want it inherited from %s", role, parent.ID)
+			}
+		}
+	}
+	if inherited != 1 {
+		t.Errorf("user holds %d roles on the dashboard, want 1", inherited)
+	}
+
+	err = f.roles.RevokeFolderLevelRoleFromUser(ctx, parent.ID, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.canDash(t, user, dash.ID, perms.READ_PERM) {
+		t.Error("user can read after the folder role was revoked")
+	}
+	err = f.roles.RevokeFolderLevelRoleFromUser(ctx, parent.ID, owner)
+	if !errors.Is(err, er.ErrCannotRevokeLastAdmin) {
+		t.Errorf("revoking the only folder admin: got %v, want %v", err, er.ErrCannotRevokeLastAdmin)
+	}
+}
+
+func TestGetAllRolesCached(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+
+	roles, err := f.roles.GetAllRoles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"admin":     "comment,delete,edit,edit_access,read",
+		"editor":    "comment,edit,read",
+		"viewer":    "read",
+		"commenter": "comment,read",
+		"manager":   "comment,delete,edit,edit_access,read",
+	}
+	checkRoles := func(roles []*models.Role) {
+		t.Helper()
+		if len(roles) != len(want) {
+			t.Fatalf("got %d roles, want %d", len(roles), len(want))
+		}
+		for _, role := range roles {
+			names := []string{}
+			for _, p := range role.Permissions {
+				names = append(names, p.Name)
+			}
+			sort.Strings(names)
+			if got := strings.Join(names, ","); got != want[role.Name] {
+				t.Errorf("permissions of %s: %s, want %s", role.Name, got, want[role.Name])
+			}
+		}
+	}
+	checkRoles(roles)
+
+	raw, err := f.cache.Get(ctx, "all-roles").Bytes()
+	if err != nil {
+		t.Fatalf("roles were not cached: %v", err)
+	}
+	cached := []*models.Role{}
+	err = json.Unmarshal(raw, &cached)
+	if err != nil {
+		t.Fatalf("cached roles: %v", err)
+	}
+	checkRoles(cached)
+
+	roles, err = f.roles.GetAllRoles(ctx)
+	if err != nil {
+		t.Fatalf("roles from cache: %v", err)
+	}
+	checkRoles(roles)
+
+	for name, want := range map[string]bool{"viewer": true, "owner": false} {
+		exists, err := f.roles.RoleExists(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != want {
+			t.Errorf("RoleExists(%q) = %v, want %v", name, exists, want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/repository"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// service for full-text search over dashboards and views
+type SearchService struct {
+	sR *repository.SearchRepository
+	L  logrus.FieldLogger
+}
+
+// Creates a new instance of SearchService
+func NewSearchService(r *repository.SearchRepository, l logrus.FieldLogger) *SearchService {
+	return &SearchService{r, l}
+}
+
+// Search the dashboards and views a user can read
+func (s *SearchService) Search(ctx context.Context, userId uuid.UUID, opts *models.SearchOptions) (*models.SearchPage, error) {
+	results, total, err := s.sR.Search(ctx, userId, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SearchPage{Items: results, Total: total}, nil
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"backend/dashboard/repository"
+	"backend/utils"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	embedAudience = "samudai-embed"
+	embedIssuer   = "samudai-dash"
+	// longest lifetime a caller can ask for on an embed token
+	maxEmbedTTL = time.Hour
+)
+
+// service for public share links and embed tokens of dashboards and views
+type ShareService struct {
+	sr       *repository.ShareRepository
+	ds       *repository.DashRepository
+	vR       *repository.ViewRepository
+	rs       *RoleService
+	embedTTL time.Duration
+	L        logrus.FieldLogger
+}
+
+// Creates a new instance of ShareService. Embed tokens live for embedTTL unless asked otherwise.
+func NewShareService(sr *repository.ShareRepository, d *repository.DashRepository, v *repository.ViewRepository, rs *RoleService, embedTTL time.Duration, l logrus.FieldLogger) *ShareService {
+	return &ShareService{sr, d, v, rs, embedTTL, l}
+}
+
+// Sharing a dashboard or view publicly is access management, so it needs edit_access.
+// A view must belong to the dashboard.
+func (s *ShareService) checkCanShare(ctx context.Context, userId, dashId uuid.UUID, viewId *uuid.UUID) error {
+	var can bool
+	var err error
+	if viewId == nil {
+		can, err = s.rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.ACCESS_MOD)
+	} else {
+		view, verr := s.vR.GetViewById(ctx, *viewId)
+		if verr == sql.ErrNoRows || (verr == nil && view.DashID != dashId) {
+			return er.ErrNotFound
+		}
+		if verr != nil {
+			return verr
+		}
+		can, err = s.rs.ExistsPermissionForUserForView(ctx, userId, *viewId, perms.ACCESS_MOD)
+	}
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+	return nil
+}
+
+// Create a share link. The returned link carries its token, which is not stored and cannot be read again.
+func (s *ShareService) CreateShareLink(ctx context.Context, userId, dashId uuid.UUID, viewId *uuid.UUID, expiresAt *time.Time, password string) (*models.ShareLink, error) {
+	err := s.checkCanShare(ctx, userId, dashId, viewId)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return nil, er.ErrInvalidExpiry
+		}
+		utc := expiresAt.UTC()
+		expiresAt = &utc
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+	tokenHash, err := utils.Hash(token)
+	if err != nil {
+		return nil, err
+	}
+	var passwordHash *string
+	if password != "" {
+		//salted and slow, share passwords are picked by people and guessed offline otherwise
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		hashString := string(hash)
+		passwordHash = &hashString
+	}
+
+	link := &models.ShareLink{DashID: dashId, ViewID: viewId, ExpiresAt: expiresAt, CreatedBy: userId, HasPassword: passwordHash != nil}
+	err = s.sr.AddShareLink(ctx, link, tokenHash, passwordHash)
+	if err != nil {
+		return nil, err
+	}
+	link.Token = token
+	return link, nil
+}
+
+// Get share links of a dashboard and its views
+func (s *ShareService) GetShareLinks(ctx context.Context, userId, dashId uuid.UUID) ([]*models.ShareLink, error) {
+	err := s.checkCanShare(ctx, userId, dashId, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.sr.GetShareLinksForDashboard(ctx, dashId)
+}
+
+// Revoke a share link. Needs the same permission as creating it.
+func (s *ShareService) RevokeShareLink(ctx context.Context, userId, linkId uuid.UUID) error {
+	link, err := s.sr.GetShareLink(ctx, linkId)
+	if err == sql.ErrNoRows {
+		return er.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	err = s.checkCanShare(ctx, userId, link.DashID, link.ViewID)
+	if err != nil {
+		return err
+	}
+	return s.sr.RevokeShareLink(ctx, linkId)
+}
+
+// Issue a signed token granting read access to a dashboard, or a single view of it, for ttl.
+// A zero ttl uses the default lifetime.
+func (s *ShareService) CreateEmbedToken(ctx context.Context, userId, dashId uuid.UUID, viewId *uuid.UUID, ttl time.Duration) (*models.EmbedToken, error) {
+	err := s.checkCanShare(ctx, userId, dashId, viewId)
+	if err != nil {
+		return nil, err
+	}
+	if ttl == 0 {
+		ttl = s.embedTTL
+	}
+	if ttl < 0 || ttl > maxEmbedTTL {
+		return nil, er.ErrInvalidExpiry
+	}
+
+	claims := map[string]interface{}{"dash": dashId.String(), "by": userId.String()}
+	subject := dashId.String()
+	if viewId != nil {
+		claims["view"] = viewId.String()
+		subject = viewId.String()
+	}
+	expiresAt := time.Now().Add(ttl)
+	token, err := utils.GenerateScopedJWT(subject, embedAudience, embedIssuer, ttl, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &models.EmbedToken{Token: token, DashID: dashId, ViewID: viewId, ExpiresAt: expiresAt}, nil
+}
+
+// Resolve a share link token to the dashboard or view it shares. Links with a password
+// need the matching password. Unknown, revoked and expired links are reported as not found.
+func (s *ShareService) OpenShareLink(ctx context.Context, token, password string) (*models.SharedResource, error) {
+	tokenHash, err := utils.Hash(token)
+	if err != nil {
+		return nil, err
+	}
+	link, passwordHash, err := s.sr.GetActiveShareLinkByToken(ctx, tokenHash)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if passwordHash != nil {
+		if password == "" {
+			return nil, er.ErrPasswordRequired
+		}
+		err = bcrypt.CompareHashAndPassword([]byte(*passwordHash), []byte(password))
+		if err != nil {
+			return nil, er.ErrPasswordRequired
+		}
+	}
+
+	err = s.sr.TouchShareLink(ctx, link.ID)
+	if err != nil {
+		s.L.Printf("Could not record use of share link %s: %v", link.ID, err)
+	}
+	return s.sharedResource(ctx, link.CreatedBy, link.DashID, link.ViewID)
+}
+
+// Resolve an embed token to the dashboard or view it grants access to
+func (s *ShareService) OpenEmbedToken(ctx context.Context, token string) (*models.SharedResource, error) {
+	claims, err := utils.ParseScopedJWT(token, embedAudience, embedIssuer)
+	if err != nil {
+		return nil, er.ErrUnauthorized
+	}
+	dash, _ := claims["dash"].(string)
+	dashId, err := uuid.Parse(dash)
+	if err != nil {
+		return nil, er.ErrUnauthorized
+	}
+	by, _ := claims["by"].(string)
+	creatorId, err := uuid.Parse(by)
+	if err != nil {
+		return nil, er.ErrUnauthorized
+	}
+	var viewId *uuid.UUID
+	if view, ok := claims["view"].(string); ok {
+		id, err := uuid.Parse(view)
+		if err != nil {
+			return nil, er.ErrUnauthorized
+		}
+		viewId = &id
+	}
+	return s.sharedResource(ctx, creatorId, dashId, viewId)
+}
+
+// Loads a shared dashboard or a single view as seen by the user who shared it: a dashboard
+// comes with only the views its creator can read, and whatever the creator can no longer
+// read is not found.
+func (s *ShareService) sharedResource(ctx context.Context, creatorId, dashId uuid.UUID, viewId *uuid.UUID) (*models.SharedResource, error) {
+	if viewId != nil {
+		view, err := s.vR.GetViewById(ctx, *viewId)
+		if err == sql.ErrNoRows || (err == nil && view.DashID != dashId) {
+			return nil, er.ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		can, err := s.rs.ExistsPermissionForUserForView(ctx, creatorId, *viewId, perms.READ_PERM)
+		if err != nil {
+			return nil, err
+		}
+		if !can {
+			return nil, er.ErrNotFound
+		}
+		return &models.SharedResource{View: view}, nil
+	}
+
+	can, err := s.rs.ExistsPermissionForUserForDashboard(ctx, creatorId, dashId, perms.READ_PERM)
+	if err != nil {
+		return nil, err
+	}
+	if !can {
+		return nil, er.ErrNotFound
+	}
+	dash, err := s.ds.GetDash(ctx, dashId)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	dash.Views, err = s.vR.GetViewsByDashIdForUser(ctx, dashId, creatorId)
+	if err != nil {
+		return nil, err
+	}
+	// where the dashboard is filed is internal to the organisation
+	dash.FolderID = nil
+	return &models.SharedResource{Dashboard: dash}, nil
+}
+
+// random url safe token of 256 bits
+func newShareToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"backend/dashboard/models"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Storage of dashboards used by DashService, implemented by repository.DashRepository
+type DashStore interface {
+	AddDash(ctx context.Context, dash *models.Dash, userId uuid.UUID) error
+	GetDash(ctx context.Context, id uuid.UUID) (*models.Dash, error)
+	CloneDash(ctx context.Context, srcId uuid.UUID, dash *models.Dash, userId uuid.UUID, includeGrants bool) error
+	ListDashboardsForUser(ctx context.Context, userId uuid.UUID, opts *models.DashListOptions) ([]*models.Dash, int, error)
+	UpdateDash(ctx context.Context, dash *models.Dash) error
+	UpdateLayout(ctx context.Context, id uuid.UUID, layout []*models.LayoutItem) error
+	TrashDash(ctx context.Context, id, userId uuid.UUID) error
+	SetTags(ctx context.Context, id uuid.UUID, tags []string) error
+	GetTagsForUser(ctx context.Context, userId uuid.UUID) ([]*models.TagCount, error)
+	SetFolder(ctx context.Context, id uuid.UUID, folderId *uuid.UUID) error
+	StarDash(ctx context.Context, id, userId uuid.UUID) error
+	UnstarDash(ctx context.Context, id, userId uuid.UUID) error
+	GetStarredDashsForUser(ctx context.Context, userId uuid.UUID) ([]*models.Dash, error)
+	RecordVisit(ctx context.Context, id, userId uuid.UUID, keep int) error
+	GetRecentDashsForUser(ctx context.Context, userId uuid.UUID, limit int) ([]*models.Dash, error)
+	SetTemplate(ctx context.Context, id uuid.UUID, isTemplate bool, vars []*models.TemplateVariable) error
+	GetTemplatesForUser(ctx context.Context, userId uuid.UUID) ([]*models.Dash, error)
+}
+
+// Storage of views used by ViewService and DashService, implemented by repository.ViewRepository
+type ViewStore interface {
+	AddView(ctx context.Context, view *models.View, userId uuid.UUID) error
+	GetView(ctx context.Context, viewId, userId uuid.UUID) (*models.View, error)
+	GetViewById(ctx context.Context, viewId uuid.UUID) (*models.View, error)
+	GetViewsByDashId(ctx context.Context, dashId uuid.UUID) ([]*models.View, error)
+	GetViewsByDashIdForUser(ctx context.Context, dashId, userId uuid.UUID) ([]*models.View, error)
+	GetViewsByDashIdsForUser(ctx context.Context, dashIds []uuid.UUID, userId uuid.UUID) (map[uuid.UUID][]*models.View, error)
+	TrashView(ctx context.Context, id, userId uuid.UUID) error
+}
+
+// Storage of roles and grants used by RoleService, implemented by repository.RoleRepository
+type RoleStore interface {
+	GetAllRoles(ctx context.Context) ([]*models.Role, error)
+	GetPermissionsForRoleId(ctx context.Context, roleId int) ([]*models.Permission, error)
+	GrantDashLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, dashId uuid.UUID, expiresAt *time.Time) error
+	RevokeDashLevelRoleFromUser(ctx context.Context, userId uuid.UUID, dashId uuid.UUID) error
+	GrantViewLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, viewId uuid.UUID, expiresAt *time.Time) error
+	RevokeViewLevelRoleFromUser(ctx context.Context, userId uuid.UUID, viewId uuid.UUID) error
+	GrantFolderLevelRoleToUser(ctx context.Context, userId uuid.UUID, roleName string, folderId uuid.UUID) error
+	RevokeFolderLevelRoleFromUser(ctx context.Context, userId uuid.UUID, folderId uuid.UUID) error
+	GetDashIdForView(ctx context.Context, viewId uuid.UUID) (uuid.UUID, error)
+	GetRolesForUserForDashboard(ctx context.Context, userId uuid.UUID, dashId uuid.UUID) ([]*models.Role, error)
+	GetRolesForUsersForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.Role, error)
+	GetRolesForUsersForFolder(ctx context.Context, folderId uuid.UUID) ([]*models.Role, error)
+	GetGrantsForDashboard(ctx context.Context, dashId uuid.UUID) ([]*models.Grant, error)
+	GetUsersWithPermissionForDashboard(ctx context.Context, dashId uuid.UUID, permName string) ([]uuid.UUID, error)
+	ExistsPermissionForUserForDashboard(ctx context.Context, userId uuid.UUID, dashID uuid.UUID, permName string) (bool, error)
+	ExistsPermissionForUserForView(ctx context.Context, userId uuid.UUID, viewID uuid.UUID, permName string) (bool, error)
+	ExistsPermissionForUserForFolder(ctx context.Context, userId uuid.UUID, folderId uuid.UUID, permName string) (bool, error)
+	IsOnlyAdminForDashboard(ctx context.Context, userId uuid.UUID, dashID uuid.UUID) (bool, error)
+	IsOnlyAdminForView(ctx context.Context, userId uuid.UUID, viewID uuid.UUID) (bool, error)
+	IsOnlyAdminForFolder(ctx context.Context, userId uuid.UUID, folderId uuid.UUID) (bool, error)
+}
+
+// Storage of lapsing grants used by GrantExpiryService, implemented by repository.RoleRepository
+type GrantExpiryStore interface {
+	GetGrantsExpiringWithin(ctx context.Context, window time.Duration) ([]*models.ExpiringGrant, error)
+	MarkExpiryNotified(ctx context.Context, grant *models.ExpiringGrant) error
+	DeleteExpiredGrants(ctx context.Context) ([]*models.ExpiringGrant, error)
+	GetUsersWithPermissionForDashboard(ctx context.Context, dashId uuid.UUID, permName string) ([]uuid.UUID, error)
+	GetUsersWithPermissionForView(ctx context.Context, viewId uuid.UUID, permName string) ([]uuid.UUID, error)
+}
+
+// Storage of webhooks and their deliveries used by WebhookService, implemented by
+// repository.WebhookRepository
+type WebhookStore interface {
+	AddWebhook(ctx context.Context, hook *models.Webhook) error
+	GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	GetWebhooksForUser(ctx context.Context, userId uuid.UUID, permName string, includeGlobal bool) ([]*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, hook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	EnqueueDeliveries(ctx context.Context, e *models.Event, payload, idsOnly []byte) (int64, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	FinishDelivery(ctx context.Context, id uuid.UUID, status string, statusCode *int, lastErr *string, retryIn time.Duration) error
+	GetDeliveries(ctx context.Context, webhookId uuid.UUID, limit, offset int) ([]*models.WebhookDelivery, int, error)
+	Redeliver(ctx context.Context, webhookId, deliveryId uuid.UUID) (*models.WebhookDelivery, error)
+}
+
+// Storage of notification inboxes used by NotificationService, implemented by
+// repository.NotificationRepository
+type NotificationStore interface {
+	AddNotification(ctx context.Context, n *models.Notification) (bool, error)
+	GetNotifications(ctx context.Context, userId uuid.UUID, unreadOnly bool, limit, offset int) (*models.NotificationPage, error)
+	CountUnread(ctx context.Context, userId uuid.UUID) (int, error)
+	MarkRead(ctx context.Context, userId uuid.UUID, ids []uuid.UUID) (int64, error)
+	GetPreferences(ctx context.Context, userId uuid.UUID) (map[string]bool, error)
+	SetPreferences(ctx context.Context, userId uuid.UUID, prefs map[string]bool) error
+}
+
+// Key value cache, implemented by *redis.Client. A missing key gets redis.Nil.
+type Cache interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// redis pub/sub channel carrying live events of a dashboard to every replica
+	streamChannelPrefix = "dash-events:"
+	// redis stream keeping recent events of a dashboard for resuming streams
+	streamLogPrefix = "dash-events-log:"
+	// events kept per dashboard, and for how long after the last one
+	streamHistory    = 1000
+	streamHistoryTTL = 24 * time.Hour
+	// events buffered per subscriber before it is dropped as too slow
+	streamBuffer = 64
+)
+
+// event types sent on dashboard streams. Access requests stay with those deciding them.
+var streamedEvents = map[string]bool{
+	models.EventDashCreated:  true,
+	models.EventDashUpdated:  true,
+	models.EventDashDeleted:  true,
+	models.EventViewCreated:  true,
+	models.EventViewDeleted:  true,
+	models.EventGrantCreated: true,
+	models.EventGrantRevoked: true,
+}
+
+// service streaming changes of dashboards to open sessions. Events are fanned out across
+// replicas through redis pub/sub and kept in a redis stream so clients can resume.
+type StreamService struct {
+	rs   *RoleService
+	rdb  *redis.Client
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[*StreamSubscription]bool
+	L    logrus.FieldLogger
+}
+
+// Live events of a dashboard for one client. Events is closed when the subscription is
+// closed or the client falls too far behind, after which it should resume from the last
+// event it got.
+type StreamSubscription struct {
+	DashID uuid.UUID
+	Events chan *models.StreamEvent
+	// events after the Last-Event-ID the client resumed from
+	Backlog []*models.StreamEvent
+	// set when events after Last-Event-ID are no longer kept, the client has to reload
+	Reset bool
+
+	s    *StreamService
+	once sync.Once
+}
+
+// Creates a new instance of StreamService
+func NewStreamService(rs *RoleService, rdb *redis.Client, l logrus.FieldLogger) *StreamService {
+	return &StreamService{rs: rs, rdb: rdb, subs: map[uuid.UUID]map[*StreamSubscription]bool{}, L: l}
+}
+
+// Log the event and send it to every replica. Meant to be subscribed to the event bus.
+func (s *StreamService) Publish(ctx context.Context, e *models.Event) {
+	if !streamedEvents[e.Type] {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		s.L.Printf("Could not encode event %s: %v", e.ID, err)
+		return
+	}
+	idsOnly, err := json.Marshal(e.WithoutData())
+	if err != nil {
+		s.L.Printf("Could not encode event %s: %v", e.ID, err)
+		return
+	}
+	view := ""
+	if e.ViewID != nil {
+		view = e.ViewID.String()
+	}
+
+	key := streamLogPrefix + e.DashID.String()
+	id, err := s.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: streamHistory,
+		Approx: true,
+		Values: map[string]interface{}{"type": e.Type, "data": data, "view": view, "ids": idsOnly},
+	}).Result()
+	if err != nil {
+		s.L.Printf("Could not log event %s: %v", e.ID, err)
+		return
+	}
+	s.rdb.Expire(ctx, key, streamHistoryTTL)
+
+	msg, err := json.Marshal(&models.StreamEvent{ID: id, Type: e.Type, Data: data, ViewID: e.ViewID, IDsOnly: idsOnly})
+	if err != nil {
+		return
+	}
+	err = s.rdb.Publish(ctx, streamChannelPrefix+e.DashID.String(), msg).Err()
+	if err != nil {
+		s.L.Printf("Could not publish event %s: %v", e.ID, err)
+	}
+}
+
+// Relay events published by any replica to the subscribers of this one until ctx is done
+func (s *StreamService) Run(ctx context.Context) {
+	pubsub := s.rdb.PSubscribe(ctx, streamChannelPrefix+"*")
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			dashId, err := uuid.Parse(strings.TrimPrefix(msg.Channel, streamChannelPrefix))
+			if err != nil {
+				continue
+			}
+			ev := &models.StreamEvent{}
+			err = json.Unmarshal([]byte(msg.Payload), ev)
+			if err != nil {
+				s.L.Printf("Could not decode event on %s: %v", msg.Channel, err)
+				continue
+			}
+			s.broadcast(dashId, ev)
+		}
+	}
+}
+
+// Subscribe to the events of a dashboard the user can read. When lastEventId is set the
+// events after it are loaded into the backlog. The subscription must be closed.
+func (s *StreamService) Subscribe(ctx context.Context, userId, dashId uuid.UUID, lastEventId string) (*StreamSubscription, error) {
+	can, err := s.CanRead(ctx, userId, dashId)
+	if err != nil {
+		return nil, err
+	}
+	if !can {
+		return nil, er.ErrNoPerm
+	}
+
+	// subscribe before reading the backlog so nothing falls in between, duplicates
+	// are left for the caller to skip by id
+	sub := &StreamSubscription{DashID: dashId, Events: make(chan *models.StreamEvent, streamBuffer), s: s}
+	s.mu.Lock()
+	if s.subs[dashId] == nil {
+		s.subs[dashId] = map[*StreamSubscription]bool{}
+	}
+	s.subs[dashId][sub] = true
+	s.mu.Unlock()
+
+	if lastEventId == "" {
+		return sub, nil
+	}
+	err = s.loadBacklog(ctx, sub, lastEventId)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Returns true if the user can still read the dashboard
+func (s *StreamService) CanRead(ctx context.Context, userId, dashId uuid.UUID) (bool, error) {
+	return s.rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.READ_PERM)
+}
+
+// Data of the event as the user may see it. Events about a view the user cannot read come
+// with ids only, the view itself and grants on it are not for them.
+func (s *StreamService) DataFor(ctx context.Context, userId uuid.UUID, ev *models.StreamEvent) (json.RawMessage, error) {
+	if ev.ViewID == nil {
+		return ev.Data, nil
+	}
+	can, err := s.rs.ExistsPermissionForUserForView(ctx, userId, *ev.ViewID, perms.READ_PERM)
+	if err != nil {
+		return nil, err
+	}
+	if !can {
+		return ev.IDsOnly, nil
+	}
+	return ev.Data, nil
+}
+
+// Stop receiving events
+func (sub *StreamSubscription) Close() {
+	sub.s.mu.Lock()
+	defer sub.s.mu.Unlock()
+	sub.closeLocked()
+}
+
+func (sub *StreamSubscription) closeLocked() {
+	sub.once.Do(func() {
+		delete(sub.s.subs[sub.DashID], sub)
+		if len(sub.s.subs[sub.DashID]) == 0 {
+			delete(sub.s.subs, sub.DashID)
+		}
+		close(sub.Events)
+	})
+}
+
+func (s *StreamService) loadBacklog(ctx context.Context, sub *StreamSubscription, lastEventId string) error {
+	key := streamLogPrefix + sub.DashID.String()
+	if !validStreamID(lastEventId) {
+		sub.Reset = true
+		return nil
+	}
+
+	oldest, err := s.rdb.XRangeN(ctx, key, "-", "+", 1).Result()
+	if err != nil {
+		return err
+	}
+	if len(oldest) == 0 {
+		return nil
+	}
+	// events between lastEventId and the oldest kept one may have been trimmed
+	if CompareStreamIDs(lastEventId, oldest[0].ID) < 0 {
+		sub.Reset = true
+		return nil
+	}
+
+	msgs, err := s.rdb.XRangeN(ctx, key, "("+lastEventId, "+", streamHistory).Result()
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		t, _ := msg.Values["type"].(string)
+		data, _ := msg.Values["data"].(string)
+		ids, _ := msg.Values["ids"].(string)
+		ev := &models.StreamEvent{ID: msg.ID, Type: t, Data: json.RawMessage(data), IDsOnly: json.RawMessage(ids)}
+		if view, _ := msg.Values["view"].(string); view != "" {
+			viewId, err := uuid.Parse(view)
+			if err != nil {
+				continue
+			}
+			ev.ViewID = &viewId
+		}
+		sub.Backlog = append(sub.Backlog, ev)
+	}
+	return nil
+}
+
+// hand the event to local subscribers of the dashboard, dropping those that fell behind
+func (s *StreamService) broadcast(dashId uuid.UUID, ev *models.StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs[dashId] {
+		select {
+		case sub.Events <- ev:
+		default:
+			s.L.Printf("Dropping slow subscriber of dashboard %s", dashId)
+			sub.closeLocked()
+		}
+	}
+}
+
+// Compares two redis stream ids of the form <ms>-<seq>, returning -1, 0 or 1
+func CompareStreamIDs(a, b string) int {
+	am, as := splitStreamID(a)
+	bm, bs := splitStreamID(b)
+	switch {
+	case am < bm || (am == bm && as < bs):
+		return -1
+	case am == bm && as == bs:
+		return 0
+	default:
+		return 1
+	}
+}
+
+func validStreamID(id string) bool {
+	ms, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return false
+	}
+	_, err1 := strconv.ParseUint(ms, 10, 64)
+	_, err2 := strconv.ParseUint(seq, 10, 64)
+	return err1 == nil && err2 == nil
+}
+
+func splitStreamID(id string) (uint64, uint64) {
+	ms, seq, _ := strings.Cut(id, "-")
+	m, _ := strconv.ParseUint(ms, 10, 64)
+	n, _ := strconv.ParseUint(seq, 10, 64)
+	return m, n
+}
@@ -0,0 +1,80 @@
+package services_test
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/services"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCompareStreamIDs(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1-0", "1-0", 0},
+		{"1-0", "1-1", -1},
+		{"1-1", "1-0", 1},
+		{"2-0", "10-0", -1},
+		{"10-0", "9-99", 1},
+		{"1700000000000-2", "1700000000000-10", -1},
+	}
+	for _, c := range cases {
+		if got := services.CompareStreamIDs(c.a, c.b); got != c.want {
+			t.Errorf("CompareStreamIDs(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestStreamViewEventsNeedRead(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	stream := services.NewStreamService(f.roles, nil, l)
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	err := f.roles.AddUserToDash(ctx, dash.ID, user, "viewer", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := f.addView(t, owner, dash.ID, "revenue")
+
+	e := f.events[len(f.events)-1]
+	data, _ := json.Marshal(e)
+	idsOnly, _ := json.Marshal(e.WithoutData())
+	ev := &models.StreamEvent{ID: "1-0", Type: e.Type, Data: data, ViewID: e.ViewID, IDsOnly: idsOnly}
+
+	got, err := stream.DataFor(ctx, owner, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("creator of the view got %s", got)
+	}
+	//reading the dashboard is not enough to see its views
+	got, err = stream.DataFor(ctx, user, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(idsOnly) {
+		t.Errorf("viewer of the dashboard got %s, want ids only", got)
+	}
+
+	err = f.roles.AddUserToView(ctx, view.ID, user, "viewer", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = stream.DataFor(ctx, user, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("viewer of the view got %s", got)
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// matches {{name}} placeholders, allowing whitespace inside the braces
+var templateVarRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Mark a dashboard as template declaring the given variables. Requires edit permission.
+func (s *DashService) MarkTemplate(ctx context.Context, userId, dashId uuid.UUID, vars []*models.TemplateVariable) error {
+	can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.WRITE_PERM)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+
+	seen := map[string]bool{}
+	for _, v := range vars {
+		if !templateVarRegex.MatchString("{{" + v.Name + "}}") {
+			return fmt.Errorf("%w: invalid variable name %q", er.ErrTemplateVariables, v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%w: duplicate variable %q", er.ErrTemplateVariables, v.Name)
+		}
+		seen[v.Name] = true
+	}
+
+	err = s.ds.SetTemplate(ctx, dashId, true, vars)
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, models.EventDashUpdated, dashId, userId, map[string]interface{}{"isTemplate": true, "variables": vars})
+	return nil
+}
+
+// Turn a template back into a regular dashboard. Requires edit permission.
+func (s *DashService) UnmarkTemplate(ctx context.Context, userId, dashId uuid.UUID) error {
+	can, err := s.Rs.ExistsPermissionForUserForDashboard(ctx, userId, dashId, perms.WRITE_PERM)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+
+	err = s.ds.SetTemplate(ctx, dashId, false, nil)
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, models.EventDashUpdated, dashId, userId, map[string]interface{}{"isTemplate": false})
+	return nil
+}
+
+// Get the template catalogue visible to a user
+func (s *DashService) GetTemplatesForUser(ctx context.Context, userId uuid.UUID) ([]*models.Dash, error) {
+	return s.ds.GetTemplatesForUser(ctx, userId)
+}
+
+// Create a new dashboard from a template, substituting variable values into the names,
+// descriptions and configs of the dashboard and the views the user can read.
+func (s *DashService) InstantiateTemplate(ctx context.Context, userId, templateId uuid.UUID, name, description string, values map[string]string) (*models.Dash, error) {
+	tmpl, err := s.GetDashByIdForUser(ctx, userId, templateId)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !tmpl.IsTemplate {
+		return nil, er.ErrNotTemplate
+	}
+
+	resolved, err := resolveTemplateValues(tmpl.Variables, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = tmpl.Name
+	}
+	if description == "" {
+		description = tmpl.Description
+	}
+	dash := &models.Dash{
+		Name:        substituteVars(name, resolved),
+		Description: substituteVars(description, resolved),
+		Layout:      tmpl.Layout,
+	}
+	for _, v := range tmpl.Views {
+		config, err := substituteConfig(v.Config, resolved)
+		if err != nil {
+			return nil, err
+		}
+		dash.Views = append(dash.Views, &models.View{
+			ID:          v.ID,
+			Name:        substituteVars(v.Name, resolved),
+			Description: substituteVars(v.Description, resolved),
+			Config:      config,
+		})
+	}
+
+	err = s.ds.CloneDash(ctx, templateId, dash, userId, false)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, models.EventDashCreated, dash.ID, userId, dash)
+	return dash, nil
+}
+
+// checks values against the declared variables, filling in defaults
+func resolveTemplateValues(vars []*models.TemplateVariable, values map[string]string) (map[string]string, error) {
+	resolved := map[string]string{}
+	declared := map[string]bool{}
+	for _, v := range vars {
+		declared[v.Name] = true
+		if val, ok := values[v.Name]; ok {
+			resolved[v.Name] = val
+		} else if v.Default != nil {
+			resolved[v.Name] = *v.Default
+		} else {
+			return nil, fmt.Errorf("%w: missing value for %q", er.ErrTemplateVariables, v.Name)
+		}
+	}
+	for name := range values {
+		if !declared[name] {
+			return nil, fmt.Errorf("%w: unknown variable %q", er.ErrTemplateVariables, name)
+		}
+	}
+	return resolved, nil
+}
+
+// replaces declared placeholders, leaving unknown ones untouched
+func substituteVars(s string, values map[string]string) string {
+	return templateVarRegex.ReplaceAllStringFunc(s, func(match string) string {
+		name := templateVarRegex.FindStringSubmatch(match)[1]
+		if val, ok := values[name]; ok {
+			return val
+		}
+		return match
+	})
+}
+
+// substitutes variables in every string value of a json config
+func substituteConfig(config json.RawMessage, values map[string]string) (json.RawMessage, error) {
+	if len(config) == 0 {
+		return config, nil
+	}
+	var doc interface{}
+	err := json.Unmarshal(config, &doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(substituteJSON(doc, values))
+}
+
+func substituteJSON(node interface{}, values map[string]string) interface{} {
+	switch n := node.(type) {
+	case string:
+		return substituteVars(n, values)
+	case []interface{}:
+		for i := range n {
+			n[i] = substituteJSON(n[i], values)
+		}
+		return n
+	case map[string]interface{}:
+		for k := range n {
+			n[k] = substituteJSON(n[k], values)
+		}
+		return n
+	default:
+		return n
+	}
+}
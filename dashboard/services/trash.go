@@ -0,0 +1,130 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"backend/dashboard/repository"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// service to list, restore and purge trashed dashboards and views
+type TrashService struct {
+	ds        *repository.DashRepository
+	vR        *repository.ViewRepository
+	rR        *repository.RoleRepository
+	retention time.Duration
+	L         logrus.FieldLogger
+}
+
+// Creates a new instance of TrashService. Trashed items older than retention are purged.
+func NewTrashService(d *repository.DashRepository, v *repository.ViewRepository, r *repository.RoleRepository, retention time.Duration, l logrus.FieldLogger) *TrashService {
+	return &TrashService{d, v, r, retention, l}
+}
+
+// Get trashed dashboards and views the user is allowed to restore
+func (s *TrashService) GetTrashForUser(ctx context.Context, userId uuid.UUID) (*models.Trash, error) {
+	dashs, err := s.ds.GetTrashedDashsForUser(ctx, userId, perms.DELETE_PERM)
+	if err != nil {
+		return nil, err
+	}
+	views, err := s.vR.GetTrashedViewsForUser(ctx, userId, perms.DELETE_PERM)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Trash{Dashboards: dashs, Views: views, Retention: s.retention.String()}, nil
+}
+
+// Restore a trashed dashboard along with its views. Only users with delete permission can restore.
+func (s *TrashService) RestoreDash(ctx context.Context, userId, dashId uuid.UUID) error {
+	can, err := s.rR.ExistsPermissionForUserForTrashedDashboard(ctx, userId, dashId, perms.DELETE_PERM)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+
+	dash, err := s.ds.GetTrashedDash(ctx, dashId)
+	if err == sql.ErrNoRows {
+		return er.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if s.expired(*dash.DeletedAt) {
+		return er.ErrRestoreWindowExpired
+	}
+
+	err = s.ds.RestoreDash(ctx, dashId)
+	if err == sql.ErrNoRows {
+		return er.ErrNotFound
+	}
+	return err
+}
+
+// Restore a trashed view. Only users with delete permission on the view can restore.
+func (s *TrashService) RestoreView(ctx context.Context, userId, viewId uuid.UUID) error {
+	can, err := s.rR.ExistsPermissionForUserForTrashedView(ctx, userId, viewId, perms.DELETE_PERM)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+
+	view, err := s.vR.GetTrashedView(ctx, viewId)
+	if err == sql.ErrNoRows {
+		return er.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if s.expired(*view.DeletedAt) {
+		return er.ErrRestoreWindowExpired
+	}
+
+	err = s.vR.RestoreView(ctx, viewId)
+	if err == sql.ErrNoRows {
+		return er.ErrNotFound
+	}
+	return err
+}
+
+// Permanently delete everything that has been in trash for longer than the retention window
+func (s *TrashService) Purge(ctx context.Context) error {
+	dashs, views, err := s.ds.PurgeTrash(ctx, s.retention)
+	if err != nil {
+		return err
+	}
+	if dashs > 0 || views > 0 {
+		s.L.Printf("Purged %d dashboards and %d views from trash", dashs, views)
+	}
+	return nil
+}
+
+// Run Purge every interval until ctx is cancelled
+func (s *TrashService) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Purge(ctx); err != nil {
+			s.L.Printf("Could not purge trash: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *TrashService) expired(deletedAt time.Time) bool {
+	return time.Since(deletedAt) > s.retention
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"backend/dashboard/models"
+	"backend/logging"
+	"backend/middlewares"
+	"backend/tracing"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Resolves users through the auth service on behalf of the calling user
+type UserDirectory struct {
+	baseURL string
+	client  *http.Client
+	l       logrus.FieldLogger
+}
+
+// Creates a new UserDirectory talking to the auth service at baseURL. Calls carry the
+// trace context of the request they are made for.
+func NewUserDirectory(baseURL string, l logrus.FieldLogger) *UserDirectory {
+	client := &http.Client{Timeout: 5 * time.Second, Transport: tracing.Transport(nil)}
+	return &UserDirectory{baseURL, client, l}
+}
+
+type lookupUsersRequest struct {
+	Emails []string    `json:"emails,omitempty"`
+	Ids    []uuid.UUID `json:"ids,omitempty"`
+}
+
+type lookupUsersResponse struct {
+	Success bool           `json:"success"`
+	Data    []*models.User `json:"data"`
+	Error   string         `json:"error"`
+}
+
+// Get users with the given emails. Unknown emails are left out.
+func (d *UserDirectory) LookupByEmails(ctx context.Context, emails []string) ([]*models.User, error) {
+	if len(emails) == 0 {
+		return []*models.User{}, nil
+	}
+	return d.lookup(ctx, &lookupUsersRequest{Emails: emails})
+}
+
+// Get users with the given ids. Unknown ids are left out.
+func (d *UserDirectory) LookupByIds(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	if len(ids) == 0 {
+		return []*models.User{}, nil
+	}
+	return d.lookup(ctx, &lookupUsersRequest{Ids: ids})
+}
+
+func (d *UserDirectory) lookup(ctx context.Context, body *lookupUsersRequest) ([]*models.User, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.baseURL+"/users/lookup", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", middlewares.TokenFromContext(ctx))
+	if id := logging.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(middlewares.RequestIDHeader, id)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := &lookupUsersResponse{}
+	err = json.NewDecoder(resp.Body).Decode(res)
+	if err != nil {
+		return nil, fmt.Errorf("user lookup: %s: %w", resp.Status, err)
+	}
+	if !res.Success {
+		return nil, fmt.Errorf("user lookup: %s: %s", resp.Status, res.Error)
+	}
+	return res.Data, nil
+}
@@ -3,57 +3,72 @@ package services
 import (
 	er "backend/dashboard/errors"
 	"backend/dashboard/models"
-	"backend/dashboard/repository"
+	"backend/dashboard/perms"
 	"context"
-	"log"
 
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 )
 
 type ViewService struct {
-	vR *repository.ViewRepository
-	Rs *RoleService
-	l  *log.Logger
+	vR     ViewStore
+	Rs     *RoleService
+	events *EventBus
+	l      logrus.FieldLogger
 }
 
-func NewViewService(r *repository.ViewRepository, rs *RoleService, l *log.Logger) *ViewService {
-	return &ViewService{r, rs, l}
+func NewViewService(r ViewStore, rs *RoleService, events *EventBus, l logrus.FieldLogger) *ViewService {
+	return &ViewService{r, rs, events, l}
 }
 
 // Get all views attached to a particular dashboard
-func (s *ViewService) GetViewsByDashIdForUser(dashId, userId uuid.UUID) ([]*models.View, error) {
-	views, err := s.vR.GetViewsByDashIdForUser(dashId, userId)
+func (s *ViewService) GetViewsByDashIdForUser(ctx context.Context, dashId, userId uuid.UUID) ([]*models.View, error) {
+	views, err := s.vR.GetViewsByDashIdForUser(ctx, dashId, userId)
 	if err != nil {
 		return nil, err
 	}
-	log.Println(dashId, userId)
-	log.Println("Views: ", views)
+	s.l.WithField("dash_id", dashId).WithField("user_id", userId).Debugf("Loaded %d views", len(views))
 	return views, nil
 }
 
-func (s *ViewService) AddView(v *models.View, userId uuid.UUID) error {
-	err := s.vR.AddView(context.Background(), v, userId)
+func (s *ViewService) AddView(ctx context.Context, v *models.View, userId uuid.UUID) error {
+	err := s.vR.AddView(ctx, v, userId)
 	if err != nil {
 		return err
 	}
+	s.events.Publish(ctx, &models.Event{
+		Type: models.EventViewCreated, DashID: v.DashID, ViewID: &v.ID, ActorID: &userId, Data: v,
+	})
 	return nil
 }
 
-func (s *ViewService) GetView(viewId, userId uuid.UUID) (*models.View, error) {
-	return s.vR.GetView(viewId, userId)
+func (s *ViewService) GetView(ctx context.Context, viewId, userId uuid.UUID) (*models.View, error) {
+	return s.vR.GetView(ctx, viewId, userId)
 }
 
 // func (s *ViewService) ExistsPermissionForViewForDashboard(viewId, dashId, userId uuid.UUID, perm string) (bool, error) {
 // 	return s.Rs.ExistsPermissionForUserForDashboard(userId, dashId, perm)
 // }
 
-func (s *ViewService) DeleteView(viewId, userId uuid.UUID) error {
-	can, err := s.Rs.ExistsPermissionForUserForView(userId, viewId, "delete")
+// Move a view with given id to trash
+func (s *ViewService) DeleteView(ctx context.Context, viewId, userId uuid.UUID) error {
+	can, err := s.Rs.ExistsPermissionForUserForView(ctx, userId, viewId, perms.DELETE_PERM)
 	if err != nil {
 		return err
 	}
 	if !can {
 		return er.ErrNoPerm
 	}
-	return s.vR.DeleteView(viewId)
+	view, err := s.vR.GetViewById(ctx, viewId)
+	if err != nil {
+		return err
+	}
+	err = s.vR.TrashView(ctx, viewId, userId)
+	if err != nil {
+		return err
+	}
+	s.events.Publish(ctx, &models.Event{
+		Type: models.EventViewDeleted, DashID: view.DashID, ViewID: &viewId, ActorID: &userId,
+	})
+	return nil
 }
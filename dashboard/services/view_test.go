@@ -0,0 +1,118 @@
+package services_test
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Names of the views of a dashboard the user sees
+func viewNames(t *testing.T, f *fixture, userId, dashId uuid.UUID) []string {
+	t.Helper()
+	dash, err := f.dashs.GetDashByIdForUser(context.Background(), userId, dashId)
+	if err != nil {
+		t.Fatalf("GetDashByIdForUser: %v", err)
+	}
+	names := []string{}
+	for _, v := range dash.Views {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+func TestViewsNeedTheirOwnRole(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	revenue := f.addView(t, owner, dash.ID, "revenue")
+	f.addView(t, owner, dash.ID, "costs")
+
+	if got := viewNames(t, f, owner, dash.ID); len(got) != 2 {
+		t.Fatalf("creator sees views %v, want both", got)
+	}
+
+	//a role on the dashboard does not reach its views
+	err := f.roles.AddUserToDash(ctx, dash.ID, user, "editor", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := viewNames(t, f, user, dash.ID); len(got) != 0 {
+		t.Fatalf("editor of the dashboard sees views %v, want none", got)
+	}
+
+	err = f.roles.AddUserToView(ctx, revenue.ID, user, "viewer", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := viewNames(t, f, user, dash.ID); len(got) != 1 || got[0] != "revenue" {
+		t.Errorf("user sees views %v, want [revenue]", got)
+	}
+
+	page, err := f.dashs.ListDashboardsForUser(ctx, user, &models.DashListOptions{Sort: models.SortByName, Limit: 10}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || len(page.Items[0].Views) != 1 || page.Items[0].Views[0].ID != revenue.ID {
+		t.Errorf("listing shows %+v, want the dashboard with the revenue view", page.Items)
+	}
+}
+
+func TestViewRoleAloneHidesDashboard(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	view := f.addView(t, owner, dash.ID, "revenue")
+
+	err := f.roles.AddUserToView(ctx, view.ID, user, "viewer", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.views.GetView(ctx, view.ID, user)
+	if err != nil {
+		t.Fatalf("reading the view: %v", err)
+	}
+	if got.ID != view.ID || got.DashID != dash.ID {
+		t.Errorf("got view %+v", got)
+	}
+	_, err = f.dashs.GetDashByIdForUser(ctx, user, dash.ID)
+	if !errors.Is(err, er.ErrNoPerm) {
+		t.Errorf("reading the dashboard with a role on a view only: got %v, want %v", err, er.ErrNoPerm)
+	}
+}
+
+func TestDeleteView(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	owner, user := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	view := f.addView(t, owner, dash.ID, "revenue")
+	err := f.roles.AddUserToView(ctx, view.ID, user, "editor", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.views.DeleteView(ctx, view.ID, user)
+	if !errors.Is(err, er.ErrNoPerm) {
+		t.Fatalf("deleting as editor: got %v, want %v", err, er.ErrNoPerm)
+	}
+	err = f.views.DeleteView(ctx, view.ID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := viewNames(t, f, owner, dash.ID); len(got) != 0 {
+		t.Errorf("views in trash still shown: %v", got)
+	}
+	_, err = f.views.GetView(ctx, view.ID, user)
+	if err == nil {
+		t.Error("view in trash can still be read")
+	}
+	if last := f.events[len(f.events)-1]; last.Type != models.EventViewDeleted || last.DashID != dash.ID {
+		t.Errorf("last event %s on %s, want %s on %s", last.Type, last.DashID, models.EventViewDeleted, dash.ID)
+	}
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	er "backend/dashboard/errors"
+	"backend/dashboard/models"
+	"backend/dashboard/perms"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// deliveries claimed per round of the dispatcher
+	deliveryBatchSize = 20
+	// deliveries are given up on after this many attempts
+	maxDeliveryAttempts = 8
+	// first retry waits this long, doubling on every attempt after
+	deliveryBackoff    = 30 * time.Second
+	maxDeliveryBackoff = time.Hour
+	// time allowed for a receiver to answer
+	deliveryTimeout = 10 * time.Second
+)
+
+// deliveries only go to addresses reachable from the internet, never to the services
+// next to this one
+var errPrivateTarget = errors.New("webhook url does not resolve to a public address")
+
+// Headers sent along with every delivery
+const (
+	WebhookIDHeader        = "X-Webhook-Id"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// service managing webhooks and delivering events to them
+type WebhookService struct {
+	wR     WebhookStore
+	rs     *RoleService
+	admins map[uuid.UUID]bool
+	client *http.Client
+	wake   chan struct{}
+	L      logrus.FieldLogger
+}
+
+// Creates a new instance of WebhookService. Only the given system admins manage global webhooks.
+// Deliveries to loopback and private addresses are refused unless allowPrivateTargets is set,
+// which is meant for receivers on a developer machine.
+func NewWebhookService(w WebhookStore, rs *RoleService, admins []uuid.UUID, allowPrivateTargets bool, l logrus.FieldLogger) *WebhookService {
+	adminSet := map[uuid.UUID]bool{}
+	for _, id := range admins {
+		adminSet[id] = true
+	}
+	dialer := &net.Dialer{Timeout: deliveryTimeout}
+	dial := publicDialContext(dialer)
+	if allowPrivateTargets {
+		dial = dialer.DialContext
+	}
+	client := &http.Client{
+		Timeout: deliveryTimeout,
+		// no proxy, it would dial the receiver past the address check
+		Transport: &http.Transport{DialContext: dial, TLSHandshakeTimeout: deliveryTimeout},
+		// a redirect is an answer like any other, it is not followed
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &WebhookService{w, rs, adminSet, client, make(chan struct{}, 1), l}
+}
+
+// Signature of a delivery: hex encoded HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+// webhook secret, prefixed with "sha256=". Receivers recompute it to authenticate deliveries.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Register a webhook. Webhooks on a dashboard need edit_access on it, global ones need a
+// system admin. A secret is generated unless one is given.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userId uuid.UUID, hook *models.Webhook) (*models.Webhook, error) {
+	err := s.checkCanManage(ctx, userId, hook.DashID)
+	if err != nil {
+		return nil, err
+	}
+	err = checkWebhook(hook)
+	if err != nil {
+		return nil, err
+	}
+
+	if hook.Secret == "" {
+		hook.Secret, err = newShareToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	hook.CreatedBy = userId
+	hook.Active = true
+	err = s.wR.AddWebhook(ctx, hook)
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Get the webhooks the user can manage
+func (s *WebhookService) GetWebhooks(ctx context.Context, userId uuid.UUID) ([]*models.Webhook, error) {
+	return s.wR.GetWebhooksForUser(ctx, userId, perms.ACCESS_MOD, s.admins[userId])
+}
+
+// Get a webhook the user can manage
+func (s *WebhookService) GetWebhook(ctx context.Context, userId, id uuid.UUID) (*models.Webhook, error) {
+	hook, err := s.wR.GetWebhook(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkCanManage(ctx, userId, hook.DashID)
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Change the url, events and state of a webhook. The dashboard and secret stay as they are.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, userId, id uuid.UUID, update *models.Webhook) (*models.Webhook, error) {
+	hook, err := s.GetWebhook(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	err = checkWebhook(update)
+	if err != nil {
+		return nil, err
+	}
+
+	hook.URL, hook.Events, hook.Active = update.URL, update.Events, update.Active
+	err = s.wR.UpdateWebhook(ctx, hook)
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Delete a webhook and its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userId, id uuid.UUID) error {
+	_, err := s.GetWebhook(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	return s.wR.DeleteWebhook(ctx, id)
+}
+
+// Get a page of the delivery log of a webhook, newest first
+func (s *WebhookService) GetDeliveries(ctx context.Context, userId, id uuid.UUID, limit, offset int) ([]*models.WebhookDelivery, int, error) {
+	_, err := s.GetWebhook(ctx, userId, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.wR.GetDeliveries(ctx, id, limit, offset)
+}
+
+// Queue a past delivery of a webhook again, whatever became of it
+func (s *WebhookService) Redeliver(ctx context.Context, userId, id, deliveryId uuid.UUID) (*models.WebhookDelivery, error) {
+	_, err := s.GetWebhook(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.wR.Redeliver(ctx, id, deliveryId)
+	if err == sql.ErrNoRows {
+		return nil, er.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.wakeDispatcher()
+	return d, nil
+}
+
+// Queue an event for the webhooks subscribed to it. Meant to be subscribed to the event bus.
+// Webhooks on a dashboard get the data of a view event only when their creator can read the view.
+func (s *WebhookService) Enqueue(ctx context.Context, e *models.Event) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		s.L.Printf("Could not encode event %s: %v", e.ID, err)
+		return
+	}
+	idsOnly, err := json.Marshal(e.WithoutData())
+	if err != nil {
+		s.L.Printf("Could not encode event %s: %v", e.ID, err)
+		return
+	}
+	n, err := s.wR.EnqueueDeliveries(ctx, e, payload, idsOnly)
+	if err != nil {
+		s.L.Printf("Could not queue deliveries of event %s: %v", e.ID, err)
+		return
+	}
+	if n > 0 {
+		s.wakeDispatcher()
+	}
+}
+
+// Send the deliveries that are due, returning how many were attempted
+func (s *WebhookService) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := s.wR.ClaimDueDeliveries(ctx, deliveryBatchSize, 2*deliveryTimeout)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func(d *models.WebhookDelivery) {
+			defer wg.Done()
+			s.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// Send due deliveries every interval, or as soon as new ones are queued, until ctx is done
+func (s *WebhookService) RunDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.Dispatch(ctx)
+		if err != nil {
+			s.L.Printf("Could not dispatch webhook deliveries: %v", err)
+		}
+		// a full batch likely means more are due
+		if n == deliveryBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// send a claimed delivery and record the outcome
+func (s *WebhookService) attempt(ctx context.Context, d *models.WebhookDelivery) {
+	code, err := s.send(ctx, d)
+
+	status, retryIn := models.DeliverySucceeded, time.Duration(0)
+	var statusCode *int
+	var lastErr *string
+	if code != 0 {
+		statusCode = &code
+	}
+	if err != nil {
+		msg := err.Error()
+		lastErr = &msg
+		status, retryIn = models.DeliveryPending, deliveryRetryDelay(d.Attempts)
+		if d.Attempts >= maxDeliveryAttempts {
+			status, retryIn = models.DeliveryFailed, 0
+		}
+	}
+
+	err = s.wR.FinishDelivery(ctx, d.ID, status, statusCode, lastErr, retryIn)
+	if err != nil {
+		s.L.Printf("Could not record delivery %s: %v", d.ID, err)
+	}
+}
+
+// POST the payload to the webhook url. Any 2xx answer is a success. Only the status code of
+// other answers is kept, their body could be anything the receiver holds.
+func (s *WebhookService) send(ctx context.Context, d *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, d.WebhookID.String())
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookDeliveryHeader, d.ID.String())
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(d.Secret, timestamp, d.Payload))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver answered %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+func (s *WebhookService) wakeDispatcher() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *WebhookService) checkCanManage(ctx context.Context, userId uuid.UUID, dashId *uuid.UUID) error {
+	if dashId == nil {
+		if !s.admins[userId] {
+			return er.ErrNoPerm
+		}
+		return nil
+	}
+	can, err := s.rs.ExistsPermissionForUserForDashboard(ctx, userId, *dashId, perms.ACCESS_MOD)
+	if err != nil {
+		return err
+	}
+	if !can {
+		return er.ErrNoPerm
+	}
+	return nil
+}
+
+// url must be absolute http(s) and every event type known
+func checkWebhook(hook *models.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return er.ErrInvalidWebhookURL
+	}
+
+	known := map[string]bool{}
+	for _, t := range models.EventTypes {
+		known[t] = true
+	}
+	if len(hook.Events) == 0 {
+		return er.ErrInvalidEvent
+	}
+	for _, t := range hook.Events {
+		if !known[t] {
+			return er.ErrInvalidEvent
+		}
+	}
+	return nil
+}
+
+// Dials like dialer, once every address the host resolves to is public. The checked address
+// is the one dialed, so a second lookup cannot answer differently.
+func publicDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, errPrivateTarget
+		}
+		for _, ip := range ips {
+			if !isPublicIP(ip.IP) {
+				return nil, errPrivateTarget
+			}
+		}
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+	}
+}
+
+// false for loopback, private, link-local, multicast and unspecified addresses
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// wait before the next attempt after the given number of attempts
+func deliveryRetryDelay(attempts int) time.Duration {
+	delay := deliveryBackoff
+	for i := 1; i < attempts && delay < maxDeliveryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxDeliveryBackoff {
+		delay = maxDeliveryBackoff
+	}
+	return delay
+}
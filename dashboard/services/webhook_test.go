@@ -0,0 +1,308 @@
+package services_test
+
+import (
+	"backend/dashboard/models"
+	"backend/dashboard/repository/memory"
+	"backend/dashboard/services"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const webhookSecret = "whsec"
+
+// Receiver of deliveries, answering with the codes in answers and 200 once they run out
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	answers  []int
+	payloads map[string][]json.RawMessage
+	badSigs  int
+}
+
+func newReceiver(t *testing.T, answers ...int) *receiver {
+	rec := &receiver{answers: answers, payloads: map[string][]json.RawMessage{}}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		sig := services.SignWebhookPayload(webhookSecret, r.Header.Get(services.WebhookTimestampHeader), body)
+		if r.Header.Get(services.WebhookSignatureHeader) != sig {
+			rec.badSigs++
+		}
+		hookId := r.Header.Get(services.WebhookIDHeader)
+		rec.payloads[hookId] = append(rec.payloads[hookId], body)
+
+		code := http.StatusOK
+		if len(rec.answers) > 0 {
+			code, rec.answers = rec.answers[0], rec.answers[1:]
+		}
+		w.WriteHeader(code)
+		w.Write([]byte("connection to dash_db refused"))
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (rec *receiver) received(hookId uuid.UUID) []json.RawMessage {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.payloads[hookId.String()]
+}
+
+func newWebhooks(f *fixture, allowPrivateTargets bool) *services.WebhookService {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return services.NewWebhookService(memory.NewWebhookRepository(f.store), f.roles, nil, allowPrivateTargets, l)
+}
+
+// Webhook on a dashboard receiving view events, signed with webhookSecret
+func addWebhook(t *testing.T, hooks *services.WebhookService, userId, dashId uuid.UUID, url string) *models.Webhook {
+	t.Helper()
+	hook, err := hooks.CreateWebhook(context.Background(), userId, &models.Webhook{
+		DashID: &dashId,
+		URL:    url,
+		Events: []string{models.EventViewCreated},
+		Secret: webhookSecret,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	return hook
+}
+
+// The only delivery of a webhook
+func delivery(t *testing.T, hooks *services.WebhookService, userId, hookId uuid.UUID) *models.WebhookDelivery {
+	t.Helper()
+	deliveries, total, err := hooks.GetDeliveries(context.Background(), userId, hookId, 10, 0)
+	if err != nil {
+		t.Fatalf("GetDeliveries: %v", err)
+	}
+	if total != 1 {
+		t.Fatalf("%d deliveries, want 1", total)
+	}
+	return deliveries[0]
+}
+
+func dispatch(t *testing.T, hooks *services.WebhookService, want int) {
+	t.Helper()
+	n, err := hooks.Dispatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != want {
+		t.Fatalf("dispatched %d deliveries, want %d", n, want)
+	}
+}
+
+// the clock of the store as it keeps timestamps
+func (f *fixture) stamp() time.Time {
+	return f.now.Round(0).Truncate(time.Microsecond)
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	got := services.SignWebhookPayload(webhookSecret, "1700000000", []byte(`{"type":"dashboard.created"}`))
+	want := "sha256=ff73cda0d2b3af3c75ebf6e71618c1b072bd5dafae64642ae25c53d7745759a1"
+	if got != want {
+		t.Errorf("signature %s, want %s", got, want)
+	}
+	if services.SignWebhookPayload("other", "1700000000", []byte(`{"type":"dashboard.created"}`)) == want {
+		t.Error("signature does not depend on the secret")
+	}
+	if services.SignWebhookPayload(webhookSecret, "1700000001", []byte(`{"type":"dashboard.created"}`)) == want {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestDispatchRetries(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	hooks := newWebhooks(f, true)
+	rec := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	owner := uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	hook := addWebhook(t, hooks, owner, dash.ID, rec.URL)
+
+	f.addView(t, owner, dash.ID, "revenue")
+	hooks.Enqueue(ctx, f.events[len(f.events)-1])
+
+	dispatch(t, hooks, 1)
+	d := delivery(t, hooks, owner, hook.ID)
+	if d.Status != models.DeliveryPending || d.Attempts != 1 || d.LastStatusCode == nil || *d.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("after a failed attempt: %+v", d)
+	}
+	//the body of the answer is not kept, it could be anything the receiver holds
+	if d.LastError == nil || strings.Contains(*d.LastError, "dash_db") {
+		t.Errorf("last error %v, want the status code only", d.LastError)
+	}
+	if want := f.stamp().Add(30 * time.Second); !d.NextAttemptAt.Equal(want) {
+		t.Errorf("next attempt at %s, want %s", d.NextAttemptAt, want)
+	}
+
+	//nothing is due until the backoff passed
+	dispatch(t, hooks, 0)
+	f.advance(30 * time.Second)
+	dispatch(t, hooks, 1)
+	d = delivery(t, hooks, owner, hook.ID)
+	if want := f.stamp().Add(time.Minute); !d.NextAttemptAt.Equal(want) {
+		t.Errorf("next attempt at %s, want %s after the second failure", d.NextAttemptAt, want)
+	}
+
+	f.advance(time.Minute)
+	dispatch(t, hooks, 1)
+	d = delivery(t, hooks, owner, hook.ID)
+	if d.Status != models.DeliverySucceeded || d.Attempts != 3 || d.DeliveredAt == nil || d.LastError != nil {
+		t.Errorf("after the receiver answered 200: %+v", d)
+	}
+	if got := rec.received(hook.ID); len(got) != 3 || string(got[0]) != string(got[2]) {
+		t.Errorf("receiver got %d payloads, want the same payload 3 times", len(got))
+	}
+	if rec.badSigs != 0 {
+		t.Errorf("%d deliveries with a bad signature", rec.badSigs)
+	}
+}
+
+func TestDispatchGivesUp(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	hooks := newWebhooks(f, true)
+	answers := make([]int, 10)
+	for i := range answers {
+		answers[i] = http.StatusServiceUnavailable
+	}
+	rec := newReceiver(t, answers...)
+	owner := uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	hook := addWebhook(t, hooks, owner, dash.ID, rec.URL)
+	f.addView(t, owner, dash.ID, "revenue")
+	hooks.Enqueue(ctx, f.events[len(f.events)-1])
+
+	//the wait doubles from 30s on every failed attempt
+	for attempt := 1; attempt < 8; attempt++ {
+		dispatch(t, hooks, 1)
+		d := delivery(t, hooks, owner, hook.ID)
+		if want := f.stamp().Add(30 * time.Second << (attempt - 1)); d.Status != models.DeliveryPending || !d.NextAttemptAt.Equal(want) {
+			t.Fatalf("attempt %d: %s, next at %s, want pending until %s", attempt, d.Status, d.NextAttemptAt, want)
+		}
+		f.advance(2 * time.Hour)
+	}
+	dispatch(t, hooks, 1)
+	d := delivery(t, hooks, owner, hook.ID)
+	if d.Status != models.DeliveryFailed || d.Attempts != 8 {
+		t.Fatalf("after 8 attempts: %s with %d attempts, want %s", d.Status, d.Attempts, models.DeliveryFailed)
+	}
+	f.advance(2 * time.Hour)
+	dispatch(t, hooks, 0)
+}
+
+func TestWebhookRefusesPrivateTargets(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	hooks := newWebhooks(f, false)
+	rec := newReceiver(t)
+	owner := uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	hook := addWebhook(t, hooks, owner, dash.ID, rec.URL)
+	f.addView(t, owner, dash.ID, "revenue")
+	hooks.Enqueue(ctx, f.events[len(f.events)-1])
+
+	dispatch(t, hooks, 1)
+	if got := rec.received(hook.ID); len(got) != 0 {
+		t.Fatalf("delivered to loopback %d times", len(got))
+	}
+	d := delivery(t, hooks, owner, hook.ID)
+	if d.LastError == nil || !strings.Contains(*d.LastError, "public address") {
+		t.Errorf("last error %v, want the address refused", d.LastError)
+	}
+}
+
+func TestWebhookViewEventsNeedRead(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	hooks := newWebhooks(f, true)
+	rec := newReceiver(t)
+	owner, manager := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	err := f.roles.AddUserToDash(ctx, dash.ID, manager, "manager", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	own := addWebhook(t, hooks, owner, dash.ID, rec.URL)
+	managed := addWebhook(t, hooks, manager, dash.ID, rec.URL)
+
+	view := f.addView(t, owner, dash.ID, "revenue")
+	hooks.Enqueue(ctx, f.events[len(f.events)-1])
+	dispatch(t, hooks, 2)
+
+	var full, idsOnly map[string]json.RawMessage
+	for hookId, into := range map[uuid.UUID]*map[string]json.RawMessage{own.ID: &full, managed.ID: &idsOnly} {
+		got := rec.received(hookId)
+		if len(got) != 1 {
+			t.Fatalf("webhook %s got %d payloads, want 1", hookId, len(got))
+		}
+		err = json.Unmarshal(got[0], into)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(string(full["data"]), view.Name) {
+		t.Errorf("creator of the view got data %s", full["data"])
+	}
+	//the manager of the dashboard holds no role on the view
+	if _, ok := idsOnly["data"]; ok || string(idsOnly["viewId"]) != `"`+view.ID.String()+`"` {
+		t.Errorf("webhook of a user who cannot read the view got %v", idsOnly)
+	}
+}
+
+func TestWebhookStopsWhenCreatorLosesAccess(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	hooks := newWebhooks(f, true)
+	rec := newReceiver(t)
+	owner, manager := uuid.New(), uuid.New()
+	dash := f.addDash(t, owner, "sales")
+	err := f.roles.AddUserToDash(ctx, dash.ID, manager, "manager", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook, err := hooks.CreateWebhook(ctx, manager, &models.Webhook{
+		DashID: &dash.ID,
+		URL:    rec.URL,
+		Events: []string{models.EventDashUpdated, models.EventDashDeleted},
+		Secret: webhookSecret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.roles.RevokeDashLevelRoleFromUser(ctx, dash.ID, manager, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.dashs.UpdateDash(ctx, owner, dash.ID, &models.Dash{Name: "sales 2023"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hooks.Enqueue(ctx, f.events[len(f.events)-1])
+	dispatch(t, hooks, 0)
+
+	//the deletion carries no data and dashboard_perms no longer lists trashed dashboards
+	err = f.dashs.DeleteDashById(ctx, owner, dash.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hooks.Enqueue(ctx, f.events[len(f.events)-1])
+	dispatch(t, hooks, 1)
+	if got := rec.received(hook.ID); len(got) != 1 || !strings.Contains(string(got[0]), models.EventDashDeleted) {
+		t.Errorf("received %s, want the deletion only", got)
+	}
+}
@@ -0,0 +1,146 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// A dependency check, failing with the reason the dependency cannot be used
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Serves liveness and readiness of a service. Readiness runs every check, each bounded
+// by the timeout, and fails when any of them does.
+type Checker struct {
+	checks  []namedCheck
+	timeout time.Duration
+}
+
+// Creates a checker whose checks each get at most timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add a check reported under name
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name, check})
+}
+
+// Result of a single check
+type CheckResult struct {
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Body of health responses
+type Report struct {
+	Status string                  `json:"status"`
+	Checks map[string]*CheckResult `json:"checks,omitempty"`
+}
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Run every check concurrently. The report is ok only when all checks pass.
+func (c *Checker) Run(ctx context.Context) *Report {
+	report := &Report{Status: StatusOK, Checks: make(map[string]*CheckResult, len(c.checks))}
+	results := make([]*CheckResult, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func(i int, nc namedCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := nc.check(ctx)
+			res := &CheckResult{Status: StatusOK, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				res.Status = StatusUnavailable
+				res.Error = err.Error()
+			}
+			results[i] = res
+		}(i, nc)
+	}
+	wg.Wait()
+
+	for i, nc := range c.checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+// Liveness, ok as long as the process serves requests. Dependencies are left out so an
+// outage of one does not get the service restarted.
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, &Report{Status: StatusOK})
+}
+
+// Readiness, 200 when every dependency check passes and 503 otherwise
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeReport(w, code, report)
+}
+
+func writeReport(w http.ResponseWriter, code int, report *Report) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Checks that postgres answers
+func Postgres(db *sql.DB) Check {
+	return db.PingContext
+}
+
+// Checks that the schema of db is at version or later and no migration was left half applied
+func Migrations(db *sql.DB, version uint) Check {
+	return func(ctx context.Context) error {
+		var current uint
+		var dirty bool
+		err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&current, &dirty)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("no migrations applied")
+		}
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", current)
+		}
+		if current < version {
+			return fmt.Errorf("schema at version %d, expected %d", current, version)
+		}
+		return nil
+	}
+}
+
+// Checks that redis answers
+func Redis(rdb *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	}
+}
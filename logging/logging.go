@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+type contextKey string
+
+const (
+	keyLogger    contextKey = "logger"
+	keyRequestID contextKey = "request-id"
+)
+
+// Creates the logger of a service. Entries are JSON with secrets redacted, at the given level.
+func New(service string, level logrus.Level) *logrus.Entry {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(&redactHook{})
+	logger.SetLevel(level)
+
+	return logger.WithField("service", service)
+}
+
+// Adapts a logger for APIs taking a standard library logger, such as http.Server.ErrorLog
+func StdLogger(l *logrus.Entry) *log.Logger {
+	return log.New(l.WriterLevel(logrus.ErrorLevel), "", 0)
+}
+
+// Returns a copy of ctx carrying the logger
+func WithLogger(ctx context.Context, l logrus.FieldLogger) context.Context {
+	return context.WithValue(ctx, keyLogger, l)
+}
+
+// Returns the logger carried by ctx, or fallback when there is none
+func FromContext(ctx context.Context, fallback logrus.FieldLogger) logrus.FieldLogger {
+	if l, ok := ctx.Value(keyLogger).(logrus.FieldLogger); ok {
+		return l
+	}
+	return fallback
+}
+
+// Returns the logger of the request, which carries its request id and user, or fallback
+func ForRequest(r *http.Request, fallback logrus.FieldLogger) logrus.FieldLogger {
+	return FromContext(r.Context(), fallback)
+}
+
+// Returns a copy of ctx carrying the request id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, keyRequestID, id)
+}
+
+// Returns the request id carried by ctx, empty when there is none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(keyRequestID).(string)
+	return id
+}
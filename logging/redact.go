@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// fields whose values are never logged
+var sensitiveFields = []string{"password", "secret", "token", "authorization", "api_key", "apikey", "cookie"}
+
+// secrets that can show up inside messages
+var redactions = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// JSON web tokens
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), redacted},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/-]+=*`), "${1}" + redacted},
+	{regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key|authorization)["']?\s*[:=]\s*["']?)[^\s"'&,;}]+`), "${1}" + redacted},
+	// share link tokens are bearer credentials in the path
+	{regexp.MustCompile(`(/public/shares/)[^/?\s"]+`), "${1}" + redacted},
+}
+
+// Removes tokens, passwords and other secrets from s
+func Redact(s string) string {
+	for _, r := range redactions {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+func sensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, f := range sensitiveFields {
+		if strings.Contains(name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// hook redacting the message and fields of every entry before it is written
+type redactHook struct{}
+
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactHook) Fire(e *logrus.Entry) error {
+	e.Message = Redact(e.Message)
+	for k, v := range e.Data {
+		if sensitiveField(k) {
+			e.Data[k] = redacted
+			continue
+		}
+		switch val := v.(type) {
+		case string:
+			e.Data[k] = Redact(val)
+		case error:
+			e.Data[k] = Redact(val.Error())
+		}
+	}
+	return nil
+}
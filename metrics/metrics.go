@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Latency of served requests by method, mux route template and status
+var RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "http_request_duration_seconds",
+	Help:    "Time taken to serve HTTP requests, by method, route template and status.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"method", "route", "status"})
+
+// Lookups of cached values by cache and result, which is hit or miss
+var CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_requests_total",
+	Help: "Lookups of cached values, by cache and result (hit or miss).",
+}, []string{"cache", "result"})
+
+// Permission checks by scope (dashboard, view or folder) and result (allowed, denied or error)
+var PermissionChecks = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "permission_checks_total",
+	Help: "Permission checks, by scope and result (allowed, denied or error).",
+}, []string{"scope", "result"})
+
+// Login attempts by result (success, invalid_credentials or error)
+var LoginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "login_attempts_total",
+	Help: "Login attempts, by result (success, invalid_credentials or error).",
+}, []string{"result"})
+
+// Registrations by result (success, email_taken or error), kept apart from logins
+var Registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "registrations_total",
+	Help: "Registrations, by result (success, email_taken or error).",
+}, []string{"result"})
+
+// Results of a cache lookup
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// Records the result of a permission check
+func ObservePermissionCheck(scope string, allowed bool, err error) {
+	result := "denied"
+	if err != nil {
+		result = "error"
+	} else if allowed {
+		result = "allowed"
+	}
+	PermissionChecks.WithLabelValues(scope, result).Inc()
+}
+
+// Creates a registry with go runtime, process and connection pool stats of db, along with the
+// request histogram. Services register their own metrics on top.
+func NewRegistry(db *sql.DB, dbName string) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, dbName),
+		RequestDuration,
+	)
+	return reg
+}
+
+// Serves the metrics of reg in the prometheus text format
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+// max time a scrape waits for a gauge query
+const queryTimeout = 5 * time.Second
+
+// Gauge read from the database on every scrape, such as the number of dashboards.
+// Values are keyed by the value of its one label.
+type queryGauge struct {
+	desc  *prometheus.Desc
+	query func(ctx context.Context) (map[string]int64, error)
+}
+
+// Creates a gauge with the given label whose values come from query on every scrape
+func NewQueryGauge(name, help, label string, query func(ctx context.Context) (map[string]int64, error)) prometheus.Collector {
+	return &queryGauge{
+		desc:  prometheus.NewDesc(name, help, []string{label}, nil),
+		query: query,
+	}
+}
+
+func (g *queryGauge) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *queryGauge) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	counts, err := g.query(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(g.desc, err)
+		return
+	}
+	for value, count := range counts {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, float64(count), value)
+	}
+}
@@ -1,19 +1,20 @@
 package middlewares
 
 import (
+	"backend/logging"
 	"backend/utils"
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
 func validateJWT(token *jwt.Token) (interface{}, error) {
 	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		fmt.Println("Unexpected signing method: ", token.Header["alg"])
-		return nil, fmt.Errorf("invalid signing method")
+		return nil, fmt.Errorf("invalid signing method %v", token.Header["alg"])
 	}
 
 	aud := "samudai-dash"
@@ -21,19 +22,15 @@ func validateJWT(token *jwt.Token) (interface{}, error) {
 
 	checkAudience := token.Claims.(jwt.MapClaims).VerifyAudience(aud, false)
 	if !checkAudience {
-		fmt.Println("Invalid audience")
 		return nil, fmt.Errorf("invalid aud")
 	}
 
 	checkIss := token.Claims.(jwt.MapClaims).VerifyIssuer(iss, false)
 	if !checkIss {
-		fmt.Println("Invalid issuer")
 		return nil, fmt.Errorf("invalid iss")
 	}
 
-	key := os.Getenv("JWT_SECRET_KEY")
-	fmt.Println("Key: ", key)
-	return []byte(key), nil
+	return utils.JWTSigningKey(), nil
 }
 
 func AuthMiddleware(next http.Handler) http.Handler {
@@ -41,18 +38,19 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		if r.Header["Authorization"] != nil {
 			token, err := jwt.Parse(r.Header["Authorization"][0], validateJWT)
 			if err != nil || !token.Valid {
-				fmt.Println(err, token.Valid)
-				utils.WriteFailureResponse(w, http.StatusUnauthorized, "Invalid Token")
+				logging.ForRequest(r, logrus.StandardLogger()).WithError(err).Info("Rejected token")
+				utils.WriteError(w, r, utils.ErrInvalidToken)
 				return
 			}
 
 			userId := token.Claims.(jwt.MapClaims)["sub"]
 			mux.Vars(r)[KeyUser] = userId.(string)
-			fmt.Println("User ID: ", userId)
 
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), keyToken, r.Header["Authorization"][0])
+			ctx = logging.WithLogger(ctx, logging.ForRequest(r, logrus.StandardLogger()).WithField("user_id", userId))
+			next.ServeHTTP(w, r.WithContext(ctx))
 		} else {
-			utils.WriteFailureResponse(w, http.StatusUnauthorized, "No Authorization Token provided")
+			utils.WriteError(w, r, utils.ErrMissingToken)
 		}
 	})
 }
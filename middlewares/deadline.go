@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+const keyConn contextKey = "conn"
+
+// Stores the connection in the context of its requests, set as ConnContext of the server
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, keyConn, c)
+}
+
+// Gives every request timeout to write its response. Used instead of the WriteTimeout of
+// the server so long lived responses such as event streams can push their deadline.
+func WriteDeadline(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ExtendWriteDeadline(r, timeout)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Allows the response to r another d to be written
+func ExtendWriteDeadline(r *http.Request, d time.Duration) error {
+	c, ok := r.Context().Value(keyConn).(net.Conn)
+	if !ok {
+		return nil
+	}
+	return c.SetWriteDeadline(time.Now().Add(d))
+}
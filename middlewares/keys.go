@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -11,6 +12,16 @@ const (
 	KeyUser = "user-key"
 )
 
+type contextKey string
+
+const keyToken contextKey = "auth-token"
+
 func GetUserIDFromVars(r *http.Request) (uuid.UUID, error) {
 	return uuid.Parse(mux.Vars(r)[KeyUser])
 }
+
+// Returns the raw Authorization token of the request, used to call other services on behalf of the user
+func TokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(keyToken).(string)
+	return token
+}
@@ -1,14 +1,106 @@
 package middlewares
 
 import (
+	"backend/logging"
+	"backend/tracing"
 	"net/http"
+	"regexp"
+	"time"
 
-	l "github.com/sirupsen/logrus"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
-func LoggerMiddleware(next http.Handler) http.Handler {
+// header carrying the id of a request across nginx and the services
+const RequestIDHeader = "X-Request-ID"
+
+// request ids taken from clients are kept short and printable
+var requestIDRegex = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Gives every request an id, reusing the X-Request-ID header when valid, and a logger
+// carrying it. The id is echoed in the response.
+func RequestID(logger logrus.FieldLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !requestIDRegex.MatchString(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := logging.WithRequestID(r.Context(), id)
+			l := logger.WithField("request_id", id)
+			//set when tracing.Middleware runs first, so logs can be matched with traces
+			if traceId := tracing.TraceID(ctx); traceId != "" {
+				l = l.WithField("trace_id", traceId)
+			}
+			ctx = logging.WithLogger(ctx, l)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// records status and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// keeps streaming responses working through the recorder
+func (rec *responseRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Logs every request once it is served, with status, latency, bytes written and user.
+// Runs after RequestID so entries carry the request id.
+func AccessLog(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		l.Info("Request at ", r.Method, " ", r.URL.Path)
-		next.ServeHTTP(w, r)
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		fields := logrus.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      rec.status,
+			"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"bytes":       rec.bytes,
+			"remote_ip":   clientIP(r),
+			"user_agent":  r.UserAgent(),
+		}
+		// set by AuthMiddleware in the shared route variables
+		if user := mux.Vars(r)[KeyUser]; user != "" {
+			fields["user_id"] = user
+		}
+		entry := logging.ForRequest(r, logrus.StandardLogger()).WithFields(fields)
+		//health probes arrive every few seconds and would drown out other requests
+		if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			entry.Debug("request served")
+			return
+		}
+		entry.Info("request served")
 	})
 }
@@ -0,0 +1,282 @@
+package middlewares
+
+import (
+	"backend/logging"
+	"backend/utils"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Allows Requests per Window
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Parses a limit written as <requests>/<window>, e.g. 100/1m
+func ParseRateLimit(s string) (RateLimit, error) {
+	reqs, window, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("rate limit %q is not of the form <requests>/<window>", s)
+	}
+	n, err := strconv.Atoi(reqs)
+	if err != nil || n < 1 {
+		return RateLimit{}, fmt.Errorf("invalid request count in rate limit %q", s)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid window in rate limit %q", s)
+	}
+	return RateLimit{n, d}, nil
+}
+
+// Parses a limit written as <requests>/<window>, so limits can be read from config
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	limit, err := ParseRateLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// Writes the limit as <requests>/<window>
+func (l RateLimit) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d/%s", l.Requests, l.Window)), nil
+}
+
+// Outcome of counting a request against a limit
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// time until the full limit is available again
+	Reset time.Duration
+}
+
+// Counts requests per key
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// sliding window log: timestamps of requests within the window are kept in a sorted set
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, limit - count, reset}
+`)
+
+// Sliding window limits kept in redis, shared by every replica
+type RedisRateLimitStore struct {
+	rdb *redis.Client
+}
+
+// Creates a new instance of RedisRateLimitStore
+func NewRedisRateLimitStore(rdb *redis.Client) *RedisRateLimitStore {
+	return &RedisRateLimitStore{rdb}
+}
+
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	now := time.Now().UnixMilli()
+	res, err := slidingWindowScript.Run(ctx, s.rdb, []string{"ratelimit:" + key},
+		now, limit.Window.Milliseconds(), limit.Requests, strconv.FormatInt(now, 10)+"-"+uuid.NewString()).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(res) != 3 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", res)
+	}
+	return RateLimitResult{
+		Allowed:   res[0] == 1,
+		Remaining: int(res[1]),
+		Reset:     time.Duration(res[2]) * time.Millisecond,
+	}, nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Token buckets kept in memory, limiting each replica on its own
+type LocalRateLimitStore struct {
+	mu          sync.Mutex
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+// Creates a new instance of LocalRateLimitStore
+func NewLocalRateLimitStore() *LocalRateLimitStore {
+	return &LocalRateLimitStore{buckets: map[string]*tokenBucket{}, lastCleanup: time.Now()}
+}
+
+func (s *LocalRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	capacity := float64(limit.Requests)
+	// tokens refilled per second
+	rate := capacity / limit.Window.Seconds()
+	s.cleanup(now, limit.Window)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(b.tokens),
+		Reset:     time.Duration((capacity - b.tokens) / rate * float64(time.Second)),
+	}, nil
+}
+
+// drops buckets that have been full for a while, at most once per window
+func (s *LocalRateLimitStore) cleanup(now time.Time, window time.Duration) {
+	if now.Sub(s.lastCleanup) < window {
+		return
+	}
+	s.lastCleanup = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > window {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Uses the primary store, falling back to the other one while the primary fails
+type FallbackRateLimitStore struct {
+	primary  RateLimitStore
+	fallback RateLimitStore
+}
+
+// Creates a new instance of FallbackRateLimitStore
+func NewFallbackRateLimitStore(primary, fallback RateLimitStore) *FallbackRateLimitStore {
+	return &FallbackRateLimitStore{primary, fallback}
+}
+
+func (s *FallbackRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	res, err := s.primary.Take(ctx, key, limit)
+	if err == nil {
+		return res, nil
+	}
+	logging.FromContext(ctx, logrus.StandardLogger()).WithError(err).Warn("Rate limit store failed, using fallback")
+	return s.fallback.Take(ctx, key, limit)
+}
+
+// Limits requests per client: the logged in user, else the client IP.
+// Routes can be given their own limit, counted apart from the default one.
+type RateLimiter struct {
+	store     RateLimitStore
+	def       RateLimit
+	overrides map[string]RateLimit
+}
+
+// Creates a new rate limiter allowing def to every client on routes without an override
+func NewRateLimiter(store RateLimitStore, def RateLimit) *RateLimiter {
+	return &RateLimiter{store: store, def: def, overrides: map[string]RateLimit{}}
+}
+
+// Give a route its own limit. Routes are named by method and path template, e.g.
+// "POST /dashboard/import".
+func (rl *RateLimiter) Override(route string, limit RateLimit) {
+	rl.overrides[route] = limit
+}
+
+// Parses overrides written as <method> <path>=<limit> separated by semicolons, e.g.
+// "POST /dashboard/import=5/1m;GET /search=30/1m"
+func (rl *RateLimiter) ParseOverrides(s string) error {
+	for _, entry := range strings.Split(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, limit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("rate limit override %q is not of the form <method> <path>=<limit>", entry)
+		}
+		parsed, err := ParseRateLimit(limit)
+		if err != nil {
+			return err
+		}
+		rl.Override(strings.Join(strings.Fields(route), " "), parsed)
+	}
+	return nil
+}
+
+// Middleware counting each request against the limit of its client and route. It must run
+// after AuthMiddleware for requests to be counted per user.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, limit := "default", rl.def
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				name := r.Method + " " + tmpl
+				if override, ok := rl.overrides[name]; ok {
+					scope, limit = name, override
+				}
+			}
+		}
+
+		res, err := rl.store.Take(r.Context(), scope+":"+rateLimitKey(r), limit)
+		if err != nil {
+			// better to serve than to fail every request while counting is down
+			logging.ForRequest(r, logrus.StandardLogger()).WithError(err).Error("Could not count request against rate limit")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reset := int(math.Ceil(res.Reset.Seconds()))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(reset))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Window.Seconds())))
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(reset))
+			utils.WriteError(w, r, utils.ErrRateLimited)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// identity of the client making the request. Only what the server vouches for counts,
+// anything else the client sends could change on every request to get a fresh limit.
+func rateLimitKey(r *http.Request) string {
+	if user := mux.Vars(r)[KeyUser]; user != "" {
+		return "user:" + user
+	}
+	return "ip:" + clientIP(r)
+}
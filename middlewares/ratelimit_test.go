@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	cases := []struct {
+		in   string
+		want RateLimit
+	}{
+		{"100/1m", RateLimit{100, time.Minute}},
+		{" 5/30s ", RateLimit{5, 30 * time.Second}},
+		{"1/1h30m", RateLimit{1, 90 * time.Minute}},
+	}
+	for _, c := range cases {
+		got, err := ParseRateLimit(c.in)
+		if err != nil {
+			t.Errorf("ParseRateLimit(%q): %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseRateLimit(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+
+	for _, in := range []string{"", "100", "100/", "/1m", "0/1m", "-1/1m", "ten/1m", "10/0s", "10/-1m", "10/minute"} {
+		if _, err := ParseRateLimit(in); err == nil {
+			t.Errorf("ParseRateLimit(%q) succeeded", in)
+		}
+	}
+}
+
+func TestRateLimitText(t *testing.T) {
+	var l RateLimit
+	err := l.UnmarshalText([]byte("20/1m0s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := l.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "20/1m0s" {
+		t.Errorf("round trip gave %q", text)
+	}
+}
+
+func TestLocalRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalRateLimitStore()
+	limit := RateLimit{2, time.Minute}
+
+	allowed, remaining := []bool{true, true, false}, []int{1, 0, 0}
+	for i := range allowed {
+		res, err := s.Take(ctx, "a", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != allowed[i] || res.Remaining != remaining[i] {
+			t.Fatalf("request %d: allowed=%v with %d remaining, want %v with %d", i+1, res.Allowed, res.Remaining, allowed[i], remaining[i])
+		}
+		if res.Reset <= 0 || res.Reset > limit.Window {
+			t.Errorf("request %d resets in %s", i+1, res.Reset)
+		}
+	}
+
+	//keys are counted apart
+	res, err := s.Take(ctx, "b", limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Allowed {
+		t.Error("another key was limited")
+	}
+}
+
+func TestLocalRateLimitStoreRefills(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalRateLimitStore()
+	limit := RateLimit{1, 20 * time.Millisecond}
+
+	res, _ := s.Take(ctx, "a", limit)
+	if !res.Allowed {
+		t.Fatal("first request limited")
+	}
+	res, _ = s.Take(ctx, "a", limit)
+	if res.Allowed {
+		t.Fatal("second request within the window allowed")
+	}
+	time.Sleep(40 * time.Millisecond)
+	res, _ = s.Take(ctx, "a", limit)
+	if !res.Allowed {
+		t.Error("request after the window limited")
+	}
+}
+
+func TestRateLimiterIgnoresClientHeaders(t *testing.T) {
+	rl := NewRateLimiter(NewLocalRateLimitStore(), RateLimit{2, time.Minute})
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	codes := []int{}
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/public/shares/token", nil)
+		r.RemoteAddr = "203.0.113.7:4321"
+		//a header the client makes up cannot buy a fresh limit
+		r.Header.Set("X-API-Key", strconv.Itoa(i))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("answered %v, want the third request limited", codes)
+	}
+}
@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Range of addresses, read from config in CIDR notation such as 172.16.0.0/12. A bare
+// address stands for itself.
+type IPNet struct {
+	*net.IPNet
+}
+
+func (n *IPNet) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("%q is not an address or CIDR range", s)
+		}
+		bits := 8 * net.IPv4len
+		if ip.To4() == nil {
+			bits = 8 * net.IPv6len
+		}
+		s = fmt.Sprintf("%s/%d", s, bits)
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return fmt.Errorf("%q is not an address or CIDR range", s)
+	}
+	n.IPNet = ipNet
+	return nil
+}
+
+func (n IPNet) MarshalText() ([]byte, error) {
+	if n.IPNet == nil {
+		return nil, nil
+	}
+	return []byte(n.String()), nil
+}
+
+// Middleware taking the client address from X-Real-IP, as set by nginx, when the request
+// comes from one of the trusted proxies. Anyone else could send a new address with every
+// request, so their own address is kept.
+func RealIP(trusted []IPNet) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := net.ParseIP(r.Header.Get("X-Real-IP")); ip != nil && trustedProxy(net.ParseIP(clientIP(r)), trusted) {
+				r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func trustedProxy(ip net.IP, trusted []IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.IPNet != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// address of the client, the one nginx vouched for once RealIP has run
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRealIP(t *testing.T) {
+	proxy := IPNet{}
+	if err := proxy.UnmarshalText([]byte("172.16.0.0/12")); err != nil {
+		t.Fatal(err)
+	}
+	rl := NewRateLimiter(NewLocalRateLimitStore(), RateLimit{1, time.Minute})
+	h := RealIP([]IPNet{proxy})(rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	send := func(remote, realIP string) int {
+		r := httptest.NewRequest(http.MethodGet, "/public/shares/token", nil)
+		r.RemoteAddr = remote
+		r.Header.Set("X-Real-IP", realIP)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	//callers reaching the service directly cannot pick their address
+	if send("203.0.113.7:4321", "198.51.100.1") != http.StatusOK || send("203.0.113.7:4321", "198.51.100.2") != http.StatusTooManyRequests {
+		t.Error("X-Real-IP of an untrusted caller bought a fresh limit")
+	}
+	//clients behind the proxy are told apart
+	if send("172.18.0.5:5555", "198.51.100.3") != http.StatusOK || send("172.18.0.5:5555", "198.51.100.4") != http.StatusOK {
+		t.Error("clients behind the proxy share a limit")
+	}
+	if send("172.18.0.5:5555", "198.51.100.3") != http.StatusTooManyRequests {
+		t.Error("client behind the proxy was not limited")
+	}
+	if send("172.18.0.5:5555", "not an address") != http.StatusOK || send("172.18.0.5:5555", "") != http.StatusTooManyRequests {
+		t.Error("invalid X-Real-IP was not ignored")
+	}
+}
+
+func TestIPNetUnmarshal(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"10.0.0.0/8", "10.0.0.0/8"},
+		{" 192.168.1.10 ", "192.168.1.10/32"},
+		{"::1", "::1/128"},
+	}
+	for _, tt := range tests {
+		n := IPNet{}
+		err := n.UnmarshalText([]byte(tt.in))
+		if err != nil || n.String() != tt.want {
+			t.Errorf("%q: got %v, %v, want %s", tt.in, n.IPNet, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "nginx", "10.0.0.0/33"} {
+		if err := (&IPNet{}).UnmarshalText([]byte(in)); err == nil {
+			t.Errorf("%q parsed without error", in)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package openapi
+
+import (
+	"backend/health"
+	"backend/utils"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const jsonType = "application/json"
+
+// Name of the shared response every operation fails with
+const errorResponse = "Error"
+
+// A parameter of a route read from the query string or a header
+type Param struct {
+	Name        string
+	Description string
+	//string when empty, otherwise integer or boolean
+	Type     string
+	Enum     []string
+	Required bool
+}
+
+// A route as registered on the router. Bodies and responses are given as values of the
+// Go types handlers decode and write, their schemas are derived from them.
+type Route struct {
+	Method string
+	//path template as given to mux, such as /dashboard/{id}
+	Path    string
+	ID      string
+	Summary string
+	Tag     string
+	//served without a user token
+	Public  bool
+	Query   []Param
+	Headers []Param
+	//request body decoded by the handler, none when nil
+	Body interface{}
+	//body may be left out
+	OptionalBody bool
+	//status of success responses, 200 when zero
+	Status int
+	//data of the success envelope, none when nil
+	Data interface{}
+	//meta of the success envelope, none when nil
+	Meta interface{}
+	//response served as is rather than in the success envelope
+	Raw bool
+	//media type of raw responses, JSON when empty
+	ContentType string
+}
+
+var pathParam = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// Adds the operations of routes. Adding an operation or an operation id twice panics,
+// routes are static so this is a programming error.
+func (d *Document) Add(routes ...Route) {
+	for _, rt := range routes {
+		path := pathParam.ReplaceAllString(rt.Path, "{$1}")
+		item, ok := d.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			d.Paths[path] = item
+		}
+		method := strings.ToLower(rt.Method)
+		if _, ok := (*item)[method]; ok {
+			panic(fmt.Sprintf("openapi: %s %s added twice", rt.Method, path))
+		}
+		if d.operationIDs[rt.ID] {
+			panic(fmt.Sprintf("openapi: operation id %s used twice", rt.ID))
+		}
+		d.operationIDs[rt.ID] = true
+		(*item)[method] = d.operation(rt, path)
+	}
+}
+
+func (d *Document) operation(rt Route, path string) *Operation {
+	op := &Operation{
+		OperationID: rt.ID,
+		Summary:     rt.Summary,
+		Responses:   map[string]*Response{},
+		Security:    []map[string][]string{},
+	}
+	if rt.Tag != "" {
+		op.Tags = []string{rt.Tag}
+	}
+	if !rt.Public {
+		op.Security = append(op.Security, map[string][]string{bearerScheme: {}})
+	}
+
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, &Parameter{Name: m[1], In: "path", Required: true, Schema: pathSchema(m[1])})
+	}
+	for _, p := range rt.Query {
+		op.Parameters = append(op.Parameters, param(p, "query"))
+	}
+	for _, p := range rt.Headers {
+		op.Parameters = append(op.Parameters, param(p, "header"))
+	}
+
+	if rt.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: !rt.OptionalBody,
+			Content:  map[string]*MediaType{jsonType: {Schema: d.schemas.of(rt.Body)}},
+		}
+	}
+
+	status := rt.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = d.successResponse(rt, status)
+	op.Responses["default"] = &Response{Ref: "#/components/responses/" + errorResponse}
+	return op
+}
+
+// ids are uuids, other path parameters such as share tokens are opaque strings
+func pathSchema(name string) *Schema {
+	if name == "id" || strings.HasSuffix(name, "Id") {
+		return &Schema{Type: "string", Format: "uuid"}
+	}
+	return &Schema{Type: "string"}
+}
+
+func param(p Param, in string) *Parameter {
+	typ := p.Type
+	if typ == "" {
+		typ = "string"
+	}
+	return &Parameter{
+		Name:        p.Name,
+		In:          in,
+		Description: p.Description,
+		Required:    p.Required,
+		Schema:      &Schema{Type: typ, Enum: p.Enum},
+	}
+}
+
+// Response of a route that succeeded, in the envelope written by utils.WriteSuccessResponse
+// unless the route is raw
+func (d *Document) successResponse(rt Route, status int) *Response {
+	res := &Response{Description: http.StatusText(status)}
+	if rt.Raw {
+		contentType := rt.ContentType
+		if contentType == "" {
+			contentType = jsonType
+		}
+		schema := d.schemas.of(rt.Data)
+		if schema == nil {
+			schema = &Schema{Type: "string"}
+		}
+		res.Content = map[string]*MediaType{contentType: {Schema: schema}}
+		return res
+	}
+
+	envelope := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+		},
+		Required: []string{"success"},
+	}
+	if rt.Data != nil {
+		envelope.Properties["data"] = d.schemas.of(rt.Data)
+	} else {
+		envelope.Properties["message"] = &Schema{Type: "string"}
+	}
+	if rt.Meta != nil {
+		envelope.Properties["meta"] = d.schemas.of(rt.Meta)
+	}
+	res.Content = map[string]*MediaType{jsonType: {Schema: envelope}}
+	return res
+}
+
+// Failure envelope written by utils.WriteError, or the bare problem for clients accepting
+// problem details
+func (d *Document) errorResponse() *Response {
+	problem := d.schemas.of(utils.Problem{})
+	failure := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"error":   {Type: "string"},
+			"code":    {Type: "string"},
+			"problem": problem,
+		},
+		Required: []string{"success", "error", "code"},
+	}
+	return &Response{
+		Description: "Error, see code for the reason",
+		Content: map[string]*MediaType{
+			jsonType:                 {Schema: failure},
+			utils.ProblemContentType: {Schema: problem},
+		},
+	}
+}
+
+// Routes every service serves next to its API, without a token
+func ServiceRoutes() []Route {
+	return []Route{
+		{Method: http.MethodGet, Path: "/healthz", ID: "Live", Tag: "Service", Public: true, Summary: "Liveness of the service",
+			Data: health.Report{}, Raw: true},
+		{Method: http.MethodGet, Path: "/readyz", ID: "Ready", Tag: "Service", Public: true, Summary: "Readiness of the service and each of its dependencies, 503 when one is down",
+			Data: health.Report{}, Raw: true},
+		{Method: http.MethodGet, Path: "/metrics", ID: "Metrics", Tag: "Service", Public: true, Summary: "Prometheus metrics",
+			Raw: true, ContentType: "text/plain"},
+		{Method: http.MethodGet, Path: "/openapi.json", ID: "OpenAPI", Tag: "Service", Public: true, Summary: "This document",
+			Raw: true, Data: map[string]interface{}{}},
+	}
+}
+
+// Operations served by router as "METHOD path", in the form of Document.Operations. Methods
+// set on subrouters apply to the routes below them.
+func RouterOperations(router *mux.Router) ([]string, error) {
+	var ops []string
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, _ := route.GetMethods()
+		for i := len(ancestors) - 1; len(methods) == 0 && i >= 0; i-- {
+			methods, _ = ancestors[i].GetMethods()
+		}
+		if len(methods) == 0 {
+			return fmt.Errorf("route %s matches any method", tmpl)
+		}
+		for _, m := range methods {
+			ops = append(ops, m+" "+pathParam.ReplaceAllString(tmpl, "{$1}"))
+		}
+		return nil
+	})
+	sort.Strings(ops)
+	return ops, err
+}
@@ -0,0 +1,214 @@
+package openapi
+
+import (
+	"backend/utils"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	uuidType       = reflect.TypeOf(uuid.UUID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Derives schemas from Go types the way encoding/json serialises them. Named structs are
+// added to the components once and referenced from then on.
+type schemaGen struct {
+	schemas map[string]*Schema
+	//type behind each component, two types must not share a name
+	types map[string]reflect.Type
+}
+
+// Schema of values of v's type, nil when v is nil
+func (g *schemaGen) of(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *schemaGen) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	default:
+		//interfaces hold anything
+		return &Schema{}
+	}
+}
+
+// Reference to the component of the named struct t, adding it on first use
+func (g *schemaGen) ref(t reflect.Type) *Schema {
+	name := componentName(t)
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if seen, ok := g.types[name]; ok {
+		if seen != t {
+			panic(fmt.Sprintf("openapi: %s and %s are both named %s", seen, t, name))
+		}
+		return ref
+	}
+	//registered before its fields so types referring to themselves end up as refs
+	g.types[name] = t
+	g.schemas[name] = g.object(t)
+	return ref
+}
+
+// Unexported request types get the same names as exported ones
+func componentName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+// Object schema of the struct t with its exported fields. Fields of embedded structs
+// are inlined as encoding/json does.
+func (g *schemaGen) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *schemaGen) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.SplitN(tag, ",", 2)[0]
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.addFields(s, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := g.schema(f.Type)
+		if applyRules(fs, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// Sets the constraints of validate rules on s, returning whether the field is required.
+// Rules after dive apply to the items of s. References are left as they are since
+// OpenAPI 3.0 ignores keywords next to them.
+func applyRules(s *Schema, rules string) bool {
+	if rules == "" || s.Ref != "" {
+		return strings.HasPrefix(rules, "required")
+	}
+
+	required := false
+	parts := strings.Split(rules, ",")
+	for i, rule := range parts {
+		name, param := rule, ""
+		if j := strings.IndexByte(rule, '='); j >= 0 {
+			name, param = rule[:j], rule[j+1:]
+		}
+		switch name {
+		case "dive":
+			if s.Items != nil {
+				applyRules(s.Items, strings.Join(parts[i+1:], ","))
+			}
+			return required
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "uuid4":
+			s.Format = "uuid"
+		case "name":
+			setBound(s, "min", 1)
+			setBound(s, "max", utils.MaxNameLength)
+		case "oneof":
+			s.Enum = strings.Fields(param)
+		case "min", "max":
+			n, err := strconv.Atoi(param)
+			if err == nil {
+				setBound(s, name, n)
+			}
+		}
+	}
+	return required
+}
+
+// Sets a min or max bound on lengths, item counts or values depending on the type of s
+func setBound(s *Schema, which string, n int) {
+	min := which == "min"
+	switch s.Type {
+	case "string":
+		if min {
+			s.MinLength = &n
+		} else {
+			s.MaxLength = &n
+		}
+	case "array":
+		if min {
+			s.MinItems = &n
+		} else {
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		f := float64(n)
+		if min {
+			s.Minimum = &f
+		} else {
+			s.Maximum = &f
+		}
+	}
+}
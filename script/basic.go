@@ -1,263 +1,214 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	authEr "backend/auth/errors"
+	"backend/client"
+	er "backend/dashboard/errors"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
+	"os"
+	"time"
 
 	fake "github.com/brianvoe/gofakeit/v6"
+	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
-var resMap = map[string]any{}
-
-func prettyPrint(src []byte) {
-	dst := &bytes.Buffer{}
-	if err := json.Indent(dst, src, "", "  "); err != nil {
-		panic(err)
-	}
-
-	fmt.Println(dst.String())
-}
-
-func apiCall(method, url, strJson, token string) error {
-	log.Info(method + " " + url)
-	log.Info("Payload: ", strJson)
-	data := []byte(strJson)
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(data))
-	if err != nil {
-		return err
-	}
-	if token != "" {
-		req.Header.Set("Authorization", token)
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	log.Info("Status: ", resp.Status)
-	prettyPrint(body)
-	return json.Unmarshal(body, &resMap)
+// A step of the end-to-end run. It passes when run fails with want, or succeeds when want
+// is nil.
+type scenario struct {
+	name string
+	run  func(ctx context.Context) error
+	want error
 }
 
 func main() {
-	log.Info("Creating a new user")
-
-	email := fake.Email()
-	var jsonData = fmt.Sprintf(`{"username":"%s","password":"password","email":"%s"}`, fake.Name(), email)
-	err := apiCall("POST", "http://localhost:8080/register", jsonData, "")
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("Logging in as the new user")
-
-	jsonData = fmt.Sprintf(`{"email":"%s","password":"password"}`, email)
-	err = apiCall("POST", "http://localhost:8080/login", jsonData, "")
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	userId := resMap["data"].(map[string]interface{})["id"].(string)
-	jwt := resMap["data"].(map[string]interface{})["token"].(string)
-
-	log.Info("Logging in with an invalid password")
-	jsonData = fmt.Sprintf(`{"email":"%s","password":"pasword"}`, email)
-	err = apiCall("POST", "http://localhost:8080/login", jsonData, "")
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("Creating a new dashboard")
-	jsonData = fmt.Sprintf(`{"name":"%s"}`, fake.PetName())
-	err = apiCall("POST", "http://localhost:8080/dashboard", jsonData, jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	dashId := resMap["data"].(map[string]interface{})["id"].(string)
-
-	log.Info("See my for dashboard. Should have one admin with id " + userId)
-	err = apiCall("GET", "http://localhost:8080/dashboard/"+dashId+"/users", "", jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("Creating a new user for further tests")
-	email = fake.Email()
-	jsonData = fmt.Sprintf(`{"username":"%s","password":"password","email":"%s"}`, fake.Name(), email)
-	err = apiCall("POST", "http://localhost:8080/register", jsonData, "")
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	userId2 := resMap["data"].(map[string]interface{})["id"].(string)
-	userId2Jwt := resMap["data"].(map[string]interface{})["token"].(string)
-
-	log.Info("Accessing dashboard with new user. Should fail.")
-	err = apiCall("GET", "http://localhost:8080/dashboard/"+dashId, "", userId2Jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("Accessing dashboard with owner user. Should succeed.")
-	err = apiCall("GET", "http://localhost:8080/dashboard/"+dashId, "", jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("Adding new user to dashboard with viewer role. Should succeed.")
-	jsonData = fmt.Sprintf(`{"userId":"%s","role":"viewer"}`, userId2)
-	err = apiCall("POST", "http://localhost:8080/dashboard/"+dashId+"/users", jsonData, jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("See my roles for dashboard again. Should have new user " + userId + " as viewer")
-	err = apiCall("GET", "http://localhost:8080/dashboard/"+dashId+"/users", "", jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("Create new view to attach to dashboard")
-	jsonData = fmt.Sprintf(`{"dashboardId":"%s","name":"%s","description":"sample descriptions"}`, dashId, fake.PetName())
-	err = apiCall("POST", "http://localhost:8080/view", jsonData, jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	viewId := resMap["data"].(map[string]interface{})["id"].(string)
-
-	log.Info("See my dasboard again. Should have a view now.")
-	err = apiCall("GET", "http://localhost:8080/dashboard/"+dashId, "", jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("User2 has access to dashboard but not this view. User2 will not see the view.")
-	err = apiCall("GET", "http://localhost:8080/dashboard/"+dashId, "", userId2Jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("Grating view role to user2")
-	jsonData = fmt.Sprintf(`{"userId":"%s","role":"viewer"}`, userId2)
-	err = apiCall("POST", "http://localhost:8080/view/"+viewId+"/users", jsonData, jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("User2 has access to dashboard and this view. User2 will see the view.")
-	err = apiCall("GET", "http://localhost:8080/dashboard/"+dashId, "", userId2Jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	log.Info("Viewing all possible roles")
-	err = apiCall("GET", "http://localhost:8080/roles", "", jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
+	baseURL := flag.String("base-url", "http://localhost:8080", "Gateway the services are reached through")
+	timeout := flag.Duration("timeout", 2*time.Minute, "Time allowed for the whole run")
+	flag.Parse()
 
-	log.Info("Editing dashboard with new user. Should fail as new user is viewer.")
-	jsonData = `{"name":"new name", "description":"now has descriptions" }`
-	err = apiCall("PUT", "http://localhost:8080/dashboard/"+dashId, jsonData, userId2Jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
 
-	log.Info("Editing dashboard with admin user. Should succeed.")
-	jsonData = `{"name":"new name", "description":"now has descriptions" }`
-	err = apiCall("PUT", "http://localhost:8080/dashboard/"+dashId, jsonData, jwt)
-	if err != nil {
-		log.Error(err)
-		return
+	failed := run(ctx, scenarios(*baseURL))
+	if failed {
+		os.Exit(1)
 	}
+}
 
-	log.Info("Changing user2 role for dashboard to editor. Should succeed.")
-	jsonData = fmt.Sprintf(`{"userId":"%s","role":"editor"}`, userId2)
-	err = apiCall("POST", "http://localhost:8080/dashboard/"+dashId+"/users", jsonData, jwt)
-	if err != nil {
-		log.Error(err)
-		return
-	}
+// Runs the scenarios in order, stopping at the first one that fails as the ones after it
+// build on its outcome. Reports whether one failed.
+func run(ctx context.Context, scenarios []scenario) bool {
+	for i, s := range scenarios {
+		err := s.run(ctx)
+		switch {
+		case s.want == nil && err != nil:
+			log.Errorf("FAIL %s: %v", s.name, err)
+		case s.want != nil && !errors.Is(err, s.want):
+			log.Errorf("FAIL %s: expected %v, got %v", s.name, s.want, err)
+		default:
+			log.Infof("PASS %s", s.name)
+			continue
+		}
+		log.Warnf("Skipped the %d scenarios left", len(scenarios)-i-1)
+		return true
+	}
+	log.Infof("All %d scenarios passed", len(scenarios))
+	return false
+}
 
-	log.Info("Editing dashboard with new user. Should succeed as new user is editor.")
-	jsonData = `{"name":"from user 2", "description":"from user 2" }`
-	err = apiCall("PUT", "http://localhost:8080/dashboard/"+dashId, jsonData, userId2Jwt)
-	if err != nil {
-		log.Error(err)
-		return
+func scenarios(baseURL string) []scenario {
+	owner := client.New(baseURL)
+	other := client.New(baseURL)
+	ownerEmail := fake.Email()
+
+	var ownerId, otherId, dashId, viewId uuid.UUID
+
+	return []scenario{
+		{name: "Create a new user", run: func(ctx context.Context) error {
+			_, err := owner.Register(ctx, fake.Name(), ownerEmail, "password")
+			return err
+		}},
+		{name: "Log in as the new user", run: func(ctx context.Context) error {
+			var err error
+			ownerId, err = owner.Login(ctx, ownerEmail, "password")
+			return err
+		}},
+		{name: "Log in with an invalid password", want: authEr.ErrInvalidCredentials, run: func(ctx context.Context) error {
+			_, err := client.New(baseURL).Login(ctx, ownerEmail, "pasword")
+			return err
+		}},
+		{name: "Create a new dashboard", run: func(ctx context.Context) error {
+			dash, err := owner.CreateDash(ctx, client.DashInput{Name: fake.PetName()})
+			dashId = dash.ID
+			return err
+		}},
+		{name: "The dashboard has its creator as only admin", run: func(ctx context.Context) error {
+			return expectRoles(ctx, owner, dashId, map[uuid.UUID]string{ownerId: "admin"})
+		}},
+		{name: "Create a second user", run: func(ctx context.Context) error {
+			var err error
+			otherId, err = other.Register(ctx, fake.Name(), fake.Email(), "password")
+			return err
+		}},
+		{name: "The second user cannot read the dashboard", want: er.ErrNoPerm, run: func(ctx context.Context) error {
+			_, err := other.GetDash(ctx, dashId)
+			return err
+		}},
+		{name: "The owner can read the dashboard", run: func(ctx context.Context) error {
+			_, err := owner.GetDash(ctx, dashId)
+			return err
+		}},
+		{name: "Make the second user viewer of the dashboard", run: func(ctx context.Context) error {
+			return owner.AddUserToDash(ctx, dashId, client.Grant{UserID: otherId, Role: "viewer"})
+		}},
+		{name: "The dashboard has the second user as viewer", run: func(ctx context.Context) error {
+			return expectRoles(ctx, owner, dashId, map[uuid.UUID]string{ownerId: "admin", otherId: "viewer"})
+		}},
+		{name: "Add a view to the dashboard", run: func(ctx context.Context) error {
+			view, err := owner.CreateView(ctx, client.ViewInput{DashboardID: dashId, Name: fake.PetName(), Description: "sample descriptions"})
+			viewId = view.ID
+			return err
+		}},
+		{name: "The owner sees the view on the dashboard", run: func(ctx context.Context) error {
+			return expectViews(ctx, owner, dashId, 1)
+		}},
+		{name: "The second user does not see the view without a role on it", run: func(ctx context.Context) error {
+			return expectViews(ctx, other, dashId, 0)
+		}},
+		{name: "Make the second user viewer of the view", run: func(ctx context.Context) error {
+			return owner.AddUserToView(ctx, viewId, client.Grant{UserID: otherId, Role: "viewer"})
+		}},
+		{name: "The second user sees the view", run: func(ctx context.Context) error {
+			return expectViews(ctx, other, dashId, 1)
+		}},
+		{name: "List all roles", run: func(ctx context.Context) error {
+			roles, err := owner.GetRoles(ctx)
+			if err == nil && len(roles) == 0 {
+				return errors.New("no roles")
+			}
+			return err
+		}},
+		{name: "A viewer cannot edit the dashboard", want: er.ErrNoPerm, run: func(ctx context.Context) error {
+			_, err := other.UpdateDash(ctx, dashId, client.DashInput{Name: "new name", Description: "now has descriptions"})
+			return err
+		}},
+		{name: "The admin can edit the dashboard", run: func(ctx context.Context) error {
+			_, err := owner.UpdateDash(ctx, dashId, client.DashInput{Name: "new name", Description: "now has descriptions"})
+			return err
+		}},
+		{name: "Make the second user editor of the dashboard", run: func(ctx context.Context) error {
+			return owner.AddUserToDash(ctx, dashId, client.Grant{UserID: otherId, Role: "editor"})
+		}},
+		{name: "An editor can edit the dashboard", run: func(ctx context.Context) error {
+			_, err := other.UpdateDash(ctx, dashId, client.DashInput{Name: "from user 2", Description: "from user 2"})
+			return err
+		}},
+		{name: "The last admin cannot be removed", want: er.ErrCannotRevokeLastAdmin, run: func(ctx context.Context) error {
+			return owner.RemoveUserFromDash(ctx, dashId, ownerId)
+		}},
+		{name: "Make the second user admin of the dashboard", run: func(ctx context.Context) error {
+			return owner.AddUserToDash(ctx, dashId, client.Grant{UserID: otherId, Role: "admin"})
+		}},
+		{name: "The first admin can be removed once there is another", run: func(ctx context.Context) error {
+			return owner.RemoveUserFromDash(ctx, dashId, ownerId)
+		}},
+		{name: "The removed admin cannot read the dashboard", want: er.ErrNoPerm, run: func(ctx context.Context) error {
+			_, err := owner.GetDash(ctx, dashId)
+			return err
+		}},
+		{name: "The second user creates a dashboard of their own", run: func(ctx context.Context) error {
+			_, err := other.CreateDash(ctx, client.DashInput{Name: fake.PetName(), Description: "sample descriptions"})
+			return err
+		}},
+		{name: "The second user adds a view to the first dashboard", run: func(ctx context.Context) error {
+			_, err := other.CreateView(ctx, client.ViewInput{DashboardID: dashId, Name: fake.PetName(), Description: "sample descriptions"})
+			return err
+		}},
+		{name: "The second user lists both dashboards", run: func(ctx context.Context) error {
+			page, err := other.ListDashboards(ctx, client.ListOptions{})
+			if err != nil {
+				return err
+			}
+			if page.Total != 2 {
+				return fmt.Errorf("expected 2 dashboards, got %d", page.Total)
+			}
+			return nil
+		}},
 	}
+}
 
-	log.Info("Trying to delete the admin account. Should fail.")
-	jsonData = fmt.Sprintf(`{"userId":"%s"}`, userId)
-	err = apiCall("DELETE", "http://localhost:8080/dashboard/"+dashId+"/users", jsonData, jwt)
+// Checks the members of a dashboard have exactly the given roles
+func expectRoles(ctx context.Context, c *client.Client, dashId uuid.UUID, want map[uuid.UUID]string) error {
+	roles, err := c.GetDashUsers(ctx, dashId)
 	if err != nil {
-		log.Error(err)
-		return
+		return err
 	}
-
-	log.Info("Making user 2 admin of dashboard. Should succeed.")
-	jsonData = fmt.Sprintf(`{"userId":"%s","role":"admin"}`, userId2)
-	err = apiCall("POST", "http://localhost:8080/dashboard/"+dashId+"/users", jsonData, jwt)
-	if err != nil {
-		log.Error(err)
-		return
+	got := map[uuid.UUID]string{}
+	for _, role := range roles {
+		got[role.UserId] = role.Name
 	}
-
-	log.Info("Trying to delete user1 admin account now. Should succeed.")
-	jsonData = fmt.Sprintf(`{"userId":"%s"}`, userId)
-	err = apiCall("DELETE", "http://localhost:8080/dashboard/"+dashId+"/users", jsonData, jwt)
-	if err != nil {
-		log.Error(err)
-		return
+	if len(got) != len(want) {
+		return fmt.Errorf("expected roles %v, got %v", want, got)
 	}
-
-	log.Info("Creating another dashboard by user 2. Should succeed.")
-	jsonData = fmt.Sprintf(`{"name":"%s","description":"sample descriptions"}`, fake.PetName())
-	err = apiCall("POST", "http://localhost:8080/dashboard", jsonData, userId2Jwt)
-	if err != nil {
-		log.Error(err)
-		return
+	for id, name := range want {
+		if got[id] != name {
+			return fmt.Errorf("expected roles %v, got %v", want, got)
+		}
 	}
+	return nil
+}
 
-	log.Info("Adding another view to dash1 by user 2. Should succeed.")
-	jsonData = fmt.Sprintf(`{"dashboardId":"%s","name":"%s","description":"sample descriptions"}`, dashId, fake.PetName())
-	err = apiCall("POST", "http://localhost:8080/view", jsonData, userId2Jwt)
+// Checks how many views of a dashboard the client sees
+func expectViews(ctx context.Context, c *client.Client, dashId uuid.UUID, want int) error {
+	dash, err := c.GetDash(ctx, dashId)
 	if err != nil {
-		log.Error(err)
-		return
+		return err
 	}
-
-	log.Info("Getting all dashboards. Should see 2 dashboards.")
-	err = apiCall("GET", "http://localhost:8080/dashboard", "", userId2Jwt)
-	if err != nil {
-		log.Error(err)
-		return
+	if len(dash.Views) != want {
+		return fmt.Errorf("expected %d views, got %d", want, len(dash.Views))
 	}
+	return nil
 }
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// name of the tracer used for spans started by this repo
+const tracerName = "backend"
+
+// Sets up tracing for a service with the named exporter:
+//   - "otlp" sends spans over OTLP/HTTP to OTEL_EXPORTER_OTLP_ENDPOINT (localhost:4318 when unset)
+//   - "stdout" prints spans as JSON, for checking traces locally without a collector
+//   - "none" or unset records nothing, though trace context is still passed on
+//
+// The returned function flushes pending spans and must be called on shutdown.
+func Init(ctx context.Context, service, exporterName string) (func(context.Context) error, error) {
+	//W3C trace context, so traces continue across nginx and the services
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New()
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q", exporterName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(service)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Starts a span named name as a child of the span in ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// Records err on span and marks it failed
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Returns the id of the trace in ctx, or an empty string when ctx is not traced
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// Opens a postgres database whose queries are recorded as spans under the span of their context
+func OpenPostgres(dsn string) (*sql.DB, error) {
+	return otelsql.Open("postgres", dsn,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true}),
+	)
+}
+
+// Wraps base, http.DefaultTransport when nil, to record outgoing requests as spans and pass
+// the trace context on to the called service
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// Middleware starting a span for every request, named after its mux route template, which
+// continues the trace of the caller when the request carries W3C trace context. The target
+// recorded on the span has no query string and public share tokens masked, embed and share
+// tokens are credentials.
+func Middleware(service string) mux.MiddlewareFunc {
+	traced := otelmux.Middleware(service)
+	return func(next http.Handler) http.Handler {
+		return traced(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			//replaces the target otelmux took from the request uri
+			trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPTargetKey.String(target(r.URL.Path)))
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// path of a request as recorded on spans
+func target(path string) string {
+	if strings.HasPrefix(path, "/public/shares/") {
+		return "/public/shares/[REDACTED]"
+	}
+	return path
+}
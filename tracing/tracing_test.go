@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+func TestMiddlewareHidesTokens(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(prev)
+
+	r := mux.NewRouter()
+	r.Use(Middleware("test"))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	r.HandleFunc("/public/shares/{token}", ok)
+	r.HandleFunc("/public/embed", ok)
+	r.HandleFunc("/dashboard/{id}", ok)
+
+	tests := []struct {
+		uri, want string
+	}{
+		{"/public/shares/s3cret", "/public/shares/[REDACTED]"},
+		{"/public/embed?token=eyJhbGciOiJSUzI1NiJ9.e30.c2ln", "/public/embed"},
+		{"/dashboard/42?search=x", "/dashboard/42"},
+	}
+	for _, tt := range tests {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.uri, nil))
+		spans := recorder.Ended()
+		span := spans[len(spans)-1]
+		got := ""
+		for _, kv := range span.Attributes() {
+			if kv.Key == semconv.HTTPTargetKey {
+				if got != "" {
+					t.Errorf("%s: http.target recorded twice", tt.uri)
+				}
+				got = kv.Value.AsString()
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: http.target %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
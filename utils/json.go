@@ -8,10 +8,19 @@ import (
 type response struct {
 	Success  bool        `json:"success"`
 	RespData interface{} `json:"data,omitempty"`
+	Meta     interface{} `json:"meta,omitempty"`
 	Error    string      `json:"error,omitempty"`
+	Code     string      `json:"code,omitempty"`
+	Problem  *Problem    `json:"problem,omitempty"`
 	Message  string      `json:"message,omitempty"`
 }
 
+// Metadata of a paginated list. NextCursor is empty on the last page.
+type PageMeta struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int    `json:"total"`
+}
+
 func WriteSuccessResponse(w http.ResponseWriter, code int, data interface{}) error {
 	resp := response{
 		Success:  true,
@@ -22,6 +31,17 @@ func WriteSuccessResponse(w http.ResponseWriter, code int, data interface{}) err
 	return e.Encode(resp)
 }
 
+func WriteSuccessResponseWithMeta(w http.ResponseWriter, code int, data interface{}, meta interface{}) error {
+	resp := response{
+		Success:  true,
+		RespData: data,
+		Meta:     meta,
+	}
+	w.WriteHeader(code)
+	e := json.NewEncoder(w)
+	return e.Encode(resp)
+}
+
 func WriteSuccessResponseMsg(w http.ResponseWriter, code int, msg string) error {
 	resp := response{
 		Success: true,
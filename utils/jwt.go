@@ -1,8 +1,7 @@
 package utils
 
 import (
-	"log"
-	"os"
+	"errors"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -10,9 +9,21 @@ import (
 
 var (
 	jwtExpiryDuration = 4 * time.Hour
-	jwtSigningKey     = []byte(os.Getenv("JWT_SECRET_KEY"))
+	jwtSigningKey     []byte
 )
 
+// Sets the key tokens are signed and verified with, and the lifetime of login tokens.
+// Called once at startup, before any token is handled.
+func ConfigureJWT(secret string, expiry time.Duration) {
+	jwtSigningKey = []byte(secret)
+	jwtExpiryDuration = expiry
+}
+
+// Key tokens are signed and verified with
+func JWTSigningKey() []byte {
+	return jwtSigningKey
+}
+
 func GenerateJWT(subject, audience, issuer string) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
@@ -26,9 +37,44 @@ func GenerateJWT(subject, audience, issuer string) (string, error) {
 	tokenString, err := token.SignedString(jwtSigningKey)
 
 	if err != nil {
-		log.Fatalf("Error while signing token: %v", err)
 		return "", err
 	}
 
 	return tokenString, nil
 }
+
+// Generates a token for a single purpose, such as embedding a dashboard, that expires after ttl.
+// Extra claims are added to the standard ones and must not use their names.
+func GenerateScopedJWT(subject, audience, issuer string, ttl time.Duration, extra map[string]interface{}) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+	claims := token.Claims.(jwt.MapClaims)
+	for k, v := range extra {
+		claims[k] = v
+	}
+	claims["sub"] = subject
+	claims["aud"] = audience
+	claims["iss"] = issuer
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	claims["iat"] = time.Now().Unix()
+
+	return token.SignedString(jwtSigningKey)
+}
+
+// Parses a token produced by GenerateScopedJWT, checking signature, expiry, audience and issuer
+func ParseScopedJWT(tokenString, audience, issuer string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("invalid signing method")
+		}
+		return jwtSigningKey, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if !claims.VerifyAudience(audience, true) || !claims.VerifyIssuer(issuer, true) {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
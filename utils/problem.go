@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"backend/logging"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator"
+	"github.com/sirupsen/logrus"
+)
+
+// Media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// An error clients can act on, with a stable code to match on and the HTTP status it is
+// served with. Wrap one with fmt.Errorf("%w: ...") or WithDetail to say more about a
+// single occurrence, the code and status are kept.
+type APIError struct {
+	Code    string
+	Status  int
+	Message string
+}
+
+func NewAPIError(code string, status int, message string) *APIError {
+	return &APIError{Code: code, Status: status, Message: message}
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// Same error with a message specific to this occurrence
+func (e *APIError) WithDetail(format string, args ...interface{}) error {
+	return &detailedError{e, fmt.Sprintf(format, args...)}
+}
+
+type detailedError struct {
+	*APIError
+	detail string
+}
+
+func (e *detailedError) Error() string {
+	return e.detail
+}
+
+func (e *detailedError) Unwrap() error {
+	return e.APIError
+}
+
+// Errors shared by both services
+var (
+	ErrInvalidRequest = NewAPIError("invalid_request", http.StatusBadRequest, "invalid request payload")
+	ErrValidation     = NewAPIError("validation_failed", http.StatusBadRequest, "request failed validation")
+	ErrInvalidID      = NewAPIError("invalid_id", http.StatusBadRequest, "invalid id")
+	ErrInvalidQuery   = NewAPIError("invalid_query", http.StatusBadRequest, "invalid query parameter")
+	ErrMissingToken   = NewAPIError("missing_token", http.StatusUnauthorized, "no authorization token provided")
+	ErrInvalidToken   = NewAPIError("invalid_token", http.StatusUnauthorized, "invalid token")
+	ErrNotFound       = NewAPIError("not_found", http.StatusNotFound, "not found")
+	ErrRateLimited    = NewAPIError("rate_limited", http.StatusTooManyRequests, "too many requests")
+	ErrInternal       = NewAPIError("internal_error", http.StatusInternalServerError, "internal server error")
+	ErrNotImplemented = NewAPIError("not_implemented", http.StatusNotImplemented, "not implemented")
+)
+
+// RFC 7807 problem details, extended with the error code and the id of the request
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	//invalid fields of a request that failed validation
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// Finds the API error behind err. Missing rows are not found and failed validation is a bad
+// request, anything else is an internal error.
+func AsAPIError(err error) *APIError {
+	var apiErr *APIError
+	var validationErrs validator.ValidationErrors
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case errors.As(err, &validationErrs):
+		return ErrValidation
+	default:
+		return ErrInternal
+	}
+}
+
+// Builds the problem served for err. Errors that are not API errors get no detail so
+// database and other internal messages never reach clients.
+func NewProblem(r *http.Request, err error) *Problem {
+	apiErr := AsAPIError(err)
+	p := &Problem{
+		Type:      "/problems/" + apiErr.Code,
+		Title:     apiErr.Message,
+		Status:    apiErr.Status,
+		Instance:  r.URL.Path,
+		Code:      apiErr.Code,
+		RequestID: logging.RequestIDFromContext(r.Context()),
+	}
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		err = newValidationError(validationErrs)
+	}
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		p.Errors = invalid.Fields
+	}
+
+	//only messages of API errors are meant for clients
+	var wrapped *APIError
+	if errors.As(err, &wrapped) && err.Error() != apiErr.Message {
+		p.Detail = err.Error()
+	}
+	return p
+}
+
+// Writes err as a problem. Clients accepting application/problem+json get the bare problem,
+// others the usual failure envelope with the problem inside. Internal errors are logged
+// as only their code reaches the client.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) error {
+	p := NewProblem(r, err)
+	if p.Status >= http.StatusInternalServerError {
+		logging.ForRequest(r, logrus.StandardLogger()).WithError(err).Error("Request failed")
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), ProblemContentType) {
+		w.Header().Set("Content-Type", ProblemContentType)
+		w.WriteHeader(p.Status)
+		return json.NewEncoder(w).Encode(p)
+	}
+
+	msg := p.Detail
+	if msg == "" {
+		msg = p.Title
+	}
+	resp := response{
+		Success: false,
+		Error:   msg,
+		Code:    p.Code,
+		Problem: p,
+	}
+	w.WriteHeader(p.Status)
+	e := json.NewEncoder(w)
+	return e.Encode(resp)
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// Wait between attempts of Retry, doubling from Initial up to Max
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// Calls fn until it succeeds or ctx is done, waiting with exponential backoff between
+// attempts. onRetry, when set, hears of every failed attempt and the wait before the next.
+// Returns the error of the last attempt when ctx ends first.
+func Retry(ctx context.Context, b Backoff, fn func(ctx context.Context) error, onRetry func(err error, wait time.Duration)) error {
+	wait := b.Initial
+	for {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if onRetry != nil {
+			onRetry(err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		wait *= 2
+		if wait > b.Max {
+			wait = b.Max
+		}
+	}
+}
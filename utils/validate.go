@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+)
+
+// Largest request body DecodeJSON accepts
+const MaxBodyBytes = 1 << 20
+
+// Longest name of a dashboard, view, folder or template
+const MaxNameLength = 100
+
+var ErrBodyTooLarge = NewAPIError("body_too_large", http.StatusRequestEntityTooLarge, "request body is too large")
+
+// A field of a request that failed a rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Every invalid field of a request. Served as validation_failed with the fields listed.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// Single validator for all requests, it caches the rules of every struct it has seen
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	//report fields by the name clients send them under
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	//uuids are checked in their text form, the nil uuid counts as unset
+	v.RegisterCustomTypeFunc(func(f reflect.Value) interface{} {
+		id := f.Interface().(uuid.UUID)
+		if id == uuid.Nil {
+			return ""
+		}
+		return id.String()
+	}, uuid.UUID{})
+
+	v.RegisterValidation("name", func(fl validator.FieldLevel) bool {
+		name := strings.TrimSpace(fl.Field().String())
+		return name != "" && utf8.RuneCountInString(name) <= MaxNameLength
+	})
+	return v
+}
+
+// Adds a rule usable in validate tags. Rules get the context of the request being
+// validated. Must be called at startup, before any request is validated.
+func RegisterValidation(tag string, fn func(ctx context.Context, fl validator.FieldLevel) bool, message string) {
+	validate.RegisterValidationCtx(tag, fn)
+	ruleMessages[tag] = message
+}
+
+// Checks v against its validate tags, returning a ValidationError listing every invalid field
+func Validate(ctx context.Context, v interface{}) error {
+	err := validate.StructCtx(ctx, v)
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		return newValidationError(errs)
+	}
+	return err
+}
+
+func newValidationError(errs validator.ValidationErrors) *ValidationError {
+	fields := make([]FieldError, len(errs))
+	for i, fe := range errs {
+		fields[i] = FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Message: ruleMessage(fe)}
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// path of the field from the root of the request, such as layout[0].viewId
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+// messages of custom rules by tag
+var ruleMessages = map[string]string{
+	"name": fmt.Sprintf("must be 1 to %d characters", MaxNameLength),
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	if msg, ok := ruleMessages[fe.Tag()]; ok {
+		return msg
+	}
+
+	unit := "characters"
+	switch fe.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		unit = ""
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be an email address"
+	case "url":
+		return "must be a url"
+	case "uuid4":
+		return "must be a version 4 uuid"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min":
+		return strings.TrimSpace(fmt.Sprintf("must be at least %s %s", fe.Param(), unit))
+	case "max":
+		return strings.TrimSpace(fmt.Sprintf("must be at most %s %s", fe.Param(), unit))
+	default:
+		return fmt.Sprintf("failed the %s rule", fe.Tag())
+	}
+}
+
+// Decodes the JSON body of r into dst. Bodies over MaxBodyBytes, unknown fields, values of
+// the wrong type and anything after the first value are rejected with an API error.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		return decodeError(err)
+	}
+	if dec.More() {
+		return ErrInvalidRequest.WithDetail("request body must hold a single json value")
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var sizeErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrInvalidRequest.WithDetail("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrInvalidRequest.WithDetail("request body is truncated")
+	case errors.As(err, &syntaxErr):
+		return ErrInvalidRequest.WithDetail("malformed json at offset %d", syntaxErr.Offset)
+	case errors.As(err, &sizeErr):
+		return ErrBodyTooLarge.WithDetail("request body must be at most %d bytes", sizeErr.Limit)
+	case errors.As(err, &typeErr):
+		return &ValidationError{Fields: []FieldError{{Field: typeErr.Field, Rule: "type", Message: "must be " + jsonType(typeErr.Type)}}}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &ValidationError{Fields: []FieldError{{Field: field, Rule: "unknown", Message: "is not a known field"}}}
+	default:
+		//values decoding themselves, such as uuids and times, fail with their own errors
+		return ErrInvalidRequest.WithDetail("%v", err)
+	}
+}
+
+// name of the JSON type a Go value is decoded from
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// Decodes the JSON body of r into dst and validates it, writing the error response and
+// returning false when either fails
+func DecodeAndValidate(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	err := DecodeJSON(w, r, dst)
+	if err == nil {
+		err = Validate(r.Context(), dst)
+	}
+	if err != nil {
+		WriteError(w, r, err)
+		return false
+	}
+	return true
+}